@@ -6,6 +6,7 @@ import (
 
 	"auth-service/internal/config"
 	"auth-service/internal/handler"
+	"auth-service/internal/logic"
 	"auth-service/internal/svc"
 
 	"github.com/zeromicro/go-zero/core/conf"
@@ -24,8 +25,12 @@ func main() {
 	defer server.Stop()
 
 	ctx := svc.NewServiceContext(c)
+	defer ctx.Stop()
 	handler.RegisterHandlers(server, ctx)
 
+	stopLDAPSync := logic.StartLDAPSync(ctx)
+	defer stopLDAPSync()
+
 	fmt.Printf("Starting server at %s:%d...\n", c.Host, c.Port)
 	server.Start()
 }
@@ -30,49 +30,306 @@ type Config struct {
 		CachePrefix string
 	}
 
+	// 邮件发送配置 (用于验证码、密码重置等通知邮件)
+	Email EmailConfig `json:",optional"`
+
+	// 短信发送配置 (用于手机号验证码)
+	SMS SMSConfig `json:",optional"`
+
+	// 前端地址，用于生成邮件中的链接 (如 https://app.example.com，密码重置页面为 {FrontendURL}/reset-password?token=...)
+	FrontendURL string `json:",optional"`
+
+	// 邮箱验证配置
+	EmailVerification EmailVerificationConfig `json:",optional"`
+
+	// 邮箱免密登录配置
+	MagicLink MagicLinkConfig `json:",optional"`
+
 	// SSO 配置
-	SSO SSOConfig
+	SSO SSOConfig `json:",optional"`
+}
+
+// EmailConfig SMTP 邮件配置，Host 为空时不发送邮件
+type EmailConfig struct {
+	Host     string `json:",optional"`
+	Port     int    `json:",optional"`
+	Username string `json:",optional"`
+	Password string `json:",optional"`
+	From     string `json:",optional"`
+}
+
+// SMSConfig 短信网关配置，Provider 为空时不发送短信
+type SMSConfig struct {
+	Provider string `json:",optional"` // 短信网关: log (本地开发)、aliyun、tencent 或 twilio
+
+	LogFile string           `json:",optional"` // log: 验证码追加写入的文件 (为空时写入服务日志)
+	Aliyun  AliyunSMSConfig  `json:",optional"`
+	Tencent TencentSMSConfig `json:",optional"`
+	Twilio  TwilioSMSConfig  `json:",optional"`
+	Timeout int64            `json:",optional"` // 请求短信网关的超时时间 (秒, 默认 5)
+}
+
+// AliyunSMSConfig 阿里云短信服务，模板中的验证码变量为 ${code}
+type AliyunSMSConfig struct {
+	AccessKeyID     string `json:",optional"`
+	AccessKeySecret string `json:",optional"`
+	SignName        string `json:",optional"` // 短信签名
+	TemplateCode    string `json:",optional"` // 短信模板 CODE
+	RegionID        string `json:",optional"` // 默认 cn-hangzhou
+	Endpoint        string `json:",optional"` // 默认 https://dysmsapi.aliyuncs.com
+}
+
+// TencentSMSConfig 腾讯云短信，模板的第一个参数为验证码
+type TencentSMSConfig struct {
+	SecretID   string `json:",optional"`
+	SecretKey  string `json:",optional"`
+	SdkAppID   string `json:",optional"` // 短信应用 SdkAppId
+	SignName   string `json:",optional"` // 短信签名
+	TemplateID string `json:",optional"` // 短信模板 ID
+	Region     string `json:",optional"` // 默认 ap-guangzhou
+	Endpoint   string `json:",optional"` // 默认 https://sms.tencentcloudapi.com
+}
+
+// TwilioSMSConfig Twilio Messaging，手机号需为 E.164 格式 (如 +8613800138000)
+type TwilioSMSConfig struct {
+	AccountSID          string `json:",optional"`
+	AuthToken           string `json:",optional"`
+	From                string `json:",optional"` // 发送号码，与 MessagingServiceSID 二选一
+	MessagingServiceSID string `json:",optional"`
+	Body                string `json:",optional"` // 短信内容，{code} 替换为验证码 (默认 Your verification code is {code})
+	Endpoint            string `json:",optional"` // 默认 https://api.twilio.com
+}
+
+// 邮箱未验证的用户登录时的处理方式
+const (
+	EmailVerificationEnforceNone     = "none"     // 不限制
+	EmailVerificationEnforceBlock    = "block"    // 拒绝登录
+	EmailVerificationEnforceRestrict = "restrict" // 签发受限令牌，只能查看用户信息
+)
+
+// EmailVerificationConfig 本地注册用户的邮箱验证，需要同时配置 Email 与 Redis
+type EmailVerificationConfig struct {
+	Enable bool `json:",optional"` // 注册后发送邮箱验证码，并开放验证与重发接口
+
+	// 邮箱未验证的用户以密码登录时的处理方式: none (默认)、block 或 restrict
+	Enforce string `json:",default=none,options=none|block|restrict"`
+}
+
+// MagicLinkConfig 通过邮件中的一次性链接登录，需要同时配置 Email、Redis 与 FrontendURL
+type MagicLinkConfig struct {
+	Enable bool `json:",optional"` // 开放登录链接的申请与使用接口
+
+	// 要求申请链接时提供设备标识，链接只能在申请它的浏览器中使用
+	DeviceBinding bool `json:",optional"`
 }
 
 // SSOConfig SSO 统一配置
 type SSOConfig struct {
-	// 默认身份提供者: local, oidc, ldap
-	DefaultProvider string
+	// 默认身份提供者: local, ldap, radius 或 OIDC 提供者名称
+	DefaultProvider string `json:",optional"`
 
-	// OpenID Connect 配置
-	OIDC OIDCConfig
+	// 登录完成后允许跳转的前端地址 (如 https://app.example.com/sso)，站内相对路径始终允许
+	AllowedRedirectURLs []string `json:",optional"`
+
+	// OpenID Connect 配置 (单个提供者, 名称默认为 oidc)
+	OIDC OIDCConfig `json:",optional"`
+
+	// 多个命名的 OpenID Connect 提供者 (如 keycloak, google, azure)
+	OIDCProviders []OIDCConfig `json:",optional"`
+
+	// 社交 OAuth2 登录提供者 (GitHub, GitLab, 微信, 钉钉, 飞书)
+	OAuth2 []OAuth2Config `json:",optional"`
 
 	// LDAP 配置
-	LDAP LDAPConfig
+	LDAP LDAPConfig `json:",optional"`
+
+	// RADIUS 配置
+	RADIUS RADIUSConfig `json:",optional"`
+
+	// 上游令牌代理: 保存 IdP 签发的令牌，供受信任的客户端代用户调用上游 API
+	TokenBroker TokenBrokerConfig `json:",optional"`
+}
+
+// TokenBrokerConfig 上游令牌代理配置
+type TokenBrokerConfig struct {
+	Enabled       bool                `json:",optional"`
+	EncryptionKey string              `json:",optional"` // 令牌加密密钥 (启用时必填)
+	TokenTTL      int64               `json:",optional"` // 令牌保存时长 (秒, 默认 2592000 即 30 天)
+	Clients       []TokenBrokerClient `json:",optional"` // 允许获取上游令牌的客户端
+}
+
+// TokenBrokerClient 允许获取上游令牌的客户端，请求时通过 X-Client-Id / X-Client-Secret 头认证
+type TokenBrokerClient struct {
+	ClientID     string
+	ClientSecret string
+	Providers    []string `json:",optional"` // 允许获取的提供者 (为空表示全部)
 }
 
 // OIDCConfig OpenID Connect 配置
 type OIDCConfig struct {
-	Enabled            bool
-	ProviderURL        string // OIDC Provider URL (如 https://accounts.google.com)
-	ClientID           string
-	ClientSecret       string
-	RedirectURL        string   // 回调 URL
-	Scopes             []string // 请求的 scopes
-	InsecureSkipVerify bool     // 是否跳过 TLS 证书验证
+	Name               string   `json:",optional"` // 提供者名称, 用于路由 /sso/oidc/{name}/login (默认 oidc)
+	DisplayName        string   `json:",optional"` // 登录页显示名称
+	Icon               string   `json:",optional"` // 登录页图标 URL
+	Enabled            bool     `json:",optional"`
+	ProviderURL        string   `json:",optional"` // OIDC Provider URL (如 https://accounts.google.com)
+	ClientID           string   `json:",optional"`
+	ClientSecret       string   `json:",optional"` // 公共客户端 (仅依赖 PKCE) 可留空
+	RedirectURL        string   `json:",optional"` // 回调 URL
+	Scopes             []string `json:",optional"` // 请求的 scopes
+	InsecureSkipVerify bool     `json:",optional"` // 是否跳过 TLS 证书验证
+
+	// 信任该 IdP 断言的已验证邮箱: 邮箱与已有账号相同时直接关联。
+	// 未开启或邮箱未验证时，用户需先证明对已有账号的所有权 (密码或邮箱验证码) 才能关联
+	TrustEmail bool `json:",optional"`
+
+	DiscoveryRefreshInterval  int64 `json:",optional"` // 发现文档刷新间隔 (秒, 默认 3600; 响应带 Cache-Control max-age 时以其为准)
+	DiscoveryRetryMaxInterval int64 `json:",optional"` // 发现文档拉取失败后的最大重试间隔 (秒, 默认 300)
+
+	// 声明映射 (留空的字段使用标准 OIDC 声明)
+	ClaimMapping ClaimMapping `json:",optional"`
+
+	// 首次登录时自动开通账号的策略
+	Provisioning ProvisioningConfig `json:",optional"`
+}
+
+// ProvisioningConfig SSO 用户首次登录时自动开通 (JIT) 本地账号的策略，默认允许所有用户开通且立即可用
+type ProvisioningConfig struct {
+	Disabled        bool     `json:",optional"` // 禁止自动开通，只允许已关联或已存在的账号登录
+	AllowedDomains  []string `json:",optional"` // 允许开通的邮箱域名 (OIDC / OAuth2 要求邮箱已验证，为空表示不限制)
+	AllowedGroups   []string `json:",optional"` // 允许开通的组 (组名或组 DN，不区分大小写，为空表示不限制)
+	RequireApproval bool     `json:",optional"` // 新账号处于待审批状态，管理员审批后才能登录
+	DefaultRoles    []string `json:",optional"` // 新账号的默认角色
+}
+
+// ClaimMapping 联合登录的声明/属性映射。
+// 每个字段按顺序列出候选项，取第一个非空结果。候选项可以是声明名 (支持 a.b 形式的嵌套声明，
+// LDAP 属性名不区分大小写)，也可以是包含 ${name} 占位符的表达式，如 "${given_name} ${family_name}"。
+// 占位符支持过滤器: ${email|localpart}, ${name|lower}，可用过滤器为 lower, upper, trim, localpart, domain。
+type ClaimMapping struct {
+	Username      []string `json:",optional"` // 用户名
+	Email         []string `json:",optional"` // 邮箱
+	EmailVerified []string `json:",optional"` // 邮箱是否已验证 (布尔声明)
+	Nickname      []string `json:",optional"` // 昵称
+	Phone         []string `json:",optional"` // 手机号
+	Groups        []string `json:",optional"` // 组 (多值声明或属性)
+}
+
+// OAuth2Config 社交 OAuth2 登录配置
+type OAuth2Config struct {
+	Name               string   `json:",optional"` // 提供者名称, 用于路由 /sso/oauth2/{name}/login (默认同 Type)
+	Type               string   `json:",optional"` // 适配器类型: github, gitlab, wechat, dingtalk, feishu
+	DisplayName        string   `json:",optional"` // 登录页显示名称
+	Icon               string   `json:",optional"` // 登录页图标 URL
+	Enabled            bool     `json:",optional"`
+	ClientID           string   `json:",optional"` // 微信为 AppID, 钉钉为 AppKey
+	ClientSecret       string   `json:",optional"`
+	RedirectURL        string   `json:",optional"` // 回调 URL
+	Scopes             []string `json:",optional"` // 请求的 scopes (留空使用平台默认值)
+	BaseURL            string   `json:",optional"` // 私有部署地址 (如自建 GitLab, GitHub Enterprise)
+	AuthURL            string   `json:",optional"` // 覆盖授权端点
+	TokenURL           string   `json:",optional"` // 覆盖令牌端点
+	UserInfoURL        string   `json:",optional"` // 覆盖用户信息端点
+	InsecureSkipVerify bool     `json:",optional"` // 是否跳过 TLS 证书验证
+	TrustEmail         bool     `json:",optional"` // 信任平台断言的已验证邮箱，邮箱与已有账号相同时直接关联
+
+	// 首次登录时自动开通账号的策略
+	Provisioning ProvisioningConfig `json:",optional"`
 }
 
 // LDAPConfig LDAP 配置
 type LDAPConfig struct {
-	Enabled         bool
-	Host            string   // LDAP 服务器地址
-	Port            int      // LDAP 端口 (默认 389, LDAPS 默认 636)
-	UseSSL          bool     // 是否使用 SSL
-	UseTLS          bool     // 是否使用 StartTLS
-	InsecureSkipTLS bool     // 是否跳过 TLS 证书验证 (仅用于测试)
-	BindDN          string   // 绑定 DN
-	BindPassword    string   // 绑定密码
-	BaseDN          string   // 搜索基准 DN
-	UserFilter      string   // 用户过滤器 (如 "(uid=%s)" 或 "(sAMAccountName=%s)")
-	GroupFilter     string   // 组过滤器 (可选)
-	UserAttributes  []string // 需要获取的用户属性
-	UsernameAttr    string   // 用户名属性 (如 uid, sAMAccountName)
-	EmailAttr       string   // 邮箱属性 (如 mail)
-	DisplayNameAttr string   // 显示名称属性 (如 displayName, cn)
-	GroupMemberAttr string   // 组成员属性 (如 memberOf)
+	Enabled         bool     `json:",optional"`
+	Host            string   `json:",optional"` // LDAP 服务器地址
+	Port            int      `json:",optional"` // LDAP 端口 (默认 389, LDAPS 默认 636)
+	UseSSL          bool     `json:",optional"` // 是否使用 SSL
+	UseTLS          bool     `json:",optional"` // 是否使用 StartTLS
+	InsecureSkipTLS bool     `json:",optional"` // 是否跳过 TLS 证书验证 (仅用于测试)
+	BindDN          string   `json:",optional"` // 绑定 DN
+	BindPassword    string   `json:",optional"` // 绑定密码
+	BaseDN          string   `json:",optional"` // 搜索基准 DN
+	UserFilter      string   `json:",optional"` // 用户过滤器 (如 "(uid=%s)" 或 "(sAMAccountName=%s)")
+	GroupFilter     string   `json:",optional"` // 组过滤器 (可选)
+	UserAttributes  []string `json:",optional"` // 需要获取的用户属性
+	UsernameAttr    string   `json:",optional"` // 用户名属性 (如 uid, sAMAccountName)
+	EmailAttr       string   `json:",optional"` // 邮箱属性 (如 mail)
+	DisplayNameAttr string   `json:",optional"` // 显示名称属性 (如 displayName, cn)
+	GroupMemberAttr string   `json:",optional"` // 组成员属性 (如 memberOf)
+	// 目录类型: openldap (默认, 修改密码使用 RFC 3062 Password Modify 扩展操作) 或 ad
+	// (修改 unicodePwd 属性, AD 要求连接已加密: UseSSL 或 UseTLS)
+	ServerType string `json:",optional"`
+	// 用户搜索的分页大小 (Simple Paged Results 控件, 默认 500, AD 单页上限 1000)
+	PageSize int `json:",optional"`
+
+	// 多服务器故障转移: Servers 为 host 或 host:port 列表 (配置后忽略 Host/Port)，
+	// SRVDomain 通过 DNS SRV 记录 _ldap._tcp.<SRVDomain> 发现服务器 (如 AD 域控制器)，静态配置的服务器作为后备
+	Servers            []string `json:",optional"`
+	ServerSelection    string   `json:",optional"` // 服务器选择策略: priority (默认, 按顺序故障转移) 或 round_robin
+	SRVDomain          string   `json:",optional"`
+	SRVRefreshInterval int64    `json:",optional"` // SRV 记录刷新间隔 (秒, 默认 300)
+	FailoverCooldown   int64    `json:",optional"` // 连接失败的服务器在该时长内排在最后尝试 (秒, 默认 30)
+	DialTimeout        int64    `json:",optional"` // 单台服务器的连接超时 (秒, 默认 5)
+
+	// 属性映射 (优先于 UsernameAttr 等单项配置，留空的字段使用常见 LDAP/AD 属性)
+	AttributeMapping ClaimMapping `json:",optional"`
+
+	// 首次登录时自动开通账号的策略
+	Provisioning ProvisioningConfig `json:",optional"`
+
+	// 管理员绑定的搜索连接池 (用户密码校验始终使用独立的短连接)
+	Pool LDAPPoolConfig `json:",optional"`
+
+	// 组到角色的映射: 用户所在的组 (含嵌套组) 匹配时授予对应角色，登录时同步到用户角色并写入令牌
+	GroupRoles       []LDAPGroupRoleConfig `json:",optional"`
+	NestedGroups     string                `json:",optional"` // 嵌套组展开方式: none (默认), in_chain (AD LDAP_MATCHING_RULE_IN_CHAIN), recursive (逐层搜索, 适用于 OpenLDAP)
+	NestedGroupDepth int                   `json:",optional"` // recursive 展开的最大层数 (默认 10)
+	GroupBaseDN      string                `json:",optional"` // 组搜索基准 DN (默认同 BaseDN)
+
+	// 定时目录同步: 创建/更新本地用户与角色，禁用目录中已删除或已禁用的用户并吊销其会话
+	Sync LDAPSyncConfig `json:",optional"`
+}
+
+// LDAPSyncConfig LDAP 目录同步配置
+type LDAPSyncConfig struct {
+	Enabled     bool   `json:",optional"` // 是否定时同步
+	Interval    int64  `json:",optional"` // 同步间隔 (秒, 默认 3600)
+	Filter      string `json:",optional"` // 用户过滤器 (默认将 UserFilter 中的 %s 替换为 *)
+	PageSize    int    `json:",optional"` // 分页大小 (默认同 LDAP.PageSize)
+	LockTimeout int64  `json:",optional"` // 多实例部署时同步锁的超时 (秒, 默认 1800)
+}
+
+// LDAPGroupRoleConfig LDAP 组到角色的映射
+type LDAPGroupRoleConfig struct {
+	// Group 组 DN 或通配模式 (如 "cn=app-*,ou=groups,dc=example,dc=com")，不含 "=" 时匹配组的 CN，均不区分大小写
+	Group string   `json:",optional"`
+	Roles []string `json:",optional"`
+}
+
+// LDAPPoolConfig LDAP 连接池配置
+type LDAPPoolConfig struct {
+	MaxOpen             int   `json:",optional"` // 最大连接数 (默认 10)
+	MaxIdle             int   `json:",optional"` // 最大空闲连接数 (默认等于 MaxOpen, 小于 0 时不复用连接)
+	IdleTimeout         int64 `json:",optional"` // 空闲连接超时关闭 (秒, 默认 300; AD 默认 900 秒后断开空闲连接)
+	MaxLifetime         int64 `json:",optional"` // 连接最长使用时间 (秒, 默认 1800)
+	HealthCheckInterval int64 `json:",optional"` // 空闲超过该时长的连接在取出前先探活 (秒, 默认 30)
+	WaitTimeout         int64 `json:",optional"` // 连接数已满时等待空闲连接的时长 (秒, 默认 5)
+}
+
+// RADIUSConfig RADIUS 认证配置
+type RADIUSConfig struct {
+	Enabled bool `json:",optional"`
+	// RADIUS 服务器 host 或 host:port 列表 (默认端口 1812)，按顺序尝试，无响应时转移到下一台
+	Servers       []string `json:",optional"`
+	Secret        string   `json:",optional"` // 共享密钥
+	AuthMethod    string   `json:",optional"` // 认证方式: pap (默认) 或 mschapv2
+	NASIdentifier string   `json:",optional"` // 请求中的 NAS-Identifier (默认 auth-service)
+
+	Timeout          int64 `json:",optional"` // 单台服务器的响应超时 (秒, 默认 3)，超时后转移到下一台
+	RetryInterval    int64 `json:",optional"` // 等待响应期间重发请求的间隔 (秒, 默认 1)
+	FailoverCooldown int64 `json:",optional"` // 无响应的服务器在该时长内排在最后尝试 (秒, 默认 30)
+
+	// 要求响应携带正确的 Message-Authenticator 属性 (防御 BlastRADIUS 伪造响应，服务器均已支持时建议开启)
+	RequireMessageAuthenticator bool `json:",optional"`
+
+	// 首次登录时自动开通账号的策略 (AllowedGroups 匹配 Access-Accept 中的 Class 属性)
+	Provisioning ProvisioningConfig `json:",optional"`
 }
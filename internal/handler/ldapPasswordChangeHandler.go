@@ -0,0 +1,31 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package handler
+
+import (
+	"net/http"
+
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func LDAPPasswordChangeHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.LDAPPasswordChangeReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := logic.NewLDAPPasswordChangeLogic(r.Context(), svcCtx)
+		resp, err := l.LDAPPasswordChange(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
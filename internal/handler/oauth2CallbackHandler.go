@@ -0,0 +1,34 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package handler
+
+import (
+	"net/http"
+
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func OAuth2CallbackHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.OAuth2CallbackReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := logic.NewOAuth2CallbackLogic(r.Context(), svcCtx)
+		resp, err := l.OAuth2Callback(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else if redirect, ok := resp.Data.(types.SSORedirectResp); ok {
+			// 浏览器回调模式: 跳转回登录时指定的前端地址
+			http.Redirect(w, r, redirect.Location, http.StatusFound)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
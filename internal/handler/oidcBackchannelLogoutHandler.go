@@ -0,0 +1,37 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package handler
+
+import (
+	"net/http"
+
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+func OIDCBackchannelLogoutHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Back-Channel Logout 1.0: 响应不得被缓存
+		w.Header().Set("Cache-Control", "no-store")
+
+		var req types.OIDCBackchannelLogoutReq
+		if err := httpx.Parse(r, &req); err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+			return
+		}
+
+		l := logic.NewOIDCBackchannelLogoutLogic(r.Context(), svcCtx)
+		resp, err := l.OIDCBackchannelLogout(&req)
+		if err != nil {
+			httpx.ErrorCtx(r.Context(), w, err)
+		} else if resp.Code != 0 {
+			// IdP 依据 HTTP 状态码判断登出是否成功
+			httpx.WriteJsonCtx(r.Context(), w, http.StatusBadRequest, resp)
+		} else {
+			httpx.OkJsonCtx(r.Context(), w, resp)
+		}
+	}
+}
@@ -24,6 +24,9 @@ func OIDCCallbackHandler(svcCtx *svc.ServiceContext) http.HandlerFunc {
 		resp, err := l.OIDCCallback(&req)
 		if err != nil {
 			httpx.ErrorCtx(r.Context(), w, err)
+		} else if redirect, ok := resp.Data.(types.SSORedirectResp); ok {
+			// 浏览器回调模式: 跳转回登录时指定的前端地址
+			http.Redirect(w, r, redirect.Location, http.StatusFound)
 		} else {
 			httpx.OkJsonCtx(r.Context(), w, resp)
 		}
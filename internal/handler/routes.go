@@ -35,6 +35,16 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/login",
 				Handler: LoginHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/login/magic-link",
+				Handler: MagicLinkHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/login/magic-link/consume",
+				Handler: MagicLinkConsumeHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodPost,
 				Path:    "/logout",
@@ -50,6 +60,16 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/password/reset",
 				Handler: ConfirmPasswordHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/email/verify",
+				Handler: VerifyEmailHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/email/verify/resend",
+				Handler: ResendVerificationHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodPost,
 				Path:    "/refresh",
@@ -86,6 +106,27 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 		rest.WithTimeout(3000*time.Millisecond),
 	)
 
+	server.AddRoutes(
+		rest.WithMiddlewares(
+			[]rest.Middleware{serverCtx.AuthInterceptor},
+			[]rest.Route{
+				{
+					Method:  http.MethodPost,
+					Path:    "/phone/verify/send",
+					Handler: SendPhoneCodeHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/phone/verify",
+					Handler: VerifyPhoneHandler(serverCtx),
+				},
+			}...,
+		),
+		rest.WithJwt(serverCtx.Config.Auth.AccessSecret),
+		rest.WithPrefix("/api/v1"),
+		rest.WithTimeout(10000*time.Millisecond),
+	)
+
 	server.AddRoutes(
 		[]rest.Route{
 			{
@@ -93,6 +134,41 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/sso/ldap/login",
 				Handler: LDAPLoginHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/sso/ldap/password",
+				Handler: LDAPPasswordChangeHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/sso/radius/login",
+				Handler: RADIUSLoginHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/sso/:provider/login",
+				Handler: SSOLoginHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/sso/oauth2/:provider/callback",
+				Handler: OAuth2CallbackHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/sso/oauth2/:provider/login",
+				Handler: OAuth2LoginHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/sso/oidc/:provider/backchannel-logout",
+				Handler: OIDCBackchannelLogoutHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/sso/oidc/backchannel-logout",
+				Handler: OIDCBackchannelLogoutHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodGet,
 				Path:    "/sso/oidc/callback",
@@ -103,6 +179,31 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 				Path:    "/sso/oidc/login",
 				Handler: OIDCLoginHandler(serverCtx),
 			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/sso/oidc/:provider/callback",
+				Handler: OIDCCallbackHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodGet,
+				Path:    "/sso/oidc/:provider/login",
+				Handler: OIDCLoginHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/sso/exchange",
+				Handler: SSOExchangeHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/sso/link/code",
+				Handler: SSOLinkCodeHandler(serverCtx),
+			},
+			{
+				Method:  http.MethodPost,
+				Path:    "/sso/link/confirm",
+				Handler: SSOLinkConfirmHandler(serverCtx),
+			},
 			{
 				Method:  http.MethodGet,
 				Path:    "/sso/providers",
@@ -112,4 +213,76 @@ func RegisterHandlers(server *rest.Server, serverCtx *svc.ServiceContext) {
 		rest.WithPrefix("/api/v1"),
 		rest.WithTimeout(10000*time.Millisecond),
 	)
+
+	server.AddRoutes(
+		rest.WithMiddlewares(
+			[]rest.Middleware{serverCtx.AuthInterceptor},
+			[]rest.Route{
+				{
+					Method:  http.MethodGet,
+					Path:    "/sso/oidc/:provider/token",
+					Handler: UpstreamTokenHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/sso/link",
+					Handler: SSOLinkHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/sso/linked",
+					Handler: SSOLinkedAccountsHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/sso/unlink",
+					Handler: SSOUnlinkHandler(serverCtx),
+				},
+			}...,
+		),
+		rest.WithJwt(serverCtx.Config.Auth.AccessSecret),
+		rest.WithPrefix("/api/v1"),
+		rest.WithTimeout(10000*time.Millisecond),
+	)
+
+	server.AddRoutes(
+		rest.WithMiddlewares(
+			[]rest.Middleware{serverCtx.AuthInterceptor},
+			[]rest.Route{
+				{
+					Method:  http.MethodGet,
+					Path:    "/admin/users/pending",
+					Handler: ListPendingUsersHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/admin/users/approve",
+					Handler: ApproveUserHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/admin/users/reject",
+					Handler: RejectUserHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/admin/users/password/reset",
+					Handler: ResetUserPasswordHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodPost,
+					Path:    "/admin/ldap/sync",
+					Handler: LDAPSyncHandler(serverCtx),
+				},
+				{
+					Method:  http.MethodGet,
+					Path:    "/admin/ldap/sync",
+					Handler: LDAPSyncStatusHandler(serverCtx),
+				},
+			}...,
+		),
+		rest.WithJwt(serverCtx.Config.Auth.AccessSecret),
+		rest.WithPrefix("/api/v1"),
+		rest.WithTimeout(3000*time.Millisecond),
+	)
 }
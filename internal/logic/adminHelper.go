@@ -0,0 +1,46 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+)
+
+// requireAdmin 校验当前登录用户具有管理员角色
+func requireAdmin(ctx context.Context, svcCtx *svc.ServiceContext) error {
+	userID, ok := ctx.Value("userID").(int64)
+	if !ok || userID == 0 {
+		return types.ErrUnauthorized
+	}
+
+	_, err := svcCtx.UserRoleModel.FindOneByUserIdRole(ctx, uint64(userID), mysql.RoleAdmin)
+	if err == mysql.ErrNotFound {
+		return types.ErrForbidden
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find user role: %w", err)
+	}
+	return nil
+}
+
+// findPendingUser 按 Public ID 查找待审批的用户，不是待审批状态时返回错误响应
+func findPendingUser(ctx context.Context, svcCtx *svc.ServiceContext, publicID string) (*mysql.User, *types.BaseResponse, error) {
+	user, err := svcCtx.UserModel.FindOneByPublicId(ctx, publicID)
+	if err == mysql.ErrNotFound {
+		return nil, nil, types.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user.AccountStatus != mysql.UserStatusPending {
+		return nil, &types.BaseResponse{
+			Code:    1028,
+			Message: "user is not pending approval",
+		}, nil
+	}
+	return user, nil, nil
+}
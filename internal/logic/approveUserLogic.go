@@ -0,0 +1,52 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ApproveUserLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewApproveUserLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ApproveUserLogic {
+	return &ApproveUserLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// ApproveUser 审批通过待开通的用户，账号变为正常状态
+func (l *ApproveUserLogic) ApproveUser(req *types.ReviewUserReq) (resp *types.BaseResponse, err error) {
+	if err := requireAdmin(l.ctx, l.svcCtx); err != nil {
+		return nil, err
+	}
+
+	user, denied, err := findPendingUser(l.ctx, l.svcCtx, req.UserID)
+	if err != nil || denied != nil {
+		return denied, err
+	}
+
+	user.AccountStatus = mysql.UserStatusActive
+	if err := l.svcCtx.UserModel.Update(l.ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	l.Infof("Pending user %s approved by user %v", user.PublicId, l.ctx.Value("userID"))
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+	}, nil
+}
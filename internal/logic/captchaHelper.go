@@ -0,0 +1,20 @@
+package logic
+
+import (
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+)
+
+// verifyCaptcha 开启验证码时校验图形验证码: 未提供时返回 ErrCaptchaRequired，不匹配时返回 ErrCaptchaInvalid
+func verifyCaptcha(svcCtx *svc.ServiceContext, captchaID, captchaAnswer string) error {
+	if !svcCtx.Config.Captcha.Enable {
+		return nil
+	}
+	if captchaID == "" && captchaAnswer == "" {
+		return types.ErrCaptchaRequired
+	}
+	if !svcCtx.Captcha.Verify(captchaID, captchaAnswer, true) {
+		return types.ErrCaptchaInvalid
+	}
+	return nil
+}
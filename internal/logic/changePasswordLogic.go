@@ -2,6 +2,7 @@ package logic
 
 import (
 	"context"
+	"errors"
 
 	"auth-service/internal/svc"
 	"auth-service/internal/types"
@@ -54,6 +55,19 @@ func (l *ChangePasswordLogic) ChangePassword(req *types.ChangePasswordReq) (resp
 		}, nil
 	}
 
+	// LDAP 用户的密码保存在目录中，修改请求转发到目录
+	userDN, err := ldapPasswordDN(l.ctx, l.svcCtx, user)
+	if err != nil {
+		l.Errorf("Failed to resolve password provider for user %d: %v", userID, err)
+		return &types.BaseResponse{
+			Code:    500,
+			Message: "密码更新失败",
+		}, nil
+	}
+	if userDN != "" {
+		return l.changeLDAPPassword(userDN, req), nil
+	}
+
 	// 校验旧密码
 	if !l.svcCtx.PasswordEncoder.Compare(user.PasswordHash, req.OldPassword) {
 		l.Info("Old password verification failed")
@@ -80,3 +94,47 @@ func (l *ChangePasswordLogic) ChangePassword(req *types.ChangePasswordReq) (resp
 		Message: "密码修改成功",
 	}, nil
 }
+
+// changeLDAPPassword 在 LDAP 目录中修改密码，目录的密码策略拒绝时返回具体原因
+func (l *ChangePasswordLogic) changeLDAPPassword(userDN string, req *types.ChangePasswordReq) *types.BaseResponse {
+	if !ldapEnabled(l.svcCtx) {
+		return &types.BaseResponse{
+			Code:    503,
+			Message: "目录服务不可用，暂时无法修改密码",
+		}
+	}
+
+	err := l.svcCtx.LDAP.ChangePassword(l.ctx, userDN, req.OldPassword, req.NewPassword)
+	switch {
+	case err == nil:
+	case errors.Is(err, svc.ErrLDAPInvalidCredentials):
+		l.Info("Old password verification failed against LDAP")
+		return &types.BaseResponse{
+			Code:    400,
+			Message: "旧密码错误",
+		}
+	case errors.Is(err, svc.ErrLDAPPasswordPolicy):
+		return &types.BaseResponse{
+			Code:    400,
+			Message: withLDAPDiagnostic("新密码不符合目录的密码策略", err),
+		}
+	case errors.Is(err, svc.ErrLDAPPasswordChangeRefused):
+		l.Errorf("LDAP refused password change for %s: %v", userDN, err)
+		return &types.BaseResponse{
+			Code:    403,
+			Message: withLDAPDiagnostic("目录拒绝修改密码", err),
+		}
+	default:
+		l.Errorf("Failed to change LDAP password for %s: %v", userDN, err)
+		return &types.BaseResponse{
+			Code:    500,
+			Message: "密码更新失败",
+		}
+	}
+
+	l.Infof("LDAP password changed successfully for %s", userDN)
+	return &types.BaseResponse{
+		Code:    200,
+		Message: "密码修改成功",
+	}
+}
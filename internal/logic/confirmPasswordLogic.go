@@ -2,9 +2,11 @@ package logic
 
 import (
 	"context"
+	"errors"
 
 	"auth-service/internal/svc"
 	"auth-service/internal/types"
+	"auth-service/model/mysql"
 
 	"github.com/zeromicro/go-zero/core/logx"
 )
@@ -23,26 +25,56 @@ func NewConfirmPasswordLogic(ctx context.Context, svcCtx *svc.ServiceContext) *C
 	}
 }
 
+// ConfirmPassword 凭邮件中的重置令牌设置新密码。令牌只能使用一次，
+// 重置后吊销用户已签发的全部令牌，所有已登录的会话需要重新登录
 func (l *ConfirmPasswordLogic) ConfirmPassword(req *types.ConfirmPasswordReq) (resp *types.BaseResponse, err error) {
-	l.Info("ConfirmPassword request received")
+	invalid := &types.BaseResponse{
+		Code:    400,
+		Message: "重置链接无效或已过期",
+	}
+	if l.svcCtx.Redis == nil {
+		return invalid, nil
+	}
 
-	// 注意：这是一个简化的实现
-	// 完整实现需要：
-	// 1. 从URL参数或请求头中获取重置令牌
-	// 2. 验证重置令牌是否有效和未过期
-	// 3. 从令牌中获取用户信息
-	// 4. 更新用户密码
-	// 5. 使重置令牌失效
+	reset, err := svc.ConsumePasswordReset(l.ctx, l.svcCtx.Redis, req.Token)
+	if errors.Is(err, svc.ErrInvalidPasswordReset) {
+		return invalid, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	// 由于当前API定义中没有传递重置令牌，这里返回一个提示信息
-	// 实际项目中，重置令牌通常通过URL参数传递，如 /auth/password/reset/confirm?token=xxx
+	// 令牌签发后账号被删除、禁用 (或仍待审批) 或修改了邮箱时令牌失效
+	user, err := l.svcCtx.UserModel.FindOne(l.ctx, reset.UserID)
+	if err == mysql.ErrNotFound {
+		return invalid, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Email != reset.Email || accountStatusError(user) != nil {
+		l.Infof("Password reset token of user %s is no longer valid", user.PublicId)
+		return invalid, nil
+	}
 
-	resp = &types.BaseResponse{
-		Code:    200,
-		Message: "密码重置功能需要配合邮件系统实现。请在实际使用时补充重置令牌验证逻辑。",
-		Data:    nil,
+	// 更新密码; 收到重置邮件说明用户持有该邮箱
+	user.PasswordHash = l.svcCtx.PasswordEncoder.Hash(req.NewPassword)
+	user.EmailVerified = 1
+	if err := l.svcCtx.UserModel.Update(l.ctx, user); err != nil {
+		l.Errorf("Failed to update password: %v", err)
+		return &types.BaseResponse{
+			Code:    500,
+			Message: "密码更新失败",
+		}, nil
 	}
 
-	l.Info("ConfirmPassword: This is a placeholder implementation")
-	return resp, nil
+	if err := l.svcCtx.JWT.RevokeUserTokens(user.Id); err != nil {
+		return nil, err
+	}
+
+	l.Infof("Password reset successfully for user %s", user.PublicId)
+	return &types.BaseResponse{
+		Code:    200,
+		Message: "密码重置成功，请使用新密码登录",
+	}, nil
 }
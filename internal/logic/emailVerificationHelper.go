@@ -0,0 +1,31 @@
+package logic
+
+import (
+	"context"
+
+	"auth-service/internal/svc"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+)
+
+// emailVerificationEnabled 邮箱验证已开启且邮件与 Redis 均已配置
+func emailVerificationEnabled(svcCtx *svc.ServiceContext) bool {
+	return svcCtx.Config.EmailVerification.Enable && svcCtx.Email != nil && svcCtx.Redis != nil
+}
+
+// sendEmailVerification 生成邮箱验证码并在后台发送，此前发送的验证码失效
+func sendEmailVerification(ctx context.Context, svcCtx *svc.ServiceContext, userID uint64, email string) error {
+	code, err := svc.CreateEmailVerification(ctx, svcCtx.Redis, userID, email)
+	if err != nil {
+		return err
+	}
+
+	logger := logx.WithContext(ctx)
+	threading.GoSafe(func() {
+		if err := svcCtx.Email.SendVerificationCode(email, code); err != nil {
+			logger.Errorf("Failed to send verification code to user %d: %v", userID, err)
+		}
+	})
+	return nil
+}
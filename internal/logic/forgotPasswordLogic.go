@@ -5,11 +5,15 @@ package logic
 
 import (
 	"context"
+	"net/url"
+	"strings"
 
 	"auth-service/internal/svc"
 	"auth-service/internal/types"
+	"auth-service/model/mysql"
 
 	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
 )
 
 type ForgotPasswordLogic struct {
@@ -26,8 +30,80 @@ func NewForgotPasswordLogic(ctx context.Context, svcCtx *svc.ServiceContext) *Fo
 	}
 }
 
+// ForgotPassword 向注册邮箱发送密码重置链接。
+// 无论邮箱是否已注册都返回相同的响应，邮件在后台发送，响应时间也不泄露邮箱是否存在
 func (l *ForgotPasswordLogic) ForgotPassword(req *types.ForgotPasswordReq) (resp *types.BaseResponse, err error) {
-	// todo: add your logic here and delete this line
+	// 校验验证码（如果开启了验证码）
+	if err := verifyCaptcha(l.svcCtx, req.CaptchaID, req.CaptchaAnswer); err != nil {
+		return nil, err
+	}
+
+	if l.svcCtx.Email == nil || l.svcCtx.Redis == nil || l.svcCtx.Config.FrontendURL == "" {
+		l.Error("Password reset requires Email, Redis and FrontendURL to be configured")
+		return &types.BaseResponse{
+			Code:    503,
+			Message: "密码重置功能未启用",
+		}, nil
+	}
+
+	resp = &types.BaseResponse{
+		Code:    200,
+		Message: "如果该邮箱已注册，重置密码的邮件将发送到该邮箱",
+	}
+
+	// 同一邮箱在冷却时间内只发送一次
+	allowed, err := svc.AllowPasswordReset(l.ctx, l.svcCtx.Redis, req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		l.Infof("Password reset for %s is cooling down", maskEmail(req.Email))
+		return resp, nil
+	}
+
+	user, err := l.svcCtx.UserModel.FindOneByEmail(l.ctx, req.Email)
+	if err == mysql.ErrNotFound {
+		l.Infof("Password reset requested for unknown email %s", maskEmail(req.Email))
+		return resp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if skip := l.skipReason(user); skip != "" {
+		l.Infof("Not sending password reset to user %s: %s", user.PublicId, skip)
+		return resp, nil
+	}
+
+	token, err := svc.CreatePasswordReset(l.ctx, l.svcCtx.Redis, &svc.PasswordReset{UserID: user.Id, Email: user.Email})
+	if err != nil {
+		return nil, err
+	}
+	resetURL := strings.TrimRight(l.svcCtx.Config.FrontendURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+
+	email, publicID, logger := user.Email, user.PublicId, l.Logger
+	threading.GoSafe(func() {
+		if err := l.svcCtx.Email.SendResetEmail(email, resetURL); err != nil {
+			logger.Errorf("Failed to send password reset email to user %s: %v", publicID, err)
+		}
+	})
+	return resp, nil
+}
 
-	return
+// skipReason 不能通过邮件重置密码的账号返回原因: 待审批或已禁用、邮箱为占位邮箱或密码保存在 LDAP 目录中
+func (l *ForgotPasswordLogic) skipReason(user *mysql.User) string {
+	if err := accountStatusError(user); err != nil {
+		return err.Error()
+	}
+	if isPlaceholderEmail(user.Email) {
+		return "no real email address"
+	}
+	userDN, err := ldapPasswordDN(l.ctx, l.svcCtx, user)
+	if err != nil {
+		l.Errorf("Failed to resolve password provider for user %s: %v", user.PublicId, err)
+		return "password provider unknown"
+	}
+	if userDN != "" {
+		return "password is managed by the directory"
+	}
+	return ""
 }
@@ -49,6 +49,9 @@ func (l *GetProfileLogic) GetProfile() (resp *types.UserProfileResp, err error)
 		Nickname:  user.Nickname.String,
 		CreatedAt: user.CreatedAt.Unix(),
 		UpdatedAt: user.UpdatedAt.Unix(),
+
+		EmailVerified: user.EmailVerified != 0,
+		PhoneVerified: user.PhoneVerified != 0,
 	}
 
 	l.Infof("User profile retrieved successfully for user %s", user.PublicId)
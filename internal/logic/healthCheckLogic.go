@@ -2,6 +2,7 @@ package logic
 
 import (
 	"context"
+	"time"
 
 	"auth-service/internal/svc"
 	"auth-service/internal/types"
@@ -28,6 +29,36 @@ func (l *HealthCheckLogic) HealthCheck() (resp *types.BaseResponse, err error) {
 	resp = &types.BaseResponse{
 		Code:    200,
 		Message: "OK",
+		Data: types.HealthCheckResp{
+			SSO: l.ssoHealth(),
+		},
 	}
 	return resp, nil
 }
+
+// ssoHealth 汇总 OIDC 提供者的发现文档状态；IdP 不可用不影响服务存活。
+// 接口无需认证，只返回是否可用与最近刷新时间，失败原因 (可能包含 IdP 的响应内容) 只写入日志
+func (l *HealthCheckLogic) ssoHealth() []types.SSOProviderHealth {
+	health := make([]types.SSOProviderHealth, 0)
+	for _, entry := range l.svcCtx.OIDC.List() {
+		status := entry.Status()
+		if !status.Ready || status.ConsecutiveFailures > 0 {
+			l.Infof("OIDC provider %s is unhealthy (%d consecutive failures, next refresh at %s): %s",
+				entry.Name, status.ConsecutiveFailures, status.NextRefresh.Format(time.RFC3339), status.LastError)
+		}
+		health = append(health, types.SSOProviderHealth{
+			ID:            entry.Name,
+			Type:          string(types.SSOProviderOIDC),
+			Ready:         status.Ready,
+			LastRefreshAt: unixOrZero(status.LastRefresh),
+		})
+	}
+	return health
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,88 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type LDAPPasswordChangeLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewLDAPPasswordChangeLogic(ctx context.Context, svcCtx *svc.ServiceContext) *LDAPPasswordChangeLogic {
+	return &LDAPPasswordChangeLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// LDAPPasswordChange 目录要求修改密码 (密码已过期或必须修改) 的用户凭登录返回的令牌修改密码，之后使用新密码重新登录
+func (l *LDAPPasswordChangeLogic) LDAPPasswordChange(req *types.LDAPPasswordChangeReq) (resp *types.BaseResponse, err error) {
+	if !ldapEnabled(l.svcCtx) {
+		return &types.BaseResponse{
+			Code:    1001,
+			Message: "LDAP login is disabled",
+		}, nil
+	}
+	if l.svcCtx.Redis == nil {
+		return invalidPasswordChangeToken(), nil
+	}
+
+	change, err := svc.GetLDAPPasswordChange(l.ctx, l.svcCtx.Redis, req.ChangeToken)
+	if errors.Is(err, svc.ErrInvalidPasswordChangeToken) {
+		return invalidPasswordChangeToken(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.svcCtx.LDAP.ChangePassword(l.ctx, change.DN, req.OldPassword, req.NewPassword)
+	if errors.Is(err, svc.ErrLDAPInvalidCredentials) {
+		// 旧密码错误时令牌作废，需要重新登录
+		if err := svc.ConsumeLDAPPasswordChange(l.ctx, l.svcCtx.Redis, req.ChangeToken); err != nil && !errors.Is(err, svc.ErrInvalidPasswordChangeToken) {
+			return nil, err
+		}
+		l.Infof("LDAP password change for %s failed: %v", change.Username, err)
+		return &types.BaseResponse{
+			Code:    1002,
+			Message: "authentication failed",
+		}, nil
+	}
+	if err != nil {
+		// 不满足密码策略时令牌保留，用户可以换一个密码重试
+		if denied := ldapPasswordFailure(err); denied != nil {
+			l.Infof("LDAP refused password change for %s: %v", change.Username, err)
+			return denied, nil
+		}
+		return nil, fmt.Errorf("failed to change LDAP password: %w", err)
+	}
+
+	if err := svc.ConsumeLDAPPasswordChange(l.ctx, l.svcCtx.Redis, req.ChangeToken); err != nil && !errors.Is(err, svc.ErrInvalidPasswordChangeToken) {
+		l.Errorf("Failed to delete password change token: %v", err)
+	}
+	l.Infof("LDAP user %s changed the required password", change.Username)
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+	}, nil
+}
+
+func invalidPasswordChangeToken() *types.BaseResponse {
+	return &types.BaseResponse{
+		Code:    1038,
+		Message: "invalid or expired password change token",
+	}
+}
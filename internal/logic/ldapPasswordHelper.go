@@ -0,0 +1,132 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+)
+
+// ldapPasswordDN 返回密码保存在 LDAP 目录中的用户的 DN: 账号由 LDAP 开通 (没有本地密码) 且关联了 LDAP 身份。
+// 其它用户 (包括设置了本地密码后又关联 LDAP 的用户) 返回空字符串，密码保存在本地
+func ldapPasswordDN(ctx context.Context, svcCtx *svc.ServiceContext, user *mysql.User) (string, error) {
+	if hasLocalPassword(user) {
+		return "", nil
+	}
+
+	identity, err := svcCtx.UserIdentityModel.FindOneByUserIdProvider(ctx, user.Id, ldapIdentityProvider)
+	if err == mysql.ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to find LDAP identity: %w", err)
+	}
+	return identity.ProviderUserId, nil
+}
+
+// ldapEnabled LDAP 提供者是否可用
+func ldapEnabled(svcCtx *svc.ServiceContext) bool {
+	return svcCtx.LDAP != nil && svcCtx.LDAP.IsEnabled()
+}
+
+// withLDAPDiagnostic 在提示信息后附加目录返回的诊断信息 (如密码策略的具体要求)
+func withLDAPDiagnostic(message string, err error) string {
+	if diagnostic := svc.LDAPDiagnosticMessage(err); diagnostic != "" {
+		return message + ": " + diagnostic
+	}
+	return message
+}
+
+// ldapPasswordFailure 目录拒绝修改密码时的响应，其它错误返回 nil
+func ldapPasswordFailure(err error) *types.BaseResponse {
+	switch {
+	case errors.Is(err, svc.ErrLDAPPasswordPolicy):
+		return &types.BaseResponse{
+			Code:    1030,
+			Message: withLDAPDiagnostic("password does not satisfy the directory password policy", err),
+		}
+	case errors.Is(err, svc.ErrLDAPPasswordChangeRefused):
+		return &types.BaseResponse{
+			Code:    1031,
+			Message: withLDAPDiagnostic("directory refused the password change", err),
+		}
+	}
+	return nil
+}
+
+// ldapAuthFailure 目录拒绝登录时的响应。密码错误与用户不存在使用同一个响应，不泄露用户是否存在；
+// AD 只在密码正确时返回其它原因 (账号锁定除外)
+func ldapAuthFailure(err error) *types.BaseResponse {
+	switch {
+	case errors.Is(err, svc.ErrLDAPPasswordMustChange):
+		return &types.BaseResponse{
+			Code:    1032,
+			Message: "password must be changed before signing in",
+		}
+	case errors.Is(err, svc.ErrLDAPPasswordExpired):
+		return &types.BaseResponse{
+			Code:    1033,
+			Message: "password has expired and must be changed",
+		}
+	case errors.Is(err, svc.ErrLDAPAccountLocked):
+		return &types.BaseResponse{
+			Code:    1034,
+			Message: "account is locked in the directory",
+		}
+	case errors.Is(err, svc.ErrLDAPAccountDisabled):
+		return &types.BaseResponse{
+			Code:    1035,
+			Message: "account is disabled in the directory",
+		}
+	case errors.Is(err, svc.ErrLDAPAccountExpired):
+		return &types.BaseResponse{
+			Code:    1036,
+			Message: "account has expired in the directory",
+		}
+	case errors.Is(err, svc.ErrLDAPLogonRestricted):
+		return &types.BaseResponse{
+			Code:    1037,
+			Message: "logon is not permitted at this time or from this workstation",
+		}
+	}
+	return &types.BaseResponse{
+		Code:    1002,
+		Message: "authentication failed",
+	}
+}
+
+// ldapRejection 目录拒绝登录时的响应，目录要求修改密码时附带修改密码令牌，用户修改密码后重新登录
+func ldapRejection(ctx context.Context, svcCtx *svc.ServiceContext, username string, err error) (*types.BaseResponse, error) {
+	resp := ldapAuthFailure(err)
+	change, err := issueLDAPPasswordChange(ctx, svcCtx, username, err)
+	if err != nil {
+		return nil, err
+	}
+	if change != nil {
+		resp.Data = change
+	}
+	return resp, nil
+}
+
+// issueLDAPPasswordChange 目录要求修改密码时签发修改密码令牌，其它原因或 Redis 不可用时返回 nil
+func issueLDAPPasswordChange(ctx context.Context, svcCtx *svc.ServiceContext, username string, err error) (*types.LDAPPasswordChangeResp, error) {
+	var bindErr *svc.LDAPBindError
+	if svcCtx.Redis == nil || !errors.As(err, &bindErr) {
+		return nil, nil
+	}
+	if !errors.Is(err, svc.ErrLDAPPasswordMustChange) && !errors.Is(err, svc.ErrLDAPPasswordExpired) {
+		return nil, nil
+	}
+
+	token, err := svc.CreateLDAPPasswordChange(ctx, svcCtx.Redis, &svc.LDAPPasswordChange{DN: bindErr.DN, Username: username})
+	if err != nil {
+		return nil, err
+	}
+	return &types.LDAPPasswordChangeResp{
+		ChangeToken: token,
+		ExpiresIn:   int64(svc.LDAPPasswordChangeTTL.Seconds()),
+	}, nil
+}
@@ -0,0 +1,365 @@
+package logic
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	ldapSyncLockKey = "auth:ldap:sync:lock"
+	ldapSyncLastKey = "auth:ldap:sync:last"
+
+	defaultLDAPSyncInterval    = 3600 * time.Second
+	defaultLDAPSyncLockTimeout = 1800 * time.Second
+	maxLDAPSyncErrors          = 20
+)
+
+// ErrLDAPSyncRunning 已有同步在执行 (本实例或其它实例)
+var ErrLDAPSyncRunning = errors.New("LDAP sync is already running")
+
+// ldapSyncResult 单个目录用户的同步结果
+type ldapSyncResult int
+
+const (
+	ldapSyncUnchanged ldapSyncResult = iota
+	ldapSyncCreated
+	ldapSyncUpdated
+	ldapSyncDisabled
+	ldapSyncSkipped
+)
+
+// StartLDAPSync 按 Sync.Interval 定时同步 LDAP 目录，返回停止函数；未启用同步时不做任何事
+func StartLDAPSync(svcCtx *svc.ServiceContext) (stop func()) {
+	cfg := svcCtx.Config.SSO.LDAP.Sync
+	if !cfg.Enabled || svcCtx.LDAP == nil || !svcCtx.LDAP.IsEnabled() || svcCtx.Redis == nil {
+		return func() {}
+	}
+	interval := defaultLDAPSyncInterval
+	if cfg.Interval > 0 {
+		interval = time.Duration(cfg.Interval) * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				runLDAPSyncInBackground(ctx, svcCtx)
+			}
+		}
+	}()
+	logx.Infof("LDAP directory sync scheduled every %s", interval)
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// runLDAPSyncInBackground 执行一次同步并记录日志，用于定时任务与管理员触发
+func runLDAPSyncInBackground(ctx context.Context, svcCtx *svc.ServiceContext) {
+	_, err := RunLDAPSync(ctx, svcCtx)
+	if errors.Is(err, ErrLDAPSyncRunning) {
+		logx.Info("Skipping LDAP sync: another sync is running")
+		return
+	}
+	if err != nil {
+		logx.Errorf("LDAP sync failed: %v", err)
+	}
+}
+
+// RunLDAPSync 同步 LDAP 目录到本地用户:
+//   - 已关联的用户: 更新邮箱、昵称与组映射的角色；目录中已禁用的账号禁用并吊销令牌
+//   - 未关联的目录用户: 按开通策略创建账号；已有同名或同邮箱的本地账号时跳过，由首次登录关联
+//   - 已关联但目录中不存在的用户: 禁用并吊销令牌
+//
+// 目录中重新启用的账号不会自动启用 (无法区分是同步禁用还是管理员禁用)。
+// 多实例部署时通过 Redis 锁保证同一时间只有一个同步在执行，结果保存在 Redis 中供查询。
+func RunLDAPSync(ctx context.Context, svcCtx *svc.ServiceContext) (*types.LDAPSyncSummary, error) {
+	if svcCtx.LDAP == nil || !svcCtx.LDAP.IsEnabled() {
+		return nil, errors.New("LDAP is not enabled")
+	}
+
+	lockTimeout := defaultLDAPSyncLockTimeout
+	if timeout := svcCtx.Config.SSO.LDAP.Sync.LockTimeout; timeout > 0 {
+		lockTimeout = time.Duration(timeout) * time.Second
+	}
+	token := uuid.New().String()
+	locked, err := svcCtx.Redis.SetNX(ctx, ldapSyncLockKey, token, lockTimeout).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire LDAP sync lock: %w", err)
+	}
+	if !locked {
+		return nil, ErrLDAPSyncRunning
+	}
+	defer releaseLDAPSyncLock(svcCtx.Redis, token)
+
+	// 同步不能超过锁的有效期，否则可能与其它实例同时执行
+	ctx, cancel := context.WithTimeout(ctx, lockTimeout)
+	defer cancel()
+
+	// 边分页读取目录边同步，不在内存中保留全部目录用户；
+	// 读取中途失败时目录用户不完整，不能据此禁用本地用户，直接返回错误
+	summary := &types.LDAPSyncSummary{StartedAt: time.Now().Unix()}
+	seen := make(map[string]bool)
+	for userInfo, err := range svcCtx.LDAP.SyncUsers(ctx) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list LDAP users: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		identityID := svc.LDAPIdentityID(userInfo)
+		seen[identityID] = true
+		summary.Scanned++
+
+		result, err := syncLDAPUser(ctx, svcCtx, userInfo)
+		if err != nil {
+			summary.Failed++
+			addLDAPSyncError(summary, fmt.Sprintf("%s: %v", userInfo.DN, err))
+			continue
+		}
+		countLDAPSyncResult(summary, result)
+	}
+
+	if err := disableMissingLDAPUsers(ctx, svcCtx, seen, summary); err != nil {
+		addLDAPSyncError(summary, err.Error())
+	}
+
+	summary.FinishedAt = time.Now().Unix()
+	saveLDAPSyncSummary(svcCtx.Redis, summary)
+	logx.WithContext(ctx).Infof("LDAP sync finished: scanned=%d created=%d updated=%d disabled=%d skipped=%d failed=%d",
+		summary.Scanned, summary.Created, summary.Updated, summary.Disabled, summary.Skipped, summary.Failed)
+	return summary, nil
+}
+
+// syncLDAPUser 同步单个目录用户
+func syncLDAPUser(ctx context.Context, svcCtx *svc.ServiceContext, userInfo *svc.LDAPUserInfo) (ldapSyncResult, error) {
+	identityID := svc.LDAPIdentityID(userInfo)
+	user, _, err := findIdentityUser(ctx, svcCtx, ldapIdentityProvider, identityID)
+	if err != nil {
+		return ldapSyncUnchanged, err
+	}
+
+	if user == nil {
+		return provisionLDAPUser(ctx, svcCtx, userInfo)
+	}
+
+	if userInfo.Disabled {
+		disabled, err := disableLDAPUser(ctx, svcCtx, user, "disabled in directory")
+		if err != nil || !disabled {
+			return ldapSyncUnchanged, err
+		}
+		return ldapSyncDisabled, nil
+	}
+
+	result := ldapSyncUnchanged
+	changed := false
+	if userInfo.Email != "" && !strings.EqualFold(user.Email, userInfo.Email) {
+		user.Email = userInfo.Email
+		changed = true
+	}
+	if userInfo.DisplayName != "" && user.Nickname.String != userInfo.DisplayName {
+		user.Nickname = sql.NullString{String: userInfo.DisplayName, Valid: true}
+		changed = true
+	}
+	if changed {
+		if err := svcCtx.UserModel.Update(ctx, user); err != nil {
+			return ldapSyncUnchanged, fmt.Errorf("failed to update user: %w", err)
+		}
+		result = ldapSyncUpdated
+	}
+
+	if err := syncLDAPRoles(ctx, svcCtx, user.Id, userInfo.Roles); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// provisionLDAPUser 为尚未关联的目录用户按开通策略创建账号并关联身份
+func provisionLDAPUser(ctx context.Context, svcCtx *svc.ServiceContext, userInfo *svc.LDAPUserInfo) (ldapSyncResult, error) {
+	policy := svcCtx.IdentityProviders.Provisioning(ldapIdentityProvider)
+	if userInfo.Disabled || policy.Check(userInfo.Email, true, userInfo.Groups) != nil {
+		return ldapSyncSkipped, nil
+	}
+
+	// 同名或同邮箱的本地账号在用户首次 LDAP 登录并确认账号所有权后关联
+	existing, _, err := findMatchingUser(ctx, svcCtx, &ssoAccount{MatchUsername: userInfo.Username, MatchEmail: userInfo.Email})
+	if err != nil {
+		return ldapSyncUnchanged, err
+	}
+	if existing != nil {
+		return ldapSyncSkipped, nil
+	}
+
+	user, err := createLDAPUser(ctx, svcCtx, userInfo, policy)
+	if err != nil {
+		return ldapSyncUnchanged, err
+	}
+	_, err = svcCtx.UserIdentityModel.Insert(ctx, &mysql.UserIdentity{
+		UserId:         user.Id,
+		Provider:       ldapIdentityProvider,
+		ProviderUserId: svc.LDAPIdentityID(userInfo),
+		Email:          sql.NullString{String: userInfo.Email, Valid: userInfo.Email != ""},
+		LinkedAt:       time.Now(),
+	})
+	if err != nil {
+		return ldapSyncUnchanged, fmt.Errorf("failed to create user identity: %w", err)
+	}
+	if err := syncLDAPRoles(ctx, svcCtx, user.Id, userInfo.Roles); err != nil {
+		return ldapSyncCreated, err
+	}
+	return ldapSyncCreated, nil
+}
+
+// createLDAPUser 为 LDAP 用户创建本地账号 (无本地密码)，初始状态与默认角色由开通策略决定
+func createLDAPUser(ctx context.Context, svcCtx *svc.ServiceContext, userInfo *svc.LDAPUserInfo, policy *svc.ProvisioningPolicy) (*mysql.User, error) {
+	// 目录中的邮箱由组织管理，视为已验证
+	return createSSOUser(ctx, svcCtx, ldapIdentityProvider, policy, ssoProfile{
+		Username:      cmp.Or(userInfo.Username, userInfo.Email),
+		Email:         userInfo.Email,
+		EmailVerified: true,
+		Nickname:      userInfo.DisplayName,
+	})
+}
+
+// disableMissingLDAPUsers 禁用已关联 LDAP 身份但不在本次目录结果中的用户
+func disableMissingLDAPUsers(ctx context.Context, svcCtx *svc.ServiceContext, seen map[string]bool, summary *types.LDAPSyncSummary) error {
+	identities, err := svcCtx.UserIdentityModel.FindAllByProvider(ctx, ldapIdentityProvider)
+	if err != nil {
+		return fmt.Errorf("failed to list LDAP identities: %w", err)
+	}
+	// 目录返回空结果多半是过滤器或基准 DN 配置错误，此时不能禁用全部用户
+	if len(seen) == 0 && len(identities) > 0 {
+		return fmt.Errorf("directory returned no users, refusing to disable %d linked accounts", len(identities))
+	}
+
+	for _, identity := range identities {
+		if seen[identity.ProviderUserId] {
+			continue
+		}
+		user, err := svcCtx.UserModel.FindOne(ctx, identity.UserId)
+		if err == mysql.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			summary.Failed++
+			addLDAPSyncError(summary, fmt.Sprintf("%s: failed to find user: %v", identity.ProviderUserId, err))
+			continue
+		}
+
+		disabled, err := disableLDAPUser(ctx, svcCtx, user, "removed from directory")
+		if err != nil {
+			summary.Failed++
+			addLDAPSyncError(summary, fmt.Sprintf("%s: %v", identity.ProviderUserId, err))
+			continue
+		}
+		if disabled {
+			summary.Disabled++
+		}
+	}
+	return nil
+}
+
+// disableLDAPUser 禁用账号并吊销其已签发的令牌，账号已禁用时返回 false
+func disableLDAPUser(ctx context.Context, svcCtx *svc.ServiceContext, user *mysql.User, reason string) (bool, error) {
+	if user.AccountStatus == mysql.UserStatusDisabled {
+		return false, nil
+	}
+
+	user.AccountStatus = mysql.UserStatusDisabled
+	if err := svcCtx.UserModel.Update(ctx, user); err != nil {
+		return false, fmt.Errorf("failed to disable user: %w", err)
+	}
+	if err := svcCtx.JWT.RevokeUserTokens(user.Id); err != nil {
+		return true, fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	logx.WithContext(ctx).Infof("Disabled LDAP user %s (%d): %s", user.Username, user.Id, reason)
+	return true, nil
+}
+
+// getLDAPSyncStatus 返回同步是否正在执行与最近一次同步结果
+func getLDAPSyncStatus(ctx context.Context, rdb redis.UniversalClient) (*types.LDAPSyncStatusResp, error) {
+	running, err := rdb.Exists(ctx, ldapSyncLockKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get LDAP sync status: %w", err)
+	}
+	status := &types.LDAPSyncStatusResp{Running: running > 0}
+
+	data, err := rdb.Get(ctx, ldapSyncLastKey).Bytes()
+	if err == redis.Nil {
+		return status, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get LDAP sync summary: %w", err)
+	}
+	var summary types.LDAPSyncSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("failed to decode LDAP sync summary: %w", err)
+	}
+	status.Last = &summary
+	return status, nil
+}
+
+func countLDAPSyncResult(summary *types.LDAPSyncSummary, result ldapSyncResult) {
+	switch result {
+	case ldapSyncCreated:
+		summary.Created++
+	case ldapSyncUpdated:
+		summary.Updated++
+	case ldapSyncDisabled:
+		summary.Disabled++
+	case ldapSyncSkipped:
+		summary.Skipped++
+	}
+}
+
+func addLDAPSyncError(summary *types.LDAPSyncSummary, message string) {
+	logx.Errorf("LDAP sync: %s", message)
+	if len(summary.Errors) < maxLDAPSyncErrors {
+		summary.Errors = append(summary.Errors, message)
+	}
+}
+
+func saveLDAPSyncSummary(rdb redis.UniversalClient, summary *types.LDAPSyncSummary) {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		logx.Errorf("Failed to encode LDAP sync summary: %v", err)
+		return
+	}
+	if err := rdb.Set(context.Background(), ldapSyncLastKey, data, 0).Err(); err != nil {
+		logx.Errorf("Failed to save LDAP sync summary: %v", err)
+	}
+}
+
+// releaseLDAPSyncLock 只释放自己持有的锁 (锁可能已超时并被其它实例获取)
+func releaseLDAPSyncLock(rdb redis.UniversalClient, token string) {
+	ctx := context.Background()
+	current, err := rdb.Get(ctx, ldapSyncLockKey).Result()
+	if err != nil || current != token {
+		return
+	}
+	if err := rdb.Del(ctx, ldapSyncLockKey).Err(); err != nil {
+		logx.Errorf("Failed to release LDAP sync lock: %v", err)
+	}
+}
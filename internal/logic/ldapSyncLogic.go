@@ -0,0 +1,63 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+)
+
+type LDAPSyncLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewLDAPSyncLogic(ctx context.Context, svcCtx *svc.ServiceContext) *LDAPSyncLogic {
+	return &LDAPSyncLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// LDAPSync 立即在后台执行一次目录同步，结果通过同步状态接口查询
+func (l *LDAPSyncLogic) LDAPSync() (resp *types.BaseResponse, err error) {
+	if err := requireAdmin(l.ctx, l.svcCtx); err != nil {
+		return nil, err
+	}
+	if l.svcCtx.LDAP == nil || !l.svcCtx.LDAP.IsEnabled() {
+		return &types.BaseResponse{
+			Code:    1001,
+			Message: "LDAP login is disabled",
+		}, nil
+	}
+
+	status, err := getLDAPSyncStatus(l.ctx, l.svcCtx.Redis)
+	if err != nil {
+		return nil, err
+	}
+	if status.Running {
+		return &types.BaseResponse{
+			Code:    1029,
+			Message: "LDAP sync is already running",
+		}, nil
+	}
+
+	// 同步可能较慢，不受请求超时与请求上下文取消的影响
+	threading.GoSafe(func() {
+		runLDAPSyncInBackground(context.Background(), l.svcCtx)
+	})
+	l.Infof("LDAP sync triggered by admin")
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "sync started",
+	}, nil
+}
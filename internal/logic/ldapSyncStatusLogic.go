@@ -0,0 +1,45 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type LDAPSyncStatusLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewLDAPSyncStatusLogic(ctx context.Context, svcCtx *svc.ServiceContext) *LDAPSyncStatusLogic {
+	return &LDAPSyncStatusLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// LDAPSyncStatus 查询目录同步是否正在执行与最近一次同步结果
+func (l *LDAPSyncStatusLogic) LDAPSyncStatus() (resp *types.BaseResponse, err error) {
+	if err := requireAdmin(l.ctx, l.svcCtx); err != nil {
+		return nil, err
+	}
+
+	status, err := getLDAPSyncStatus(l.ctx, l.svcCtx.Redis)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+		Data:    status,
+	}, nil
+}
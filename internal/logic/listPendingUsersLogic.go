@@ -0,0 +1,68 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ListPendingUsersLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewListPendingUsersLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ListPendingUsersLogic {
+	return &ListPendingUsersLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// ListPendingUsers 列出 SSO 自动开通后等待审批的用户
+func (l *ListPendingUsersLogic) ListPendingUsers() (resp *types.BaseResponse, err error) {
+	if err := requireAdmin(l.ctx, l.svcCtx); err != nil {
+		return nil, err
+	}
+
+	users, err := l.svcCtx.UserModel.FindAllByAccountStatus(l.ctx, mysql.UserStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending users: %w", err)
+	}
+
+	pending := make([]types.PendingUser, 0, len(users))
+	for _, user := range users {
+		identities, err := l.svcCtx.UserIdentityModel.FindAllByUserId(l.ctx, user.Id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list user identities: %w", err)
+		}
+		providers := make([]string, 0, len(identities))
+		for _, identity := range identities {
+			providers = append(providers, identity.Provider)
+		}
+
+		pending = append(pending, types.PendingUser{
+			UserID:    user.PublicId,
+			Username:  user.Username,
+			Email:     user.Email,
+			Nickname:  user.Nickname.String,
+			Providers: providers,
+			CreatedAt: user.CreatedAt.Unix(),
+		})
+	}
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+		Data:    types.PendingUsersResp{Users: pending},
+	}, nil
+}
@@ -3,6 +3,7 @@ package logic
 import (
 	"context"
 
+	"auth-service/internal/config"
 	"auth-service/internal/svc"
 	"auth-service/internal/types"
 	model "auth-service/model/mysql"
@@ -29,17 +30,9 @@ func (l *LoginLogic) Login(req *types.LoginReq) (resp *types.LoginResp, err erro
 	l.Info("Login request received", ", username: ", req.Username, ", captchaId: ", req.CaptchaID)
 
 	// 校验验证码（如果开启了验证码）
-	if l.svcCtx.Config.Captcha.Enable {
-		if req.CaptchaID != "" || req.CaptchaAnswer != "" {
-			match := l.svcCtx.Captcha.Verify(req.CaptchaID, req.CaptchaAnswer, true)
-			if !match {
-				l.Info("Captcha verify failed", ", captchaId: ", req.CaptchaID)
-				return nil, types.ErrCaptchaInvalid
-			}
-		} else {
-			l.Info("Captcha is required but not provided")
-			return nil, types.ErrCaptchaRequired
-		}
+	if err := verifyCaptcha(l.svcCtx, req.CaptchaID, req.CaptchaAnswer); err != nil {
+		l.Info("Captcha verify failed", ", captchaId: ", req.CaptchaID, ", error: ", err)
+		return nil, err
 	}
 
 	var (
@@ -104,8 +97,26 @@ func (l *LoginLogic) Login(req *types.LoginReq) (resp *types.LoginResp, err erro
 		return nil, types.ErrInvalidPassword
 	}
 
+	// 待审批 (SSO 开通后等待审批) 或已禁用 (如目录同步禁用) 的账号不能以本地密码登录
+	if err := accountStatusError(&user); err != nil {
+		l.Info("Account is not active", ", username: ", req.Username, ", status: ", user.AccountStatus)
+		return nil, err
+	}
+
+	// 邮箱未验证时按配置拒绝登录或签发受限令牌
+	var scope string
+	if user.EmailVerified == 0 && l.svcCtx.Config.EmailVerification.Enable {
+		switch l.svcCtx.Config.EmailVerification.Enforce {
+		case config.EmailVerificationEnforceBlock:
+			l.Info("Email is not verified", ", username: ", req.Username)
+			return nil, types.ErrEmailNotVerified
+		case config.EmailVerificationEnforceRestrict:
+			scope = svc.ScopeEmailUnverified
+		}
+	}
+
 	// 生成 JWT Pair
-	tokenPair, err := l.svcCtx.JWT.Generate(user.Id, user.Username)
+	tokenPair, _, err := issueLoginTokens(l.ctx, l.svcCtx, &user, scope)
 	if err != nil {
 		l.Errorf("Failed to generate JWT: %v", err)
 		return nil, types.ErrGenerateToken
@@ -126,6 +137,8 @@ func (l *LoginLogic) Login(req *types.LoginReq) (resp *types.LoginResp, err erro
 		AccessExpiresAt:  tokenPair.AccessExpiresAt,
 		RefreshToken:     tokenPair.RefreshToken,
 		RefreshExpiresAt: tokenPair.RefreshExpiresAt,
+		EmailVerified:    user.EmailVerified != 0,
+		Scope:            scope,
 	}
 	return resp, nil
 }
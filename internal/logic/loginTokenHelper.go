@@ -0,0 +1,36 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"auth-service/internal/svc"
+	"auth-service/model/mysql"
+)
+
+// userRoleNames 返回用户当前的全部角色 (手动分配、开通时分配与目录同步的角色)
+func userRoleNames(ctx context.Context, svcCtx *svc.ServiceContext, userID uint64) ([]string, error) {
+	userRoles, err := svcCtx.UserRoleModel.FindAllByUserId(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user roles: %w", err)
+	}
+	roles := make([]string, 0, len(userRoles))
+	for _, userRole := range userRoles {
+		roles = append(roles, userRole.Role)
+	}
+	return roles, nil
+}
+
+// issueLoginTokens 为登录的用户签发包含其当前角色的令牌对，scope 不为空时签发受限令牌。
+// 各种登录方式都通过此函数签发令牌，返回令牌中的角色
+func issueLoginTokens(ctx context.Context, svcCtx *svc.ServiceContext, user *mysql.User, scope string) (*svc.TokenPair, []string, error) {
+	roles, err := userRoleNames(ctx, svcCtx, user.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	tokenPair, err := svcCtx.JWT.GenerateWithScope(user.Id, user.Username, roles, scope)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	return tokenPair, roles, nil
+}
@@ -2,6 +2,7 @@ package logic
 
 import (
 	"context"
+	"strings"
 
 	"auth-service/internal/svc"
 	"auth-service/internal/types"
@@ -24,6 +25,9 @@ func NewLogoutLogic(ctx context.Context, svcCtx *svc.ServiceContext) *LogoutLogi
 }
 
 func (l *LogoutLogic) Logout(req *types.LogoutReq) (resp *types.BaseResponse, err error) {
+	// 令牌失效前先取出会话 ID
+	claims, verifyErr := l.svcCtx.JWT.VerifyAccessToken(req.AccessToken)
+
 	// 登出
 	if err := l.svcCtx.JWT.Logout(req.AccessToken, req.RefreshToken); err != nil {
 		return &types.BaseResponse{
@@ -36,5 +40,46 @@ func (l *LogoutLogic) Logout(req *types.LogoutReq) (resp *types.BaseResponse, er
 		Code:    0,
 		Message: "Logout",
 	}
+
+	// OIDC 登录的会话还需跳转到 IdP 结束 IdP 会话
+	if verifyErr == nil && claims.SessionID != "" && l.svcCtx.Redis != nil {
+		if err := svc.DeleteLoginSession(l.ctx, l.svcCtx.Redis, claims.SessionID); err != nil {
+			l.Logger.Errorf("Failed to delete login session: %v", err)
+		}
+		if endSessionURL := l.endOIDCSession(claims.SessionID, req.PostLogoutRedirectURL); endSessionURL != "" {
+			resp.Data = types.LogoutResp{EndSessionURL: endSessionURL}
+		}
+	}
 	return resp, nil
 }
+
+// endOIDCSession 删除会话与 IdP 会话的关联，返回 IdP 的 end_session URL (非 OIDC 会话返回空)
+func (l *LogoutLogic) endOIDCSession(sessionID, postLogoutRedirectURL string) string {
+	session, err := svc.GetOIDCSession(l.ctx, l.svcCtx.Redis, sessionID)
+	if err != nil {
+		l.Logger.Errorf("Failed to get OIDC session: %v", err)
+		return ""
+	}
+	if session == nil {
+		return ""
+	}
+	if err := svc.DeleteOIDCSession(l.ctx, l.svcCtx.Redis, sessionID, session); err != nil {
+		l.Logger.Errorf("Failed to delete OIDC session: %v", err)
+	}
+
+	// 提供者为空时 Get 会回退到默认提供者，不能据此结束 IdP 会话
+	if session.Provider == "" {
+		return ""
+	}
+	provider, ok := l.svcCtx.OIDC.Get(session.Provider)
+	if !ok {
+		return ""
+	}
+
+	// 只把白名单内的绝对地址交给 IdP
+	if postLogoutRedirectURL != "" && (strings.HasPrefix(postLogoutRedirectURL, "/") ||
+		!svc.IsAllowedRedirectURL(l.svcCtx.Config.SSO.AllowedRedirectURLs, postLogoutRedirectURL)) {
+		postLogoutRedirectURL = ""
+	}
+	return provider.GetLogoutURL(session.IDToken, postLogoutRedirectURL)
+}
@@ -0,0 +1,115 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type MagicLinkConsumeLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewMagicLinkConsumeLogic(ctx context.Context, svcCtx *svc.ServiceContext) *MagicLinkConsumeLogic {
+	return &MagicLinkConsumeLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// MagicLinkConsume 使用邮件中的登录链接登录，成功时签发与密码登录相同的令牌。
+// 能收到链接即证明拥有该邮箱，因此同时将邮箱标记为已验证
+func (l *MagicLinkConsumeLogic) MagicLinkConsume(req *types.MagicLinkConsumeReq) (resp *types.BaseResponse, err error) {
+	if !magicLinkEnabled(l.svcCtx) {
+		return &types.BaseResponse{
+			Code:    503,
+			Message: "邮箱登录功能未启用",
+		}, nil
+	}
+
+	invalid := &types.BaseResponse{
+		Code:    400,
+		Message: "登录链接无效或已过期",
+	}
+	link, err := svc.ConsumeMagicLink(l.ctx, l.svcCtx.Redis, l.svcCtx.Config.Auth.AccessSecret, req.Token, req.DeviceID)
+	if errors.Is(err, svc.ErrInvalidMagicLink) {
+		return invalid, nil
+	}
+	if errors.Is(err, svc.ErrMagicLinkDevice) {
+		return &types.BaseResponse{
+			Code:    400,
+			Message: "请在申请登录链接的浏览器中打开此链接",
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 链接发送后用户修改了邮箱时链接失效
+	user, err := l.svcCtx.UserModel.FindOne(l.ctx, link.UserID)
+	if err == mysql.ErrNotFound {
+		return invalid, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Email != link.Email {
+		l.Infof("Magic link of user %s no longer matches the account email", user.PublicId)
+		return invalid, nil
+	}
+	if denied := checkAccountStatus(user); denied != nil {
+		return denied, nil
+	}
+
+	tokenPair, _, err := issueLoginTokens(l.ctx, l.svcCtx, user, "")
+	if err != nil {
+		l.Errorf("Failed to generate JWT: %v", err)
+		return nil, types.ErrGenerateToken
+	}
+
+	// 在会话登记中记录登录方式
+	session := &svc.LoginSession{
+		Method:    svc.SessionMethodEmail,
+		UserID:    user.Id,
+		CreatedAt: time.Now().Unix(),
+	}
+	ttl := time.Duration(l.svcCtx.Config.Auth.RefreshExpiresIn) * time.Second
+	if err := svc.SaveLoginSession(l.ctx, l.svcCtx.Redis, tokenPair.SessionID, session, ttl); err != nil {
+		l.Errorf("Failed to save login session: %v", err)
+	}
+
+	user.EmailVerified = 1
+	user.LastLoginAt = sql.NullTime{Time: time.Now(), Valid: true}
+	if err := l.svcCtx.UserModel.Update(l.ctx, user); err != nil {
+		l.Errorf("Failed to update user %s after magic link login: %v", user.PublicId, err)
+	}
+
+	l.Infof("User %s logged in with magic link", user.PublicId)
+	return &types.BaseResponse{
+		Code:    200,
+		Message: "登录成功",
+		Data: &types.LoginResp{
+			UserID:           user.PublicId,
+			Username:         user.Username,
+			Email:            user.Email,
+			AccessToken:      tokenPair.AccessToken,
+			AccessExpiresAt:  tokenPair.AccessExpiresAt,
+			RefreshToken:     tokenPair.RefreshToken,
+			RefreshExpiresAt: tokenPair.RefreshExpiresAt,
+			EmailVerified:    true,
+		},
+	}, nil
+}
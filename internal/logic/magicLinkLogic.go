@@ -0,0 +1,101 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/threading"
+)
+
+type MagicLinkLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewMagicLinkLogic(ctx context.Context, svcCtx *svc.ServiceContext) *MagicLinkLogic {
+	return &MagicLinkLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// MagicLink 向注册邮箱发送一次性登录链接。
+// 与忘记密码相同，无论邮箱是否已注册都返回相同的响应，邮件在后台发送
+func (l *MagicLinkLogic) MagicLink(req *types.MagicLinkReq) (resp *types.BaseResponse, err error) {
+	// 校验验证码（如果开启了验证码）
+	if err := verifyCaptcha(l.svcCtx, req.CaptchaID, req.CaptchaAnswer); err != nil {
+		return nil, err
+	}
+
+	if !magicLinkEnabled(l.svcCtx) {
+		return &types.BaseResponse{
+			Code:    503,
+			Message: "邮箱登录功能未启用",
+		}, nil
+	}
+	if l.svcCtx.Config.MagicLink.DeviceBinding && req.DeviceID == "" {
+		return &types.BaseResponse{
+			Code:    400,
+			Message: "缺少设备标识",
+		}, nil
+	}
+
+	resp = &types.BaseResponse{
+		Code:    200,
+		Message: "如果该邮箱已注册，登录链接将发送到该邮箱",
+	}
+
+	// 同一邮箱的发送间隔与每小时上限，超出时静默丢弃
+	allowed, err := svc.AllowMagicLink(l.ctx, l.svcCtx.Redis, req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		l.Infof("Magic link for %s is rate limited", maskEmail(req.Email))
+		return resp, nil
+	}
+
+	user, err := l.svcCtx.UserModel.FindOneByEmail(l.ctx, req.Email)
+	if err == mysql.ErrNotFound {
+		l.Infof("Magic link requested for unknown email %s", maskEmail(req.Email))
+		return resp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.AccountStatus != mysql.UserStatusActive || isPlaceholderEmail(user.Email) {
+		l.Infof("Not sending magic link to user %s", user.PublicId)
+		return resp, nil
+	}
+
+	token, err := svc.CreateMagicLink(l.ctx, l.svcCtx.Redis, l.svcCtx.Config.Auth.AccessSecret,
+		&svc.MagicLink{UserID: user.Id, Email: user.Email}, req.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	loginURL := strings.TrimRight(l.svcCtx.Config.FrontendURL, "/") + "/magic-link?token=" + url.QueryEscape(token)
+
+	email, publicID, logger := user.Email, user.PublicId, l.Logger
+	threading.GoSafe(func() {
+		if err := l.svcCtx.Email.SendMagicLink(email, loginURL); err != nil {
+			logger.Errorf("Failed to send magic link to user %s: %v", publicID, err)
+		}
+	})
+	return resp, nil
+}
+
+// magicLinkEnabled 邮箱登录需要开启配置并提供邮件、Redis 与前端地址
+func magicLinkEnabled(svcCtx *svc.ServiceContext) bool {
+	return svcCtx.Config.MagicLink.Enable && svcCtx.Email != nil && svcCtx.Redis != nil && svcCtx.Config.FrontendURL != ""
+}
@@ -0,0 +1,165 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+
+	"encoding/json"
+	"fmt"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type OAuth2CallbackLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewOAuth2CallbackLogic(ctx context.Context, svcCtx *svc.ServiceContext) *OAuth2CallbackLogic {
+	return &OAuth2CallbackLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+func (l *OAuth2CallbackLogic) OAuth2Callback(req *types.OAuth2CallbackReq) (resp *types.BaseResponse, err error) {
+	// 1. Verify State
+	cachedKey := fmt.Sprintf("auth:oauth2:state:%s", req.State)
+	// 原子地取出并删除 state，并发的回调只有一个能使用同一 state
+	stateData, err := l.svcCtx.Redis.GetDel(l.ctx, cachedKey).Result()
+	if err == redis.Nil {
+		return &types.BaseResponse{
+			Code:    1002,
+			Message: "invalid or expired state",
+		}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to check state: %w", err)
+	}
+
+	var state svc.OAuth2State
+	if err := json.Unmarshal([]byte(stateData), &state); err != nil || state.Provider != req.Provider {
+		return &types.BaseResponse{
+			Code:    1002,
+			Message: "invalid or expired state",
+		}, nil
+	}
+
+	resp, err = l.complete(&state, req)
+	if state.RedirectURL == "" {
+		return resp, err
+	}
+	// Browser flow: hand the result back to the redirect URL given at login
+	return redirectSSOResult(l.ctx, l.svcCtx, state.RedirectURL, resp, err)
+}
+
+// complete 完成授权码交换并匹配本地用户
+func (l *OAuth2CallbackLogic) complete(state *svc.OAuth2State, req *types.OAuth2CallbackReq) (resp *types.BaseResponse, err error) {
+
+	provider, ok := l.svcCtx.OAuth2.Get(state.Provider)
+	if !ok {
+		return &types.BaseResponse{
+			Code:    1001,
+			Message: "OAuth2 login is disabled",
+		}, nil
+	}
+
+	if req.Error != "" || req.Code == "" {
+		return &types.BaseResponse{
+			Code:    1003,
+			Message: fmt.Sprintf("OAuth2 login failed: %s - %s", req.Error, req.ErrorDescription),
+		}, nil
+	}
+
+	// 2. Exchange Code for Token
+	token, err := provider.ExchangeCode(l.ctx, req.Code)
+	if err != nil {
+		l.Logger.Errorf("Failed to exchange code: %v", err)
+		return &types.BaseResponse{
+			Code:    1004,
+			Message: "Failed to exchange code",
+		}, nil
+	}
+
+	// 3. Get User Info
+	userInfo, err := provider.GetUserInfo(l.ctx, token)
+	if err != nil {
+		l.Logger.Errorf("Failed to get user info: %v", err)
+		return &types.BaseResponse{
+			Code:    1005,
+			Message: "Failed to get user info",
+		}, nil
+	}
+
+	// Link flow: attach the identity to the signed-in user instead of logging in
+	if state.LinkUserID != 0 {
+		return linkIdentity(l.ctx, l.svcCtx, state.LinkUserID, state.Provider, userInfo.ProviderUserID, userInfo.Email)
+	}
+
+	// 4. Find or Create User, by the linked identity (provider + user ID) first.
+	// Only a verified real email can match an existing account, and only links when trusted
+	email := oauth2VerifiedEmail(userInfo)
+	account, resp, err := findOrCreateSSOUser(l.ctx, l.svcCtx, &ssoAccount{
+		Provider:       state.Provider,
+		ProviderUserID: userInfo.ProviderUserID,
+		MatchEmail:     email,
+		AutoLink:       canAutoLink(email != "", l.svcCtx.OAuth2.TrustEmail(state.Provider)),
+		Policy:         l.svcCtx.OAuth2.Provisioning(state.Provider),
+		Profile: ssoProfile{
+			Username:      userInfo.Username,
+			Email:         email,
+			EmailVerified: email != "",
+			Nickname:      userInfo.DisplayName,
+			Groups:        userInfo.Groups,
+		},
+	})
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	user := account.User
+	// The identity stays linked so the account can sign in once approved
+	if denied := checkAccountStatus(user); denied != nil {
+		return denied, nil
+	}
+
+	// 5. Generate JWT
+	tokenPair, _, err := issueLoginTokens(l.ctx, l.svcCtx, user, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+		Data: types.OAuth2CallbackResp{
+			UserID:           user.PublicId,
+			Username:         user.Username,
+			Email:            user.Email,
+			DisplayName:      userInfo.DisplayName,
+			AccessToken:      tokenPair.AccessToken,
+			AccessExpiresAt:  tokenPair.AccessExpiresAt,
+			RefreshToken:     tokenPair.RefreshToken,
+			RefreshExpiresAt: tokenPair.RefreshExpiresAt,
+			TokenType:        "Bearer",
+			IsNewUser:        account.IsNewUser,
+			Provider:         state.Provider,
+		},
+	}, nil
+}
+
+// oauth2VerifiedEmail 返回可用于匹配和创建本地用户的邮箱。
+// 社交平台的邮箱未必经过验证 (微信等甚至不返回邮箱)，此时返回空字符串，以随机的占位邮箱创建账号，
+// 社交账号只通过 user_identity 中的关联找回本地用户。
+func oauth2VerifiedEmail(userInfo *types.SSOUserInfo) string {
+	if userInfo.EmailVerified && userInfo.Email != "" && !isPlaceholderEmail(userInfo.Email) {
+		return userInfo.Email
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+
+	"fmt"
+	"time"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// logoutTokenReplayTTL 已处理的登出令牌 jti 的保留时间
+const logoutTokenReplayTTL = 10 * time.Minute
+
+type OIDCBackchannelLogoutLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewOIDCBackchannelLogoutLogic(ctx context.Context, svcCtx *svc.ServiceContext) *OIDCBackchannelLogoutLogic {
+	return &OIDCBackchannelLogoutLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// OIDCBackchannelLogout 处理 IdP 的后端登出通知，吊销与 sid / sub 关联的所有本地会话
+func (l *OIDCBackchannelLogoutLogic) OIDCBackchannelLogout(req *types.OIDCBackchannelLogoutReq) (resp *types.BaseResponse, err error) {
+	providerName, logoutToken := l.verify(req)
+	if logoutToken == nil {
+		return &types.BaseResponse{
+			Code:    1010,
+			Message: "invalid logout token",
+		}, nil
+	}
+
+	// Reject replayed logout tokens
+	replayKey := fmt.Sprintf("auth:oidc:logout:jti:%s:%s", providerName, logoutToken.JTI)
+	fresh, err := l.svcCtx.Redis.SetNX(l.ctx, replayKey, "1", logoutTokenReplayTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to record logout token: %w", err)
+	}
+	if !fresh {
+		return &types.BaseResponse{
+			Code:    1010,
+			Message: "invalid logout token",
+		}, nil
+	}
+
+	sessionIDs, err := svc.FindOIDCSessions(l.ctx, l.svcCtx.Redis, providerName, logoutToken.Subject, logoutToken.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sessionID := range sessionIDs {
+		if err := l.svcCtx.JWT.RevokeSession(sessionID); err != nil {
+			return nil, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		session, err := svc.GetOIDCSession(l.ctx, l.svcCtx.Redis, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		if session != nil {
+			if err := svc.DeleteOIDCSession(l.ctx, l.svcCtx.Redis, sessionID, session); err != nil {
+				l.Logger.Errorf("Failed to delete OIDC session %s: %v", sessionID, err)
+			}
+		}
+	}
+
+	l.Logger.Infof("OIDC back-channel logout from %s revoked %d session(s) (sub=%q, sid=%q)",
+		providerName, len(sessionIDs), logoutToken.Subject, logoutToken.SessionID)
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+	}, nil
+}
+
+// verify 使用指定提供者校验登出令牌；未指定提供者时依次尝试所有已启用的提供者
+func (l *OIDCBackchannelLogoutLogic) verify(req *types.OIDCBackchannelLogoutReq) (string, *svc.OIDCLogoutToken) {
+	var names []string
+	if req.Provider != "" {
+		names = []string{req.Provider}
+	} else {
+		for _, entry := range l.svcCtx.OIDC.List() {
+			names = append(names, entry.Name)
+		}
+	}
+
+	for _, name := range names {
+		provider, ok := l.svcCtx.OIDC.Get(name)
+		if !ok {
+			continue
+		}
+		logoutToken, err := provider.VerifyLogoutToken(l.ctx, req.LogoutToken)
+		if err != nil {
+			l.Logger.Infof("Logout token rejected by OIDC provider %s: %v", name, err)
+			continue
+		}
+		return name, logoutToken
+	}
+	return "", nil
+}
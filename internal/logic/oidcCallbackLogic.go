@@ -4,16 +4,16 @@
 package logic
 
 import (
+	"cmp"
 	"context"
 
-	"database/sql"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"auth-service/internal/svc"
 	"auth-service/internal/types"
-	"auth-service/model/mysql"
 
-	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"github.com/zeromicro/go-zero/core/logx"
 )
@@ -33,16 +33,10 @@ func NewOIDCCallbackLogic(ctx context.Context, svcCtx *svc.ServiceContext) *OIDC
 }
 
 func (l *OIDCCallbackLogic) OIDCCallback(req *types.OIDCCallbackReq) (resp *types.BaseResponse, err error) {
-	if !l.svcCtx.OIDC.IsEnabled() {
-		return &types.BaseResponse{
-			Code:    1001,
-			Message: "OIDC login is disabled",
-		}, nil
-	}
-
 	// 1. Verify State
 	cachedKey := fmt.Sprintf("auth:oidc:state:%s", req.State)
-	_, err = l.svcCtx.Redis.Get(l.ctx, cachedKey).Result()
+	// 原子地取出并删除 state，并发的回调只有一个能使用同一 state (及其 PKCE verifier / nonce)
+	stateData, err := l.svcCtx.Redis.GetDel(l.ctx, cachedKey).Result()
 	if err == redis.Nil {
 		return &types.BaseResponse{
 			Code:    1002,
@@ -51,7 +45,43 @@ func (l *OIDCCallbackLogic) OIDCCallback(req *types.OIDCCallbackReq) (resp *type
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to check state: %w", err)
 	}
-	defer l.svcCtx.Redis.Del(l.ctx, cachedKey)
+
+	var state svc.OIDCState
+	if err := json.Unmarshal([]byte(stateData), &state); err != nil || state.Nonce == "" {
+		return &types.BaseResponse{
+			Code:    1002,
+			Message: "invalid or expired state",
+		}, nil
+	}
+
+	resp, err = l.complete(&state, req)
+	if state.RedirectURL == "" {
+		return resp, err
+	}
+	// Browser flow: hand the result back to the redirect URL given at login
+	return redirectSSOResult(l.ctx, l.svcCtx, state.RedirectURL, resp, err)
+}
+
+// complete 完成授权码交换、ID Token 校验和本地用户匹配
+func (l *OIDCCallbackLogic) complete(state *svc.OIDCState, req *types.OIDCCallbackReq) (resp *types.BaseResponse, err error) {
+	if state.Provider == "" {
+		state.Provider = svc.DefaultOIDCProviderName
+	}
+	// The callback must come back through the provider that started the flow
+	if req.Provider != "" && req.Provider != state.Provider {
+		return &types.BaseResponse{
+			Code:    1002,
+			Message: "invalid or expired state",
+		}, nil
+	}
+
+	provider, ok := l.svcCtx.OIDC.Get(state.Provider)
+	if !ok {
+		return &types.BaseResponse{
+			Code:    1001,
+			Message: "OIDC login is disabled",
+		}, nil
+	}
 
 	if req.Error != "" {
 		return &types.BaseResponse{
@@ -61,7 +91,7 @@ func (l *OIDCCallbackLogic) OIDCCallback(req *types.OIDCCallbackReq) (resp *type
 	}
 
 	// 2. Exchange Code for Token
-	tokenResp, err := l.svcCtx.OIDC.ExchangeCode(l.ctx, req.Code)
+	tokenResp, err := provider.ExchangeCode(l.ctx, req.Code, state.CodeVerifier)
 	if err != nil {
 		l.Logger.Errorf("Failed to exchange code: %v", err)
 		return &types.BaseResponse{
@@ -70,8 +100,18 @@ func (l *OIDCCallbackLogic) OIDCCallback(req *types.OIDCCallbackReq) (resp *type
 		}, nil
 	}
 
-	// 3. Get User Info
-	userInfo, err := l.svcCtx.OIDC.GetUserInfo(l.ctx, tokenResp.AccessToken)
+	// 3. Verify ID Token (signature, iss, aud, exp, azp, nonce)
+	idToken, err := provider.VerifyIDToken(l.ctx, tokenResp.IDToken, state.Nonce)
+	if err != nil {
+		l.Logger.Errorf("Failed to verify ID token: %v", err)
+		return &types.BaseResponse{
+			Code:    1006,
+			Message: "invalid ID token",
+		}, nil
+	}
+
+	// 4. Get User Info
+	userInfo, err := provider.GetUserInfo(l.ctx, tokenResp.AccessToken)
 	if err != nil {
 		l.Logger.Errorf("Failed to get user info: %v", err)
 		return &types.BaseResponse{
@@ -80,99 +120,80 @@ func (l *OIDCCallbackLogic) OIDCCallback(req *types.OIDCCallbackReq) (resp *type
 		}, nil
 	}
 
-	// 4. Find or Create User
-	var user *mysql.User
-	var isNewUser bool
-
-	// Try to find by Email
-	if userInfo.Email != "" {
-		user, err = l.svcCtx.UserModel.FindOneByEmail(l.ctx, userInfo.Email)
-		if err != nil && err != mysql.ErrNotFound {
-			return nil, fmt.Errorf("failed to find user by email: %w", err)
-		}
+	// UserInfo sub must match the ID token sub (OIDC Core 5.3.2)
+	if userInfo.Sub != idToken.Subject {
+		l.Logger.Errorf("UserInfo sub %q does not match ID token sub %q", userInfo.Sub, idToken.Subject)
+		return &types.BaseResponse{
+			Code:    1006,
+			Message: "invalid ID token",
+		}, nil
 	}
 
-	if user == nil {
-		// New User Logic
-		isNewUser = true
+	// 5. Map ID token and UserInfo claims (UserInfo takes precedence) to the local profile
+	identity := l.svcCtx.OIDC.Mapper(state.Provider).Map(svc.MergeClaims(idToken.ClaimSet(), userInfo.ClaimSet()))
 
-		email := userInfo.Email
-		if email == "" {
-			// Generate placeholder email to satisfy unique constraint
-			email = fmt.Sprintf("%s@no-email.placeholder", uuid.New().String())
-		}
-
-		// Determine Username
-		username := userInfo.PreferredUsername
-		if username == "" {
-			username = email
-		}
-		if username == "" || userInfo.Email == "" { // if original email was empty, username might be set to placeholder email which is ugly but unique.
-			username = "user_" + uuid.New().String()[:8]
-		}
-
-		// Ensure username uniqueness
-		for {
-			_, err := l.svcCtx.UserModel.FindOneByUsername(l.ctx, username)
-			if err == mysql.ErrNotFound {
-				break
-			}
-			if err != nil {
-				return nil, err
-			}
-			username = fmt.Sprintf("%s_%s", username, uuid.New().String()[:4])
-		}
-
-		// Create Password
-		newPass := uuid.New().String()
-		hashedPass := l.svcCtx.PasswordEncoder.Hash(newPass)
-
-		newUser := &mysql.User{
-			PublicId:      uuid.New().String(),
-			Username:      username,
-			Email:         email,
-			EmailVerified: 0,
-			PasswordHash:  hashedPass,
-			AccountStatus: mysql.UserStatusActive, // 1
-		}
+	// Link flow: attach the identity to the signed-in user instead of logging in
+	if state.LinkUserID != 0 {
+		return linkIdentity(l.ctx, l.svcCtx, state.LinkUserID, state.Provider, idToken.Subject, identity.Email)
+	}
 
-		if userInfo.EmailVerified {
-			newUser.EmailVerified = 1
-		}
-		if userInfo.Name != "" {
-			newUser.Nickname = sql.NullString{String: userInfo.Name, Valid: true}
-		}
+	// 6. Find or Create User, by the linked identity (provider + sub) first, then by email.
+	// An email match alone does not prove ownership of the local account
+	account, resp, err := findOrCreateSSOUser(l.ctx, l.svcCtx, &ssoAccount{
+		Provider:       state.Provider,
+		ProviderUserID: idToken.Subject,
+		MatchEmail:     identity.Email,
+		AutoLink:       canAutoLink(identity.EmailVerified, l.svcCtx.OIDC.TrustEmail(state.Provider)),
+		Session: &svc.OIDCSession{
+			Provider:     state.Provider,
+			Subject:      idToken.Subject,
+			IdPSessionID: idToken.SessionID,
+			IDToken:      tokenResp.IDToken,
+		},
+		Policy: l.svcCtx.OIDC.Provisioning(state.Provider),
+		Profile: ssoProfile{
+			Username:      cmp.Or(identity.Username, identity.Email),
+			Email:         identity.Email,
+			EmailVerified: identity.EmailVerified,
+			Nickname:      identity.Nickname,
+			Phone:         identity.Phone,
+			Groups:        identity.Groups,
+		},
+	})
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	user := account.User
+	// The identity stays linked so the account can sign in once approved
+	if denied := checkAccountStatus(user); denied != nil {
+		return denied, nil
+	}
 
-		res, err := l.svcCtx.UserModel.Insert(l.ctx, newUser)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create user: %w", err)
-		}
+	// 7. Generate JWT
+	tokenPair, _, err := issueLoginTokens(l.ctx, l.svcCtx, user, "")
+	if err != nil {
+		return nil, err
+	}
 
-		uid, err := res.LastInsertId()
-		if err != nil {
-			return nil, err
-		}
+	// 8. Link the local session to the IdP session for RP-initiated and back-channel logout
+	session := &svc.OIDCSession{
+		Provider:     state.Provider,
+		Subject:      idToken.Subject,
+		IdPSessionID: idToken.SessionID,
+		IDToken:      tokenResp.IDToken,
+		UserID:       user.Id,
+		CreatedAt:    time.Now().Unix(),
+	}
+	ttl := time.Duration(l.svcCtx.Config.Auth.RefreshExpiresIn) * time.Second
+	if err := svc.SaveOIDCSession(l.ctx, l.svcCtx.Redis, tokenPair.SessionID, session, ttl); err != nil {
+		l.Logger.Errorf("Failed to save OIDC session: %v", err)
+	}
 
-		// Fetch the complete user object
-		user, err = l.svcCtx.UserModel.FindOne(l.ctx, uint64(uid))
-		if err != nil {
-			return nil, err
+	// 9. Keep the upstream tokens for brokered retrieval
+	if l.svcCtx.UpstreamTokens != nil {
+		if err := l.svcCtx.UpstreamTokens.Save(l.ctx, user.Id, state.Provider, svc.NewUpstreamToken(tokenResp)); err != nil {
+			l.Logger.Errorf("Failed to save upstream token: %v", err)
 		}
-	} else {
-		// Existing user logic (optional: update info)
-		// For example, if email was not verified but now OIDC says it is
-		/*
-			if user.EmailVerified == 0 && userInfo.EmailVerified {
-				user.EmailVerified = 1
-				_ = l.svcCtx.UserModel.Update(l.ctx, user)
-			}
-		*/
-	}
-
-	// 5. Generate JWT
-	tokenPair, err := l.svcCtx.JWT.Generate(user.Id, user.Username)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
 	return &types.BaseResponse{
@@ -187,8 +208,8 @@ func (l *OIDCCallbackLogic) OIDCCallback(req *types.OIDCCallbackReq) (resp *type
 			RefreshToken:     tokenPair.RefreshToken,
 			RefreshExpiresAt: tokenPair.RefreshExpiresAt,
 			TokenType:        "Bearer",
-			IsNewUser:        isNewUser,
-			Provider:         "oidc",
+			IsNewUser:        account.IsNewUser,
+			Provider:         state.Provider,
 		},
 	}, nil
 }
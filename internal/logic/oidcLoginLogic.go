@@ -6,6 +6,7 @@ package logic
 import (
 	"context"
 
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -31,7 +32,16 @@ func NewOIDCLoginLogic(ctx context.Context, svcCtx *svc.ServiceContext) *OIDCLog
 }
 
 func (l *OIDCLoginLogic) OIDCLogin(req *types.OIDCLoginReq) (resp *types.BaseResponse, err error) {
-	if !l.svcCtx.OIDC.IsEnabled() {
+	return l.authorize(req.Provider, req.RedirectURL, 0)
+}
+
+// authorize 生成授权跳转 URL；linkUserID 不为 0 时回调将身份关联到该用户而不是登录
+func (l *OIDCLoginLogic) authorize(providerName, redirectURL string, linkUserID uint64) (resp *types.BaseResponse, err error) {
+	if providerName == "" {
+		providerName = svc.DefaultOIDCProviderName
+	}
+	provider, ok := l.svcCtx.OIDC.Get(providerName)
+	if !ok {
 		return &types.BaseResponse{
 			Code:    1001,
 			Message: "OIDC login is disabled",
@@ -40,15 +50,40 @@ func (l *OIDCLoginLogic) OIDCLogin(req *types.OIDCLoginReq) (resp *types.BaseRes
 
 	state := uuid.New().String()
 	nonce := uuid.New().String()
+	codeVerifier, err := svc.GenerateCodeVerifier()
+	if err != nil {
+		l.Logger.Errorf("failed to generate PKCE code verifier: %v", err)
+		return &types.BaseResponse{
+			Code:    500,
+			Message: "internal server error",
+		}, nil
+	}
+
+	// A redirect URL switches the callback to browser mode, so it must be allowlisted
+	if redirectURL != "" && !svc.IsAllowedRedirectURL(l.svcCtx.Config.SSO.AllowedRedirectURLs, redirectURL) {
+		return &types.BaseResponse{
+			Code:    1008,
+			Message: "redirect URL is not allowed",
+		}, nil
+	}
 
 	// Cache state to verify callback later
 	key := fmt.Sprintf("auth:oidc:state:%s", state)
-	redirectURL := req.RedirectURL
-	if redirectURL == "" {
-		redirectURL = "/"
+
+	// Bind nonce and PKCE verifier to state so the callback can use them
+	stateData, err := json.Marshal(svc.OIDCState{
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		RedirectURL:  redirectURL,
+		CreatedAt:    time.Now().Unix(),
+		LinkUserID:   linkUserID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OIDC state: %w", err)
 	}
 
-	err = l.svcCtx.Redis.Set(l.ctx, key, redirectURL, 5*time.Minute).Err()
+	err = l.svcCtx.Redis.Set(l.ctx, key, stateData, 5*time.Minute).Err()
 	if err != nil {
 		l.Logger.Errorf("failed to cache OIDC state: %v", err)
 		return &types.BaseResponse{
@@ -57,7 +92,15 @@ func (l *OIDCLoginLogic) OIDCLogin(req *types.OIDCLoginReq) (resp *types.BaseRes
 		}, nil
 	}
 
-	authURL := l.svcCtx.OIDC.GetAuthorizationURL(state, nonce)
+	authURL := provider.GetAuthorizationURL(state, nonce, svc.CodeChallengeS256(codeVerifier))
+	if authURL == "" {
+		// Discovery document not loaded yet (IdP unreachable), retried in background
+		l.svcCtx.Redis.Del(l.ctx, key)
+		return &types.BaseResponse{
+			Code:    1007,
+			Message: "OIDC provider is temporarily unavailable",
+		}, nil
+	}
 
 	return &types.BaseResponse{
 		Code:    0,
@@ -0,0 +1,48 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type RADIUSLoginLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewRADIUSLoginLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RADIUSLoginLogic {
+	return &RADIUSLoginLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// RADIUSLogin RADIUS 登录。服务器返回 Access-Challenge (如要求输入一次性密码) 时返回挑战令牌，
+// 客户端携带挑战令牌与回应内容再次调用本接口完成登录
+func (l *RADIUSLoginLogic) RADIUSLogin(req *types.RADIUSLoginReq) (resp *types.BaseResponse, err error) {
+	entry, ok := l.svcCtx.IdentityProviders.Get(string(types.SSOProviderRADIUS))
+	if !ok {
+		return &types.BaseResponse{
+			Code:    1001,
+			Message: "RADIUS login is disabled",
+		}, nil
+	}
+
+	return identityLogin(l.ctx, l.svcCtx, entry, &types.SSOLoginReq{
+		Provider:       entry.Name,
+		Username:       req.Username,
+		Password:       req.Password,
+		ChallengeToken: req.ChallengeToken,
+		CaptchaID:      req.CaptchaID,
+		CaptchaAnswer:  req.CaptchaAnswer,
+	})
+}
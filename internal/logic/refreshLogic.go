@@ -26,6 +26,23 @@ func NewRefreshLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RefreshLo
 func (l *RefreshLogic) Refresh(req *types.RefreshReq) (resp *types.RefreshResp, err error) {
 	l.Info("Refresh request received")
 
+	claims, err := l.svcCtx.JWT.VerifyRefreshToken(req.RefreshToken)
+	if err != nil {
+		l.Errorf("Failed to verify refresh token: %v", err)
+		return nil, types.ErrInvalidRefreshToken
+	}
+
+	// 已禁用或待审批的账号不再续签令牌
+	user, err := l.svcCtx.UserModel.FindOne(l.ctx, claims.UserID)
+	if err != nil {
+		l.Errorf("Failed to load user %d for refresh: %v", claims.UserID, err)
+		return nil, types.ErrInvalidRefreshToken
+	}
+	if err := accountStatusError(user); err != nil {
+		l.Infof("Refresh rejected for user %d: %v", user.Id, err)
+		return nil, err
+	}
+
 	// 使用 JWT 服务刷新令牌
 	tokenPair, err := l.svcCtx.JWT.Refresh(req.RefreshToken)
 	if err != nil {
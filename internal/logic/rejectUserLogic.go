@@ -0,0 +1,52 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type RejectUserLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewRejectUserLogic(ctx context.Context, svcCtx *svc.ServiceContext) *RejectUserLogic {
+	return &RejectUserLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// RejectUser 拒绝待开通的用户，账号变为禁用状态
+func (l *RejectUserLogic) RejectUser(req *types.ReviewUserReq) (resp *types.BaseResponse, err error) {
+	if err := requireAdmin(l.ctx, l.svcCtx); err != nil {
+		return nil, err
+	}
+
+	user, denied, err := findPendingUser(l.ctx, l.svcCtx, req.UserID)
+	if err != nil || denied != nil {
+		return denied, err
+	}
+
+	user.AccountStatus = mysql.UserStatusDisabled
+	if err := l.svcCtx.UserModel.Update(l.ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	l.Infof("Pending user %s rejected by user %v", user.PublicId, l.ctx.Value("userID"))
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+	}, nil
+}
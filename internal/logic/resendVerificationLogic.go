@@ -0,0 +1,76 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ResendVerificationLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewResendVerificationLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ResendVerificationLogic {
+	return &ResendVerificationLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// ResendVerification 重新发送邮箱验证码。与忘记密码相同，无论邮箱是否已注册都返回相同的响应
+func (l *ResendVerificationLogic) ResendVerification(req *types.ResendVerificationReq) (resp *types.BaseResponse, err error) {
+	// 校验验证码（如果开启了验证码）
+	if err := verifyCaptcha(l.svcCtx, req.CaptchaID, req.CaptchaAnswer); err != nil {
+		return nil, err
+	}
+
+	if !emailVerificationEnabled(l.svcCtx) {
+		return &types.BaseResponse{
+			Code:    503,
+			Message: "邮箱验证功能未启用",
+		}, nil
+	}
+
+	resp = &types.BaseResponse{
+		Code:    200,
+		Message: "如果该邮箱已注册且尚未验证，验证码将发送到该邮箱",
+	}
+
+	// 同一邮箱在发送间隔内只发送一次
+	allowed, err := svc.AllowEmailVerification(l.ctx, l.svcCtx.Redis, req.Email)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		l.Infof("Email verification for %s is cooling down", maskEmail(req.Email))
+		return resp, nil
+	}
+
+	user, err := l.svcCtx.UserModel.FindOneByEmail(l.ctx, req.Email)
+	if err == mysql.ErrNotFound {
+		l.Infof("Email verification requested for unknown email %s", maskEmail(req.Email))
+		return resp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.EmailVerified != 0 || user.AccountStatus == mysql.UserStatusDisabled || isPlaceholderEmail(user.Email) {
+		l.Infof("Not sending verification code to user %s", user.PublicId)
+		return resp, nil
+	}
+
+	if err := sendEmailVerification(l.ctx, l.svcCtx, user.Id, user.Email); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
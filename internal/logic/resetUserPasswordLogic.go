@@ -0,0 +1,90 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type ResetUserPasswordLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewResetUserPasswordLogic(ctx context.Context, svcCtx *svc.ServiceContext) *ResetUserPasswordLogic {
+	return &ResetUserPasswordLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// ResetUserPassword 管理员重置用户密码: LDAP 用户在目录中重置，其它用户重置本地密码。
+// 重置后吊销用户已签发的全部令牌
+func (l *ResetUserPasswordLogic) ResetUserPassword(req *types.ResetUserPasswordReq) (resp *types.BaseResponse, err error) {
+	if err := requireAdmin(l.ctx, l.svcCtx); err != nil {
+		return nil, err
+	}
+
+	user, err := l.svcCtx.UserModel.FindOneByPublicId(l.ctx, req.UserID)
+	if err == mysql.ErrNotFound {
+		return nil, types.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	userDN, err := ldapPasswordDN(l.ctx, l.svcCtx, user)
+	if err != nil {
+		return nil, err
+	}
+	if userDN != "" {
+		if denied, err := l.resetLDAPPassword(userDN, req.NewPassword); err != nil || denied != nil {
+			return denied, err
+		}
+	} else {
+		user.PasswordHash = l.svcCtx.PasswordEncoder.Hash(req.NewPassword)
+		if err := l.svcCtx.UserModel.Update(l.ctx, user); err != nil {
+			return nil, fmt.Errorf("failed to update password: %w", err)
+		}
+	}
+
+	if err := l.svcCtx.JWT.RevokeUserTokens(user.Id); err != nil {
+		return nil, fmt.Errorf("failed to revoke user tokens: %w", err)
+	}
+	l.Infof("Password of user %s reset by user %v", user.PublicId, l.ctx.Value("userID"))
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+	}, nil
+}
+
+// resetLDAPPassword 在目录中重置密码，目录拒绝时返回错误响应
+func (l *ResetUserPasswordLogic) resetLDAPPassword(userDN, newPassword string) (*types.BaseResponse, error) {
+	if !ldapEnabled(l.svcCtx) {
+		return &types.BaseResponse{
+			Code:    1001,
+			Message: "LDAP login is disabled",
+		}, nil
+	}
+
+	err := l.svcCtx.LDAP.ResetPassword(l.ctx, userDN, newPassword)
+	if err == nil {
+		return nil, nil
+	}
+	if denied := ldapPasswordFailure(err); denied != nil {
+		l.Infof("LDAP refused password reset for %s: %v", userDN, err)
+		return denied, nil
+	}
+	return nil, fmt.Errorf("failed to reset LDAP password: %w", err)
+}
@@ -0,0 +1,89 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+	"errors"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type SendPhoneCodeLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSendPhoneCodeLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SendPhoneCodeLogic {
+	return &SendPhoneCodeLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// SendPhoneCode 向当前用户绑定的手机号发送短信验证码
+func (l *SendPhoneCodeLogic) SendPhoneCode() (resp *types.BaseResponse, err error) {
+	userID, ok := l.ctx.Value("userID").(int64)
+	if !ok || userID == 0 {
+		l.Error("Failed to get userID from context")
+		return nil, types.ErrUnauthorized
+	}
+
+	if l.svcCtx.SMS == nil || l.svcCtx.Redis == nil {
+		return &types.BaseResponse{
+			Code:    503,
+			Message: "短信验证功能未启用",
+		}, nil
+	}
+
+	user, err := l.svcCtx.UserModel.FindOne(l.ctx, uint64(userID))
+	if err != nil {
+		l.Errorf("Failed to find user by id %d: %v", userID, err)
+		return nil, types.ErrUserNotFound
+	}
+	if !user.Phone.Valid || user.Phone.String == "" {
+		return &types.BaseResponse{
+			Code:    400,
+			Message: "未绑定手机号",
+		}, nil
+	}
+	if user.PhoneVerified != 0 {
+		return &types.BaseResponse{
+			Code:    400,
+			Message: "手机号已验证",
+		}, nil
+	}
+
+	phone := user.Phone.String
+	if err := svc.AllowPhoneVerification(l.ctx, l.svcCtx.Redis, phone); errors.Is(err, svc.ErrPhoneVerificationLimited) {
+		return &types.BaseResponse{
+			Code:    429,
+			Message: "验证码发送过于频繁，请稍后再试",
+		}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	code, err := svc.CreatePhoneVerification(l.ctx, l.svcCtx.Redis, user.Id, phone)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.svcCtx.SMS.SendVerificationCode(l.ctx, phone, code); err != nil {
+		l.Errorf("Failed to send SMS verification code to user %s: %v", user.PublicId, err)
+		return &types.BaseResponse{
+			Code:    500,
+			Message: "短信发送失败，请稍后再试",
+		}, nil
+	}
+
+	return &types.BaseResponse{
+		Code:    200,
+		Message: "验证码已发送",
+	}, nil
+}
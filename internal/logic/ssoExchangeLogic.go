@@ -0,0 +1,49 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+
+	"errors"
+	"fmt"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type SSOExchangeLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSSOExchangeLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SSOExchangeLogic {
+	return &SSOExchangeLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// SSOExchange 用 SSO 回调跳转携带的一次性交换码换取登录结果 (令牌对)
+func (l *SSOExchangeLogic) SSOExchange(req *types.SSOExchangeReq) (resp *types.BaseResponse, err error) {
+	data, err := svc.ConsumeSSOHandoff(l.ctx, l.svcCtx.Redis, req.Code)
+	if errors.Is(err, svc.ErrInvalidHandoffCode) {
+		return &types.BaseResponse{
+			Code:    1009,
+			Message: "invalid or expired code",
+		}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to exchange handoff code: %w", err)
+	}
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+		Data:    data,
+	}, nil
+}
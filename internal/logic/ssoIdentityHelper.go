@@ -0,0 +1,211 @@
+package logic
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// ldapIdentityProvider LDAP 身份在 user_identity 中的提供者名称
+const ldapIdentityProvider = "ldap"
+
+// findIdentityUser 按 提供者 + 提供者用户 ID 查找已关联的本地用户，未关联时返回 nil。
+// 关联的本地用户已被删除时清理该关联，按未关联处理。
+func findIdentityUser(ctx context.Context, svcCtx *svc.ServiceContext, provider, providerUserID string) (*mysql.User, *mysql.UserIdentity, error) {
+	if providerUserID == "" {
+		return nil, nil, nil
+	}
+
+	identity, err := svcCtx.UserIdentityModel.FindOneByProviderProviderUserId(ctx, provider, providerUserID)
+	if err == mysql.ErrNotFound {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find user identity: %w", err)
+	}
+
+	user, err := svcCtx.UserModel.FindOne(ctx, identity.UserId)
+	if err == mysql.ErrNotFound {
+		logx.WithContext(ctx).Infof("Removing identity %s/%s of deleted user %d", provider, providerUserID, identity.UserId)
+		if err := svcCtx.UserIdentityModel.Delete(ctx, identity.Id); err != nil {
+			return nil, nil, fmt.Errorf("failed to delete stale user identity: %w", err)
+		}
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find user by identity: %w", err)
+	}
+	return user, identity, nil
+}
+
+// recordIdentityLogin 登录成功后更新关联的最后登录时间，首次通过该身份登录时建立关联
+func recordIdentityLogin(ctx context.Context, svcCtx *svc.ServiceContext, identity *mysql.UserIdentity, userID uint64, provider, providerUserID, email string) error {
+	now := time.Now()
+	if identity != nil {
+		identity.LastLoginAt = sql.NullTime{Time: now, Valid: true}
+		if err := svcCtx.UserIdentityModel.Update(ctx, identity); err != nil {
+			return fmt.Errorf("failed to update user identity: %w", err)
+		}
+		return nil
+	}
+	if providerUserID == "" {
+		return nil
+	}
+
+	_, err := svcCtx.UserIdentityModel.Insert(ctx, &mysql.UserIdentity{
+		UserId:         userID,
+		Provider:       provider,
+		ProviderUserId: providerUserID,
+		Email:          sql.NullString{String: email, Valid: email != ""},
+		LinkedAt:       now,
+		LastLoginAt:    sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create user identity: %w", err)
+	}
+	return nil
+}
+
+// linkIdentity 将外部身份关联到已登录的用户 (关联流程的最后一步)
+func linkIdentity(ctx context.Context, svcCtx *svc.ServiceContext, userID uint64, provider, providerUserID, email string) (*types.BaseResponse, error) {
+	if providerUserID == "" {
+		return &types.BaseResponse{
+			Code:    1005,
+			Message: "Failed to get user info",
+		}, nil
+	}
+
+	existing, err := svcCtx.UserIdentityModel.FindOneByProviderProviderUserId(ctx, provider, providerUserID)
+	switch {
+	case err == nil && existing.UserId != userID:
+		return &types.BaseResponse{
+			Code:    1014,
+			Message: "this account is already linked to another user",
+		}, nil
+	case err == nil:
+		// Linking the same identity twice is a no-op
+		return &types.BaseResponse{
+			Code:    0,
+			Message: "success",
+			Data:    toLinkedAccount(existing),
+		}, nil
+	case err != mysql.ErrNotFound:
+		return nil, fmt.Errorf("failed to find user identity: %w", err)
+	}
+
+	if _, err := svcCtx.UserIdentityModel.FindOneByUserIdProvider(ctx, userID, provider); err == nil {
+		return &types.BaseResponse{
+			Code:    1015,
+			Message: "another account of this provider is already linked, unlink it first",
+		}, nil
+	} else if err != mysql.ErrNotFound {
+		return nil, fmt.Errorf("failed to find user identity: %w", err)
+	}
+
+	identity := &mysql.UserIdentity{
+		UserId:         userID,
+		Provider:       provider,
+		ProviderUserId: providerUserID,
+		Email:          sql.NullString{String: email, Valid: email != ""},
+		LinkedAt:       time.Now(),
+	}
+	if _, err := svcCtx.UserIdentityModel.Insert(ctx, identity); err != nil {
+		return nil, fmt.Errorf("failed to link user identity: %w", err)
+	}
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+		Data:    toLinkedAccount(identity),
+	}, nil
+}
+
+// canAutoLink 邮箱与已有账号相同时，仅当 IdP 断言邮箱已验证且提供者受信任时才直接关联，
+// 否则任何控制了同邮箱 IdP 账号的人都能接管该账号
+func canAutoLink(emailVerified, trusted bool) bool {
+	return emailVerified && trusted
+}
+
+// requireLinkConfirmation 保存待确认关联并要求用户证明对已有账号的所有权
+func requireLinkConfirmation(ctx context.Context, svcCtx *svc.ServiceContext, link *svc.PendingLink, user *mysql.User) (*types.BaseResponse, error) {
+	var methods []string
+	if hasLocalPassword(user) {
+		methods = append(methods, "password")
+	}
+	if svcCtx.Email != nil && !isPlaceholderEmail(user.Email) {
+		methods = append(methods, "email_code")
+	}
+	// Without a way to confirm (or Redis to keep the pending link) the user has to link from the account settings
+	if len(methods) == 0 || svcCtx.Redis == nil {
+		return &types.BaseResponse{
+			Code:    1019,
+			Message: "an account with this email already exists, sign in to it and link this provider from the account settings",
+		}, nil
+	}
+
+	link.UserID = user.Id
+	token, err := svc.CreatePendingLink(ctx, svcCtx.Redis, link)
+	if err != nil {
+		return nil, err
+	}
+	logx.WithContext(ctx).Infof("Identity %s/%s matches existing user %d, ownership confirmation required", link.Provider, link.ProviderUserID, user.Id)
+
+	return &types.BaseResponse{
+		Code:    1018,
+		Message: "an account with this email already exists, confirm ownership to link it",
+		Data: types.SSOLinkRequiredResp{
+			LinkToken: token,
+			Email:     maskEmail(user.Email),
+			Methods:   methods,
+			ExpiresAt: time.Now().Add(svc.PendingLinkTTL).Unix(),
+		},
+	}, nil
+}
+
+// invalidLinkToken 关联令牌无效或已过期
+func invalidLinkToken() *types.BaseResponse {
+	return &types.BaseResponse{
+		Code:    1020,
+		Message: "invalid or expired link token",
+	}
+}
+
+// isPlaceholderEmail SSO 开通账号时因缺少真实邮箱而生成的占位邮箱
+func isPlaceholderEmail(email string) bool {
+	return strings.HasSuffix(strings.ToLower(email), ".placeholder")
+}
+
+// maskEmail 邮箱脱敏: alice@example.com -> a***@example.com
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return email
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// hasLocalPassword 用户是否设置了本地密码 (SSO 自动开通的账号没有本地密码)
+func hasLocalPassword(user *mysql.User) bool {
+	return user.PasswordHash != ""
+}
+
+func toLinkedAccount(identity *mysql.UserIdentity) types.SSOLinkedAccount {
+	account := types.SSOLinkedAccount{
+		Provider:       identity.Provider,
+		ProviderUserID: identity.ProviderUserId,
+		Email:          identity.Email.String,
+		LinkedAt:       identity.LinkedAt.Unix(),
+	}
+	if identity.LastLoginAt.Valid {
+		account.LastLoginAt = identity.LastLoginAt.Time.Unix()
+	}
+	return account
+}
@@ -0,0 +1,79 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type SSOLinkCodeLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSSOLinkCodeLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SSOLinkCodeLogic {
+	return &SSOLinkCodeLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// SSOLinkCode 向待关联的已有账号邮箱发送验证码，用于证明账号所有权
+func (l *SSOLinkCodeLogic) SSOLinkCode(req *types.SSOLinkCodeReq) (resp *types.BaseResponse, err error) {
+	link, err := svc.GetPendingLink(l.ctx, l.svcCtx.Redis, req.LinkToken)
+	if errors.Is(err, svc.ErrInvalidLinkToken) {
+		return invalidLinkToken(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	user, err := l.svcCtx.UserModel.FindOne(l.ctx, link.UserID)
+	if err == mysql.ErrNotFound {
+		return invalidLinkToken(), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if l.svcCtx.Email == nil || isPlaceholderEmail(user.Email) {
+		return &types.BaseResponse{
+			Code:    1022,
+			Message: "email verification is unavailable for this account",
+		}, nil
+	}
+
+	if link.CodeSentAt != 0 && time.Since(time.Unix(link.CodeSentAt, 0)) < svc.PendingLinkCodeInterval {
+		return &types.BaseResponse{
+			Code:    1023,
+			Message: "verification code was sent recently, please try again later",
+		}, nil
+	}
+
+	code, err := link.SetEmailCode()
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.UpdatePendingLink(l.ctx, l.svcCtx.Redis, req.LinkToken, link); err != nil {
+		return nil, err
+	}
+	if err := l.svcCtx.Email.SendVerificationCode(user.Email, code); err != nil {
+		l.Logger.Errorf("Failed to send link verification code to user %d: %v", user.Id, err)
+		return nil, types.ErrInternalServer
+	}
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+	}, nil
+}
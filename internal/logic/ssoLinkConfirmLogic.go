@@ -0,0 +1,122 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type SSOLinkConfirmLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSSOLinkConfirmLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SSOLinkConfirmLogic {
+	return &SSOLinkConfirmLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// SSOLinkConfirm 用已有账号的密码或邮箱验证码证明所有权，关联外部身份并登录
+func (l *SSOLinkConfirmLogic) SSOLinkConfirm(req *types.SSOLinkConfirmReq) (resp *types.BaseResponse, err error) {
+	link, err := svc.GetPendingLink(l.ctx, l.svcCtx.Redis, req.LinkToken)
+	if errors.Is(err, svc.ErrInvalidLinkToken) {
+		return invalidLinkToken(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	user, err := l.svcCtx.UserModel.FindOne(l.ctx, link.UserID)
+	if err == mysql.ErrNotFound {
+		return invalidLinkToken(), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	// Count the attempt before checking it, so concurrent guesses cannot exceed the limit
+	attempts, err := svc.CountPendingLinkAttempt(l.ctx, l.svcCtx.Redis, req.LinkToken)
+	if err != nil {
+		return nil, err
+	}
+	if attempts > svc.PendingLinkMaxAttempts || !l.verifyOwnership(link, user, req) {
+		if attempts >= svc.PendingLinkMaxAttempts {
+			// Too many failures: the user has to sign in through the provider again
+			_ = svc.ConsumePendingLink(l.ctx, l.svcCtx.Redis, req.LinkToken)
+		}
+		l.Logger.Infof("Ownership verification failed for user %d linking %s", user.Id, link.Provider)
+		return &types.BaseResponse{
+			Code:    1021,
+			Message: "ownership verification failed",
+		}, nil
+	}
+
+	// The link token is single use, a concurrent confirmation loses here
+	if err := svc.ConsumePendingLink(l.ctx, l.svcCtx.Redis, req.LinkToken); errors.Is(err, svc.ErrInvalidLinkToken) {
+		return invalidLinkToken(), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	resp, err = linkIdentity(l.ctx, l.svcCtx, user.Id, link.Provider, link.ProviderUserID, link.Email)
+	if err != nil || resp.Code != 0 {
+		return resp, err
+	}
+	if denied := checkAccountStatus(user); denied != nil {
+		return denied, nil
+	}
+
+	tokenPair, _, err := issueLoginTokens(l.ctx, l.svcCtx, user, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if link.Session != nil {
+		link.Session.UserID = user.Id
+		link.Session.CreatedAt = time.Now().Unix()
+		ttl := time.Duration(l.svcCtx.Config.Auth.RefreshExpiresIn) * time.Second
+		if err := svc.SaveOIDCSession(l.ctx, l.svcCtx.Redis, tokenPair.SessionID, link.Session, ttl); err != nil {
+			l.Logger.Errorf("Failed to save OIDC session: %v", err)
+		}
+	}
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+		Data: types.SSOLinkConfirmResp{
+			UserID:           user.PublicId,
+			Username:         user.Username,
+			Email:            user.Email,
+			AccessToken:      tokenPair.AccessToken,
+			AccessExpiresAt:  tokenPair.AccessExpiresAt,
+			RefreshToken:     tokenPair.RefreshToken,
+			RefreshExpiresAt: tokenPair.RefreshExpiresAt,
+			TokenType:        "Bearer",
+			Provider:         link.Provider,
+		},
+	}, nil
+}
+
+// verifyOwnership 校验已有账号的密码或邮箱验证码
+func (l *SSOLinkConfirmLogic) verifyOwnership(link *svc.PendingLink, user *mysql.User, req *types.SSOLinkConfirmReq) bool {
+	switch {
+	case req.Password != "":
+		return hasLocalPassword(user) && l.svcCtx.PasswordEncoder.Compare(user.PasswordHash, req.Password)
+	case req.EmailCode != "":
+		return link.VerifyEmailCode(req.EmailCode)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,91 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type SSOLinkLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSSOLinkLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SSOLinkLogic {
+	return &SSOLinkLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// SSOLink 为当前用户关联外部身份。
+// LDAP、RADIUS 等用户名密码类提供者直接校验凭据后关联；OIDC / OAuth2 返回授权 URL，由回调完成关联。
+func (l *SSOLinkLogic) SSOLink(req *types.SSOLinkReq) (resp *types.BaseResponse, err error) {
+	userID, ok := l.ctx.Value("userID").(int64)
+	if !ok || userID == 0 {
+		return nil, types.ErrUnauthorized
+	}
+
+	if entry, ok := l.svcCtx.IdentityProviders.Get(req.Provider); ok {
+		return l.linkCredentials(uint64(userID), entry, req)
+	}
+
+	if _, ok := l.svcCtx.OIDC.Get(req.Provider); ok {
+		resp, err = NewOIDCLoginLogic(l.ctx, l.svcCtx).authorize(req.Provider, req.RedirectURL, uint64(userID))
+		return toSSOLinkResp(resp, err)
+	}
+	if _, ok := l.svcCtx.OAuth2.Get(req.Provider); ok {
+		resp, err = NewOAuth2LoginLogic(l.ctx, l.svcCtx).authorize(req.Provider, req.RedirectURL, uint64(userID))
+		return toSSOLinkResp(resp, err)
+	}
+
+	return &types.BaseResponse{
+		Code:    1001,
+		Message: "SSO provider is disabled",
+	}, nil
+}
+
+// linkCredentials 校验用户名密码类提供者的凭据后关联，提供者要求进一步验证时返回挑战令牌
+func (l *SSOLinkLogic) linkCredentials(userID uint64, entry *svc.IdentityProviderEntry, req *types.SSOLinkReq) (*types.BaseResponse, error) {
+	if req.Username == "" || req.Password == "" {
+		return nil, types.ErrInvalidCredentials
+	}
+
+	userInfo, resp, err := authenticateIdentity(l.ctx, l.svcCtx, entry, req.Username, req.Password, req.ChallengeToken)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	return linkIdentity(l.ctx, l.svcCtx, userID, entry.Name, userInfo.ProviderUserID, userInfo.Email)
+}
+
+// toSSOLinkResp 将登录授权响应转换为关联响应
+func toSSOLinkResp(resp *types.BaseResponse, err error) (*types.BaseResponse, error) {
+	if err != nil || resp == nil || resp.Code != 0 {
+		return resp, err
+	}
+
+	var authURL string
+	switch data := resp.Data.(type) {
+	case types.OIDCLoginResp:
+		authURL = data.AuthorizationURL
+	case types.OAuth2LoginResp:
+		authURL = data.AuthorizationURL
+	}
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+		Data: types.SSOLinkResp{
+			AuthorizationURL: authURL,
+			Message:          "continue at the authorization URL to link the account",
+		},
+	}, nil
+}
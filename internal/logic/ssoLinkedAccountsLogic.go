@@ -0,0 +1,52 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type SSOLinkedAccountsLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSSOLinkedAccountsLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SSOLinkedAccountsLogic {
+	return &SSOLinkedAccountsLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// SSOLinkedAccounts 列出当前用户已关联的外部身份
+func (l *SSOLinkedAccountsLogic) SSOLinkedAccounts() (resp *types.BaseResponse, err error) {
+	userID, ok := l.ctx.Value("userID").(int64)
+	if !ok || userID == 0 {
+		return nil, types.ErrUnauthorized
+	}
+
+	identities, err := l.svcCtx.UserIdentityModel.FindAllByUserId(l.ctx, uint64(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user identities: %w", err)
+	}
+
+	accounts := make([]types.SSOLinkedAccount, 0, len(identities))
+	for _, identity := range identities {
+		accounts = append(accounts, toLinkedAccount(identity))
+	}
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+		Data:    types.SSOLinkedAccountsResp{Accounts: accounts},
+	}, nil
+}
@@ -0,0 +1,176 @@
+package logic
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// identityLogin 用户名密码类身份提供者的统一登录流程: 校验凭据后按关联身份查找本地用户，
+// 用户名或邮箱与已有账号相同时要求确认所有权后关联，否则按开通策略创建账号，最后签发令牌
+func identityLogin(ctx context.Context, svcCtx *svc.ServiceContext, entry *svc.IdentityProviderEntry, req *types.SSOLoginReq) (*types.BaseResponse, error) {
+	// 1. Verify Captcha; challenge tokens are only issued after the credentials were accepted
+	if req.ChallengeToken == "" {
+		if err := verifyCaptcha(svcCtx, req.CaptchaID, req.CaptchaAnswer); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. Authenticate against the provider
+	userInfo, resp, err := authenticateIdentity(ctx, svcCtx, entry, req.Username, req.Password, req.ChallengeToken)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	// 3. Find or Create User, by the linked identity first. A local account with the same username or email
+	// is only linked once its owner confirms it, otherwise anyone controlling a matching directory account
+	// could take it over
+	account, resp, err := findOrCreateSSOUser(ctx, svcCtx, &ssoAccount{
+		Provider:       entry.Name,
+		ProviderUserID: userInfo.ProviderUserID,
+		MatchUsername:  userInfo.Username,
+		MatchEmail:     userInfo.Email,
+		Policy:         entry.Provisioning,
+		Profile: ssoProfile{
+			Username:      cmp.Or(userInfo.Username, userInfo.Email),
+			Email:         userInfo.Email,
+			EmailVerified: userInfo.EmailVerified,
+			Nickname:      userInfo.DisplayName,
+			Groups:        userInfo.Groups,
+		},
+	})
+	if resp != nil || err != nil {
+		return resp, err
+	}
+	user := account.User
+
+	// Directory roles only apply to accounts linked to the identity (by confirmation or on creation)
+	if entry.SyncRoles {
+		if err := syncLDAPRoles(ctx, svcCtx, user.Id, userInfo.Roles); err != nil {
+			return nil, err
+		}
+	}
+	// The identity stays linked so the account can sign in once approved
+	if denied := checkAccountStatus(user); denied != nil {
+		return denied, nil
+	}
+
+	// 4. Generate Token
+	tokenPair, roles, err := issueLoginTokens(ctx, svcCtx, user, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. Build Response
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+		Data: types.SSOLoginResp{
+			UserID:           user.PublicId,
+			Username:         user.Username,
+			Email:            user.Email,
+			DisplayName:      userInfo.DisplayName,
+			Groups:           userInfo.Groups,
+			Roles:            roles,
+			AccessToken:      tokenPair.AccessToken,
+			AccessExpiresAt:  tokenPair.AccessExpiresAt,
+			RefreshToken:     tokenPair.RefreshToken,
+			RefreshExpiresAt: tokenPair.RefreshExpiresAt,
+			TokenType:        "Bearer",
+			IsNewUser:        account.IsNewUser,
+			Provider:         entry.Name,
+		},
+	}, nil
+}
+
+// authenticateIdentity 以身份提供者校验凭据，携带挑战令牌时继续之前的挑战。
+// 认证未完成 (凭据错误、需要回应挑战等) 时返回相应的响应
+func authenticateIdentity(ctx context.Context, svcCtx *svc.ServiceContext, entry *svc.IdentityProviderEntry, username, password, challengeToken string) (*types.SSOUserInfo, *types.BaseResponse, error) {
+	creds := svc.IdentityCredentials{Username: username, Password: password}
+	if challengeToken != "" {
+		if svcCtx.Redis == nil {
+			return nil, invalidIdentityChallenge(), nil
+		}
+		pending, err := svc.ConsumeIdentityChallenge(ctx, svcCtx.Redis, challengeToken)
+		if errors.Is(err, svc.ErrInvalidIdentityChallenge) {
+			return nil, invalidIdentityChallenge(), nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if pending.Provider != entry.Name || pending.Username != username {
+			return nil, invalidIdentityChallenge(), nil
+		}
+		creds.State = pending.State
+	}
+
+	userInfo, err := entry.Provider.Authenticate(ctx, creds)
+	if err == nil {
+		return userInfo, nil, nil
+	}
+
+	var challenge *svc.IdentityChallenge
+	if errors.As(err, &challenge) {
+		resp, err := issueIdentityChallenge(ctx, svcCtx, entry.Name, username, challenge)
+		return nil, resp, err
+	}
+	if !errors.Is(err, svc.ErrIdentityRejected) {
+		return nil, nil, fmt.Errorf("%s authentication failed: %w", entry.Name, err)
+	}
+
+	logx.WithContext(ctx).Infof("%s authentication failed for %s: %v", entry.Name, username, err)
+	if handle, ok := identityRejectionHandlers[entry.Type]; ok {
+		resp, err := handle(ctx, svcCtx, username, err)
+		return nil, resp, err
+	}
+	return nil, &types.BaseResponse{
+		Code:    1002,
+		Message: "authentication failed",
+	}, nil
+}
+
+// identityRejectionHandler 将某类提供者拒绝登录的错误映射为具体的失败响应 (如目录账号锁定、要求修改密码)
+type identityRejectionHandler func(ctx context.Context, svcCtx *svc.ServiceContext, username string, err error) (*types.BaseResponse, error)
+
+// identityRejectionHandlers 按提供者类型注册的拒绝处理，未注册的提供者返回通用的认证失败
+var identityRejectionHandlers = map[types.SSOProviderType]identityRejectionHandler{
+	types.SSOProviderLDAP: ldapRejection,
+}
+
+// issueIdentityChallenge 保存提供者返回的挑战，返回挑战令牌与提供者的提示
+func issueIdentityChallenge(ctx context.Context, svcCtx *svc.ServiceContext, provider, username string, challenge *svc.IdentityChallenge) (*types.BaseResponse, error) {
+	if svcCtx.Redis == nil {
+		return nil, errors.New("identity provider challenges require Redis")
+	}
+	token, err := svc.CreateIdentityChallenge(ctx, svcCtx.Redis, &svc.PendingChallenge{
+		Provider: provider,
+		Username: username,
+		State:    challenge.State,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &types.BaseResponse{
+		Code:    1039,
+		Message: "additional authentication required",
+		Data: &types.SSOChallengeResp{
+			ChallengeToken: token,
+			Message:        challenge.Message,
+			ExpiresIn:      int64(svc.IdentityChallengeTTL.Seconds()),
+		},
+	}, nil
+}
+
+// invalidIdentityChallenge 挑战令牌无效、已过期或不属于该用户
+func invalidIdentityChallenge() *types.BaseResponse {
+	return &types.BaseResponse{
+		Code:    1040,
+		Message: "invalid or expired challenge",
+	}
+}
@@ -0,0 +1,41 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type SSOLoginLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSSOLoginLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SSOLoginLogic {
+	return &SSOLoginLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// SSOLogin 以用户名密码类身份提供者 (LDAP、RADIUS 等) 登录。
+// 提供者要求进一步验证时返回挑战令牌，客户端携带挑战令牌与回应内容再次调用本接口完成登录
+func (l *SSOLoginLogic) SSOLogin(req *types.SSOLoginReq) (resp *types.BaseResponse, err error) {
+	entry, ok := l.svcCtx.IdentityProviders.Get(req.Provider)
+	if !ok {
+		return &types.BaseResponse{
+			Code:    1001,
+			Message: "SSO provider is disabled",
+		}, nil
+	}
+
+	return identityLogin(l.ctx, l.svcCtx, entry, req)
+}
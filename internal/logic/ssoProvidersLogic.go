@@ -2,6 +2,7 @@ package logic
 
 import (
 	"context"
+	"fmt"
 
 	"auth-service/internal/svc"
 	"auth-service/internal/types"
@@ -35,25 +36,60 @@ func (l *SSOProvidersLogic) SSOProviders() (*types.SSOProvidersResp, error) {
 		},
 	}
 
-	// 检查 OIDC
-	if l.svcCtx.OIDC.IsEnabled() {
+	// 检查 OIDC (按配置顺序列出所有已启用的命名提供者)
+	for _, entry := range l.svcCtx.OIDC.List() {
+		if entry.Client == nil || !entry.Client.IsEnabled() {
+			continue
+		}
+		name := entry.DisplayName
+		if name == "" {
+			name = "OpenID Connect"
+		}
 		providers = append(providers, types.SSOProvider{
-			ID:       string(types.SSOProviderOIDC),
-			Name:     "OpenID Connect",
+			ID:       entry.Name,
+			Name:     name,
 			Type:     string(types.SSOProviderOIDC),
+			Icon:     entry.Icon,
+			Enabled:  entry.Status().Ready, // 发现文档尚未加载时暂不可用
+			LoginURL: fmt.Sprintf("/api/v1/sso/oidc/%s/login", entry.Name),
+		})
+	}
+
+	// 检查社交 OAuth2 提供者
+	for _, entry := range l.svcCtx.OAuth2.List() {
+		if entry.Client == nil || !entry.Client.IsEnabled() {
+			continue
+		}
+		name := entry.DisplayName
+		if name == "" {
+			name = entry.Name
+		}
+		providers = append(providers, types.SSOProvider{
+			ID:       entry.Name,
+			Name:     name,
+			Type:     string(types.SSOProviderOAuth2),
+			Icon:     entry.Icon,
 			Enabled:  true,
-			LoginURL: "/api/v1/sso/oidc/login",
+			LoginURL: fmt.Sprintf("/api/v1/sso/oauth2/%s/login", entry.Name),
 		})
 	}
 
-	// 检查 LDAP
-	if l.svcCtx.LDAP.IsEnabled() {
+	// 检查用户名密码类身份提供者 (LDAP、RADIUS 等)
+	for _, entry := range l.svcCtx.IdentityProviders.List() {
+		if entry.Provider == nil || !entry.Provider.IsEnabled() {
+			continue
+		}
+		name := entry.DisplayName
+		if name == "" {
+			name = entry.Name
+		}
 		providers = append(providers, types.SSOProvider{
-			ID:       string(types.SSOProviderLDAP),
-			Name:     "LDAP / Active Directory",
-			Type:     string(types.SSOProviderLDAP),
+			ID:       entry.Name,
+			Name:     name,
+			Type:     string(entry.Type),
+			Icon:     entry.Icon,
 			Enabled:  true,
-			LoginURL: "/api/v1/sso/ldap/login",
+			LoginURL: fmt.Sprintf("/api/v1/sso/%s/login", entry.Name),
 		})
 	}
 
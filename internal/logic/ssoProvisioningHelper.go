@@ -0,0 +1,281 @@
+package logic
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+
+	"github.com/google/uuid"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// ssoAccount 外部身份登录时查找或开通本地账号所需的信息 (OIDC、OAuth2 与 LDAP / RADIUS 共用)
+type ssoAccount struct {
+	Provider       string
+	ProviderUserID string
+	MatchUsername  string           // 按用户名匹配已有账号 (目录用户)，为空时不匹配
+	MatchEmail     string           // 按邮箱匹配已有账号，为空时不匹配
+	AutoLink       bool             // 邮箱匹配时直接关联 (IdP 断言邮箱已验证且提供者受信任)，否则要求确认所有权
+	Session        *svc.OIDCSession // 待确认关联时保存的 IdP 会话
+	Policy         *svc.ProvisioningPolicy
+	Profile        ssoProfile // 开通账号时使用的资料
+}
+
+// ssoProfile 自动开通账号时使用的外部身份资料
+type ssoProfile struct {
+	Username      string // 为空时使用 "提供者_随机后缀"
+	Email         string // 为空时使用占位邮箱
+	EmailVerified bool
+	Nickname      string
+	Phone         string
+	Groups        []string
+}
+
+// ssoUser 外部身份对应的本地账号
+type ssoUser struct {
+	User      *mysql.User
+	Linked    bool // 登录前身份已关联到该账号
+	IsNewUser bool
+}
+
+// findOrCreateSSOUser 按关联身份查找本地账号；未关联时匹配同名或同邮箱的已有账号 (不能直接关联时要求确认所有权)，
+// 仍未找到时按开通策略创建账号，并记录身份登录。需要确认或拒绝开通时返回相应的响应
+func findOrCreateSSOUser(ctx context.Context, svcCtx *svc.ServiceContext, account *ssoAccount) (*ssoUser, *types.BaseResponse, error) {
+	user, link, err := findIdentityUser(ctx, svcCtx, account.Provider, account.ProviderUserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := &ssoUser{User: user, Linked: link != nil}
+
+	if user == nil {
+		existing, autoLink, err := findMatchingUser(ctx, svcCtx, account)
+		if err != nil {
+			return nil, nil, err
+		}
+		if existing != nil && !autoLink {
+			resp, err := requireLinkConfirmation(ctx, svcCtx, &svc.PendingLink{
+				Provider:       account.Provider,
+				ProviderUserID: account.ProviderUserID,
+				Email:          account.Profile.Email,
+				Session:        account.Session,
+			}, existing)
+			return nil, resp, err
+		}
+		result.User = existing
+	}
+
+	if result.User == nil {
+		profile := account.Profile
+		if denied := checkProvisioning(ctx, account.Policy, account.Provider, profile.Email, profile.EmailVerified, profile.Groups); denied != nil {
+			return nil, denied, nil
+		}
+		if result.User, err = createSSOUser(ctx, svcCtx, account.Provider, account.Policy, profile); err != nil {
+			return nil, nil, err
+		}
+		result.IsNewUser = true
+	}
+
+	if err := recordIdentityLogin(ctx, svcCtx, link, result.User.Id, account.Provider, account.ProviderUserID, account.Profile.Email); err != nil {
+		logx.WithContext(ctx).Errorf("Failed to record identity login: %v", err)
+	}
+	return result, nil, nil
+}
+
+// findMatchingUser 按用户名、邮箱依次查找与外部身份同名或同邮箱的本地账号，
+// 只有邮箱匹配且允许直接关联时 autoLink 为 true (用户名相同从不证明所有权)
+func findMatchingUser(ctx context.Context, svcCtx *svc.ServiceContext, account *ssoAccount) (user *mysql.User, autoLink bool, err error) {
+	if account.MatchUsername != "" {
+		user, err := svcCtx.UserModel.FindOneByUsername(ctx, account.MatchUsername)
+		if err == nil {
+			return user, false, nil
+		}
+		if err != mysql.ErrNotFound {
+			return nil, false, fmt.Errorf("failed to find user by username: %w", err)
+		}
+	}
+	if account.MatchEmail != "" {
+		user, err := svcCtx.UserModel.FindOneByEmail(ctx, account.MatchEmail)
+		if err == nil {
+			return user, account.AutoLink, nil
+		}
+		if err != mysql.ErrNotFound {
+			return nil, false, fmt.Errorf("failed to find user by email: %w", err)
+		}
+	}
+	return nil, false, nil
+}
+
+// createSSOUser 为外部身份创建本地账号 (无本地密码)，初始状态与默认角色由开通策略决定
+func createSSOUser(ctx context.Context, svcCtx *svc.ServiceContext, provider string, policy *svc.ProvisioningPolicy, profile ssoProfile) (*mysql.User, error) {
+	email := profile.Email
+	if email == "" {
+		// Generate placeholder email to satisfy unique constraint
+		email = fmt.Sprintf("%s@no-email.placeholder", uuid.New().String())
+	}
+	username := profile.Username
+	if username == "" {
+		username = provider + "_" + uuid.New().String()[:8]
+	}
+
+	// Ensure username uniqueness
+	for {
+		_, err := svcCtx.UserModel.FindOneByUsername(ctx, username)
+		if err == mysql.ErrNotFound {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		username = fmt.Sprintf("%s_%s", username, uuid.New().String()[:4])
+	}
+
+	// No local password: the account signs in through the provider until one is set
+	newUser := &mysql.User{
+		PublicId:      uuid.New().String(),
+		Username:      username,
+		Email:         email,
+		EmailVerified: 0,
+		PasswordHash:  "",
+		AccountStatus: policy.InitialStatus(),
+	}
+	if profile.EmailVerified && profile.Email != "" {
+		newUser.EmailVerified = 1
+	}
+	if profile.Nickname != "" {
+		newUser.Nickname = sql.NullString{String: profile.Nickname, Valid: true}
+	}
+	if profile.Phone != "" {
+		// Phone numbers are unique; only take the provider value when no local account uses it
+		_, err := svcCtx.UserModel.FindOneByPhone(ctx, profile.Phone)
+		if err == mysql.ErrNotFound {
+			newUser.Phone = sql.NullString{String: profile.Phone, Valid: true}
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to find user by phone: %w", err)
+		}
+	}
+
+	res, err := svcCtx.UserModel.Insert(ctx, newUser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	uid, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := assignDefaultRoles(ctx, svcCtx, policy, uint64(uid)); err != nil {
+		logx.WithContext(ctx).Errorf("Failed to assign default roles: %v", err)
+	}
+
+	return svcCtx.UserModel.FindOne(ctx, uint64(uid))
+}
+
+// checkProvisioning 检查是否允许为外部身份自动开通账号，不允许时返回拒绝响应
+func checkProvisioning(ctx context.Context, policy *svc.ProvisioningPolicy, provider, email string, emailVerified bool, groups []string) *types.BaseResponse {
+	err := policy.Check(email, emailVerified, groups)
+	if err == nil {
+		return nil
+	}
+	logx.WithContext(ctx).Infof("Refusing to provision %s user %q: %v", provider, email, err)
+
+	if errors.Is(err, svc.ErrProvisioningDisabled) {
+		return &types.BaseResponse{
+			Code:    1024,
+			Message: "no account is linked to this identity and automatic account creation is disabled",
+		}
+	}
+	return &types.BaseResponse{
+		Code:    1025,
+		Message: "this identity is not allowed to create an account",
+	}
+}
+
+// assignDefaultRoles 为新开通的账号分配策略中的默认角色
+func assignDefaultRoles(ctx context.Context, svcCtx *svc.ServiceContext, policy *svc.ProvisioningPolicy, userID uint64) error {
+	for _, role := range policy.DefaultRoles() {
+		_, err := svcCtx.UserRoleModel.Insert(ctx, &mysql.UserRole{
+			UserId: userID,
+			Role:   role,
+			Source: mysql.RoleSourceProvisioning,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to assign role %s: %w", role, err)
+		}
+	}
+	return nil
+}
+
+// syncLDAPRoles 将 LDAP 组映射到的角色同步到用户角色: 补充缺少的角色，回收不再授予的 LDAP 来源角色
+// (手动分配或开通时分配的角色不受影响)
+func syncLDAPRoles(ctx context.Context, svcCtx *svc.ServiceContext, userID uint64, roles []string) error {
+	existing, err := svcCtx.UserRoleModel.FindAllByUserId(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find user roles: %w", err)
+	}
+
+	granted := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		granted[role] = true
+	}
+
+	has := make(map[string]bool, len(existing))
+	for _, userRole := range existing {
+		if userRole.Source == mysql.RoleSourceLDAP && !granted[userRole.Role] {
+			if err := svcCtx.UserRoleModel.Delete(ctx, userRole.Id); err != nil {
+				return fmt.Errorf("failed to revoke role %s: %w", userRole.Role, err)
+			}
+			continue
+		}
+		has[userRole.Role] = true
+	}
+
+	for _, role := range roles {
+		if has[role] {
+			continue
+		}
+		_, err := svcCtx.UserRoleModel.Insert(ctx, &mysql.UserRole{
+			UserId: userID,
+			Role:   role,
+			Source: mysql.RoleSourceLDAP,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to assign role %s: %w", role, err)
+		}
+		has[role] = true
+	}
+	return nil
+}
+
+// checkAccountStatus SSO 登录只签发给状态正常的账号: 待审批或已禁用的账号返回拒绝响应
+func checkAccountStatus(user *mysql.User) *types.BaseResponse {
+	switch accountStatusError(user) {
+	case types.ErrAccountPending:
+		return &types.BaseResponse{
+			Code:    1026,
+			Message: "account is pending administrator approval",
+		}
+	case types.ErrAccountDisabled:
+		return &types.BaseResponse{
+			Code:    1027,
+			Message: "account is disabled",
+		}
+	}
+	return nil
+}
+
+// accountStatusError 待审批或已禁用的账号返回对应的错误，供返回错误而非响应的本地登录使用
+func accountStatusError(user *mysql.User) error {
+	switch user.AccountStatus {
+	case mysql.UserStatusPending:
+		return types.ErrAccountPending
+	case mysql.UserStatusDisabled:
+		return types.ErrAccountDisabled
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package logic
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// redirectSSOResult 将 SSO 回调结果转换为浏览器跳转。
+// 登录成功时只在跳转地址上携带一次性交换码，前端通过 /sso/exchange 换取令牌；
+// 失败时携带 error / error_code / error_description 参数。
+func redirectSSOResult(ctx context.Context, svcCtx *svc.ServiceContext, redirectURL string, resp *types.BaseResponse, err error) (*types.BaseResponse, error) {
+	params := url.Values{}
+
+	switch {
+	case err != nil:
+		logx.WithContext(ctx).Errorf("SSO callback failed: %v", err)
+		params.Set("error", "server_error")
+	case resp.Code != 0:
+		params.Set("error", "login_failed")
+		params.Set("error_code", strconv.FormatInt(resp.Code, 10))
+		params.Set("error_description", resp.Message)
+		if data, ok := resp.Data.(types.SSOLinkRequiredResp); ok {
+			// The frontend asks for the password or email code and confirms the link
+			params.Set("link_token", data.LinkToken)
+		}
+	default:
+		code, err := svc.CreateSSOHandoff(ctx, svcCtx.Redis, resp.Data)
+		if err != nil {
+			logx.WithContext(ctx).Errorf("Failed to create SSO handoff code: %v", err)
+			params.Set("error", "server_error")
+		} else {
+			params.Set("code", code)
+		}
+	}
+
+	return &types.BaseResponse{
+		Code:    302,
+		Message: "redirect",
+		Data: types.SSORedirectResp{
+			Location: svc.AppendQuery(redirectURL, params),
+		},
+	}, nil
+}
@@ -0,0 +1,87 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+	"fmt"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/model/mysql"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type SSOUnlinkLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewSSOUnlinkLogic(ctx context.Context, svcCtx *svc.ServiceContext) *SSOUnlinkLogic {
+	return &SSOUnlinkLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// SSOUnlink 解除当前用户与外部身份的关联，拒绝移除最后一种登录方式
+func (l *SSOUnlinkLogic) SSOUnlink(req *types.SSOUnlinkReq) (resp *types.BaseResponse, err error) {
+	userID, ok := l.ctx.Value("userID").(int64)
+	if !ok || userID == 0 {
+		return nil, types.ErrUnauthorized
+	}
+
+	identity, err := l.svcCtx.UserIdentityModel.FindOneByUserIdProvider(l.ctx, uint64(userID), req.Provider)
+	if err == mysql.ErrNotFound {
+		return &types.BaseResponse{
+			Code:    1016,
+			Message: "no account of this provider is linked",
+		}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user identity: %w", err)
+	}
+
+	user, err := l.svcCtx.UserModel.FindOne(l.ctx, uint64(userID))
+	if err == mysql.ErrNotFound {
+		return nil, types.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	identities, err := l.svcCtx.UserIdentityModel.FindAllByUserId(l.ctx, uint64(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user identities: %w", err)
+	}
+	loginMethods := len(identities)
+	if hasLocalPassword(user) {
+		loginMethods++
+	}
+	if loginMethods <= 1 {
+		return &types.BaseResponse{
+			Code:    1017,
+			Message: "cannot unlink the last login method, set a password first",
+		}, nil
+	}
+
+	if err := l.svcCtx.UserIdentityModel.Delete(l.ctx, identity.Id); err != nil {
+		return nil, fmt.Errorf("failed to unlink user identity: %w", err)
+	}
+
+	// Upstream tokens belong to the unlinked identity
+	if l.svcCtx.UpstreamTokens != nil {
+		if err := l.svcCtx.UpstreamTokens.Delete(l.ctx, uint64(userID), req.Provider); err != nil {
+			l.Logger.Errorf("Failed to delete upstream token of user %d: %v", userID, err)
+		}
+	}
+
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+	}, nil
+}
@@ -0,0 +1,117 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"auth-service/internal/config"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type UpstreamTokenLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewUpstreamTokenLogic(ctx context.Context, svcCtx *svc.ServiceContext) *UpstreamTokenLogic {
+	return &UpstreamTokenLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// UpstreamToken 向受信任的客户端返回当前用户在上游 IdP 的访问令牌，即将过期时先刷新
+func (l *UpstreamTokenLogic) UpstreamToken(req *types.UpstreamTokenReq) (resp *types.BaseResponse, err error) {
+	userID, ok := l.ctx.Value("userID").(int64)
+	if !ok || userID == 0 {
+		return nil, types.ErrUnauthorized
+	}
+
+	if l.svcCtx.UpstreamTokens == nil {
+		return &types.BaseResponse{
+			Code:    1011,
+			Message: "token broker is disabled",
+		}, nil
+	}
+
+	if !l.clientPermitted(req.ClientID, req.ClientSecret, req.Provider) {
+		l.Logger.Infof("Token broker client %q is not permitted for provider %s", req.ClientID, req.Provider)
+		return &types.BaseResponse{
+			Code:    1012,
+			Message: "client is not permitted to retrieve upstream tokens",
+		}, nil
+	}
+
+	provider, ok := l.svcCtx.OIDC.Get(req.Provider)
+	if !ok {
+		return &types.BaseResponse{
+			Code:    1001,
+			Message: "OIDC login is disabled",
+		}, nil
+	}
+
+	token, err := l.svcCtx.UpstreamTokens.GetFresh(l.ctx, uint64(userID), req.Provider, provider)
+	if err != nil {
+		if !errors.Is(err, svc.ErrUpstreamTokenNotFound) && !errors.Is(err, svc.ErrUpstreamTokenExpired) {
+			l.Logger.Errorf("Failed to get upstream token for user %d: %v", userID, err)
+		}
+		// The user has to sign in through the provider again to obtain new upstream tokens
+		return &types.BaseResponse{
+			Code:    1013,
+			Message: "upstream token is unavailable, please sign in with the provider again",
+		}, nil
+	}
+
+	tokenType := token.TokenType
+	if tokenType == "" {
+		tokenType = "Bearer"
+	}
+	return &types.BaseResponse{
+		Code:    0,
+		Message: "success",
+		Data: types.UpstreamTokenResp{
+			Provider:    req.Provider,
+			AccessToken: token.AccessToken,
+			TokenType:   tokenType,
+			ExpiresAt:   token.ExpiresAt,
+			Scope:       token.Scope,
+		},
+	}, nil
+}
+
+// clientPermitted 校验令牌代理客户端的凭据及其可访问的提供者
+func (l *UpstreamTokenLogic) clientPermitted(clientID, clientSecret, provider string) bool {
+	if clientID == "" || clientSecret == "" {
+		return false
+	}
+
+	var client *config.TokenBrokerClient
+	for i := range l.svcCtx.Config.SSO.TokenBroker.Clients {
+		if l.svcCtx.Config.SSO.TokenBroker.Clients[i].ClientID == clientID {
+			client = &l.svcCtx.Config.SSO.TokenBroker.Clients[i]
+			break
+		}
+	}
+	if client == nil || subtle.ConstantTimeCompare([]byte(client.ClientSecret), []byte(clientSecret)) != 1 {
+		return false
+	}
+
+	if len(client.Providers) == 0 {
+		return true
+	}
+	for _, name := range client.Providers {
+		if name == provider {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+// Code scaffolded by goctl. Safe to edit.
+// goctl 1.9.2
+
+package logic
+
+import (
+	"context"
+	"errors"
+
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+type VerifyPhoneLogic struct {
+	logx.Logger
+	ctx    context.Context
+	svcCtx *svc.ServiceContext
+}
+
+func NewVerifyPhoneLogic(ctx context.Context, svcCtx *svc.ServiceContext) *VerifyPhoneLogic {
+	return &VerifyPhoneLogic{
+		Logger: logx.WithContext(ctx),
+		ctx:    ctx,
+		svcCtx: svcCtx,
+	}
+}
+
+// VerifyPhone 以短信验证码验证当前用户绑定的手机号
+func (l *VerifyPhoneLogic) VerifyPhone(req *types.VerifyPhoneReq) (resp *types.BaseResponse, err error) {
+	userID, ok := l.ctx.Value("userID").(int64)
+	if !ok || userID == 0 {
+		l.Error("Failed to get userID from context")
+		return nil, types.ErrUnauthorized
+	}
+
+	if l.svcCtx.SMS == nil || l.svcCtx.Redis == nil {
+		return &types.BaseResponse{
+			Code:    503,
+			Message: "短信验证功能未启用",
+		}, nil
+	}
+
+	user, err := l.svcCtx.UserModel.FindOne(l.ctx, uint64(userID))
+	if err != nil {
+		l.Errorf("Failed to find user by id %d: %v", userID, err)
+		return nil, types.ErrUserNotFound
+	}
+
+	invalid := &types.BaseResponse{
+		Code:    400,
+		Message: "验证码无效或已过期",
+	}
+	if !user.Phone.Valid || user.Phone.String == "" {
+		return invalid, nil
+	}
+
+	// 验证码按手机号保存，需确认是发给当前用户的
+	verification, err := svc.VerifyPhoneVerification(l.ctx, l.svcCtx.Redis, user.Phone.String, req.Code)
+	if errors.Is(err, svc.ErrInvalidVerificationCode) {
+		return invalid, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if verification.UserID != user.Id {
+		return invalid, nil
+	}
+
+	if user.PhoneVerified == 0 {
+		user.PhoneVerified = 1
+		if err := l.svcCtx.UserModel.Update(l.ctx, user); err != nil {
+			l.Errorf("Failed to mark phone verified: %v", err)
+			return &types.BaseResponse{
+				Code:    500,
+				Message: "手机号验证失败",
+			}, nil
+		}
+	}
+
+	l.Infof("Phone verified for user %s", user.PublicId)
+	return &types.BaseResponse{
+		Code:    200,
+		Message: "手机号验证成功",
+	}, nil
+}
@@ -30,6 +30,11 @@ type Claims interface {
 	VerifyExpiresAt() bool
 }
 
+// ScopedClaims 受限令牌的声明 (可选实现)，访问范围非空时只能访问为该范围开放的路径
+type ScopedClaims interface {
+	GetScope() string
+}
+
 type TokenInfo struct {
 	UserID int64
 	Token  string
@@ -41,6 +46,7 @@ type TokenValidator func(tokenString string) (Claims, error)
 
 type AuthInterceptorMiddleware struct {
 	tokenValidator TokenValidator
+	scopePaths     map[string]map[string]bool // 访问范围 -> 允许访问的路径
 }
 
 // NewAuthInterceptorMiddleware 创建新的认证拦截器中间件
@@ -53,6 +59,19 @@ func (m *AuthInterceptorMiddleware) SetTokenValidator(validator TokenValidator)
 	m.tokenValidator = validator
 }
 
+// AllowScope 允许指定访问范围的受限令牌访问这些路径
+func (m *AuthInterceptorMiddleware) AllowScope(scope string, paths ...string) {
+	if m.scopePaths == nil {
+		m.scopePaths = make(map[string]map[string]bool)
+	}
+	if m.scopePaths[scope] == nil {
+		m.scopePaths[scope] = make(map[string]bool)
+	}
+	for _, path := range paths {
+		m.scopePaths[scope][path] = true
+	}
+}
+
 func (m *AuthInterceptorMiddleware) Handle(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// 1. 根据配置获取 Token
@@ -79,7 +98,15 @@ func (m *AuthInterceptorMiddleware) Handle(next http.HandlerFunc) http.HandlerFu
 			return
 		}
 
-		// 4. 将用户信息和 Token 来源存入上下文
+		// 4. 受限令牌只能访问为其访问范围开放的路径
+		if scoped, ok := claims.(ScopedClaims); ok {
+			if scope := scoped.GetScope(); scope != "" && !m.scopePaths[scope][r.URL.Path] {
+				http.Error(w, "令牌的访问范围受限", http.StatusForbidden)
+				return
+			}
+		}
+
+		// 5. 将用户信息和 Token 来源存入上下文
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, UserIDKey, claims.GetUserID())
 		ctx = context.WithValue(ctx, contextKey("tokenSource"), tokenInfo.Source)
@@ -87,7 +114,7 @@ func (m *AuthInterceptorMiddleware) Handle(next http.HandlerFunc) http.HandlerFu
 
 		newReq := r.WithContext(ctx)
 
-		// 5. 调用下一个处理器
+		// 6. 调用下一个处理器
 		next(w, newReq)
 	}
 }
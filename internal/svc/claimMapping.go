@@ -0,0 +1,331 @@
+package svc
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"auth-service/internal/config"
+)
+
+// MappedIdentity 按声明映射规则得到的统一用户资料，用于用户开通
+type MappedIdentity struct {
+	Username      string
+	Email         string
+	EmailVerified bool
+	Nickname      string
+	Phone         string
+	Groups        []string
+}
+
+// DefaultOIDCClaimMapping 标准 OIDC 声明映射
+var DefaultOIDCClaimMapping = config.ClaimMapping{
+	Username:      []string{"preferred_username"},
+	Email:         []string{"email"},
+	EmailVerified: []string{"email_verified"},
+	Nickname:      []string{"name", "${given_name} ${family_name}", "nickname"},
+	Phone:         []string{"phone_number"},
+	Groups:        []string{"groups"},
+}
+
+// DefaultLDAPAttributeMapping 常见 LDAP / AD 属性映射
+var DefaultLDAPAttributeMapping = config.ClaimMapping{
+	Username: []string{"uid", "sAMAccountName", "cn"},
+	Email:    []string{"mail"},
+	Nickname: []string{"displayName", "cn"},
+	Phone:    []string{"telephoneNumber", "mobile"},
+	Groups:   []string{"memberOf"},
+}
+
+// claimExprPattern 匹配表达式中的 ${name} 或 ${name|filter|...} 占位符
+var claimExprPattern = regexp.MustCompile(`\$\{([^}|]+)((?:\|[a-z]+)*)\}`)
+
+// claimFilters 表达式占位符可用的过滤器
+var claimFilters = map[string]func(string) string{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+	"localpart": func(s string) string {
+		if i := strings.LastIndex(s, "@"); i >= 0 {
+			return s[:i]
+		}
+		return s
+	},
+	"domain": func(s string) string {
+		if i := strings.LastIndex(s, "@"); i >= 0 {
+			return s[i+1:]
+		}
+		return ""
+	},
+}
+
+// ClaimMapper 将 OIDC 声明或 LDAP 属性映射为统一的用户资料
+type ClaimMapper struct {
+	mapping config.ClaimMapping
+}
+
+// NewClaimMapper 创建声明映射器，override 中非空的字段覆盖 defaults 中的对应字段
+func NewClaimMapper(defaults, override config.ClaimMapping) (*ClaimMapper, error) {
+	mapping := defaults
+	if len(override.Username) > 0 {
+		mapping.Username = override.Username
+	}
+	if len(override.Email) > 0 {
+		mapping.Email = override.Email
+	}
+	if len(override.EmailVerified) > 0 {
+		mapping.EmailVerified = override.EmailVerified
+	}
+	if len(override.Nickname) > 0 {
+		mapping.Nickname = override.Nickname
+	}
+	if len(override.Phone) > 0 {
+		mapping.Phone = override.Phone
+	}
+	if len(override.Groups) > 0 {
+		mapping.Groups = override.Groups
+	}
+
+	fields := [][]string{mapping.Username, mapping.Email, mapping.EmailVerified, mapping.Nickname, mapping.Phone, mapping.Groups}
+	for _, candidates := range fields {
+		for _, candidate := range candidates {
+			if err := validateClaimCandidate(candidate); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &ClaimMapper{mapping: mapping}, nil
+}
+
+// validateClaimCandidate 校验候选项中引用的过滤器是否存在
+func validateClaimCandidate(candidate string) error {
+	if strings.TrimSpace(candidate) == "" {
+		return fmt.Errorf("empty claim mapping entry")
+	}
+	for _, match := range claimExprPattern.FindAllStringSubmatch(candidate, -1) {
+		for _, filter := range strings.Split(strings.TrimPrefix(match[2], "|"), "|") {
+			if filter == "" {
+				continue
+			}
+			if _, ok := claimFilters[filter]; !ok {
+				return fmt.Errorf("unknown claim mapping filter %q in %q", filter, candidate)
+			}
+		}
+	}
+	return nil
+}
+
+// Map 映射 OIDC 声明 (ID Token 与 UserInfo 合并后的声明集合)
+func (m *ClaimMapper) Map(claims map[string]interface{}) *MappedIdentity {
+	identity := &MappedIdentity{
+		Username: resolveClaimString(claims, m.mapping.Username),
+		Email:    resolveClaimString(claims, m.mapping.Email),
+		Nickname: resolveClaimString(claims, m.mapping.Nickname),
+		Phone:    resolveClaimString(claims, m.mapping.Phone),
+		Groups:   resolveClaimList(claims, m.mapping.Groups),
+	}
+	verified := resolveClaimString(claims, m.mapping.EmailVerified)
+	identity.EmailVerified, _ = strconv.ParseBool(verified)
+	return identity
+}
+
+// MapAttributes 映射 LDAP 条目属性
+func (m *ClaimMapper) MapAttributes(attributes map[string][]string) *MappedIdentity {
+	claims := make(map[string]interface{}, len(attributes))
+	for name, values := range attributes {
+		claims[name] = values
+	}
+	return m.Map(claims)
+}
+
+// Attributes 返回映射规则引用的全部声明/属性名
+func (m *ClaimMapper) Attributes() []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" && !seen[strings.ToLower(name)] {
+			seen[strings.ToLower(name)] = true
+			names = append(names, name)
+		}
+	}
+
+	fields := [][]string{m.mapping.Username, m.mapping.Email, m.mapping.EmailVerified, m.mapping.Nickname, m.mapping.Phone, m.mapping.Groups}
+	for _, candidates := range fields {
+		for _, candidate := range candidates {
+			if !strings.Contains(candidate, "${") {
+				add(candidate)
+				continue
+			}
+			for _, match := range claimExprPattern.FindAllStringSubmatch(candidate, -1) {
+				add(strings.TrimSpace(match[1]))
+			}
+		}
+	}
+	return names
+}
+
+// MergeClaims 合并多个声明集合，后面的集合覆盖前面的同名声明
+func MergeClaims(sets ...map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for _, set := range sets {
+		for name, value := range set {
+			merged[name] = value
+		}
+	}
+	return merged
+}
+
+// claimsOf 将结构体形式的声明转为声明集合，并合并解码时保留的原始声明
+func claimsOf(v interface{}, raw map[string]interface{}) map[string]interface{} {
+	claims := make(map[string]interface{})
+	if data, err := json.Marshal(v); err == nil {
+		_ = json.Unmarshal(data, &claims)
+	}
+	for name, value := range raw {
+		claims[name] = value
+	}
+	return claims
+}
+
+// resolveClaimString 依次尝试候选项，返回第一个非空结果
+func resolveClaimString(claims map[string]interface{}, candidates []string) string {
+	for _, candidate := range candidates {
+		var value string
+		if strings.Contains(candidate, "${") {
+			value = renderClaimExpr(claims, candidate)
+		} else {
+			value = claimString(lookupClaim(claims, candidate))
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// resolveClaimList 依次尝试候选项，返回第一个非空的多值结果
+func resolveClaimList(claims map[string]interface{}, candidates []string) []string {
+	for _, candidate := range candidates {
+		if strings.Contains(candidate, "${") {
+			if value := strings.TrimSpace(renderClaimExpr(claims, candidate)); value != "" {
+				return []string{value}
+			}
+			continue
+		}
+		if values := claimList(lookupClaim(claims, candidate)); len(values) > 0 {
+			return values
+		}
+	}
+	return nil
+}
+
+// renderClaimExpr 渲染表达式，所有占位符均为空时返回空串，以便继续尝试下一个候选项
+func renderClaimExpr(claims map[string]interface{}, expr string) string {
+	resolved := false
+	result := claimExprPattern.ReplaceAllStringFunc(expr, func(placeholder string) string {
+		match := claimExprPattern.FindStringSubmatch(placeholder)
+		value := claimString(lookupClaim(claims, strings.TrimSpace(match[1])))
+		for _, filter := range strings.Split(strings.TrimPrefix(match[2], "|"), "|") {
+			if fn, ok := claimFilters[filter]; ok {
+				value = fn(value)
+			}
+		}
+		if value != "" {
+			resolved = true
+		}
+		return value
+	})
+	if !resolved {
+		return ""
+	}
+	return result
+}
+
+// lookupClaim 查找声明: 先按完整名称 (声明名可能本身含 "." 如 URL 形式)，
+// 再按 a.b 嵌套路径，最后不区分大小写匹配 (LDAP 属性名不区分大小写)
+func lookupClaim(claims map[string]interface{}, name string) interface{} {
+	if value, ok := claims[name]; ok {
+		return value
+	}
+
+	if strings.Contains(name, ".") {
+		var current interface{} = claims
+		found := true
+		for _, part := range strings.Split(name, ".") {
+			obj, ok := current.(map[string]interface{})
+			if !ok {
+				found = false
+				break
+			}
+			if current, ok = obj[part]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			return current
+		}
+	}
+
+	// 仅大小写不同的多个声明按名称排序后取第一个，保证结果稳定
+	var matched []string
+	for key := range claims {
+		if strings.EqualFold(key, name) {
+			matched = append(matched, key)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	slices.Sort(matched)
+	return claims[matched[0]]
+}
+
+// claimString 将声明值转为字符串，多值声明取第一个值
+func claimString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			return claimString(v[0])
+		}
+	}
+	return ""
+}
+
+// claimList 将声明值转为字符串列表
+func claimList(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s := claimString(item); s != "" {
+				values = append(values, s)
+			}
+		}
+		return values
+	case nil:
+		return nil
+	default:
+		if s := claimString(v); s != "" {
+			return []string{s}
+		}
+	}
+	return nil
+}
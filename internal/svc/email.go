@@ -45,6 +45,42 @@ func (c *Client) SendResetEmail(to, resetURL string) error {
 	return c.sendEmail([]string{to}, subject, body)
 }
 
+// SendVerificationCode 发送邮箱验证码
+func (c *Client) SendVerificationCode(to, code string) error {
+	subject := "邮箱验证码"
+	body := fmt.Sprintf(`
+        <html>
+        <body>
+            <h2>邮箱验证码</h2>
+            <p>您的验证码为：</p>
+            <p style="font-size: 24px; font-weight: bold; letter-spacing: 4px;">%s</p>
+            <p><strong>注意：</strong>验证码将在10分钟后失效。如果这不是您本人的操作，请忽略此邮件。</p>
+        </body>
+        </html>
+    `, code)
+
+	return c.sendEmail([]string{to}, subject, body)
+}
+
+// SendMagicLink 发送免密登录链接
+func (c *Client) SendMagicLink(to, loginURL string) error {
+	subject := "登录链接"
+	body := fmt.Sprintf(`
+        <html>
+        <body>
+            <h2>登录链接</h2>
+            <p>请点击下面的链接登录您的账户：</p>
+            <a href="%s" style="background-color: #007bff; color: white; padding: 10px 20px; text-decoration: none; border-radius: 5px;">登录</a>
+            <p>如果链接无法点击，请复制以下地址到浏览器中打开：</p>
+            <p>%s</p>
+            <p><strong>注意：</strong>此链接只能使用一次，将在15分钟后失效。如果这不是您本人的操作，请忽略此邮件。</p>
+        </body>
+        </html>
+    `, loginURL, loginURL)
+
+	return c.sendEmail([]string{to}, subject, body)
+}
+
 var (
 	sendMail = smtp.SendMail
 )
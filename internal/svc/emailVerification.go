@@ -0,0 +1,42 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// EmailVerificationTTL 邮箱验证码的有效期 (与验证码邮件中的提示一致)
+	EmailVerificationTTL = 10 * time.Minute
+	// EmailVerificationMaxAttempts 验证码的最大失败次数，超过后验证码作废
+	EmailVerificationMaxAttempts = 5
+	// EmailVerificationResendInterval 同一邮箱两次发送验证码的最小间隔
+	EmailVerificationResendInterval = time.Minute
+)
+
+// 验证接口无需登录，按邮箱查找验证码
+func emailVerificationKey(email string) string {
+	return fmt.Sprintf("auth:email:verify:%s", targetHash(email))
+}
+
+func emailVerificationCooldownKey(email string) string {
+	return fmt.Sprintf("auth:email:verify:cooldown:%s", targetHash(email))
+}
+
+// AllowEmailVerification 同一邮箱在发送间隔内只允许发送一次验证码
+func AllowEmailVerification(ctx context.Context, rdb redis.UniversalClient, email string) (bool, error) {
+	return allowVerificationCode(ctx, rdb, emailVerificationCooldownKey(email), EmailVerificationResendInterval)
+}
+
+// CreateEmailVerification 为用户的邮箱生成验证码，此前发送的验证码失效
+func CreateEmailVerification(ctx context.Context, rdb redis.UniversalClient, userID uint64, email string) (string, error) {
+	return createVerificationCode(ctx, rdb, emailVerificationKey(email), userID, email, EmailVerificationTTL)
+}
+
+// VerifyEmailVerification 校验邮箱验证码，返回的 Target 为发送验证码时的邮箱
+func VerifyEmailVerification(ctx context.Context, rdb redis.UniversalClient, email, code string) (*VerificationCode, error) {
+	return checkVerificationCode(ctx, rdb, emailVerificationKey(email), code, EmailVerificationMaxAttempts, EmailVerificationTTL)
+}
@@ -0,0 +1,124 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"auth-service/internal/types"
+)
+
+// LDAPIdentityID LDAP 身份的提供者用户 ID (DN 不区分大小写，统一转为小写)
+func LDAPIdentityID(info *LDAPUserInfo) string {
+	return strings.ToLower(info.DN)
+}
+
+// ldapIdentityProvider 以 LDAP 目录绑定校验用户名与密码
+type ldapIdentityProvider struct {
+	client LDAPClient
+}
+
+// NewLDAPIdentityProvider 将 LDAP 客户端适配为身份提供者
+func NewLDAPIdentityProvider(client LDAPClient) IdentityProvider {
+	return &ldapIdentityProvider{client: client}
+}
+
+// NewLDAPIdentityEntry LDAP 身份提供者的注册项，登录时以目录组映射的角色同步用户角色
+func NewLDAPIdentityEntry(client LDAPClient, policy *ProvisioningPolicy) IdentityProviderEntry {
+	return IdentityProviderEntry{
+		Type:        types.SSOProviderLDAP,
+		DisplayName: "LDAP / Active Directory",
+		Provider:    NewLDAPIdentityProvider(client),
+		SyncRoles:   true,
+
+		Provisioning: policy,
+	}
+}
+
+func (p *ldapIdentityProvider) IsEnabled() bool {
+	return p.client != nil && p.client.IsEnabled()
+}
+
+// Authenticate 目录返回的任何错误都视为登录失败，原始错误 (如 AD 账号状态) 保留在错误链中
+func (p *ldapIdentityProvider) Authenticate(ctx context.Context, creds IdentityCredentials) (*types.SSOUserInfo, error) {
+	info, err := p.client.Authenticate(ctx, creds.Username, creds.Password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIdentityRejected, err)
+	}
+
+	// 目录中的邮箱由组织管理，视为已验证
+	return &types.SSOUserInfo{
+		Provider:       string(types.SSOProviderLDAP),
+		ProviderUserID: LDAPIdentityID(info),
+		Email:          info.Email,
+		Username:       info.Username,
+		DisplayName:    info.DisplayName,
+		FirstName:      info.FirstName,
+		LastName:       info.LastName,
+		Groups:         info.Groups,
+		Roles:          info.Roles,
+		EmailVerified:  true,
+	}, nil
+}
+
+// radiusIdentityProvider 以 RADIUS Access-Request 校验用户名与密码，支持 Access-Challenge
+type radiusIdentityProvider struct {
+	client RADIUSClient
+}
+
+// radiusChallengeState 挑战状态: State 只在签发它的服务器上有效，需要与服务器一同保存
+type radiusChallengeState struct {
+	Server string `json:"server"`
+	State  []byte `json:"state"`
+}
+
+// NewRADIUSIdentityProvider 将 RADIUS 客户端适配为身份提供者
+func NewRADIUSIdentityProvider(client RADIUSClient) IdentityProvider {
+	return &radiusIdentityProvider{client: client}
+}
+
+func (p *radiusIdentityProvider) IsEnabled() bool {
+	return p.client != nil && p.client.IsEnabled()
+}
+
+// Authenticate 服务器返回 Access-Challenge 时返回 *IdentityChallenge，回应时发送到签发挑战的服务器
+func (p *radiusIdentityProvider) Authenticate(ctx context.Context, creds IdentityCredentials) (*types.SSOUserInfo, error) {
+	var (
+		result *RADIUSResult
+		err    error
+	)
+	if creds.State == "" {
+		result, err = p.client.Authenticate(ctx, creds.Username, creds.Password)
+	} else {
+		var state radiusChallengeState
+		if err := json.Unmarshal([]byte(creds.State), &state); err != nil {
+			return nil, fmt.Errorf("%w: malformed challenge state", ErrIdentityRejected)
+		}
+		challenge := &RADIUSChallenge{Server: state.Server, State: state.State}
+		result, err = p.client.RespondChallenge(ctx, challenge, creds.Username, creds.Password)
+	}
+	if errors.Is(err, ErrRADIUSRejected) {
+		return nil, fmt.Errorf("%w: %w", ErrIdentityRejected, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if challenge := result.Challenge; challenge != nil {
+		state, err := json.Marshal(&radiusChallengeState{Server: challenge.Server, State: challenge.State})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode challenge state: %w", err)
+		}
+		return nil, &IdentityChallenge{Message: challenge.Message, State: string(state)}
+	}
+
+	// RADIUS 不返回邮箱，用户名不区分大小写
+	return &types.SSOUserInfo{
+		Provider:       string(types.SSOProviderRADIUS),
+		ProviderUserID: strings.ToLower(result.User.Username),
+		Username:       result.User.Username,
+		Groups:         result.User.Groups,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// IdentityChallengeTTL 待回应挑战的有效期 (RADIUS 服务器等通常只在短时间内保留挑战状态)
+const IdentityChallengeTTL = 2 * time.Minute
+
+// ErrInvalidIdentityChallenge 挑战令牌不存在、已过期或已被使用
+var ErrInvalidIdentityChallenge = errors.New("invalid or expired challenge")
+
+// PendingChallenge 等待用户回应的挑战 (如输入一次性密码)
+type PendingChallenge struct {
+	Provider  string `json:"provider"`
+	Username  string `json:"username"`
+	State     string `json:"state"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func identityChallengeKey(token string) string {
+	return fmt.Sprintf("auth:sso:challenge:%s", token)
+}
+
+// CreateIdentityChallenge 保存待回应的挑战并返回挑战令牌
+func CreateIdentityChallenge(ctx context.Context, rdb redis.UniversalClient, pending *PendingChallenge) (string, error) {
+	token, err := randomURLToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate challenge token: %w", err)
+	}
+
+	pending.CreatedAt = time.Now().Unix()
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode challenge: %w", err)
+	}
+	if err := rdb.Set(ctx, identityChallengeKey(token), data, IdentityChallengeTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store challenge: %w", err)
+	}
+	return token, nil
+}
+
+// ConsumeIdentityChallenge 取出并删除待回应的挑战 (挑战状态只能使用一次)
+func ConsumeIdentityChallenge(ctx context.Context, rdb redis.UniversalClient, token string) (*PendingChallenge, error) {
+	if token == "" {
+		return nil, ErrInvalidIdentityChallenge
+	}
+
+	data, err := rdb.GetDel(ctx, identityChallengeKey(token)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidIdentityChallenge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge: %w", err)
+	}
+
+	var pending PendingChallenge
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, ErrInvalidIdentityChallenge
+	}
+	return &pending, nil
+}
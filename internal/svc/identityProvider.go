@@ -0,0 +1,114 @@
+package svc
+
+import (
+	"context"
+	"errors"
+
+	"auth-service/internal/types"
+)
+
+// ErrIdentityRejected 身份提供者拒绝了凭据 (用户名或密码错误等)
+var ErrIdentityRejected = errors.New("identity provider rejected the credentials")
+
+// IdentityProvider 以用户名与密码登录的身份提供者 (LDAP、RADIUS 等)。
+// 提供者只负责校验凭据并返回统一格式的用户信息，账号匹配、自动开通与令牌签发由登录流程统一完成
+type IdentityProvider interface {
+	IsEnabled() bool
+	// Authenticate 校验凭据。凭据错误时返回的错误包装 ErrIdentityRejected，
+	// 需要进一步验证 (如输入一次性密码) 时返回 *IdentityChallenge
+	Authenticate(ctx context.Context, creds IdentityCredentials) (*types.SSOUserInfo, error)
+}
+
+// IdentityCredentials 登录凭据
+type IdentityCredentials struct {
+	Username string
+	Password string // 密码；回应挑战时为回应内容 (如一次性密码)
+	State    string // 回应挑战时为提供者返回的 IdentityChallenge.State
+}
+
+// IdentityChallenge 身份提供者要求进一步验证，用户回应后携带 State 再次认证
+type IdentityChallenge struct {
+	Message string // 提示用户输入的内容
+	State   string // 提供者的挑战状态，只保存在服务端
+}
+
+func (c *IdentityChallenge) Error() string {
+	return "additional authentication required"
+}
+
+// IdentityProviderEntry 已注册的身份提供者
+type IdentityProviderEntry struct {
+	Name        string                // 提供者名称 (路由参数，同时是 user_identity 中的提供者名称)
+	Type        types.SSOProviderType // 提供者类型
+	DisplayName string                // 登录页显示名称
+	Icon        string                // 登录页图标 URL
+	Provider    IdentityProvider
+	SyncRoles   bool // 以提供者返回的角色同步用户的目录来源角色 (LDAP 组映射)
+
+	Provisioning *ProvisioningPolicy // 首次登录时自动开通账号的策略 (为空时允许所有用户开通)
+}
+
+// IdentityProviders 按名称管理身份提供者，保持注册顺序
+type IdentityProviders struct {
+	entries []*IdentityProviderEntry
+	byName  map[string]*IdentityProviderEntry
+}
+
+// NewIdentityProviders 创建身份提供者集合
+func NewIdentityProviders(entries ...IdentityProviderEntry) *IdentityProviders {
+	providers := &IdentityProviders{
+		byName: make(map[string]*IdentityProviderEntry),
+	}
+	for _, entry := range entries {
+		providers.Register(entry)
+	}
+	return providers
+}
+
+// Register 注册提供者，名称为空或已存在时返回 false
+func (p *IdentityProviders) Register(entry IdentityProviderEntry) bool {
+	if entry.Name == "" {
+		entry.Name = string(entry.Type)
+	}
+	if entry.Name == "" {
+		return false
+	}
+	if _, exists := p.byName[entry.Name]; exists {
+		return false
+	}
+
+	p.entries = append(p.entries, &entry)
+	p.byName[entry.Name] = &entry
+	return true
+}
+
+// Get 按名称获取已启用的提供者
+func (p *IdentityProviders) Get(name string) (*IdentityProviderEntry, bool) {
+	if p == nil {
+		return nil, false
+	}
+	entry, ok := p.byName[name]
+	if !ok || entry.Provider == nil || !entry.Provider.IsEnabled() {
+		return nil, false
+	}
+	return entry, true
+}
+
+// Provisioning 返回提供者的自动开通策略
+func (p *IdentityProviders) Provisioning(name string) *ProvisioningPolicy {
+	if p == nil {
+		return nil
+	}
+	if entry, ok := p.byName[name]; ok {
+		return entry.Provisioning
+	}
+	return nil
+}
+
+// List 返回所有已注册的提供者
+func (p *IdentityProviders) List() []*IdentityProviderEntry {
+	if p == nil {
+		return nil
+	}
+	return p.entries
+}
@@ -2,19 +2,56 @@ package svc
 
 import (
 	"context"
+	"iter"
+
+	"auth-service/internal/types"
 )
 
 // LDAPClient defines the interface for LDAP operations
 type LDAPClient interface {
 	Authenticate(ctx context.Context, username, password string) (*LDAPUserInfo, error)
 	GetUserGroups(ctx context.Context, userDN string) ([]string, error)
+	SyncUsers(ctx context.Context) iter.Seq2[*LDAPUserInfo, error]
+	ChangePassword(ctx context.Context, userDN, oldPassword, newPassword string) error
+	ResetPassword(ctx context.Context, userDN, newPassword string) error
+	IsEnabled() bool
+}
+
+// RADIUSClient defines the interface for RADIUS authentication
+type RADIUSClient interface {
 	IsEnabled() bool
+	Authenticate(ctx context.Context, username, password string) (*RADIUSResult, error)
+	RespondChallenge(ctx context.Context, challenge *RADIUSChallenge, username, response string) (*RADIUSResult, error)
 }
 
 // OIDCClient defines the interface for OIDC operations
 type OIDCClient interface {
 	IsEnabled() bool
-	GetAuthorizationURL(state, nonce string) string
-	ExchangeCode(ctx context.Context, code string) (*OIDCTokenResponse, error)
+	GetAuthorizationURL(state, nonce, codeChallenge string) string
+	ExchangeCode(ctx context.Context, code, codeVerifier string) (*OIDCTokenResponse, error)
+	VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*OIDCIDToken, error)
 	GetUserInfo(ctx context.Context, accessToken string) (*OIDCUserInfo, error)
+	RefreshAccessToken(ctx context.Context, refreshToken string) (*OIDCTokenResponse, error)
+	VerifyLogoutToken(ctx context.Context, rawLogoutToken string) (*OIDCLogoutToken, error)
+	GetLogoutURL(idToken, postLogoutRedirectURI string) string
+}
+
+// EmailSender defines the interface for sending notification emails
+type EmailSender interface {
+	SendResetEmail(to, resetURL string) error
+	SendVerificationCode(to, code string) error
+	SendMagicLink(to, loginURL string) error
+}
+
+// SMSSender defines the interface for sending SMS verification codes
+type SMSSender interface {
+	SendVerificationCode(ctx context.Context, phone, code string) error
+}
+
+// OAuth2Client defines the interface for social OAuth2 operations
+type OAuth2Client interface {
+	IsEnabled() bool
+	GetAuthorizationURL(state string) string
+	ExchangeCode(ctx context.Context, code string) (*OAuth2Token, error)
+	GetUserInfo(ctx context.Context, token *OAuth2Token) (*types.SSOUserInfo, error)
 }
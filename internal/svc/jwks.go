@@ -0,0 +1,192 @@
+package svc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/syncx"
+)
+
+// JWKSCache 缓存 OIDC 提供者的签名公钥，遇到未知 kid 时自动刷新
+type JWKSCache struct {
+	uri        string
+	httpClient *http.Client
+	flight     syncx.SingleFlight // 合并并发的刷新请求
+
+	mu        sync.RWMutex
+	keys      map[string]interface{} // kid -> *rsa.PublicKey / *ecdsa.PublicKey
+	fetchedAt time.Time
+}
+
+// jsonWebKey JWK 公钥 (RFC 7517)
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// jsonWebKeySet JWK 集合
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// NewJWKSCache 创建 JWKS 缓存
+func NewJWKSCache(uri string, httpClient *http.Client) *JWKSCache {
+	return &JWKSCache{
+		uri:        uri,
+		httpClient: httpClient,
+		flight:     syncx.NewSingleFlight(),
+		keys:       make(map[string]interface{}),
+	}
+}
+
+// GetKey 根据 kid 获取公钥，未命中时刷新一次 JWKS
+func (c *JWKSCache) GetKey(ctx context.Context, kid string) (interface{}, error) {
+	if key, ok := c.lookup(kid); ok {
+		return key, nil
+	}
+
+	// 提供者可能已轮换密钥，重新拉取一次
+	if _, err := c.flight.Do(c.uri, func() (any, error) {
+		return nil, c.Refresh(ctx)
+	}); err != nil {
+		return nil, err
+	}
+	if key, ok := c.lookup(kid); ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("no JWKS key found for kid %q", kid)
+}
+
+// lookup 在缓存中查找公钥；kid 为空且只有一个公钥时直接返回该公钥
+func (c *JWKSCache) lookup(kid string) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+// FetchedAt 返回最近一次成功拉取 JWKS 的时间
+func (c *JWKSCache) FetchedAt() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.fetchedAt
+}
+
+// Refresh 重新拉取 JWKS
+func (c *JWKSCache) Refresh(ctx context.Context) error {
+	if c.uri == "" {
+		return fmt.Errorf("jwks_uri is not configured")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.uri, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create jwks request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("jwks endpoint returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var set jsonWebKeySet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode jwks: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// 跳过无法识别的公钥，不影响其它公钥使用
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+
+	return nil
+}
+
+// publicKey 将 JWK 转换为 Go 公钥
+func (k *jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64URLInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := decodeBase64URLInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA exponent: %w", err)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve: %s", k.Crv)
+		}
+		x, err := decodeBase64URLInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC x coordinate: %w", err)
+		}
+		y, err := decodeBase64URLInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC y coordinate: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+	}
+}
+
+func decodeBase64URLInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -23,12 +23,16 @@ const (
 	RefreshToken TokenType = "refresh"
 )
 
+// ScopeEmailUnverified 邮箱未验证的用户签发的受限令牌，只能访问验证邮箱所需的接口
+const ScopeEmailUnverified = "email_unverified"
+
 // 令牌对
 type TokenPair struct {
 	AccessToken      string `json:"accessToken"`
 	AccessExpiresAt  int64  `json:"accessExpiresAt"`
 	RefreshToken     string `json:"refreshToken"`
 	RefreshExpiresAt int64  `json:"refreshExpiresAt"`
+	SessionID        string `json:"-"` // 会话 ID, 刷新令牌时保持不变
 }
 
 type CustomClaims struct {
@@ -38,6 +42,14 @@ type CustomClaims struct {
 	Username string    `json:"username"`
 	TokenID  string    `json:"tokenId"`
 	Type     TokenType `json:"type"`
+	// SessionID 登录会话 ID，刷新令牌时保持不变，用于按会话吊销 (如 OIDC 后端登出)
+	SessionID string `json:"sid,omitempty"`
+	// Roles 登录时用户的角色，刷新令牌时沿用
+	Roles []string `json:"roles,omitempty"`
+	// Scope 受限令牌的访问范围，为空时不受限；刷新令牌时沿用
+	Scope string `json:"scope,omitempty"`
+	// IssuedAtNano 纳秒精度的签发时间，与用户令牌的吊销时间比较 (iat 只精确到秒)
+	IssuedAtNano int64 `json:"iatNano,omitempty"`
 }
 
 type JWT struct {
@@ -62,17 +74,32 @@ func NewJWT(c config.Config, rdb redis.UniversalClient) *JWT {
 	}
 }
 
+// Generate 为新的登录会话生成令牌对
 func (j *JWT) Generate(userID uint64, username string) (*TokenPair, error) {
+	return j.generateWithSession(userID, username, nil, "", generateTokenID())
+}
+
+// GenerateWithRoles 为新的登录会话生成包含用户角色的令牌对
+func (j *JWT) GenerateWithRoles(userID uint64, username string, roles []string) (*TokenPair, error) {
+	return j.generateWithSession(userID, username, roles, "", generateTokenID())
+}
+
+// GenerateWithScope 为新的登录会话生成受限令牌对，令牌只能访问为该范围开放的接口
+func (j *JWT) GenerateWithScope(userID uint64, username string, roles []string, scope string) (*TokenPair, error) {
+	return j.generateWithSession(userID, username, roles, scope, generateTokenID())
+}
+
+func (j *JWT) generateWithSession(userID uint64, username string, roles []string, scope string, sessionID string) (*TokenPair, error) {
 	tokenID := generateTokenID()
 
 	// 生成 Access Token
-	accessToken, accessExpiresAt, err := j.generateToken(userID, username, tokenID, AccessToken)
+	accessToken, accessExpiresAt, err := j.generateToken(userID, username, roles, scope, tokenID, sessionID, AccessToken)
 	if err != nil {
 		return nil, err
 	}
 
 	// 生成 Refresh Token
-	refreshToken, refreshExpiresAt, err := j.generateToken(userID, username, tokenID, RefreshToken)
+	refreshToken, refreshExpiresAt, err := j.generateToken(userID, username, roles, scope, tokenID, sessionID, RefreshToken)
 	if err != nil {
 		return nil, err
 	}
@@ -82,10 +109,11 @@ func (j *JWT) Generate(userID uint64, username string) (*TokenPair, error) {
 		AccessExpiresAt:  accessExpiresAt,
 		RefreshToken:     refreshToken,
 		RefreshExpiresAt: refreshExpiresAt,
+		SessionID:        sessionID,
 	}, nil
 }
 
-func (j *JWT) generateToken(userID uint64, username string, tokenID string, tokenType TokenType) (string, int64, error) {
+func (j *JWT) generateToken(userID uint64, username string, roles []string, scope string, tokenID string, sessionID string, tokenType TokenType) (string, int64, error) {
 
 	var expireTime time.Time
 	var secret []byte
@@ -104,10 +132,14 @@ func (j *JWT) generateToken(userID uint64, username string, tokenID string, toke
 	}
 
 	claims := CustomClaims{
-		UserID:   userID,
-		Username: username,
-		TokenID:  tokenID,
-		Type:     tokenType,
+		UserID:       userID,
+		Username:     username,
+		TokenID:      tokenID,
+		Type:         tokenType,
+		SessionID:    sessionID,
+		Roles:        roles,
+		Scope:        scope,
+		IssuedAtNano: now.UnixNano(),
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: expireTime.Unix(),
 			IssuedAt:  now.Unix(),
@@ -163,6 +195,26 @@ func (j *JWT) verifyToken(tokenString string, expectedType TokenType, secret []b
 		if claims.Type != expectedType {
 			return nil, errors.New("invalid token type")
 		}
+		// 检查会话是否已被吊销
+		if j.rdb != nil && claims.SessionID != "" {
+			revoked, err := j.isSessionRevoked(claims.SessionID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check session revocation: %v", err)
+			}
+			if revoked {
+				return nil, errors.New("session is revoked")
+			}
+		}
+		// 检查用户的令牌是否已被整体吊销
+		if j.rdb != nil {
+			revoked, err := j.isUserRevoked(claims)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check user revocation: %v", err)
+			}
+			if revoked {
+				return nil, errors.New("user tokens are revoked")
+			}
+		}
 		return claims, nil
 	}
 
@@ -187,8 +239,12 @@ func (j *JWT) Refresh(refreshToken string) (*TokenPair, error) {
 		}
 	}
 
-	// 生成新的 Token 对
-	return j.Generate(claims.UserID, claims.Username)
+	// 生成新的 Token 对 (沿用原会话 ID、角色与访问范围)
+	sessionID := claims.SessionID
+	if sessionID == "" {
+		sessionID = generateTokenID()
+	}
+	return j.generateWithSession(claims.UserID, claims.Username, claims.Roles, claims.Scope, sessionID)
 }
 
 func (j *JWT) Logout(accessToken string, refreshToken string) error {
@@ -237,6 +293,60 @@ func (j *JWT) BlacklistToken(tokenString string, expire time.Duration) error {
 	return j.rdb.SetEx(ctx, key, "1", expire).Err()
 }
 
+// RevokeSession 吊销整个登录会话，该会话签发 (含刷新后) 的所有令牌立即失效
+func (j *JWT) RevokeSession(sessionID string) error {
+	if j.rdb == nil {
+		return errors.New("rdb is not initialized")
+	}
+
+	key := fmt.Sprintf("%s:session:%s", j.blacklistPrefix, sessionID)
+
+	ctx := context.Background()
+	return j.rdb.SetEx(ctx, key, "1", j.refreshExpiresIn).Err()
+}
+
+func (j *JWT) isSessionRevoked(sessionID string) (bool, error) {
+	key := fmt.Sprintf("%s:session:%s", j.blacklistPrefix, sessionID)
+
+	ctx := context.Background()
+	exists, err := j.rdb.Exists(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return exists > 0, nil
+}
+
+// RevokeUserTokens 吊销用户此前签发的全部令牌 (如账号被禁用)，之后签发的令牌不受影响
+func (j *JWT) RevokeUserTokens(userID uint64) error {
+	if j.rdb == nil {
+		return errors.New("rdb is not initialized")
+	}
+
+	key := fmt.Sprintf("%s:user:%d", j.blacklistPrefix, userID)
+
+	ctx := context.Background()
+	return j.rdb.SetEx(ctx, key, time.Now().UnixNano(), j.refreshExpiresIn).Err()
+}
+
+// isUserRevoked 令牌是否签发于用户令牌被吊销之前。吊销时间精确到纳秒，吊销后立即签发的令牌
+// (如重置密码后马上登录) 不受影响；没有纳秒签发时间的令牌按秒比较，同一秒内签发的视为已吊销
+func (j *JWT) isUserRevoked(claims *CustomClaims) (bool, error) {
+	key := fmt.Sprintf("%s:user:%d", j.blacklistPrefix, claims.UserID)
+
+	ctx := context.Background()
+	revokedAt, err := j.rdb.Get(ctx, key).Int64()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if claims.IssuedAtNano == 0 {
+		return claims.IssuedAt <= revokedAt/int64(time.Second), nil
+	}
+	return claims.IssuedAtNano <= revokedAt, nil
+}
+
 func (j *JWT) isTokenBlacklisted(tokenString string) (bool, error) {
 	key := fmt.Sprintf("%s:%s", j.blacklistPrefix, tokenString)
 
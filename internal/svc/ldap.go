@@ -3,7 +3,11 @@ package svc
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"iter"
+	"net"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,9 +17,20 @@ import (
 	"github.com/zeromicro/go-zero/core/logx"
 )
 
+const (
+	// defaultLDAPDialTimeout 单台服务器的默认连接超时，超时后转移到下一台
+	defaultLDAPDialTimeout = 5 * time.Second
+	// adAccountDisable AD userAccountControl 的 ACCOUNTDISABLE 标志位
+	adAccountDisable = 0x2
+)
+
 // LDAPProvider LDAP 认证提供者
 type LDAPProvider struct {
-	config config.LDAPConfig
+	config     config.LDAPConfig
+	mapper     *ClaimMapper
+	pool       *LDAPPool       // 管理员绑定的搜索连接
+	servers    *LDAPServerSet  // 可用服务器与故障转移状态
+	groupRoles []ldapGroupRole // 组到角色的映射
 }
 
 // LDAPUserInfo LDAP 用户信息
@@ -28,6 +43,8 @@ type LDAPUserInfo struct {
 	LastName    string
 	Phone       string
 	Groups      []string
+	Roles       []string // 组映射到的角色 (配置了 GroupRoles 时)
+	Disabled    bool     // 目录中的账号已禁用 (AD userAccountControl)
 	Attributes  map[string][]string
 }
 
@@ -37,64 +54,106 @@ func NewLDAPProvider(cfg config.LDAPConfig) (*LDAPProvider, error) {
 		return nil, nil
 	}
 
+	mapper, err := NewClaimMapper(DefaultLDAPAttributeMapping, ldapAttributeMapping(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP attribute mapping: %w", err)
+	}
+	groupRoles, err := newLDAPGroupRoles(cfg.GroupRoles)
+	if err != nil {
+		return nil, fmt.Errorf("invalid LDAP group roles: %w", err)
+	}
+	if err := validateLDAPNestedGroups(cfg.NestedGroups); err != nil {
+		return nil, err
+	}
+	if err := validateLDAPServerType(cfg.ServerType); err != nil {
+		return nil, err
+	}
+
 	provider := &LDAPProvider{
-		config: cfg,
+		config:     cfg,
+		mapper:     mapper,
+		servers:    NewLDAPServerSet(cfg, nil),
+		groupRoles: groupRoles,
 	}
+	provider.pool = NewLDAPPool(cfg.Pool, provider.dial, provider.bindAsAdmin)
 
 	// 测试连接
 	conn, err := provider.connect()
 	if err != nil {
+		provider.pool.Close()
 		return nil, fmt.Errorf("failed to connect to LDAP server: %w", err)
 	}
 	defer conn.Close()
 
-	logx.Infof("LDAP provider initialized, server: %s:%d", cfg.Host, cfg.Port)
+	logx.Infof("LDAP provider initialized, servers: %v", provider.servers.Candidates())
 	return provider, nil
 }
 
-// connect 建立 LDAP 连接
+// connect 建立 LDAP 连接，按服务器选择策略依次尝试，连接失败的服务器进入冷却
 func (p *LDAPProvider) connect() (*ldap.Conn, error) {
-	var (
-		conn *ldap.Conn
-		err  error
-	)
+	servers := p.servers.Candidates()
+	if len(servers) == 0 {
+		return nil, errors.New("no LDAP server configured")
+	}
+
+	var lastErr error
+	for _, server := range servers {
+		conn, err := p.connectTo(server)
+		if err == nil {
+			p.servers.MarkHealthy(server)
+			return conn, nil
+		}
+		logx.Errorf("LDAP server %s is unavailable: %v", server, err)
+		p.servers.MarkFailed(server)
+		lastErr = err
+	}
+	return nil, lastErr
+}
 
-	address := fmt.Sprintf("%s:%d", p.config.Host, p.config.Port)
+// connectTo 连接指定的服务器
+func (p *LDAPProvider) connectTo(server LDAPServer) (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: p.config.InsecureSkipTLS,
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         server.Host,
+	}
+	dialer := &net.Dialer{Timeout: secondsOrDefault(p.config.DialTimeout, defaultLDAPDialTimeout)}
 
+	scheme := "ldap"
 	if p.config.UseSSL {
 		// LDAPS 连接
-		tlsConfig := &tls.Config{
-			InsecureSkipVerify: p.config.InsecureSkipTLS,
-			MinVersion:         tls.VersionTLS12,
-		}
-		conn, err = ldap.DialTLS("tcp", address, tlsConfig)
-	} else {
-		// 普通连接
-		conn, err = ldap.Dial("tcp", address)
-
-		// 如果需要 StartTLS
-		if err == nil && p.config.UseTLS {
-			tlsConfig := &tls.Config{
-				InsecureSkipVerify: p.config.InsecureSkipTLS,
-				MinVersion:         tls.VersionTLS12,
-				ServerName:         p.config.Host,
-			}
-			err = conn.StartTLS(tlsConfig)
-		}
+		scheme = "ldaps"
 	}
-
+	conn, err := ldap.DialURL(scheme+"://"+server.String(), ldap.DialWithDialer(dialer), ldap.DialWithTLSConfig(tlsConfig))
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial LDAP: %w", err)
 	}
 
+	// 如果需要 StartTLS
+	if !p.config.UseSSL && p.config.UseTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
 	// 设置超时
 	conn.SetTimeout(30 * time.Second)
 
 	return conn, nil
 }
 
+// dial 建立连接池使用的 LDAP 连接
+func (p *LDAPProvider) dial() (ldap.Client, error) {
+	conn, err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
 // bindAsAdmin 使用管理员账号绑定
-func (p *LDAPProvider) bindAsAdmin(conn *ldap.Conn) error {
+func (p *LDAPProvider) bindAsAdmin(conn ldap.Client) error {
 	if p.config.BindDN == "" {
 		// 匿名绑定
 		return conn.UnauthenticatedBind("")
@@ -104,31 +163,42 @@ func (p *LDAPProvider) bindAsAdmin(conn *ldap.Conn) error {
 
 // Authenticate 验证用户凭据
 func (p *LDAPProvider) Authenticate(ctx context.Context, username, password string) (*LDAPUserInfo, error) {
-	// 建立连接
+	// 先用连接池中管理员绑定的连接搜索用户
+	var (
+		userDN   string
+		userInfo *LDAPUserInfo
+	)
+	err := p.pool.Do(ctx, func(conn ldap.Client) (err error) {
+		userDN, userInfo, err = p.searchUser(conn, username)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 使用用户的 DN 和密码在独立的短连接上绑定来验证密码，不影响池中连接的绑定身份
 	conn, err := p.connect()
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
 	defer conn.Close()
 
-	// 先用管理员账号绑定来搜索用户
-	if err := p.bindAsAdmin(conn); err != nil {
-		return nil, fmt.Errorf("failed to bind as admin: %w", err)
-	}
-
-	// 搜索用户
-	userDN, userInfo, err := p.searchUser(conn, username)
-	if err != nil {
-		return nil, err
+	if err := conn.Bind(userDN, password); err != nil {
+		return nil, ldapBindError(userDN, err)
 	}
 
-	// 使用用户的 DN 和密码重新绑定来验证密码
-	if err := conn.Bind(userDN, password); err != nil {
-		ldapErr, ok := err.(*ldap.Error)
-		if ok && ldapErr.ResultCode == ldap.LDAPResultInvalidCredentials {
-			return nil, fmt.Errorf("invalid credentials")
+	// 密码校验通过后再解析 (嵌套) 组与角色；解析失败时拒绝登录，避免按不完整的组回收角色
+	if p.resolvesGroups() {
+		var groups []string
+		err := p.pool.Do(ctx, func(conn ldap.Client) (err error) {
+			groups, err = p.resolveGroups(conn, userDN)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve groups: %w", err)
 		}
-		return nil, fmt.Errorf("authentication failed: %w", err)
+		userInfo.Groups = groups
+		userInfo.Roles = p.MapRoles(groups)
 	}
 
 	logx.Infof("LDAP authentication successful for user: %s", username)
@@ -136,15 +206,12 @@ func (p *LDAPProvider) Authenticate(ctx context.Context, username, password stri
 }
 
 // searchUser 搜索用户
-func (p *LDAPProvider) searchUser(conn *ldap.Conn, username string) (string, *LDAPUserInfo, error) {
+func (p *LDAPProvider) searchUser(conn ldap.Client, username string) (string, *LDAPUserInfo, error) {
 	// 构建用户过滤器
 	filter := strings.Replace(p.config.UserFilter, "%s", ldap.EscapeFilter(username), -1)
 
 	// 构建属性列表
-	attributes := p.config.UserAttributes
-	if len(attributes) == 0 {
-		attributes = []string{"*"} // 获取所有属性
-	}
+	attributes := p.searchAttributes()
 
 	searchRequest := ldap.NewSearchRequest(
 		p.config.BaseDN,
@@ -189,95 +256,139 @@ func (p *LDAPProvider) entryToUserInfo(entry *ldap.Entry) *LDAPUserInfo {
 		userInfo.Attributes[attr.Name] = attr.Values
 	}
 
-	// 映射配置的属性
-	if p.config.UsernameAttr != "" {
-		userInfo.Username = entry.GetAttributeValue(p.config.UsernameAttr)
-	}
-	if p.config.EmailAttr != "" {
-		userInfo.Email = entry.GetAttributeValue(p.config.EmailAttr)
-	}
-	if p.config.DisplayNameAttr != "" {
-		userInfo.DisplayName = entry.GetAttributeValue(p.config.DisplayNameAttr)
-	}
-
-	// 尝试获取常见属性
-	if userInfo.Username == "" {
-		userInfo.Username = entry.GetAttributeValue("uid")
-		if userInfo.Username == "" {
-			userInfo.Username = entry.GetAttributeValue("sAMAccountName")
-		}
-		if userInfo.Username == "" {
-			userInfo.Username = entry.GetAttributeValue("cn")
-		}
-	}
-	if userInfo.Email == "" {
-		userInfo.Email = entry.GetAttributeValue("mail")
-	}
-	if userInfo.DisplayName == "" {
-		userInfo.DisplayName = entry.GetAttributeValue("displayName")
-		if userInfo.DisplayName == "" {
-			userInfo.DisplayName = entry.GetAttributeValue("cn")
-		}
+	// 按属性映射规则提取用户资料
+	mapper := p.mapper
+	if mapper == nil {
+		mapper = &ClaimMapper{mapping: DefaultLDAPAttributeMapping}
 	}
+	identity := mapper.MapAttributes(userInfo.Attributes)
+	userInfo.Username = identity.Username
+	userInfo.Email = identity.Email
+	userInfo.DisplayName = identity.Nickname
+	userInfo.Phone = identity.Phone
+	userInfo.Groups = identity.Groups
 
 	// 获取名字
 	userInfo.FirstName = entry.GetAttributeValue("givenName")
 	userInfo.LastName = entry.GetAttributeValue("sn")
-	userInfo.Phone = entry.GetAttributeValue("telephoneNumber")
 
-	// 获取组成员资格
-	if p.config.GroupMemberAttr != "" {
-		userInfo.Groups = entry.GetAttributeValues(p.config.GroupMemberAttr)
-	} else {
-		userInfo.Groups = entry.GetAttributeValues("memberOf")
+	// AD 账号状态: userAccountControl 的 ACCOUNTDISABLE 位
+	if uac, err := strconv.ParseInt(entry.GetAttributeValue("userAccountControl"), 10, 64); err == nil {
+		userInfo.Disabled = uac&adAccountDisable != 0
 	}
 
 	return userInfo
 }
 
-// GetUserGroups 获取用户的组
-func (p *LDAPProvider) GetUserGroups(ctx context.Context, userDN string) ([]string, error) {
-	conn, err := p.connect()
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect: %w", err)
+// searchAttributes 返回搜索时需要获取的属性，限定了 UserAttributes 时补充映射规则引用的属性与账号状态属性
+func (p *LDAPProvider) searchAttributes() []string {
+	if len(p.config.UserAttributes) == 0 {
+		return []string{"*"} // 获取所有属性
 	}
-	defer conn.Close()
 
-	if err := p.bindAsAdmin(conn); err != nil {
-		return nil, fmt.Errorf("failed to bind as admin: %w", err)
+	attributes := append([]string{}, p.config.UserAttributes...)
+	names := []string{"userAccountControl"}
+	if p.mapper != nil {
+		names = append(names, p.mapper.Attributes()...)
+	}
+	for _, name := range names {
+		found := false
+		for _, attr := range attributes {
+			if strings.EqualFold(attr, name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			attributes = append(attributes, name)
+		}
+	}
+	return attributes
+}
+
+// ldapAttributeMapping 合并 AttributeMapping 与旧的单项属性配置 (UsernameAttr 等)，
+// 单项配置的属性优先尝试，未取到值时仍回退到常见属性
+func ldapAttributeMapping(cfg config.LDAPConfig) config.ClaimMapping {
+	mapping := cfg.AttributeMapping
+	if len(mapping.Username) == 0 && cfg.UsernameAttr != "" {
+		mapping.Username = append([]string{cfg.UsernameAttr}, DefaultLDAPAttributeMapping.Username...)
+	}
+	if len(mapping.Email) == 0 && cfg.EmailAttr != "" {
+		mapping.Email = append([]string{cfg.EmailAttr}, DefaultLDAPAttributeMapping.Email...)
+	}
+	if len(mapping.Nickname) == 0 && cfg.DisplayNameAttr != "" {
+		mapping.Nickname = append([]string{cfg.DisplayNameAttr}, DefaultLDAPAttributeMapping.Nickname...)
 	}
+	if len(mapping.Groups) == 0 && cfg.GroupMemberAttr != "" {
+		mapping.Groups = []string{cfg.GroupMemberAttr}
+	}
+	return mapping
+}
+
+// GetUserGroups 获取用户的组
+func (p *LDAPProvider) GetUserGroups(ctx context.Context, userDN string) (groups []string, err error) {
+	err = p.pool.Do(ctx, func(conn ldap.Client) (err error) {
+		groups, err = p.searchUserGroups(conn, userDN)
+		return err
+	})
+	return groups, err
+}
 
+// ResolveGroups 获取用户所在组的 DN，按 NestedGroups 配置展开嵌套组
+func (p *LDAPProvider) ResolveGroups(ctx context.Context, userDN string) (groups []string, err error) {
+	err = p.pool.Do(ctx, func(conn ldap.Client) (err error) {
+		groups, err = p.resolveGroups(conn, userDN)
+		return err
+	})
+	return groups, err
+}
+
+// searchUserGroups 通过组过滤器或用户条目的 memberOf 属性查询用户的组
+func (p *LDAPProvider) searchUserGroups(conn ldap.Client, userDN string) ([]string, error) {
 	// 如果配置了组过滤器，使用它来搜索组
 	if p.config.GroupFilter != "" {
 		filter := strings.Replace(p.config.GroupFilter, "%s", ldap.EscapeFilter(userDN), -1)
-
-		searchRequest := ldap.NewSearchRequest(
-			p.config.BaseDN,
-			ldap.ScopeWholeSubtree,
-			ldap.NeverDerefAliases,
-			0,
-			30,
-			false,
-			filter,
-			[]string{"cn", "dn"},
-			nil,
-		)
-
-		result, err := conn.Search(searchRequest)
+		entries, err := p.searchGroupEntries(conn, filter)
 		if err != nil {
-			return nil, fmt.Errorf("failed to search groups: %w", err)
+			return nil, err
 		}
 
-		groups := make([]string, len(result.Entries))
-		for i, entry := range result.Entries {
+		groups := make([]string, len(entries))
+		for i, entry := range entries {
 			groups[i] = entry.GetAttributeValue("cn")
 		}
 		return groups, nil
 	}
 
 	// 否则从用户条目中获取 memberOf 属性
+	return p.memberOf(conn, userDN)
+}
+
+// searchGroupEntries 在组基准 DN 下搜索组
+func (p *LDAPProvider) searchGroupEntries(conn ldap.Client, filter string) ([]*ldap.Entry, error) {
+	searchRequest := ldap.NewSearchRequest(
+		p.groupBaseDN(),
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0,
+		30,
+		false,
+		filter,
+		[]string{"cn"},
+		nil,
+	)
+
+	result, err := conn.Search(searchRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search groups: %w", err)
+	}
+	return result.Entries, nil
+}
+
+// memberOf 读取条目 (用户或组) 的 memberOf 属性
+func (p *LDAPProvider) memberOf(conn ldap.Client, dn string) ([]string, error) {
 	searchRequest := ldap.NewSearchRequest(
-		userDN,
+		dn,
 		ldap.ScopeBaseObject,
 		ldap.NeverDerefAliases,
 		1,
@@ -300,57 +411,70 @@ func (p *LDAPProvider) GetUserGroups(ctx context.Context, userDN string) ([]stri
 	return result.Entries[0].GetAttributeValues("memberOf"), nil
 }
 
-// SearchUsers 搜索用户列表
+// SearchUsers 分页搜索用户，limit 大于 0 时最多返回 limit 个
 func (p *LDAPProvider) SearchUsers(ctx context.Context, filter string, limit int) ([]*LDAPUserInfo, error) {
-	conn, err := p.connect()
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect: %w", err)
-	}
-	defer conn.Close()
-
-	if err := p.bindAsAdmin(conn); err != nil {
-		return nil, fmt.Errorf("failed to bind as admin: %w", err)
+	opts := LDAPSearchOptions{Filter: filter}
+	if limit > 0 && limit < p.pageSize() {
+		opts.PageSize = limit
 	}
 
-	attributes := p.config.UserAttributes
-	if len(attributes) == 0 {
-		attributes = []string{"*"}
+	var users []*LDAPUserInfo
+	for user, err := range p.IterUsers(ctx, opts) {
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+		if limit > 0 && len(users) >= limit {
+			break
+		}
 	}
+	return users, nil
+}
 
-	searchRequest := ldap.NewSearchRequest(
-		p.config.BaseDN,
-		ldap.ScopeWholeSubtree,
-		ldap.NeverDerefAliases,
-		limit,
-		30,
-		false,
-		filter,
-		attributes,
-		nil,
-	)
+// SyncUsers 分页遍历同步范围内的用户 (过滤器为 Sync.Filter)，按配置解析每个用户的组与角色
+func (p *LDAPProvider) SyncUsers(ctx context.Context) iter.Seq2[*LDAPUserInfo, error] {
+	return p.IterUsers(ctx, LDAPSearchOptions{
+		Filter:        p.syncFilter(),
+		PageSize:      p.config.Sync.PageSize,
+		ResolveGroups: true,
+	})
+}
 
-	result, err := conn.Search(searchRequest)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search users: %w", err)
+// ListUsers 返回同步范围内的全部用户
+func (p *LDAPProvider) ListUsers(ctx context.Context) ([]*LDAPUserInfo, error) {
+	var users []*LDAPUserInfo
+	for user, err := range p.SyncUsers(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
 	}
+	return users, nil
+}
 
-	users := make([]*LDAPUserInfo, len(result.Entries))
-	for i, entry := range result.Entries {
-		users[i] = p.entryToUserInfo(entry)
+// syncFilter 同步时的用户过滤器，未配置时将 UserFilter 中的用户名占位符替换为通配符
+func (p *LDAPProvider) syncFilter() string {
+	if p.config.Sync.Filter != "" {
+		return p.config.Sync.Filter
 	}
-
-	return users, nil
+	return strings.Replace(p.config.UserFilter, "%s", "*", -1)
 }
 
 // TestConnection 测试连接
 func (p *LDAPProvider) TestConnection(ctx context.Context) error {
-	conn, err := p.connect()
-	if err != nil {
-		return err
-	}
-	defer conn.Close()
+	return p.pool.Do(ctx, ldapPing)
+}
+
+// PoolStats 返回搜索连接池状态
+func (p *LDAPProvider) PoolStats() LDAPPoolStats {
+	return p.pool.Stats()
+}
 
-	return p.bindAsAdmin(conn)
+// Close 关闭搜索连接池
+func (p *LDAPProvider) Close() {
+	if p != nil && p.pool != nil {
+		p.pool.Close()
+	}
 }
 
 // IsEnabled 检查是否启用
@@ -0,0 +1,86 @@
+package svc
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// 用户绑定失败的原因。AD 在 Invalid Credentials (49) 的诊断信息中以 "data <子码>" 给出具体原因，
+// 其它服务器只区分密码错误
+var (
+	// ErrLDAPInvalidCredentials 用户名或密码错误 (AD 52e)
+	ErrLDAPInvalidCredentials = errors.New("invalid credentials")
+	// ErrLDAPUserNotFound 目录中不存在该用户 (AD 525)
+	ErrLDAPUserNotFound = errors.New("user not found in directory")
+	// ErrLDAPLogonRestricted 不允许在当前时间或从当前工作站登录 (AD 530, 531)
+	ErrLDAPLogonRestricted = errors.New("logon is not permitted at this time or from this workstation")
+	// ErrLDAPPasswordExpired 密码已过期 (AD 532)
+	ErrLDAPPasswordExpired = errors.New("password has expired")
+	// ErrLDAPAccountDisabled 账号已禁用 (AD 533)
+	ErrLDAPAccountDisabled = errors.New("account is disabled")
+	// ErrLDAPAccountExpired 账号已过期 (AD 701)
+	ErrLDAPAccountExpired = errors.New("account has expired")
+	// ErrLDAPPasswordMustChange 登录前必须修改密码 (AD 773, 管理员重置密码或新建账号后)
+	ErrLDAPPasswordMustChange = errors.New("password must be changed before logging on")
+	// ErrLDAPAccountLocked 账号因密码错误次数过多被锁定 (AD 775)
+	ErrLDAPAccountLocked = errors.New("account is locked")
+)
+
+var adBindSubCodes = map[string]error{
+	"52e": ErrLDAPInvalidCredentials,
+	"525": ErrLDAPUserNotFound,
+	"530": ErrLDAPLogonRestricted,
+	"531": ErrLDAPLogonRestricted,
+	"532": ErrLDAPPasswordExpired,
+	"533": ErrLDAPAccountDisabled,
+	"701": ErrLDAPAccountExpired,
+	"773": ErrLDAPPasswordMustChange,
+	"775": ErrLDAPAccountLocked,
+}
+
+// 如 "80090308: LdapErr: DSID-0C09044E, comment: AcceptSecurityContext error, data 773, v4563"
+var adBindSubCodePattern = regexp.MustCompile(`(?i)\bdata ([0-9a-f]+),`)
+
+// LDAPBindError 用户绑定被拒绝。errors.Is 可匹配 Reason (ErrLDAPInvalidCredentials 等) 与原始的 LDAP 错误
+type LDAPBindError struct {
+	DN      string
+	SubCode string // AD 诊断子码 (如 "773")，其它服务器为空
+	Reason  error
+	Err     error
+}
+
+func (e *LDAPBindError) Error() string {
+	if e.SubCode == "" {
+		return e.Reason.Error()
+	}
+	return fmt.Sprintf("%v (AD data %s)", e.Reason, e.SubCode)
+}
+
+func (e *LDAPBindError) Unwrap() []error {
+	return []error{e.Reason, e.Err}
+}
+
+// ldapBindError 解析用户绑定失败的原因，不是凭据被拒绝 (如网络错误) 时原样包装返回
+func ldapBindError(dn string, err error) error {
+	if code, ok := ldapResultCode(err); !ok || code != ldap.LDAPResultInvalidCredentials {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+
+	bindErr := &LDAPBindError{DN: dn, Reason: ErrLDAPInvalidCredentials, Err: err}
+	if m := adBindSubCodePattern.FindStringSubmatch(LDAPDiagnosticMessage(err)); m != nil {
+		bindErr.SubCode = strings.ToLower(m[1])
+		if reason, ok := adBindSubCodes[bindErr.SubCode]; ok {
+			bindErr.Reason = reason
+		}
+	}
+	return bindErr
+}
+
+// mustChangePassword 绑定是否因密码需要修改 (已过期或管理员要求修改) 而被拒绝，此时旧密码是正确的
+func mustChangePassword(err error) bool {
+	return errors.Is(err, ErrLDAPPasswordMustChange) || errors.Is(err, ErrLDAPPasswordExpired)
+}
@@ -0,0 +1,223 @@
+package svc
+
+import (
+	"fmt"
+	"strings"
+
+	"auth-service/internal/config"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	// LDAPNestedGroupsNone 只使用用户直接所在的组
+	LDAPNestedGroupsNone = "none"
+	// LDAPNestedGroupsInChain 使用 AD 的 LDAP_MATCHING_RULE_IN_CHAIN 一次查询全部嵌套组
+	LDAPNestedGroupsInChain = "in_chain"
+	// LDAPNestedGroupsRecursive 逐层查询组所在的组 (适用于不支持 IN_CHAIN 的服务器, 如 OpenLDAP)
+	LDAPNestedGroupsRecursive = "recursive"
+
+	defaultLDAPNestedGroupDepth = 10
+	ldapMatchingRuleInChain     = "1.2.840.113556.1.4.1941"
+)
+
+// ldapGroupRole 一条组到角色的映射，pattern 已规范化
+type ldapGroupRole struct {
+	pattern string
+	byDN    bool // 匹配完整 DN，否则匹配组的 CN
+	roles   []string
+}
+
+// newLDAPGroupRoles 校验并规范化组到角色的映射配置
+func newLDAPGroupRoles(cfg []config.LDAPGroupRoleConfig) ([]ldapGroupRole, error) {
+	mappings := make([]ldapGroupRole, 0, len(cfg))
+	for i, c := range cfg {
+		group := strings.TrimSpace(c.Group)
+		if group == "" {
+			return nil, fmt.Errorf("group role mapping %d: group is required", i)
+		}
+		if len(c.Roles) == 0 {
+			return nil, fmt.Errorf("group role mapping %q: roles are required", group)
+		}
+		byDN := strings.Contains(group, "=")
+		pattern := strings.ToLower(group)
+		if byDN {
+			pattern = normalizeDN(group)
+		}
+		mappings = append(mappings, ldapGroupRole{pattern: pattern, byDN: byDN, roles: c.Roles})
+	}
+	return mappings, nil
+}
+
+// MapRoles 返回组 (DN 或 CN) 映射到的角色，按配置顺序去重
+func (p *LDAPProvider) MapRoles(groups []string) []string {
+	var roles []string
+	seen := make(map[string]bool)
+	for _, mapping := range p.groupRoles {
+		if !mapping.matchAny(groups) {
+			continue
+		}
+		for _, role := range mapping.roles {
+			if !seen[role] {
+				seen[role] = true
+				roles = append(roles, role)
+			}
+		}
+	}
+	return roles
+}
+
+func (m ldapGroupRole) matchAny(groups []string) bool {
+	for _, group := range groups {
+		value := strings.ToLower(groupCN(group))
+		if m.byDN {
+			value = normalizeDN(group)
+		}
+		if matchWildcard(m.pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeDN 规范化 DN 用于比较: 属性名与值转为小写，去掉分隔符两侧的空格
+func normalizeDN(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(dn))
+	}
+	rdns := make([]string, len(parsed.RDNs))
+	for i, rdn := range parsed.RDNs {
+		attrs := make([]string, len(rdn.Attributes))
+		for j, attr := range rdn.Attributes {
+			attrs[j] = strings.ToLower(attr.Type) + "=" + strings.ToLower(attr.Value)
+		}
+		rdns[i] = strings.Join(attrs, "+")
+	}
+	return strings.Join(rdns, ",")
+}
+
+// groupCN 返回组 DN 第一个 RDN 的值 (通常为 CN)，不是 DN 时原样返回
+func groupCN(group string) string {
+	parsed, err := ldap.ParseDN(group)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return group
+	}
+	return parsed.RDNs[0].Attributes[0].Value
+}
+
+// matchWildcard 匹配只含 "*" 通配符的模式 ("*" 可匹配任意字符，包括 ",")
+func matchWildcard(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(value, part)
+		if i < 0 {
+			return false
+		}
+		value = value[i+len(part):]
+	}
+	return strings.HasSuffix(value, parts[len(parts)-1])
+}
+
+// resolveGroups 返回用户所在组的 DN，按 NestedGroups 配置展开嵌套组
+func (p *LDAPProvider) resolveGroups(conn ldap.Client, userDN string) ([]string, error) {
+	switch p.nestedGroups() {
+	case LDAPNestedGroupsInChain:
+		filter := fmt.Sprintf("(member:%s:=%s)", ldapMatchingRuleInChain, ldap.EscapeFilter(userDN))
+		entries, err := p.searchGroupEntries(conn, filter)
+		if err != nil {
+			return nil, err
+		}
+		groups := make([]string, len(entries))
+		for i, entry := range entries {
+			groups[i] = entry.DN
+		}
+		return groups, nil
+	case LDAPNestedGroupsRecursive:
+		return p.resolveGroupsRecursive(conn, userDN)
+	default:
+		return p.directGroupDNs(conn, userDN)
+	}
+}
+
+// resolveGroupsRecursive 广度优先逐层查询组所在的组，已访问的组跳过 (防止循环嵌套)
+func (p *LDAPProvider) resolveGroupsRecursive(conn ldap.Client, userDN string) ([]string, error) {
+	depth := p.config.NestedGroupDepth
+	if depth <= 0 {
+		depth = defaultLDAPNestedGroupDepth
+	}
+
+	var groups []string
+	seen := map[string]bool{normalizeDN(userDN): true}
+	level := []string{userDN}
+	for i := 0; i < depth && len(level) > 0; i++ {
+		var next []string
+		for _, member := range level {
+			parents, err := p.directGroupDNs(conn, member)
+			if err != nil {
+				return nil, err
+			}
+			for _, group := range parents {
+				key := normalizeDN(group)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				groups = append(groups, group)
+				next = append(next, group)
+			}
+		}
+		level = next
+	}
+	return groups, nil
+}
+
+// directGroupDNs 返回直接包含成员的组 DN: 配置了 GroupFilter 时搜索组，否则读取成员条目的 memberOf 属性
+func (p *LDAPProvider) directGroupDNs(conn ldap.Client, memberDN string) ([]string, error) {
+	if p.config.GroupFilter != "" {
+		entries, err := p.searchGroupEntries(conn, strings.Replace(p.config.GroupFilter, "%s", ldap.EscapeFilter(memberDN), -1))
+		if err != nil {
+			return nil, err
+		}
+		groups := make([]string, len(entries))
+		for i, entry := range entries {
+			groups[i] = entry.DN
+		}
+		return groups, nil
+	}
+	return p.memberOf(conn, memberDN)
+}
+
+func (p *LDAPProvider) nestedGroups() string {
+	if p.config.NestedGroups == "" {
+		return LDAPNestedGroupsNone
+	}
+	return strings.ToLower(p.config.NestedGroups)
+}
+
+func (p *LDAPProvider) groupBaseDN() string {
+	if p.config.GroupBaseDN != "" {
+		return p.config.GroupBaseDN
+	}
+	return p.config.BaseDN
+}
+
+// resolvesGroups 是否需要在登录时解析组 (配置了角色映射或嵌套组展开)
+func (p *LDAPProvider) resolvesGroups() bool {
+	return len(p.groupRoles) > 0 || p.nestedGroups() != LDAPNestedGroupsNone
+}
+
+func validateLDAPNestedGroups(mode string) error {
+	switch strings.ToLower(mode) {
+	case "", LDAPNestedGroupsNone, LDAPNestedGroupsInChain, LDAPNestedGroupsRecursive:
+		return nil
+	}
+	return fmt.Errorf("unsupported nested group mode %q", mode)
+}
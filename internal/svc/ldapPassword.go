@@ -0,0 +1,145 @@
+package svc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	// LDAPServerTypeOpenLDAP 使用 RFC 3062 Password Modify 扩展操作修改密码 (OpenLDAP、389 DS 等)
+	LDAPServerTypeOpenLDAP = "openldap"
+	// LDAPServerTypeAD 通过修改 unicodePwd 属性修改密码 (Active Directory)
+	LDAPServerTypeAD = "ad"
+)
+
+var (
+	// ErrLDAPPasswordPolicy 新密码不满足目录的密码策略 (长度、复杂度、历史密码、最短使用期限等)
+	ErrLDAPPasswordPolicy = errors.New("password does not satisfy the directory password policy")
+	// ErrLDAPPasswordChangeRefused 目录拒绝修改密码 (权限不足或服务器不允许，如 AD 未使用加密连接)
+	ErrLDAPPasswordChangeRefused = errors.New("directory refused the password change")
+)
+
+// ChangePassword 用户自助修改目录中的密码: 以用户身份绑定并校验旧密码后修改
+func (p *LDAPProvider) ChangePassword(ctx context.Context, userDN, oldPassword, newPassword string) error {
+	// 绑定会改变连接的身份，使用独立的短连接
+	conn, err := p.connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(userDN, oldPassword); err != nil {
+		bindErr := ldapBindError(userDN, err)
+		if !p.isActiveDirectory() || !mustChangePassword(bindErr) {
+			return bindErr
+		}
+		// AD 拒绝密码已过期或必须修改密码的用户绑定。"更改密码" 权限默认授予所有人，
+		// 由管理员连接代为提交修改，AD 仍会校验旧密码
+		err = p.pool.Do(ctx, func(conn ldap.Client) error {
+			return conn.Modify(adChangePasswordRequest(userDN, oldPassword, newPassword))
+		})
+		if err != nil {
+			return ldapPasswordError(err)
+		}
+		logx.Infof("LDAP expired password changed for %s", userDN)
+		return nil
+	}
+
+	if p.isActiveDirectory() {
+		err = conn.Modify(adChangePasswordRequest(userDN, oldPassword, newPassword))
+	} else {
+		_, err = conn.PasswordModify(ldap.NewPasswordModifyRequest("", oldPassword, newPassword))
+	}
+	if err != nil {
+		return ldapPasswordError(err)
+	}
+
+	logx.Infof("LDAP password changed for %s", userDN)
+	return nil
+}
+
+// ResetPassword 管理员重置目录中的密码: 使用连接池中管理员绑定的连接，不需要旧密码
+func (p *LDAPProvider) ResetPassword(ctx context.Context, userDN, newPassword string) error {
+	err := p.pool.Do(ctx, func(conn ldap.Client) error {
+		if p.isActiveDirectory() {
+			req := ldap.NewModifyRequest(userDN, nil)
+			req.Replace("unicodePwd", []string{encodeADPassword(newPassword)})
+			return conn.Modify(req)
+		}
+		_, err := conn.PasswordModify(ldap.NewPasswordModifyRequest(userDN, "", newPassword))
+		return err
+	})
+	if err != nil {
+		return ldapPasswordError(err)
+	}
+
+	logx.Infof("LDAP password reset for %s", userDN)
+	return nil
+}
+
+// adChangePasswordRequest AD 的用户修改密码请求: 在同一个修改请求中删除旧值并添加新值，否则视为管理员重置
+func adChangePasswordRequest(userDN, oldPassword, newPassword string) *ldap.ModifyRequest {
+	req := ldap.NewModifyRequest(userDN, nil)
+	req.Delete("unicodePwd", []string{encodeADPassword(oldPassword)})
+	req.Add("unicodePwd", []string{encodeADPassword(newPassword)})
+	return req
+}
+
+func (p *LDAPProvider) isActiveDirectory() bool {
+	return strings.ToLower(p.config.ServerType) == LDAPServerTypeAD
+}
+
+// encodeADPassword 按 AD 要求编码 unicodePwd: 带双引号的 UTF-16LE 字符串
+func encodeADPassword(password string) string {
+	units := utf16.Encode([]rune(`"` + password + `"`))
+	buf := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(buf[i*2:], u)
+	}
+	return string(buf)
+}
+
+// ldapPasswordError 将目录返回的错误转换为密码相关的错误，保留原始错误以便读取服务器的诊断信息
+func ldapPasswordError(err error) error {
+	code, ok := ldapResultCode(err)
+	if !ok {
+		return fmt.Errorf("failed to change password: %w", err)
+	}
+	switch code {
+	case ldap.LDAPResultInvalidCredentials:
+		return ErrLDAPInvalidCredentials
+	case ldap.LDAPResultConstraintViolation:
+		// AD 在删除的 unicodePwd 旧值不正确时返回 00000056
+		if strings.Contains(LDAPDiagnosticMessage(err), "00000056") {
+			return fmt.Errorf("%w: %w", ErrLDAPInvalidCredentials, err)
+		}
+		return fmt.Errorf("%w: %w", ErrLDAPPasswordPolicy, err)
+	case ldap.LDAPResultInsufficientAccessRights, ldap.LDAPResultUnwillingToPerform:
+		return fmt.Errorf("%w: %w", ErrLDAPPasswordChangeRefused, err)
+	}
+	return fmt.Errorf("failed to change password: %w", err)
+}
+
+// LDAPDiagnosticMessage 返回 (可能被包装的) LDAP 错误中服务器给出的诊断信息
+func LDAPDiagnosticMessage(err error) string {
+	var ldapErr *ldap.Error
+	if !errors.As(err, &ldapErr) || ldapErr.Err == nil {
+		return ""
+	}
+	return ldapErr.Err.Error()
+}
+
+func validateLDAPServerType(serverType string) error {
+	switch strings.ToLower(serverType) {
+	case "", LDAPServerTypeOpenLDAP, LDAPServerTypeAD:
+		return nil
+	}
+	return fmt.Errorf("unsupported LDAP server type %q", serverType)
+}
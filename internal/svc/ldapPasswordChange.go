@@ -0,0 +1,80 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LDAPPasswordChangeTTL 修改密码令牌的有效期
+const LDAPPasswordChangeTTL = 10 * time.Minute
+
+// ErrInvalidPasswordChangeToken 修改密码令牌不存在、已过期或已被使用
+var ErrInvalidPasswordChangeToken = errors.New("invalid or expired password change token")
+
+// LDAPPasswordChange 目录要求修改密码 (密码已过期或管理员要求修改) 的登录。
+// 目录只在密码正确时返回这些原因，用户凭令牌修改密码后重新登录。
+type LDAPPasswordChange struct {
+	DN        string `json:"dn"`
+	Username  string `json:"username"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+func ldapPasswordChangeKey(token string) string {
+	return fmt.Sprintf("auth:sso:ldap:pwchange:%s", token)
+}
+
+// CreateLDAPPasswordChange 保存待修改密码的登录并返回修改密码令牌
+func CreateLDAPPasswordChange(ctx context.Context, rdb redis.UniversalClient, change *LDAPPasswordChange) (string, error) {
+	token, err := randomURLToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate password change token: %w", err)
+	}
+	change.CreatedAt = time.Now().Unix()
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode password change: %w", err)
+	}
+	if err := rdb.Set(ctx, ldapPasswordChangeKey(token), data, LDAPPasswordChangeTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store password change: %w", err)
+	}
+	return token, nil
+}
+
+// GetLDAPPasswordChange 获取待修改密码的登录
+func GetLDAPPasswordChange(ctx context.Context, rdb redis.UniversalClient, token string) (*LDAPPasswordChange, error) {
+	if token == "" {
+		return nil, ErrInvalidPasswordChangeToken
+	}
+
+	data, err := rdb.Get(ctx, ldapPasswordChangeKey(token)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidPasswordChangeToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get password change: %w", err)
+	}
+
+	var change LDAPPasswordChange
+	if err := json.Unmarshal(data, &change); err != nil {
+		return nil, ErrInvalidPasswordChangeToken
+	}
+	return &change, nil
+}
+
+// ConsumeLDAPPasswordChange 删除修改密码令牌，令牌已被使用或已过期时返回 ErrInvalidPasswordChangeToken
+func ConsumeLDAPPasswordChange(ctx context.Context, rdb redis.UniversalClient, token string) error {
+	n, err := rdb.Del(ctx, ldapPasswordChangeKey(token)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to delete password change: %w", err)
+	}
+	if n == 0 {
+		return ErrInvalidPasswordChangeToken
+	}
+	return nil
+}
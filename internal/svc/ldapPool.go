@@ -0,0 +1,330 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"auth-service/internal/config"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+var (
+	// ErrLDAPPoolClosed 连接池已关闭
+	ErrLDAPPoolClosed = errors.New("ldap: connection pool is closed")
+	// ErrLDAPPoolTimeout 连接数已满且在等待时间内没有空闲连接
+	ErrLDAPPoolTimeout = errors.New("ldap: timed out waiting for a pooled connection")
+)
+
+const (
+	defaultLDAPPoolMaxOpen             = 10
+	defaultLDAPPoolIdleTimeout         = 300 * time.Second
+	defaultLDAPPoolMaxLifetime         = 1800 * time.Second
+	defaultLDAPPoolHealthCheckInterval = 30 * time.Second
+	defaultLDAPPoolWaitTimeout         = 5 * time.Second
+)
+
+// LDAPPool 管理员绑定的 LDAP 连接池。
+// 池中的连接始终保持管理员绑定，只用于搜索；用户密码校验必须使用独立连接，否则会改变连接的绑定身份。
+type LDAPPool struct {
+	dial func() (ldap.Client, error)
+	bind func(ldap.Client) error
+
+	maxIdle             int
+	idleTimeout         time.Duration
+	maxLifetime         time.Duration
+	healthCheckInterval time.Duration
+	waitTimeout         time.Duration
+
+	sem    chan struct{} // 限制同时打开的连接数
+	stop   chan struct{}
+	mu     sync.Mutex
+	idle   []*ldapPoolConn
+	open   int
+	closed bool
+}
+
+type ldapPoolConn struct {
+	ldap.Client
+	createdAt time.Time
+	lastUsed  time.Time
+}
+
+// LDAPPoolStats 连接池状态
+type LDAPPoolStats struct {
+	Open int // 已打开的连接数 (含使用中)
+	Idle int // 空闲连接数
+}
+
+// NewLDAPPool 创建连接池，dial 建立新连接，bind 以管理员身份绑定 (新建连接与绑定丢失时调用)
+func NewLDAPPool(cfg config.LDAPPoolConfig, dial func() (ldap.Client, error), bind func(ldap.Client) error) *LDAPPool {
+	maxOpen := cfg.MaxOpen
+	if maxOpen <= 0 {
+		maxOpen = defaultLDAPPoolMaxOpen
+	}
+	maxIdle := cfg.MaxIdle
+	if maxIdle == 0 || maxIdle > maxOpen {
+		maxIdle = maxOpen
+	}
+	if maxIdle < 0 {
+		maxIdle = 0
+	}
+
+	p := &LDAPPool{
+		dial:                dial,
+		bind:                bind,
+		maxIdle:             maxIdle,
+		idleTimeout:         secondsOrDefault(cfg.IdleTimeout, defaultLDAPPoolIdleTimeout),
+		maxLifetime:         secondsOrDefault(cfg.MaxLifetime, defaultLDAPPoolMaxLifetime),
+		healthCheckInterval: secondsOrDefault(cfg.HealthCheckInterval, defaultLDAPPoolHealthCheckInterval),
+		waitTimeout:         secondsOrDefault(cfg.WaitTimeout, defaultLDAPPoolWaitTimeout),
+		sem:                 make(chan struct{}, maxOpen),
+		stop:                make(chan struct{}),
+	}
+	if maxIdle > 0 {
+		go p.reap()
+	}
+	return p
+}
+
+func secondsOrDefault(seconds int64, def time.Duration) time.Duration {
+	if seconds <= 0 {
+		return def
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Do 取出一个连接执行 fn。连接已被服务器断开或管理员绑定丢失时，重新连接或绑定后重试一次
+func (p *LDAPPool) Do(ctx context.Context, fn func(conn ldap.Client) error) error {
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var conn *ldapPoolConn
+		if conn, err = p.get(ctx); err != nil {
+			return err
+		}
+
+		err = fn(conn.Client)
+		if err == nil || !isLDAPConnFailure(conn, err) {
+			p.put(conn, false)
+			return err
+		}
+
+		// A lost bind (e.g. AD session expiry) is fixed by binding again, a dead connection is replaced
+		broken := conn.IsClosing() || !isLDAPBindLost(err) || p.bind(conn.Client) != nil
+		p.put(conn, broken)
+		logx.Infof("LDAP pooled connection failed (broken: %t), retrying: %v", broken, err)
+	}
+	return err
+}
+
+// Stats 返回连接池状态
+func (p *LDAPPool) Stats() LDAPPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return LDAPPoolStats{Open: p.open, Idle: len(p.idle)}
+}
+
+// Close 关闭连接池与所有空闲连接，使用中的连接在归还时关闭
+func (p *LDAPPool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	idle := p.idle
+	p.idle = nil
+	p.open -= len(idle)
+	p.mu.Unlock()
+
+	close(p.stop)
+	for _, conn := range idle {
+		conn.Close()
+	}
+}
+
+func (p *LDAPPool) get(ctx context.Context) (*ldapPoolConn, error) {
+	timer := time.NewTimer(p.waitTimeout)
+	defer timer.Stop()
+	select {
+	case p.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, ErrLDAPPoolTimeout
+	}
+
+	for {
+		conn, err := p.popIdle()
+		if err != nil {
+			<-p.sem
+			return nil, err
+		}
+		if conn == nil {
+			break
+		}
+		if p.healthy(conn) {
+			return conn, nil
+		}
+		p.discard(conn)
+	}
+
+	conn, err := p.connect()
+	if err != nil {
+		<-p.sem
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (p *LDAPPool) popIdle() (*ldapPoolConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil, ErrLDAPPoolClosed
+	}
+	n := len(p.idle)
+	if n == 0 {
+		return nil, nil
+	}
+	// Most recently used first, so surplus connections age out
+	conn := p.idle[n-1]
+	p.idle = p.idle[:n-1]
+	return conn, nil
+}
+
+func (p *LDAPPool) connect() (*ldapPoolConn, error) {
+	client, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.bind(client); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	p.open++
+	p.mu.Unlock()
+	return &ldapPoolConn{Client: client, createdAt: now, lastUsed: now}, nil
+}
+
+func (p *LDAPPool) put(conn *ldapPoolConn, broken bool) {
+	defer func() { <-p.sem }()
+
+	now := time.Now()
+	p.mu.Lock()
+	if broken || p.closed || len(p.idle) >= p.maxIdle || p.expired(conn, now) {
+		p.mu.Unlock()
+		p.discard(conn)
+		return
+	}
+	conn.lastUsed = now
+	p.idle = append(p.idle, conn)
+	p.mu.Unlock()
+}
+
+func (p *LDAPPool) discard(conn *ldapPoolConn) {
+	conn.Close()
+	p.mu.Lock()
+	p.open--
+	p.mu.Unlock()
+}
+
+// healthy 检查空闲连接是否可用，空闲较久的连接先探活 (防火墙或服务器可能已静默断开)
+func (p *LDAPPool) healthy(conn *ldapPoolConn) bool {
+	now := time.Now()
+	if conn.IsClosing() || p.expired(conn, now) {
+		return false
+	}
+	if now.Sub(conn.lastUsed) < p.healthCheckInterval {
+		return true
+	}
+	return ldapPing(conn.Client) == nil
+}
+
+func (p *LDAPPool) expired(conn *ldapPoolConn, now time.Time) bool {
+	return now.Sub(conn.lastUsed) > p.idleTimeout || now.Sub(conn.createdAt) > p.maxLifetime
+}
+
+// reap 定期关闭超时的空闲连接
+func (p *LDAPPool) reap() {
+	interval := p.idleTimeout / 2
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case now := <-ticker.C:
+			var expired []*ldapPoolConn
+			p.mu.Lock()
+			idle := p.idle[:0]
+			for _, conn := range p.idle {
+				if p.expired(conn, now) || conn.IsClosing() {
+					expired = append(expired, conn)
+				} else {
+					idle = append(idle, conn)
+				}
+			}
+			p.idle = idle
+			p.mu.Unlock()
+
+			for _, conn := range expired {
+				p.discard(conn)
+			}
+		}
+	}
+}
+
+// ldapPing 读取 Root DSE，用作开销最小的探活请求
+func ldapPing(conn ldap.Client) error {
+	_, err := conn.Search(ldap.NewSearchRequest(
+		"",
+		ldap.ScopeBaseObject,
+		ldap.NeverDerefAliases,
+		0,
+		5,
+		false,
+		"(objectClass=*)",
+		[]string{"1.1"}, // 不返回任何属性
+		nil,
+	))
+	return err
+}
+
+// ldapResultCode 返回 (可能被包装的) LDAP 错误的结果码
+func ldapResultCode(err error) (uint16, bool) {
+	var ldapErr *ldap.Error
+	if errors.As(err, &ldapErr) {
+		return ldapErr.ResultCode, true
+	}
+	return 0, false
+}
+
+// isLDAPConnFailure 错误是否由连接本身引起 (连接已断开或绑定丢失)，而不是请求本身的错误
+func isLDAPConnFailure(conn ldap.Client, err error) bool {
+	if conn.IsClosing() || isLDAPBindLost(err) {
+		return true
+	}
+	code, ok := ldapResultCode(err)
+	return ok && code == ldap.ErrorNetwork
+}
+
+// isLDAPBindLost 服务器是否丢失了连接的绑定: AD 要求先绑定时返回 Operations Error (000004DC)
+func isLDAPBindLost(err error) bool {
+	code, ok := ldapResultCode(err)
+	if !ok || code != ldap.LDAPResultOperationsError {
+		return false
+	}
+	return strings.Contains(err.Error(), "000004DC") || strings.Contains(err.Error(), "successful bind must be completed")
+}
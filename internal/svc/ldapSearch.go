@@ -0,0 +1,132 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// defaultLDAPPageSize 用户搜索的默认分页大小 (AD 的 MaxPageSize 默认为 1000)
+const defaultLDAPPageSize = 500
+
+// ErrLDAPSearchInterrupted 分页搜索中途连接断开。分页状态绑定在原连接上，
+// 换连接从头搜索会重复返回已遍历的结果，因此不重试
+var ErrLDAPSearchInterrupted = errors.New("ldap: connection lost during paged search")
+
+// LDAPSearchOptions 分页搜索用户的选项
+type LDAPSearchOptions struct {
+	Filter        string // 用户过滤器
+	PageSize      int    // 每页条目数 (默认为 LDAPConfig.PageSize)
+	ResolveGroups bool   // 按配置解析每个用户的 (嵌套) 组与角色
+}
+
+// IterUsers 使用 Simple Paged Results 控件 (RFC 2696) 分页搜索用户并逐个返回，不受服务器单次搜索条目数上限的限制。
+// 每页之前检查 ctx；提前结束遍历或 ctx 取消时通知服务器释放分页状态。
+// 遍历期间占用连接池中的一个连接；出错时返回一次错误后结束。
+func (p *LDAPProvider) IterUsers(ctx context.Context, opts LDAPSearchOptions) iter.Seq2[*LDAPUserInfo, error] {
+	return func(yield func(*LDAPUserInfo, error) bool) {
+		pageSize := opts.PageSize
+		if pageSize <= 0 {
+			pageSize = p.pageSize()
+		}
+
+		var (
+			yielded bool // 已返回过结果，连接断开后不能重试
+			stopped bool // 调用方已结束遍历
+			lastErr error
+		)
+		err := p.pool.Do(ctx, func(conn ldap.Client) error {
+			if yielded {
+				return fmt.Errorf("%w: %v", ErrLDAPSearchInterrupted, lastErr)
+			}
+			lastErr = p.searchPages(ctx, conn, opts, pageSize, func(user *LDAPUserInfo) bool {
+				yielded = true
+				stopped = !yield(user, nil)
+				return !stopped
+			})
+			return lastErr
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+// searchPages 逐页搜索，每个结果交给 fn 处理，fn 返回 false 时结束
+func (p *LDAPProvider) searchPages(ctx context.Context, conn ldap.Client, opts LDAPSearchOptions, pageSize int, fn func(*LDAPUserInfo) bool) error {
+	paging := ldap.NewControlPaging(uint32(pageSize))
+	req := ldap.NewSearchRequest(
+		p.config.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0,
+		30,
+		false,
+		opts.Filter,
+		p.searchAttributes(),
+		[]ldap.Control{paging},
+	)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			abandonPagedSearch(conn, req, paging)
+			return err
+		}
+
+		result, err := conn.Search(req)
+		if err != nil {
+			return fmt.Errorf("failed to search users: %w", err)
+		}
+		// 不支持分页的服务器不返回分页控件，一次返回全部结果
+		var cookie []byte
+		if control, ok := ldap.FindControl(result.Controls, ldap.ControlTypePaging).(*ldap.ControlPaging); ok {
+			cookie = control.Cookie
+		}
+		paging.SetCookie(cookie)
+
+		for _, entry := range result.Entries {
+			userInfo := p.entryToUserInfo(entry)
+			if opts.ResolveGroups && p.resolvesGroups() {
+				if err := ctx.Err(); err != nil {
+					abandonPagedSearch(conn, req, paging)
+					return err
+				}
+				groups, err := p.resolveGroups(conn, entry.DN)
+				if err != nil {
+					abandonPagedSearch(conn, req, paging)
+					return fmt.Errorf("failed to resolve groups of %s: %w", entry.DN, err)
+				}
+				userInfo.Groups = groups
+				userInfo.Roles = p.MapRoles(groups)
+			}
+			if !fn(userInfo) {
+				abandonPagedSearch(conn, req, paging)
+				return nil
+			}
+		}
+
+		if len(cookie) == 0 {
+			return nil
+		}
+	}
+}
+
+// abandonPagedSearch 以页大小 0 发送剩余的 cookie，通知服务器释放未遍历完的分页状态
+func abandonPagedSearch(conn ldap.Client, req *ldap.SearchRequest, paging *ldap.ControlPaging) {
+	if len(paging.Cookie) == 0 {
+		return
+	}
+	paging.PagingSize = 0
+	_, _ = conn.Search(req)
+	paging.SetCookie(nil)
+}
+
+func (p *LDAPProvider) pageSize() int {
+	if p.config.PageSize > 0 {
+		return p.config.PageSize
+	}
+	return defaultLDAPPageSize
+}
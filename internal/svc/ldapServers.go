@@ -0,0 +1,181 @@
+package svc
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"auth-service/internal/config"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	// LDAPSelectionPriority 按配置顺序使用服务器，失败时依次转移到下一台
+	LDAPSelectionPriority = "priority"
+	// LDAPSelectionRoundRobin 在健康的服务器间轮询
+	LDAPSelectionRoundRobin = "round_robin"
+
+	defaultLDAPFailoverCooldown   = 30 * time.Second
+	defaultLDAPSRVRefreshInterval = 300 * time.Second
+	ldapSRVLookupTimeout          = 5 * time.Second
+)
+
+// SRVResolver 查询 DNS SRV 记录，签名与 net.Resolver.LookupSRV 相同
+type SRVResolver func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+
+// LDAPServer LDAP 服务器地址
+type LDAPServer struct {
+	Host string
+	Port int
+}
+
+func (s LDAPServer) String() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+// LDAPServerSet 多台 LDAP 服务器的选择与故障转移。
+// 连接失败的服务器在冷却时间内排到最后 (所有服务器都失败时仍会尝试)，
+// 配置了 SRVDomain 时通过 DNS SRV 记录发现服务器 (如 AD 域控制器)，静态配置的服务器作为后备。
+type LDAPServerSet struct {
+	static      []LDAPServer
+	defaultPort int
+	forcePort   bool // LDAPS 时忽略 SRV 记录中的端口 (_ldap._tcp 记录的是 389)
+	roundRobin  bool
+	health      *serverHealth[LDAPServer]
+
+	srvDomain   string
+	srvRefresh  time.Duration
+	resolver    SRVResolver
+	srvServers  []LDAPServer
+	srvExpireAt time.Time
+
+	mu sync.Mutex // 保护 SRV 缓存
+}
+
+// NewLDAPServerSet 根据配置创建服务器集合，resolver 为空时使用系统 DNS
+func NewLDAPServerSet(cfg config.LDAPConfig, resolver SRVResolver) *LDAPServerSet {
+	defaultPort := cfg.Port
+	if defaultPort == 0 {
+		defaultPort = 389
+		if cfg.UseSSL {
+			defaultPort = 636
+		}
+	}
+	if resolver == nil {
+		resolver = net.DefaultResolver.LookupSRV
+	}
+
+	s := &LDAPServerSet{
+		defaultPort: defaultPort,
+		forcePort:   cfg.UseSSL,
+		roundRobin:  strings.EqualFold(cfg.ServerSelection, LDAPSelectionRoundRobin),
+		health:      newServerHealth[LDAPServer](secondsOrDefault(cfg.FailoverCooldown, defaultLDAPFailoverCooldown)),
+		srvDomain:   cfg.SRVDomain,
+		srvRefresh:  secondsOrDefault(cfg.SRVRefreshInterval, defaultLDAPSRVRefreshInterval),
+		resolver:    resolver,
+	}
+
+	addresses := cfg.Servers
+	if len(addresses) == 0 && cfg.Host != "" {
+		addresses = []string{net.JoinHostPort(cfg.Host, strconv.Itoa(defaultPort))}
+	}
+	for _, address := range addresses {
+		s.static = appendServer(s.static, parseLDAPServer(address, defaultPort))
+	}
+	return s
+}
+
+// parseLDAPServer 解析 host 或 host:port
+func parseLDAPServer(address string, defaultPort int) LDAPServer {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return LDAPServer{Host: strings.Trim(address, "[]"), Port: defaultPort}
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 {
+		port = defaultPort
+	}
+	return LDAPServer{Host: host, Port: port}
+}
+
+func appendServer(servers []LDAPServer, server LDAPServer) []LDAPServer {
+	for _, s := range servers {
+		if strings.EqualFold(s.Host, server.Host) && s.Port == server.Port {
+			return servers
+		}
+	}
+	return append(servers, server)
+}
+
+// Candidates 返回本次连接应依次尝试的服务器: 健康的服务器按选择策略排序，冷却中的服务器排在最后
+func (s *LDAPServerSet) Candidates() []LDAPServer {
+	servers := s.discovered()
+	for _, server := range s.static {
+		servers = appendServer(servers, server)
+	}
+
+	return s.health.order(servers, s.roundRobin)
+}
+
+// MarkFailed 标记服务器连接失败，冷却时间内优先尝试其它服务器
+func (s *LDAPServerSet) MarkFailed(server LDAPServer) {
+	s.health.markFailed(server)
+}
+
+// MarkHealthy 服务器连接成功，结束冷却
+func (s *LDAPServerSet) MarkHealthy(server LDAPServer) {
+	s.health.markHealthy(server)
+}
+
+// discovered 返回 SRV 记录发现的服务器，按刷新间隔缓存；查询失败时继续使用上一次的结果
+func (s *LDAPServerSet) discovered() []LDAPServer {
+	if s.srvDomain == "" {
+		return nil
+	}
+
+	s.mu.Lock()
+	if time.Now().Before(s.srvExpireAt) {
+		servers := append([]LDAPServer(nil), s.srvServers...)
+		s.mu.Unlock()
+		return servers
+	}
+	// Only one caller refreshes, the others keep using the cached list meanwhile
+	s.srvExpireAt = time.Now().Add(s.srvRefresh)
+	cached := append([]LDAPServer(nil), s.srvServers...)
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), ldapSRVLookupTimeout)
+	defer cancel()
+	_, records, err := s.resolver(ctx, "ldap", "tcp", s.srvDomain)
+	if err != nil {
+		logx.Errorf("Failed to look up LDAP SRV records for %s: %v", s.srvDomain, err)
+		return cached
+	}
+
+	// Lower priority values first; within a priority the resolver already randomized by weight
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Priority < records[j].Priority
+	})
+	var servers []LDAPServer
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		if host == "" {
+			continue
+		}
+		port := int(record.Port)
+		if s.forcePort || port == 0 {
+			port = s.defaultPort
+		}
+		servers = appendServer(servers, LDAPServer{Host: host, Port: port})
+	}
+
+	s.mu.Lock()
+	s.srvServers = servers
+	s.mu.Unlock()
+	return append([]LDAPServer(nil), servers...)
+}
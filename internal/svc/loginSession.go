@@ -0,0 +1,62 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SessionMethodEmail 以邮件登录链接建立的会话
+const SessionMethodEmail = "email"
+
+// LoginSession 不经过 IdP 的登录 (如邮件登录链接) 建立的本地会话，记录登录方式。
+// OIDC 登录的会话记录在 OIDCSession 中
+type LoginSession struct {
+	Method    string `json:"method"`  // 登录方式
+	UserID    uint64 `json:"user_id"` // 本地用户 ID
+	CreatedAt int64  `json:"created_at"`
+}
+
+func loginSessionKey(sessionID string) string {
+	return fmt.Sprintf("auth:session:%s", sessionID)
+}
+
+// SaveLoginSession 记录本地会话的登录方式，ttl 通常为刷新令牌的有效期
+func SaveLoginSession(ctx context.Context, rdb redis.UniversalClient, sessionID string, session *LoginSession, ttl time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode login session: %w", err)
+	}
+	if err := rdb.Set(ctx, loginSessionKey(sessionID), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save login session: %w", err)
+	}
+	return nil
+}
+
+// GetLoginSession 获取本地会话的登录记录，不存在时返回 nil
+func GetLoginSession(ctx context.Context, rdb redis.UniversalClient, sessionID string) (*LoginSession, error) {
+	data, err := rdb.Get(ctx, loginSessionKey(sessionID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login session: %w", err)
+	}
+
+	var session LoginSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to decode login session: %w", err)
+	}
+	return &session, nil
+}
+
+// DeleteLoginSession 删除本地会话的登录记录
+func DeleteLoginSession(ctx context.Context, rdb redis.UniversalClient, sessionID string) error {
+	if err := rdb.Del(ctx, loginSessionKey(sessionID)).Err(); err != nil {
+		return fmt.Errorf("failed to delete login session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package svc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// MagicLinkTTL 登录链接的有效期 (与登录邮件中的提示一致)
+	MagicLinkTTL = 15 * time.Minute
+	// MagicLinkResendInterval 同一邮箱两次发送登录链接的最小间隔
+	MagicLinkResendInterval = time.Minute
+	// MagicLinkHourlyLimit 同一邮箱 1 小时内最多发送的登录链接数
+	MagicLinkHourlyLimit = 5
+)
+
+var (
+	// ErrInvalidMagicLink 登录链接签名错误、不存在、已过期或已被使用
+	ErrInvalidMagicLink = errors.New("invalid or expired magic link")
+	// ErrMagicLinkDevice 登录链接绑定了申请它的设备，需在同一浏览器中打开
+	ErrMagicLinkDevice = errors.New("magic link was requested from another device")
+)
+
+// MagicLink 待使用的邮箱登录链接
+type MagicLink struct {
+	UserID     uint64 `json:"user_id"`
+	Email      string `json:"email"`                 // 签发时的邮箱，用户修改邮箱后链接失效
+	DeviceHash string `json:"device_hash,omitempty"` // 申请链接的设备标识的 SHA-256，为空时不绑定设备
+	CreatedAt  int64  `json:"created_at"`
+}
+
+// Redis 中只保存链接 ID 的哈希
+func magicLinkKey(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return fmt.Sprintf("auth:magiclink:%s", hex.EncodeToString(sum[:]))
+}
+
+func magicLinkCooldownKey(email string) string {
+	return fmt.Sprintf("auth:magiclink:cooldown:%s", targetHash(email))
+}
+
+func magicLinkHourlyKey(email string) string {
+	return fmt.Sprintf("auth:magiclink:hourly:%s", targetHash(email))
+}
+
+func hashDeviceID(deviceID string) string {
+	if deviceID == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(deviceID))
+	return hex.EncodeToString(sum[:])
+}
+
+// signMagicLink 以签名密钥派生的专用密钥对链接 ID 签名，伪造的链接无需访问 Redis 即被拒绝
+func signMagicLink(secret, id string) string {
+	mac := hmac.New(sha256.New, hmacSHA256([]byte(secret), "magic-link"))
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// AllowMagicLink 检查发送间隔与每小时发送上限
+func AllowMagicLink(ctx context.Context, rdb redis.UniversalClient, email string) (bool, error) {
+	ok, err := allowVerificationCode(ctx, rdb, magicLinkCooldownKey(email), MagicLinkResendInterval)
+	if err != nil || !ok {
+		return false, err
+	}
+	count, err := incrCounter(ctx, rdb, magicLinkHourlyKey(email), time.Hour)
+	if err != nil {
+		return false, err
+	}
+	return count <= MagicLinkHourlyLimit, nil
+}
+
+// CreateMagicLink 签发登录链接令牌 (链接 ID 与签名)。deviceID 非空时链接只能由同一设备使用
+func CreateMagicLink(ctx context.Context, rdb redis.UniversalClient, secret string, link *MagicLink, deviceID string) (string, error) {
+	id, err := randomURLToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate magic link: %w", err)
+	}
+
+	link.DeviceHash = hashDeviceID(deviceID)
+	link.CreatedAt = time.Now().Unix()
+	data, err := json.Marshal(link)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode magic link: %w", err)
+	}
+	if err := rdb.Set(ctx, magicLinkKey(id), data, MagicLinkTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store magic link: %w", err)
+	}
+	return id + "." + signMagicLink(secret, id), nil
+}
+
+// ConsumeMagicLink 校验签名与设备后删除登录链接 (只能使用一次)。
+// 设备不匹配时返回 ErrMagicLinkDevice，链接仍可在申请它的设备上使用
+func ConsumeMagicLink(ctx context.Context, rdb redis.UniversalClient, secret, token, deviceID string) (*MagicLink, error) {
+	id, signature, ok := strings.Cut(token, ".")
+	if !ok || id == "" || !hmac.Equal([]byte(signature), []byte(signMagicLink(secret, id))) {
+		return nil, ErrInvalidMagicLink
+	}
+
+	key := magicLinkKey(id)
+	data, err := rdb.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidMagicLink
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get magic link: %w", err)
+	}
+
+	var link MagicLink
+	if err := json.Unmarshal(data, &link); err != nil {
+		return nil, ErrInvalidMagicLink
+	}
+	if link.DeviceHash != "" && subtle.ConstantTimeCompare([]byte(link.DeviceHash), []byte(hashDeviceID(deviceID))) != 1 {
+		return nil, ErrMagicLinkDevice
+	}
+
+	// 并发使用同一链接时只有一个成功
+	n, err := rdb.Del(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete magic link: %w", err)
+	}
+	if n == 0 {
+		return nil, ErrInvalidMagicLink
+	}
+	return &link, nil
+}
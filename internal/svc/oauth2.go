@@ -0,0 +1,249 @@
+package svc
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/types"
+)
+
+// OAuth2Token 社交平台返回的令牌
+type OAuth2Token struct {
+	AccessToken  string            `json:"access_token"`
+	TokenType    string            `json:"token_type,omitempty"`
+	ExpiresIn    int64             `json:"expires_in,omitempty"`
+	RefreshToken string            `json:"refresh_token,omitempty"`
+	Scope        string            `json:"scope,omitempty"`
+	Extra        map[string]string `json:"extra,omitempty"` // 平台特有字段 (如微信 openid/unionid)
+}
+
+// OAuth2State OAuth2 状态信息 (用于防止 CSRF)
+type OAuth2State struct {
+	Provider    string `json:"provider"`
+	RedirectURL string `json:"redirect_url,omitempty"`
+	CreatedAt   int64  `json:"created_at"`
+	LinkUserID  uint64 `json:"link_user_id,omitempty"` // 关联流程: 回调时将身份关联到该用户而不是登录
+}
+
+// OAuth2Adapter 社交平台适配器，描述各平台的授权、令牌与用户信息接口
+type OAuth2Adapter interface {
+	// AuthorizationURL 构造授权跳转 URL
+	AuthorizationURL(cfg config.OAuth2Config, state string) string
+	// ExchangeCode 用授权码换取令牌
+	ExchangeCode(ctx context.Context, client *http.Client, cfg config.OAuth2Config, code string) (*OAuth2Token, error)
+	// UserInfo 获取用户资料并映射为统一格式
+	UserInfo(ctx context.Context, client *http.Client, cfg config.OAuth2Config, token *OAuth2Token) (*types.SSOUserInfo, error)
+}
+
+var (
+	oauth2AdaptersMu sync.RWMutex
+	oauth2Adapters   = map[string]OAuth2Adapter{}
+)
+
+// RegisterOAuth2Adapter 注册 OAuth2 适配器，Type 相同时覆盖已有适配器
+func RegisterOAuth2Adapter(typ string, adapter OAuth2Adapter) {
+	oauth2AdaptersMu.Lock()
+	defer oauth2AdaptersMu.Unlock()
+	oauth2Adapters[typ] = adapter
+}
+
+// OAuth2AdapterTypes 返回已注册的适配器类型
+func OAuth2AdapterTypes() []string {
+	oauth2AdaptersMu.RLock()
+	defer oauth2AdaptersMu.RUnlock()
+
+	typs := make([]string, 0, len(oauth2Adapters))
+	for typ := range oauth2Adapters {
+		typs = append(typs, typ)
+	}
+	sort.Strings(typs)
+	return typs
+}
+
+func getOAuth2Adapter(typ string) (OAuth2Adapter, bool) {
+	oauth2AdaptersMu.RLock()
+	defer oauth2AdaptersMu.RUnlock()
+	adapter, ok := oauth2Adapters[typ]
+	return adapter, ok
+}
+
+// OAuth2Provider 社交 OAuth2 登录提供者
+type OAuth2Provider struct {
+	config     config.OAuth2Config
+	adapter    OAuth2Adapter
+	httpClient *http.Client
+}
+
+// NewOAuth2Provider 创建 OAuth2 提供者
+func NewOAuth2Provider(cfg config.OAuth2Config) (*OAuth2Provider, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	adapter, ok := getOAuth2Adapter(cfg.Type)
+	if !ok {
+		return nil, fmt.Errorf("unsupported OAuth2 provider type: %s", cfg.Type)
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		},
+	}
+
+	return &OAuth2Provider{
+		config:  cfg,
+		adapter: adapter,
+		httpClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: transport,
+		},
+	}, nil
+}
+
+// GetAuthorizationURL 获取授权 URL
+func (p *OAuth2Provider) GetAuthorizationURL(state string) string {
+	return p.adapter.AuthorizationURL(p.config, state)
+}
+
+// ExchangeCode 用授权码交换令牌
+func (p *OAuth2Provider) ExchangeCode(ctx context.Context, code string) (*OAuth2Token, error) {
+	return p.adapter.ExchangeCode(ctx, p.httpClient, p.config, code)
+}
+
+// GetUserInfo 获取用户信息
+func (p *OAuth2Provider) GetUserInfo(ctx context.Context, token *OAuth2Token) (*types.SSOUserInfo, error) {
+	userInfo, err := p.adapter.UserInfo(ctx, p.httpClient, p.config, token)
+	if err != nil {
+		return nil, err
+	}
+	if userInfo.ProviderUserID == "" {
+		return nil, fmt.Errorf("%s user info has no user id", p.config.Type)
+	}
+	userInfo.Provider = p.config.Name
+	return userInfo, nil
+}
+
+// IsEnabled 检查是否启用
+func (p *OAuth2Provider) IsEnabled() bool {
+	return p != nil && p.config.Enabled
+}
+
+// OAuth2ProviderEntry 已注册的 OAuth2 提供者
+type OAuth2ProviderEntry struct {
+	Name        string // 提供者名称 (路由参数)
+	Type        string // 适配器类型
+	DisplayName string // 登录页显示名称
+	Icon        string // 登录页图标 URL
+	Client      OAuth2Client
+	TrustEmail  bool // 已验证邮箱与已有账号相同时直接关联
+
+	Provisioning *ProvisioningPolicy // 首次登录时自动开通账号的策略 (为空时允许所有用户开通)
+}
+
+// OAuth2Providers 按名称管理多个 OAuth2 提供者，保持注册顺序
+type OAuth2Providers struct {
+	entries []*OAuth2ProviderEntry
+	byName  map[string]*OAuth2ProviderEntry
+}
+
+// NewOAuth2Providers 创建 OAuth2 提供者集合
+func NewOAuth2Providers(entries ...OAuth2ProviderEntry) *OAuth2Providers {
+	providers := &OAuth2Providers{
+		byName: make(map[string]*OAuth2ProviderEntry),
+	}
+	for _, entry := range entries {
+		providers.Register(entry)
+	}
+	return providers
+}
+
+// Register 注册提供者，名称为空或已存在时返回 false
+func (p *OAuth2Providers) Register(entry OAuth2ProviderEntry) bool {
+	if entry.Name == "" {
+		entry.Name = entry.Type
+	}
+	if entry.Name == "" {
+		return false
+	}
+	if _, exists := p.byName[entry.Name]; exists {
+		return false
+	}
+
+	p.entries = append(p.entries, &entry)
+	p.byName[entry.Name] = &entry
+	return true
+}
+
+// Get 按名称获取已启用的提供者
+func (p *OAuth2Providers) Get(name string) (OAuth2Client, bool) {
+	if p == nil {
+		return nil, false
+	}
+
+	entry, ok := p.byName[name]
+	if !ok || entry.Client == nil || !entry.Client.IsEnabled() {
+		return nil, false
+	}
+	return entry.Client, true
+}
+
+// TrustEmail 提供者断言的已验证邮箱是否可用于直接关联已有账号
+func (p *OAuth2Providers) TrustEmail(name string) bool {
+	if p == nil {
+		return false
+	}
+	entry, ok := p.byName[name]
+	return ok && entry.TrustEmail
+}
+
+// Provisioning 返回提供者的自动开通策略
+func (p *OAuth2Providers) Provisioning(name string) *ProvisioningPolicy {
+	if p == nil {
+		return nil
+	}
+	if entry, ok := p.byName[name]; ok {
+		return entry.Provisioning
+	}
+	return nil
+}
+
+// List 返回所有已注册的提供者
+func (p *OAuth2Providers) List() []*OAuth2ProviderEntry {
+	if p == nil {
+		return nil
+	}
+	return p.entries
+}
+
+// doOAuth2JSON 发送请求并将 JSON 响应解码到 out
+func doOAuth2JSON(client *http.Client, req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to %s failed: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response from %s: %w", req.URL.Host, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d: %s", req.URL.Path, resp.StatusCode, string(body))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode response from %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
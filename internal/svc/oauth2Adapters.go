@@ -0,0 +1,509 @@
+package svc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"auth-service/internal/config"
+	"auth-service/internal/types"
+)
+
+// 内置的社交平台适配器类型
+const (
+	OAuth2TypeGitHub   = "github"
+	OAuth2TypeGitLab   = "gitlab"
+	OAuth2TypeWeChat   = "wechat"
+	OAuth2TypeDingTalk = "dingtalk"
+	OAuth2TypeFeishu   = "feishu"
+)
+
+func init() {
+	RegisterOAuth2Adapter(OAuth2TypeGitHub, &githubAdapter{})
+	RegisterOAuth2Adapter(OAuth2TypeGitLab, &gitlabAdapter{})
+	RegisterOAuth2Adapter(OAuth2TypeWeChat, &wechatAdapter{})
+	RegisterOAuth2Adapter(OAuth2TypeDingTalk, &dingtalkAdapter{})
+	RegisterOAuth2Adapter(OAuth2TypeFeishu, &feishuAdapter{})
+}
+
+// oauth2Endpoint 优先使用配置中的端点覆盖
+func oauth2Endpoint(override, def string) string {
+	if override != "" {
+		return override
+	}
+	return def
+}
+
+// oauth2Scopes 返回配置的 scopes，未配置时使用平台默认值
+func oauth2Scopes(cfg config.OAuth2Config, sep string, def ...string) string {
+	if len(cfg.Scopes) > 0 {
+		return strings.Join(cfg.Scopes, sep)
+	}
+	return strings.Join(def, sep)
+}
+
+// postOAuth2Form 以表单方式请求令牌端点
+func postOAuth2Form(ctx context.Context, client *http.Client, endpoint string, data url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(data.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doOAuth2JSON(client, req, out)
+}
+
+// postOAuth2JSON 以 JSON 方式请求令牌端点
+func postOAuth2JSON(ctx context.Context, client *http.Client, endpoint string, body interface{}, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode token request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doOAuth2JSON(client, req, out)
+}
+
+// getOAuth2JSON 携带访问令牌请求用户信息端点
+func getOAuth2JSON(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create userinfo request: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return doOAuth2JSON(client, req, out)
+}
+
+// standardTokenResponse RFC 6749 令牌响应 (GitHub 出错时仍返回 200)
+type standardTokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	Scope            string `json:"scope"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func (r *standardTokenResponse) token() (*OAuth2Token, error) {
+	if r.Error != "" {
+		return nil, fmt.Errorf("token endpoint error: %s - %s", r.Error, r.ErrorDescription)
+	}
+	if r.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint returned no access_token")
+	}
+	return &OAuth2Token{
+		AccessToken:  r.AccessToken,
+		TokenType:    r.TokenType,
+		ExpiresIn:    r.ExpiresIn,
+		RefreshToken: r.RefreshToken,
+		Scope:        r.Scope,
+	}, nil
+}
+
+// ===================== GitHub =====================
+
+type githubAdapter struct{}
+
+func (a *githubAdapter) urls(cfg config.OAuth2Config) (authURL, tokenURL, userURL string) {
+	// BaseURL 用于 GitHub Enterprise Server
+	if cfg.BaseURL != "" {
+		base := strings.TrimSuffix(cfg.BaseURL, "/")
+		authURL, tokenURL, userURL = base+"/login/oauth/authorize", base+"/login/oauth/access_token", base+"/api/v3/user"
+	} else {
+		authURL, tokenURL, userURL = "https://github.com/login/oauth/authorize", "https://github.com/login/oauth/access_token", "https://api.github.com/user"
+	}
+	return oauth2Endpoint(cfg.AuthURL, authURL), oauth2Endpoint(cfg.TokenURL, tokenURL), oauth2Endpoint(cfg.UserInfoURL, userURL)
+}
+
+func (a *githubAdapter) AuthorizationURL(cfg config.OAuth2Config, state string) string {
+	authURL, _, _ := a.urls(cfg)
+	params := url.Values{}
+	params.Set("client_id", cfg.ClientID)
+	params.Set("redirect_uri", cfg.RedirectURL)
+	params.Set("scope", oauth2Scopes(cfg, " ", "read:user", "user:email"))
+	params.Set("state", state)
+	return authURL + "?" + params.Encode()
+}
+
+func (a *githubAdapter) ExchangeCode(ctx context.Context, client *http.Client, cfg config.OAuth2Config, code string) (*OAuth2Token, error) {
+	_, tokenURL, _ := a.urls(cfg)
+	data := url.Values{}
+	data.Set("client_id", cfg.ClientID)
+	data.Set("client_secret", cfg.ClientSecret)
+	data.Set("code", code)
+	data.Set("redirect_uri", cfg.RedirectURL)
+
+	var resp standardTokenResponse
+	if err := postOAuth2Form(ctx, client, tokenURL, data, &resp); err != nil {
+		return nil, err
+	}
+	return resp.token()
+}
+
+func (a *githubAdapter) UserInfo(ctx context.Context, client *http.Client, cfg config.OAuth2Config, token *OAuth2Token) (*types.SSOUserInfo, error) {
+	_, _, userURL := a.urls(cfg)
+	headers := map[string]string{"Authorization": "Bearer " + token.AccessToken}
+
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		Email     string `json:"email"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := getOAuth2JSON(ctx, client, userURL, headers, &user); err != nil {
+		return nil, err
+	}
+
+	info := &types.SSOUserInfo{
+		ProviderUserID: strconv.FormatInt(user.ID, 10),
+		Username:       user.Login,
+		DisplayName:    user.Name,
+		Email:          user.Email,
+		Picture:        user.AvatarURL,
+	}
+
+	// 公开资料中的邮箱未必经过验证，以 /user/emails 中的主邮箱为准 (需要 user:email scope)
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getOAuth2JSON(ctx, client, userURL+"/emails", headers, &emails); err == nil {
+		for _, e := range emails {
+			if e.Primary {
+				info.Email = e.Email
+				info.EmailVerified = e.Verified
+				break
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// ===================== GitLab =====================
+
+type gitlabAdapter struct{}
+
+func (a *gitlabAdapter) urls(cfg config.OAuth2Config) (authURL, tokenURL, userURL string) {
+	base := strings.TrimSuffix(oauth2Endpoint(cfg.BaseURL, "https://gitlab.com"), "/")
+	return oauth2Endpoint(cfg.AuthURL, base+"/oauth/authorize"),
+		oauth2Endpoint(cfg.TokenURL, base+"/oauth/token"),
+		oauth2Endpoint(cfg.UserInfoURL, base+"/api/v4/user")
+}
+
+func (a *gitlabAdapter) AuthorizationURL(cfg config.OAuth2Config, state string) string {
+	authURL, _, _ := a.urls(cfg)
+	params := url.Values{}
+	params.Set("client_id", cfg.ClientID)
+	params.Set("redirect_uri", cfg.RedirectURL)
+	params.Set("response_type", "code")
+	params.Set("scope", oauth2Scopes(cfg, " ", "read_user"))
+	params.Set("state", state)
+	return authURL + "?" + params.Encode()
+}
+
+func (a *gitlabAdapter) ExchangeCode(ctx context.Context, client *http.Client, cfg config.OAuth2Config, code string) (*OAuth2Token, error) {
+	_, tokenURL, _ := a.urls(cfg)
+	data := url.Values{}
+	data.Set("grant_type", "authorization_code")
+	data.Set("client_id", cfg.ClientID)
+	data.Set("client_secret", cfg.ClientSecret)
+	data.Set("code", code)
+	data.Set("redirect_uri", cfg.RedirectURL)
+
+	var resp standardTokenResponse
+	if err := postOAuth2Form(ctx, client, tokenURL, data, &resp); err != nil {
+		return nil, err
+	}
+	return resp.token()
+}
+
+func (a *gitlabAdapter) UserInfo(ctx context.Context, client *http.Client, cfg config.OAuth2Config, token *OAuth2Token) (*types.SSOUserInfo, error) {
+	_, _, userURL := a.urls(cfg)
+
+	var user struct {
+		ID          int64  `json:"id"`
+		Username    string `json:"username"`
+		Name        string `json:"name"`
+		Email       string `json:"email"`
+		AvatarURL   string `json:"avatar_url"`
+		ConfirmedAt string `json:"confirmed_at"`
+	}
+	if err := getOAuth2JSON(ctx, client, userURL, map[string]string{"Authorization": "Bearer " + token.AccessToken}, &user); err != nil {
+		return nil, err
+	}
+
+	return &types.SSOUserInfo{
+		ProviderUserID: strconv.FormatInt(user.ID, 10),
+		Username:       user.Username,
+		DisplayName:    user.Name,
+		Email:          user.Email,
+		EmailVerified:  user.Email != "" && user.ConfirmedAt != "",
+		Picture:        user.AvatarURL,
+	}, nil
+}
+
+// ===================== 微信 (网站应用扫码登录) =====================
+
+type wechatAdapter struct{}
+
+// wechatError 微信接口错误 (HTTP 状态码始终为 200)
+type wechatError struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func (e wechatError) err() error {
+	if e.ErrCode != 0 {
+		return fmt.Errorf("wechat error %d: %s", e.ErrCode, e.ErrMsg)
+	}
+	return nil
+}
+
+func (a *wechatAdapter) AuthorizationURL(cfg config.OAuth2Config, state string) string {
+	params := url.Values{}
+	params.Set("appid", cfg.ClientID)
+	params.Set("redirect_uri", cfg.RedirectURL)
+	params.Set("response_type", "code")
+	params.Set("scope", oauth2Scopes(cfg, ",", "snsapi_login"))
+	params.Set("state", state)
+	return oauth2Endpoint(cfg.AuthURL, "https://open.weixin.qq.com/connect/qrconnect") + "?" + params.Encode() + "#wechat_redirect"
+}
+
+func (a *wechatAdapter) ExchangeCode(ctx context.Context, client *http.Client, cfg config.OAuth2Config, code string) (*OAuth2Token, error) {
+	params := url.Values{}
+	params.Set("appid", cfg.ClientID)
+	params.Set("secret", cfg.ClientSecret)
+	params.Set("code", code)
+	params.Set("grant_type", "authorization_code")
+	endpoint := oauth2Endpoint(cfg.TokenURL, "https://api.weixin.qq.com/sns/oauth2/access_token") + "?" + params.Encode()
+
+	var resp struct {
+		wechatError
+		AccessToken  string `json:"access_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		OpenID       string `json:"openid"`
+		Scope        string `json:"scope"`
+		UnionID      string `json:"unionid"`
+	}
+	if err := getOAuth2JSON(ctx, client, endpoint, nil, &resp); err != nil {
+		return nil, err
+	}
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+
+	return &OAuth2Token{
+		AccessToken:  resp.AccessToken,
+		ExpiresIn:    resp.ExpiresIn,
+		RefreshToken: resp.RefreshToken,
+		Scope:        resp.Scope,
+		Extra:        map[string]string{"openid": resp.OpenID, "unionid": resp.UnionID},
+	}, nil
+}
+
+func (a *wechatAdapter) UserInfo(ctx context.Context, client *http.Client, cfg config.OAuth2Config, token *OAuth2Token) (*types.SSOUserInfo, error) {
+	params := url.Values{}
+	params.Set("access_token", token.AccessToken)
+	params.Set("openid", token.Extra["openid"])
+	endpoint := oauth2Endpoint(cfg.UserInfoURL, "https://api.weixin.qq.com/sns/userinfo") + "?" + params.Encode()
+
+	var user struct {
+		wechatError
+		OpenID     string `json:"openid"`
+		Nickname   string `json:"nickname"`
+		HeadImgURL string `json:"headimgurl"`
+		UnionID    string `json:"unionid"`
+	}
+	if err := getOAuth2JSON(ctx, client, endpoint, nil, &user); err != nil {
+		return nil, err
+	}
+	if err := user.err(); err != nil {
+		return nil, err
+	}
+
+	// 同一开放平台下的多个应用共享 unionid，优先使用
+	providerUserID := user.UnionID
+	if providerUserID == "" {
+		providerUserID = user.OpenID
+	}
+
+	return &types.SSOUserInfo{
+		ProviderUserID: providerUserID,
+		DisplayName:    user.Nickname,
+		Picture:        user.HeadImgURL,
+		Attributes:     map[string]string{"openid": user.OpenID, "unionid": user.UnionID},
+	}, nil
+}
+
+// ===================== 钉钉 =====================
+
+type dingtalkAdapter struct{}
+
+func (a *dingtalkAdapter) AuthorizationURL(cfg config.OAuth2Config, state string) string {
+	params := url.Values{}
+	params.Set("client_id", cfg.ClientID)
+	params.Set("redirect_uri", cfg.RedirectURL)
+	params.Set("response_type", "code")
+	params.Set("scope", oauth2Scopes(cfg, " ", "openid"))
+	params.Set("state", state)
+	params.Set("prompt", "consent")
+	return oauth2Endpoint(cfg.AuthURL, "https://login.dingtalk.com/oauth2/auth") + "?" + params.Encode()
+}
+
+func (a *dingtalkAdapter) ExchangeCode(ctx context.Context, client *http.Client, cfg config.OAuth2Config, code string) (*OAuth2Token, error) {
+	body := map[string]string{
+		"clientId":     cfg.ClientID,
+		"clientSecret": cfg.ClientSecret,
+		"code":         code,
+		"grantType":    "authorization_code",
+	}
+
+	var resp struct {
+		AccessToken  string `json:"accessToken"`
+		RefreshToken string `json:"refreshToken"`
+		ExpireIn     int64  `json:"expireIn"`
+		CorpID       string `json:"corpId"`
+	}
+	if err := postOAuth2JSON(ctx, client, oauth2Endpoint(cfg.TokenURL, "https://api.dingtalk.com/v1.0/oauth2/userAccessToken"), body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.AccessToken == "" {
+		return nil, fmt.Errorf("dingtalk returned no accessToken")
+	}
+
+	return &OAuth2Token{
+		AccessToken:  resp.AccessToken,
+		ExpiresIn:    resp.ExpireIn,
+		RefreshToken: resp.RefreshToken,
+		Extra:        map[string]string{"corpId": resp.CorpID},
+	}, nil
+}
+
+func (a *dingtalkAdapter) UserInfo(ctx context.Context, client *http.Client, cfg config.OAuth2Config, token *OAuth2Token) (*types.SSOUserInfo, error) {
+	var user struct {
+		Nick      string `json:"nick"`
+		AvatarURL string `json:"avatarUrl"`
+		Mobile    string `json:"mobile"`
+		OpenID    string `json:"openId"`
+		UnionID   string `json:"unionId"`
+		Email     string `json:"email"`
+	}
+	headers := map[string]string{"x-acs-dingtalk-access-token": token.AccessToken}
+	if err := getOAuth2JSON(ctx, client, oauth2Endpoint(cfg.UserInfoURL, "https://api.dingtalk.com/v1.0/contact/users/me"), headers, &user); err != nil {
+		return nil, err
+	}
+
+	providerUserID := user.UnionID
+	if providerUserID == "" {
+		providerUserID = user.OpenID
+	}
+
+	return &types.SSOUserInfo{
+		ProviderUserID: providerUserID,
+		DisplayName:    user.Nick,
+		Email:          user.Email,
+		Picture:        user.AvatarURL,
+		Attributes:     map[string]string{"openId": user.OpenID, "unionId": user.UnionID, "phone": user.Mobile},
+	}, nil
+}
+
+// ===================== 飞书 =====================
+
+type feishuAdapter struct{}
+
+func (a *feishuAdapter) AuthorizationURL(cfg config.OAuth2Config, state string) string {
+	params := url.Values{}
+	params.Set("client_id", cfg.ClientID)
+	params.Set("redirect_uri", cfg.RedirectURL)
+	params.Set("response_type", "code")
+	if scope := oauth2Scopes(cfg, " "); scope != "" {
+		params.Set("scope", scope)
+	}
+	params.Set("state", state)
+	return oauth2Endpoint(cfg.AuthURL, "https://accounts.feishu.cn/open-apis/authen/v1/authorize") + "?" + params.Encode()
+}
+
+func (a *feishuAdapter) ExchangeCode(ctx context.Context, client *http.Client, cfg config.OAuth2Config, code string) (*OAuth2Token, error) {
+	body := map[string]string{
+		"grant_type":    "authorization_code",
+		"client_id":     cfg.ClientID,
+		"client_secret": cfg.ClientSecret,
+		"code":          code,
+		"redirect_uri":  cfg.RedirectURL,
+	}
+
+	var resp struct {
+		standardTokenResponse
+		Code int `json:"code"`
+	}
+	if err := postOAuth2JSON(ctx, client, oauth2Endpoint(cfg.TokenURL, "https://open.feishu.cn/open-apis/authen/v2/oauth/token"), body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 {
+		return nil, fmt.Errorf("feishu error %d: %s", resp.Code, resp.ErrorDescription)
+	}
+	return resp.token()
+}
+
+func (a *feishuAdapter) UserInfo(ctx context.Context, client *http.Client, cfg config.OAuth2Config, token *OAuth2Token) (*types.SSOUserInfo, error) {
+	var resp struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+		Data struct {
+			Name            string `json:"name"`
+			EnName          string `json:"en_name"`
+			AvatarURL       string `json:"avatar_url"`
+			OpenID          string `json:"open_id"`
+			UnionID         string `json:"union_id"`
+			UserID          string `json:"user_id"`
+			Email           string `json:"email"`
+			EnterpriseEmail string `json:"enterprise_email"`
+			Mobile          string `json:"mobile"`
+		} `json:"data"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + token.AccessToken}
+	if err := getOAuth2JSON(ctx, client, oauth2Endpoint(cfg.UserInfoURL, "https://open.feishu.cn/open-apis/authen/v1/user_info"), headers, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 {
+		return nil, fmt.Errorf("feishu error %d: %s", resp.Code, resp.Msg)
+	}
+
+	user := resp.Data
+	providerUserID := user.UnionID
+	if providerUserID == "" {
+		providerUserID = user.OpenID
+	}
+	email := user.EnterpriseEmail
+	if email == "" {
+		email = user.Email
+	}
+
+	return &types.SSOUserInfo{
+		ProviderUserID: providerUserID,
+		DisplayName:    user.Name,
+		Email:          email,
+		Picture:        user.AvatarURL,
+		Attributes: map[string]string{
+			"openId":  user.OpenID,
+			"unionId": user.UnionID,
+			"userId":  user.UserID,
+			"enName":  user.EnName,
+			"phone":   user.Mobile,
+		},
+	}, nil
+}
@@ -2,25 +2,50 @@ package svc
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"auth-service/internal/config"
 
+	"github.com/golang-jwt/jwt"
 	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/syncx"
 )
 
+// idTokenClockSkew 校验 ID Token 时间声明时允许的时钟偏差
+const idTokenClockSkew = 60 * time.Second
+
+// backChannelLogoutEvent 后端登出令牌 events 声明中必须包含的事件类型
+const backChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
 // OIDCProvider OpenID Connect 提供者
 type OIDCProvider struct {
 	config     config.OIDCConfig
-	discovery  *OIDCDiscovery
 	httpClient *http.Client
+	flight     syncx.SingleFlight // 合并并发的发现文档拉取
+
+	mu          sync.RWMutex
+	discovery   *OIDCDiscovery
+	jwks        *JWKSCache
+	lastRefresh time.Time
+	lastAttempt time.Time
+	lastErr     error
+	failures    int
+	nextRefresh time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 // OIDCDiscovery OIDC 发现文档
@@ -33,6 +58,8 @@ type OIDCDiscovery struct {
 	ScopesSupported        []string `json:"scopes_supported"`
 	ResponseTypesSupported []string `json:"response_types_supported"`
 	EndSessionEndpoint     string   `json:"end_session_endpoint,omitempty"`
+
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
 }
 
 // OIDCTokenResponse OIDC Token 响应
@@ -56,20 +83,154 @@ type OIDCUserInfo struct {
 	EmailVerified     bool   `json:"email_verified,omitempty"`
 	Picture           string `json:"picture,omitempty"`
 	Locale            string `json:"locale,omitempty"`
+
+	// Claims UserInfo 响应中的全部声明 (含自定义声明)，用于声明映射
+	Claims map[string]interface{} `json:"-"`
+}
+
+// ClaimSet 返回 UserInfo 的全部声明
+func (u *OIDCUserInfo) ClaimSet() map[string]interface{} {
+	return claimsOf(u, u.Claims)
+}
+
+// OIDCIDToken OIDC ID Token 声明
+type OIDCIDToken struct {
+	Issuer            string       `json:"iss"`
+	Subject           string       `json:"sub"`
+	Audience          OIDCAudience `json:"aud"`
+	ExpiresAt         int64        `json:"exp"`
+	IssuedAt          int64        `json:"iat"`
+	NotBefore         int64        `json:"nbf,omitempty"`
+	AuthorizedParty   string       `json:"azp,omitempty"`
+	Nonce             string       `json:"nonce,omitempty"`
+	SessionID         string       `json:"sid,omitempty"`
+	Name              string       `json:"name,omitempty"`
+	PreferredUsername string       `json:"preferred_username,omitempty"`
+	Email             string       `json:"email,omitempty"`
+	EmailVerified     bool         `json:"email_verified,omitempty"`
+
+	// Claims ID Token 中的全部声明 (含自定义声明)，用于声明映射
+	Claims map[string]interface{} `json:"-"`
+}
+
+// ClaimSet 返回 ID Token 的全部声明
+func (t *OIDCIDToken) ClaimSet() map[string]interface{} {
+	return claimsOf(t, t.Claims)
+}
+
+// OIDCAudience aud 声明，兼容字符串和字符串数组两种格式
+type OIDCAudience []string
+
+// UnmarshalJSON 解析 aud 声明
+func (a *OIDCAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = OIDCAudience{single}
+		return nil
+	}
+	var multi []string
+	if err := json.Unmarshal(data, &multi); err != nil {
+		return fmt.Errorf("invalid aud claim: %w", err)
+	}
+	*a = multi
+	return nil
+}
+
+// Contains 检查 aud 是否包含指定的 client_id
+func (a OIDCAudience) Contains(clientID string) bool {
+	for _, aud := range a {
+		if aud == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// Valid 校验 ID Token 的时间声明 (实现 jwt.Claims 接口)
+func (t *OIDCIDToken) Valid() error {
+	now := time.Now()
+	skew := int64(idTokenClockSkew / time.Second)
+
+	if t.ExpiresAt == 0 {
+		return errors.New("id_token is missing exp claim")
+	}
+	if now.Unix() > t.ExpiresAt+skew {
+		return errors.New("id_token is expired")
+	}
+	if t.IssuedAt != 0 && now.Unix() < t.IssuedAt-skew {
+		return errors.New("id_token used before issued")
+	}
+	if t.NotBefore != 0 && now.Unix() < t.NotBefore-skew {
+		return errors.New("id_token is not valid yet")
+	}
+	return nil
+}
+
+// OIDCLogoutToken OIDC 后端登出令牌声明 (OpenID Connect Back-Channel Logout 1.0)
+type OIDCLogoutToken struct {
+	Issuer    string                     `json:"iss"`
+	Subject   string                     `json:"sub,omitempty"`
+	Audience  OIDCAudience               `json:"aud"`
+	IssuedAt  int64                      `json:"iat"`
+	ExpiresAt int64                      `json:"exp,omitempty"`
+	JTI       string                     `json:"jti"`
+	SessionID string                     `json:"sid,omitempty"`
+	Events    map[string]json.RawMessage `json:"events"`
+	Nonce     string                     `json:"nonce,omitempty"`
+}
+
+// Valid 校验登出令牌的时间声明 (实现 jwt.Claims 接口)
+func (t *OIDCLogoutToken) Valid() error {
+	now := time.Now()
+	skew := int64(idTokenClockSkew / time.Second)
+
+	if t.IssuedAt == 0 {
+		return errors.New("logout_token is missing iat claim")
+	}
+	if now.Unix() < t.IssuedAt-skew {
+		return errors.New("logout_token used before issued")
+	}
+	if t.ExpiresAt != 0 && now.Unix() > t.ExpiresAt+skew {
+		return errors.New("logout_token is expired")
+	}
+	return nil
 }
 
 // OIDCState OIDC 状态信息 (用于防止 CSRF)
 type OIDCState struct {
-	Nonce       string `json:"nonce"`
-	RedirectURL string `json:"redirect_url,omitempty"`
-	CreatedAt   int64  `json:"created_at"`
+	Provider     string `json:"provider,omitempty"` // 发起登录的 OIDC 提供者名称
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier,omitempty"` // PKCE code_verifier
+	RedirectURL  string `json:"redirect_url,omitempty"`
+	CreatedAt    int64  `json:"created_at"`
+	LinkUserID   uint64 `json:"link_user_id,omitempty"` // 关联流程: 回调时将身份关联到该用户而不是登录
 }
 
-// NewOIDCProvider 创建 OIDC 提供者
+// GenerateCodeVerifier 生成 PKCE code_verifier (RFC 7636, 43 位 base64url)
+func GenerateCodeVerifier() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallengeS256 计算 PKCE S256 code_challenge
+func CodeChallengeS256(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// NewOIDCProvider 创建 OIDC 提供者。
+// 启动时拉取发现文档失败不会返回错误: 提供者会在后台按指数退避重试，
+// 之后按 Cache-Control (或 DiscoveryRefreshInterval) 周期刷新，可通过 Status 查看状态。
 func NewOIDCProvider(cfg config.OIDCConfig) (*OIDCProvider, error) {
 	if !cfg.Enabled {
 		return nil, nil
 	}
+	if cfg.ProviderURL == "" {
+		return nil, errors.New("OIDC ProviderURL is not configured")
+	}
 
 	transport := &http.Transport{
 		TLSClientConfig: &tls.Config{
@@ -83,43 +244,21 @@ func NewOIDCProvider(cfg config.OIDCConfig) (*OIDCProvider, error) {
 			Timeout:   30 * time.Second,
 			Transport: transport,
 		},
+		flight: syncx.NewSingleFlight(),
+		stop:   make(chan struct{}),
 	}
 
-	// 获取 OIDC 发现文档
-	if err := provider.fetchDiscovery(); err != nil {
-		return nil, fmt.Errorf("failed to fetch OIDC discovery: %w", err)
+	// 获取 OIDC 发现文档，失败时交由后台重试
+	if err := provider.Refresh(context.Background()); err != nil {
+		logx.Errorf("Failed to fetch OIDC discovery from %s, will retry in background: %v", cfg.ProviderURL, err)
 	}
+	go provider.run()
 
 	return provider, nil
 }
 
-// fetchDiscovery 获取 OIDC 发现文档
-func (p *OIDCProvider) fetchDiscovery() error {
-	discoveryURL := strings.TrimSuffix(p.config.ProviderURL, "/") + "/.well-known/openid-configuration"
-
-	resp, err := p.httpClient.Get(discoveryURL)
-	if err != nil {
-		return fmt.Errorf("failed to fetch discovery document: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("discovery endpoint returned %d: %s", resp.StatusCode, string(body))
-	}
-
-	var discovery OIDCDiscovery
-	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
-		return fmt.Errorf("failed to decode discovery document: %w", err)
-	}
-
-	p.discovery = &discovery
-	logx.Infof("OIDC discovery loaded from %s, issuer: %s", discoveryURL, discovery.Issuer)
-	return nil
-}
-
-// GetAuthorizationURL 获取授权 URL
-func (p *OIDCProvider) GetAuthorizationURL(state, nonce string) string {
+// GetAuthorizationURL 获取授权 URL，codeChallenge 为 PKCE S256 challenge。发现文档不可用时返回空字符串
+func (p *OIDCProvider) GetAuthorizationURL(state, nonce, codeChallenge string) string {
 	params := url.Values{}
 	params.Set("client_id", p.config.ClientID)
 	params.Set("redirect_uri", p.config.RedirectURL)
@@ -127,20 +266,36 @@ func (p *OIDCProvider) GetAuthorizationURL(state, nonce string) string {
 	params.Set("scope", strings.Join(p.config.Scopes, " "))
 	params.Set("state", state)
 	params.Set("nonce", nonce)
+	if codeChallenge != "" {
+		params.Set("code_challenge", codeChallenge)
+		params.Set("code_challenge_method", "S256")
+	}
 
-	return p.discovery.AuthorizationEndpoint + "?" + params.Encode()
+	discovery, _, err := p.ensureDiscovery(context.Background())
+	if err != nil {
+		logx.Errorf("Failed to build OIDC authorization URL: %v", err)
+		return ""
+	}
+	return discovery.AuthorizationEndpoint + "?" + params.Encode()
 }
 
-// ExchangeCode 用授权码交换令牌
-func (p *OIDCProvider) ExchangeCode(ctx context.Context, code string) (*OIDCTokenResponse, error) {
+// ExchangeCode 用授权码交换令牌，codeVerifier 为登录时生成的 PKCE code_verifier
+func (p *OIDCProvider) ExchangeCode(ctx context.Context, code, codeVerifier string) (*OIDCTokenResponse, error) {
 	data := url.Values{}
 	data.Set("grant_type", "authorization_code")
 	data.Set("code", code)
 	data.Set("redirect_uri", p.config.RedirectURL)
-	data.Set("client_id", p.config.ClientID)
-	data.Set("client_secret", p.config.ClientSecret)
+	if codeVerifier != "" {
+		data.Set("code_verifier", codeVerifier)
+	}
+	p.setClientCredentials(data)
+
+	discovery, _, err := p.ensureDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create token request: %w", err)
 	}
@@ -165,9 +320,22 @@ func (p *OIDCProvider) ExchangeCode(ctx context.Context, code string) (*OIDCToke
 	return &tokenResp, nil
 }
 
+// setClientCredentials 设置客户端凭据，公共客户端 (无 ClientSecret) 只发送 client_id
+func (p *OIDCProvider) setClientCredentials(data url.Values) {
+	data.Set("client_id", p.config.ClientID)
+	if p.config.ClientSecret != "" {
+		data.Set("client_secret", p.config.ClientSecret)
+	}
+}
+
 // GetUserInfo 获取用户信息
 func (p *OIDCProvider) GetUserInfo(ctx context.Context, accessToken string) (*OIDCUserInfo, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.discovery.UserInfoEndpoint, nil)
+	discovery, _, err := p.ensureDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discovery.UserInfoEndpoint, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create userinfo request: %w", err)
 	}
@@ -184,23 +352,129 @@ func (p *OIDCProvider) GetUserInfo(ctx context.Context, accessToken string) (*OI
 		return nil, fmt.Errorf("userinfo endpoint returned %d: %s", resp.StatusCode, string(body))
 	}
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read userinfo: %w", err)
+	}
+
 	var userInfo OIDCUserInfo
-	if err := json.NewDecoder(resp.Body).Decode(&userInfo); err != nil {
+	if err := json.Unmarshal(body, &userInfo); err != nil {
+		return nil, fmt.Errorf("failed to decode userinfo: %w", err)
+	}
+	if err := json.Unmarshal(body, &userInfo.Claims); err != nil {
 		return nil, fmt.Errorf("failed to decode userinfo: %w", err)
 	}
 
 	return &userInfo, nil
 }
 
+// VerifyIDToken 校验 ID Token 的签名、iss、aud、exp、azp 和 nonce
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*OIDCIDToken, error) {
+	if rawIDToken == "" {
+		return nil, errors.New("id_token is missing")
+	}
+
+	var claims OIDCIDToken
+	discovery, err := p.parseSignedToken(ctx, rawIDToken, &claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify id_token: %w", err)
+	}
+
+	if claims.Issuer != discovery.Issuer {
+		return nil, fmt.Errorf("id_token issuer mismatch: got %q, want %q", claims.Issuer, discovery.Issuer)
+	}
+	if !claims.Audience.Contains(p.config.ClientID) {
+		return nil, fmt.Errorf("id_token audience does not contain client_id %q", p.config.ClientID)
+	}
+	// 多个 aud 时必须携带 azp，且 azp 必须为当前客户端
+	if len(claims.Audience) > 1 && claims.AuthorizedParty == "" {
+		return nil, errors.New("id_token has multiple audiences but no azp claim")
+	}
+	if claims.AuthorizedParty != "" && claims.AuthorizedParty != p.config.ClientID {
+		return nil, fmt.Errorf("id_token azp mismatch: got %q", claims.AuthorizedParty)
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	// 签名已校验，保留全部声明供声明映射使用
+	if parts := strings.Split(rawIDToken, "."); len(parts) == 3 {
+		if payload, err := jwt.DecodeSegment(parts[1]); err == nil {
+			_ = json.Unmarshal(payload, &claims.Claims)
+		}
+	}
+
+	return &claims, nil
+}
+
+// VerifyLogoutToken 校验后端登出令牌的签名、iss、aud、events，并要求携带 sub 或 sid
+func (p *OIDCProvider) VerifyLogoutToken(ctx context.Context, rawLogoutToken string) (*OIDCLogoutToken, error) {
+	if rawLogoutToken == "" {
+		return nil, errors.New("logout_token is missing")
+	}
+
+	var claims OIDCLogoutToken
+	discovery, err := p.parseSignedToken(ctx, rawLogoutToken, &claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify logout_token: %w", err)
+	}
+
+	if claims.Issuer != discovery.Issuer {
+		return nil, fmt.Errorf("logout_token issuer mismatch: got %q, want %q", claims.Issuer, discovery.Issuer)
+	}
+	if !claims.Audience.Contains(p.config.ClientID) {
+		return nil, fmt.Errorf("logout_token audience does not contain client_id %q", p.config.ClientID)
+	}
+	if _, ok := claims.Events[backChannelLogoutEvent]; !ok {
+		return nil, errors.New("logout_token is missing the back-channel logout event")
+	}
+	// 登出令牌禁止携带 nonce，以免与 ID Token 混用
+	if claims.Nonce != "" {
+		return nil, errors.New("logout_token must not contain a nonce claim")
+	}
+	if claims.Subject == "" && claims.SessionID == "" {
+		return nil, errors.New("logout_token must contain a sub or sid claim")
+	}
+	if claims.JTI == "" {
+		return nil, errors.New("logout_token is missing jti claim")
+	}
+
+	return &claims, nil
+}
+
+// parseSignedToken 使用提供者 JWKS 校验 JWT 签名 (仅允许非对称算法) 并解析声明
+func (p *OIDCProvider) parseSignedToken(ctx context.Context, raw string, claims jwt.Claims) (*OIDCDiscovery, error) {
+	discovery, jwks, err := p.ensureDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := &jwt.Parser{
+		ValidMethods: []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"},
+	}
+	_, err = parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return jwks.GetKey(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return discovery, nil
+}
+
 // RefreshAccessToken 刷新访问令牌
 func (p *OIDCProvider) RefreshAccessToken(ctx context.Context, refreshToken string) (*OIDCTokenResponse, error) {
 	data := url.Values{}
 	data.Set("grant_type", "refresh_token")
 	data.Set("refresh_token", refreshToken)
-	data.Set("client_id", p.config.ClientID)
-	data.Set("client_secret", p.config.ClientSecret)
+	p.setClientCredentials(data)
+
+	discovery, _, err := p.ensureDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create refresh request: %w", err)
 	}
@@ -227,7 +501,8 @@ func (p *OIDCProvider) RefreshAccessToken(ctx context.Context, refreshToken stri
 
 // GetLogoutURL 获取登出 URL
 func (p *OIDCProvider) GetLogoutURL(idToken, postLogoutRedirectURI string) string {
-	if p.discovery.EndSessionEndpoint == "" {
+	discovery := p.GetDiscovery()
+	if discovery == nil || discovery.EndSessionEndpoint == "" {
 		return ""
 	}
 
@@ -239,11 +514,13 @@ func (p *OIDCProvider) GetLogoutURL(idToken, postLogoutRedirectURI string) strin
 		params.Set("post_logout_redirect_uri", postLogoutRedirectURI)
 	}
 
-	return p.discovery.EndSessionEndpoint + "?" + params.Encode()
+	return discovery.EndSessionEndpoint + "?" + params.Encode()
 }
 
-// GetDiscovery 获取发现文档
+// GetDiscovery 获取发现文档，尚未加载时返回 nil
 func (p *OIDCProvider) GetDiscovery() *OIDCDiscovery {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	return p.discovery
 }
 
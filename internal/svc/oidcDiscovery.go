@@ -0,0 +1,244 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+const (
+	// defaultDiscoveryRefreshInterval 发现文档未声明 Cache-Control 时的刷新间隔
+	defaultDiscoveryRefreshInterval = time.Hour
+	// minDiscoveryRefreshInterval / maxDiscoveryRefreshInterval 限制 Cache-Control max-age 的取值范围
+	minDiscoveryRefreshInterval = time.Minute
+	maxDiscoveryRefreshInterval = 24 * time.Hour
+	// discoveryRetryInitialInterval 拉取失败后的首次重试间隔，之后指数退避
+	discoveryRetryInitialInterval = time.Second
+	// defaultDiscoveryRetryMaxInterval 重试间隔上限
+	defaultDiscoveryRetryMaxInterval = 5 * time.Minute
+)
+
+// errDiscoveryUnavailable 发现文档尚未成功加载
+var errDiscoveryUnavailable = errors.New("OIDC discovery document is not available")
+
+// OIDCProviderStatus OIDC 提供者发现文档的加载状态
+type OIDCProviderStatus struct {
+	Ready               bool      // 是否已加载发现文档
+	Issuer              string    // 发现文档中的 issuer
+	LastRefresh         time.Time // 最近一次成功加载的时间
+	LastAttempt         time.Time // 最近一次尝试加载的时间
+	LastError           string    // 最近一次加载失败的原因 (成功后清空)
+	ConsecutiveFailures int       // 连续失败次数
+	NextRefresh         time.Time // 下一次计划刷新的时间
+}
+
+// Status 返回发现文档的加载状态
+func (p *OIDCProvider) Status() OIDCProviderStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	status := OIDCProviderStatus{
+		Ready:               p.discovery != nil,
+		LastRefresh:         p.lastRefresh,
+		LastAttempt:         p.lastAttempt,
+		ConsecutiveFailures: p.failures,
+		NextRefresh:         p.nextRefresh,
+	}
+	if p.discovery != nil {
+		status.Issuer = p.discovery.Issuer
+	}
+	if p.lastErr != nil {
+		status.LastError = p.lastErr.Error()
+	}
+	return status
+}
+
+// Refresh 立即重新拉取发现文档，并发调用会被合并为一次请求。
+// 拉取失败时保留上一次成功加载的发现文档。
+func (p *OIDCProvider) Refresh(ctx context.Context) error {
+	_, err := p.flight.Do("discovery", func() (any, error) {
+		maxAge, err := p.fetchDiscovery(ctx)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		now := time.Now()
+		p.lastAttempt = now
+		if err != nil {
+			p.lastErr = err
+			p.failures++
+			p.nextRefresh = now.Add(p.retryBackoff(p.failures))
+			return nil, err
+		}
+
+		p.lastErr = nil
+		p.failures = 0
+		p.lastRefresh = now
+		p.nextRefresh = now.Add(p.refreshInterval(maxAge))
+		return nil, nil
+	})
+	return err
+}
+
+// Stop 停止后台刷新
+func (p *OIDCProvider) Stop() {
+	if p == nil {
+		return
+	}
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+}
+
+// run 后台按计划刷新发现文档: 失败时指数退避重试，成功后按 Cache-Control 周期刷新
+func (p *OIDCProvider) run() {
+	for {
+		p.mu.RLock()
+		delay := time.Until(p.nextRefresh)
+		p.mu.RUnlock()
+		if delay < 0 {
+			delay = 0
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-p.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := p.Refresh(context.Background()); err != nil {
+			status := p.Status()
+			logx.Errorf("OIDC discovery refresh for %s failed (%d consecutive failures, next retry at %s): %v",
+				p.config.ProviderURL, status.ConsecutiveFailures, status.NextRefresh.Format(time.RFC3339), err)
+		}
+	}
+}
+
+// ensureDiscovery 返回当前的发现文档与 JWKS 缓存。
+// 尚未加载时按需拉取一次，但处于退避期间时直接返回上次的错误，避免请求风暴。
+func (p *OIDCProvider) ensureDiscovery(ctx context.Context) (*OIDCDiscovery, *JWKSCache, error) {
+	p.mu.RLock()
+	discovery, jwks := p.discovery, p.jwks
+	inBackoff := p.failures > 0 && time.Now().Before(p.nextRefresh)
+	lastErr := p.lastErr
+	p.mu.RUnlock()
+
+	if discovery != nil {
+		return discovery, jwks, nil
+	}
+	if inBackoff {
+		return nil, nil, fmt.Errorf("%w: %v", errDiscoveryUnavailable, lastErr)
+	}
+
+	if err := p.Refresh(ctx); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errDiscoveryUnavailable, err)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.discovery, p.jwks, nil
+}
+
+// fetchDiscovery 获取 OIDC 发现文档，返回 Cache-Control 中的 max-age (未声明时为 0)
+func (p *OIDCProvider) fetchDiscovery(ctx context.Context) (time.Duration, error) {
+	discoveryURL := strings.TrimSuffix(p.config.ProviderURL, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create discovery request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("discovery endpoint returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var discovery OIDCDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return 0, fmt.Errorf("failed to decode discovery document: %w", err)
+	}
+	if discovery.Issuer == "" || discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" {
+		return 0, errors.New("discovery document is missing issuer or endpoints")
+	}
+
+	p.mu.Lock()
+	// jwks_uri 未变化时保留已缓存的公钥
+	if p.jwks == nil || p.discovery == nil || p.discovery.JWKSUri != discovery.JWKSUri {
+		p.jwks = NewJWKSCache(discovery.JWKSUri, p.httpClient)
+	}
+	p.discovery = &discovery
+	p.mu.Unlock()
+
+	logx.Infof("OIDC discovery loaded from %s, issuer: %s", discoveryURL, discovery.Issuer)
+	return cacheControlMaxAge(resp.Header.Get("Cache-Control")), nil
+}
+
+// refreshInterval 计算下一次周期刷新的间隔，优先使用 Cache-Control max-age
+func (p *OIDCProvider) refreshInterval(maxAge time.Duration) time.Duration {
+	interval := defaultDiscoveryRefreshInterval
+	if p.config.DiscoveryRefreshInterval > 0 {
+		interval = time.Duration(p.config.DiscoveryRefreshInterval) * time.Second
+	}
+	if maxAge > 0 {
+		interval = maxAge
+	}
+
+	if interval < minDiscoveryRefreshInterval {
+		return minDiscoveryRefreshInterval
+	}
+	if interval > maxDiscoveryRefreshInterval {
+		return maxDiscoveryRefreshInterval
+	}
+	return interval
+}
+
+// retryBackoff 计算第 failures 次失败后的重试间隔 (1s, 2s, 4s ... 上限)
+func (p *OIDCProvider) retryBackoff(failures int) time.Duration {
+	maxInterval := defaultDiscoveryRetryMaxInterval
+	if p.config.DiscoveryRetryMaxInterval > 0 {
+		maxInterval = time.Duration(p.config.DiscoveryRetryMaxInterval) * time.Second
+	}
+
+	backoff := discoveryRetryInitialInterval
+	for i := 1; i < failures && backoff < maxInterval; i++ {
+		backoff *= 2
+	}
+	if backoff > maxInterval {
+		return maxInterval
+	}
+	return backoff
+}
+
+// cacheControlMaxAge 解析 Cache-Control 中的 max-age，no-store/no-cache 或未声明时返回 0
+func cacheControlMaxAge(header string) time.Duration {
+	for _, directive := range strings.Split(header, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		if directive == "no-store" || directive == "no-cache" {
+			return 0
+		}
+		if value, ok := strings.CutPrefix(directive, "max-age="); ok {
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds <= 0 {
+				return 0
+			}
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return 0
+}
@@ -0,0 +1,141 @@
+package svc
+
+// DefaultOIDCProviderName 未指定名称时 OIDC 提供者的默认名称 (兼容 /sso/oidc/login 路由)
+const DefaultOIDCProviderName = "oidc"
+
+// OIDCProviderEntry 已注册的 OIDC 提供者
+type OIDCProviderEntry struct {
+	Name        string // 提供者名称 (路由参数)
+	DisplayName string // 登录页显示名称
+	Icon        string // 登录页图标 URL
+	Client      OIDCClient
+	Mapper      *ClaimMapper // 声明映射 (为空时使用标准 OIDC 声明)
+	TrustEmail  bool         // 已验证邮箱与已有账号相同时直接关联
+
+	Provisioning *ProvisioningPolicy // 首次登录时自动开通账号的策略 (为空时允许所有用户开通)
+}
+
+// defaultOIDCMapper 未配置声明映射时使用的标准映射
+var defaultOIDCMapper = &ClaimMapper{mapping: DefaultOIDCClaimMapping}
+
+// OIDCProviders 按名称管理多个 OIDC 提供者，保持注册顺序
+type OIDCProviders struct {
+	entries []*OIDCProviderEntry
+	byName  map[string]*OIDCProviderEntry
+}
+
+// NewOIDCProviders 创建 OIDC 提供者集合
+func NewOIDCProviders(entries ...OIDCProviderEntry) *OIDCProviders {
+	providers := &OIDCProviders{
+		byName: make(map[string]*OIDCProviderEntry),
+	}
+	for _, entry := range entries {
+		providers.Register(entry)
+	}
+	return providers
+}
+
+// Register 注册提供者，名称已存在时返回 false
+func (p *OIDCProviders) Register(entry OIDCProviderEntry) bool {
+	if entry.Name == "" {
+		entry.Name = DefaultOIDCProviderName
+	}
+	if _, exists := p.byName[entry.Name]; exists {
+		return false
+	}
+
+	p.entries = append(p.entries, &entry)
+	p.byName[entry.Name] = &entry
+	return true
+}
+
+// Get 按名称获取已启用的提供者，名称为空时使用默认提供者
+func (p *OIDCProviders) Get(name string) (OIDCClient, bool) {
+	if p == nil {
+		return nil, false
+	}
+	if name == "" {
+		name = DefaultOIDCProviderName
+	}
+
+	entry, ok := p.byName[name]
+	if !ok || entry.Client == nil || !entry.Client.IsEnabled() {
+		return nil, false
+	}
+	return entry.Client, true
+}
+
+// Mapper 返回提供者的声明映射，未配置时使用标准 OIDC 声明映射
+func (p *OIDCProviders) Mapper(name string) *ClaimMapper {
+	if p != nil {
+		if name == "" {
+			name = DefaultOIDCProviderName
+		}
+		if entry, ok := p.byName[name]; ok && entry.Mapper != nil {
+			return entry.Mapper
+		}
+	}
+	return defaultOIDCMapper
+}
+
+// TrustEmail 提供者断言的已验证邮箱是否可用于直接关联已有账号
+func (p *OIDCProviders) TrustEmail(name string) bool {
+	if p == nil {
+		return false
+	}
+	if name == "" {
+		name = DefaultOIDCProviderName
+	}
+	entry, ok := p.byName[name]
+	return ok && entry.TrustEmail
+}
+
+// Provisioning 返回提供者的自动开通策略
+func (p *OIDCProviders) Provisioning(name string) *ProvisioningPolicy {
+	if p == nil {
+		return nil
+	}
+	if name == "" {
+		name = DefaultOIDCProviderName
+	}
+	if entry, ok := p.byName[name]; ok {
+		return entry.Provisioning
+	}
+	return nil
+}
+
+// List 返回所有已注册的提供者
+func (p *OIDCProviders) List() []*OIDCProviderEntry {
+	if p == nil {
+		return nil
+	}
+	return p.entries
+}
+
+// Stop 停止所有提供者的后台刷新
+func (p *OIDCProviders) Stop() {
+	if p == nil {
+		return
+	}
+	for _, entry := range p.entries {
+		if stopper, ok := entry.Client.(interface{ Stop() }); ok {
+			stopper.Stop()
+		}
+	}
+}
+
+// oidcStatusReporter 可上报发现文档加载状态的 OIDC 客户端 (如 *OIDCProvider)
+type oidcStatusReporter interface {
+	Status() OIDCProviderStatus
+}
+
+// Status 返回提供者的发现文档状态，客户端不支持状态上报时以 IsEnabled 作为就绪状态
+func (e *OIDCProviderEntry) Status() OIDCProviderStatus {
+	if e.Client == nil {
+		return OIDCProviderStatus{}
+	}
+	if reporter, ok := e.Client.(oidcStatusReporter); ok {
+		return reporter.Status()
+	}
+	return OIDCProviderStatus{Ready: e.Client.IsEnabled()}
+}
@@ -0,0 +1,117 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// OIDCSession 由 OIDC 登录建立的本地会话，用于 RP 发起登出和后端登出
+type OIDCSession struct {
+	Provider     string `json:"provider"`           // OIDC 提供者名称
+	Subject      string `json:"sub"`                // IdP 侧的用户标识
+	IdPSessionID string `json:"sid,omitempty"`      // IdP 会话 ID (ID Token 的 sid 声明)
+	IDToken      string `json:"id_token,omitempty"` // 登出时作为 id_token_hint
+	UserID       uint64 `json:"user_id"`            // 本地用户 ID
+	CreatedAt    int64  `json:"created_at"`
+}
+
+func oidcSessionKey(sessionID string) string {
+	return fmt.Sprintf("auth:oidc:session:%s", sessionID)
+}
+
+func oidcSubjectIndexKey(provider, subject string) string {
+	return fmt.Sprintf("auth:oidc:session:sub:%s:%s", provider, subject)
+}
+
+func oidcSidIndexKey(provider, sid string) string {
+	return fmt.Sprintf("auth:oidc:session:sid:%s:%s", provider, sid)
+}
+
+// SaveOIDCSession 记录本地会话与 IdP 会话的关联，ttl 通常为刷新令牌的有效期
+func SaveOIDCSession(ctx context.Context, rdb redis.UniversalClient, sessionID string, session *OIDCSession, ttl time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode OIDC session: %w", err)
+	}
+
+	pipe := rdb.TxPipeline()
+	pipe.Set(ctx, oidcSessionKey(sessionID), data, ttl)
+	// 按 sub / sid 建立索引，供后端登出查找需要吊销的会话
+	subKey := oidcSubjectIndexKey(session.Provider, session.Subject)
+	pipe.SAdd(ctx, subKey, sessionID)
+	pipe.Expire(ctx, subKey, ttl)
+	if session.IdPSessionID != "" {
+		sidKey := oidcSidIndexKey(session.Provider, session.IdPSessionID)
+		pipe.SAdd(ctx, sidKey, sessionID)
+		pipe.Expire(ctx, sidKey, ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to save OIDC session: %w", err)
+	}
+	return nil
+}
+
+// GetOIDCSession 获取本地会话关联的 OIDC 会话，不存在时返回 nil
+func GetOIDCSession(ctx context.Context, rdb redis.UniversalClient, sessionID string) (*OIDCSession, error) {
+	data, err := rdb.Get(ctx, oidcSessionKey(sessionID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get OIDC session: %w", err)
+	}
+
+	var session OIDCSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to decode OIDC session: %w", err)
+	}
+	return &session, nil
+}
+
+// FindOIDCSessions 查找与 IdP 会话关联的本地会话 ID。
+// sid 不为空时只匹配该 IdP 会话，否则匹配该用户 (sub) 的全部会话。
+func FindOIDCSessions(ctx context.Context, rdb redis.UniversalClient, provider, subject, sid string) ([]string, error) {
+	key := oidcSubjectIndexKey(provider, subject)
+	if sid != "" {
+		key = oidcSidIndexKey(provider, sid)
+	}
+
+	sessionIDs, err := rdb.SMembers(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find OIDC sessions: %w", err)
+	}
+	if sid == "" || subject == "" {
+		return sessionIDs, nil
+	}
+
+	// 同时携带 sub 和 sid 时，确认会话确实属于该用户
+	matched := make([]string, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		session, err := GetOIDCSession(ctx, rdb, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		if session != nil && session.Subject == subject {
+			matched = append(matched, sessionID)
+		}
+	}
+	return matched, nil
+}
+
+// DeleteOIDCSession 删除本地会话与 IdP 会话的关联
+func DeleteOIDCSession(ctx context.Context, rdb redis.UniversalClient, sessionID string, session *OIDCSession) error {
+	pipe := rdb.TxPipeline()
+	pipe.Del(ctx, oidcSessionKey(sessionID))
+	pipe.SRem(ctx, oidcSubjectIndexKey(session.Provider, session.Subject), sessionID)
+	if session.IdPSessionID != "" {
+		pipe.SRem(ctx, oidcSidIndexKey(session.Provider, session.IdPSessionID), sessionID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete OIDC session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package svc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// PasswordResetTTL 密码重置令牌的有效期 (与重置邮件中的提示一致)
+	PasswordResetTTL = 30 * time.Minute
+
+	// PasswordResetCooldown 同一邮箱两次发送重置邮件的最小间隔
+	PasswordResetCooldown = time.Minute
+)
+
+// ErrInvalidPasswordReset 重置令牌不存在、已过期、已被使用或已被更新的令牌取代
+var ErrInvalidPasswordReset = errors.New("invalid or expired password reset token")
+
+// PasswordReset 待使用的密码重置令牌
+type PasswordReset struct {
+	UserID    uint64 `json:"user_id"`
+	Email     string `json:"email"` // 签发时的邮箱，用户修改邮箱后令牌失效
+	CreatedAt int64  `json:"created_at"`
+}
+
+// Redis 中只保存令牌的哈希，泄露 Redis 数据不会泄露可用的令牌
+func passwordResetKey(tokenHash string) string {
+	return fmt.Sprintf("auth:password:reset:%s", tokenHash)
+}
+
+// passwordResetUserKey 用户当前有效的令牌哈希，签发新令牌时使旧令牌失效
+func passwordResetUserKey(userID uint64) string {
+	return fmt.Sprintf("auth:password:reset:user:%d", userID)
+}
+
+// passwordResetCooldownKey 以邮箱哈希作为键，Redis 中不保存明文邮箱
+func passwordResetCooldownKey(email string) string {
+	return fmt.Sprintf("auth:password:reset:cooldown:%s", targetHash(email))
+}
+
+func hashPasswordResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// AllowPasswordReset 同一邮箱在冷却时间内只允许发送一次重置邮件
+func AllowPasswordReset(ctx context.Context, rdb redis.UniversalClient, email string) (bool, error) {
+	ok, err := rdb.SetNX(ctx, passwordResetCooldownKey(email), 1, PasswordResetCooldown).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check password reset cooldown: %w", err)
+	}
+	return ok, nil
+}
+
+// CreatePasswordReset 为用户签发密码重置令牌，同时使该用户此前签发的令牌失效
+func CreatePasswordReset(ctx context.Context, rdb redis.UniversalClient, reset *PasswordReset) (string, error) {
+	token, err := randomURLToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate password reset token: %w", err)
+	}
+
+	reset.CreatedAt = time.Now().Unix()
+	data, err := json.Marshal(reset)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode password reset: %w", err)
+	}
+
+	tokenHash := hashPasswordResetToken(token)
+	previous, err := rdb.SetArgs(ctx, passwordResetUserKey(reset.UserID), tokenHash, redis.SetArgs{Get: true, TTL: PasswordResetTTL}).Result()
+	if err != nil && err != redis.Nil {
+		return "", fmt.Errorf("failed to store password reset: %w", err)
+	}
+	if previous != "" {
+		rdb.Del(ctx, passwordResetKey(previous))
+	}
+	if err := rdb.Set(ctx, passwordResetKey(tokenHash), data, PasswordResetTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store password reset: %w", err)
+	}
+	return token, nil
+}
+
+// ConsumePasswordReset 取出并删除密码重置令牌 (令牌只能使用一次)
+func ConsumePasswordReset(ctx context.Context, rdb redis.UniversalClient, token string) (*PasswordReset, error) {
+	if token == "" {
+		return nil, ErrInvalidPasswordReset
+	}
+
+	tokenHash := hashPasswordResetToken(token)
+	data, err := rdb.GetDel(ctx, passwordResetKey(tokenHash)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidPasswordReset
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get password reset: %w", err)
+	}
+
+	var reset PasswordReset
+	if err := json.Unmarshal(data, &reset); err != nil {
+		return nil, ErrInvalidPasswordReset
+	}
+	// 只在仍指向本令牌时删除，避免删除并发签发的新令牌
+	userKey := passwordResetUserKey(reset.UserID)
+	if current, err := rdb.Get(ctx, userKey).Result(); err == nil && current == tokenHash {
+		rdb.Del(ctx, userKey)
+	}
+	return &reset, nil
+}
@@ -0,0 +1,66 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// PhoneVerificationTTL 短信验证码的有效期
+	PhoneVerificationTTL = 5 * time.Minute
+	// PhoneVerificationMaxAttempts 验证码的最大失败次数，超过后验证码作废
+	PhoneVerificationMaxAttempts = 5
+	// PhoneVerificationResendInterval 同一手机号两次发送验证码的最小间隔
+	PhoneVerificationResendInterval = time.Minute
+	// PhoneVerificationDailyLimit 同一手机号 24 小时内最多发送的验证码条数 (限制短信费用)
+	PhoneVerificationDailyLimit = 10
+)
+
+// ErrPhoneVerificationLimited 发送过于频繁或已达到当日发送上限
+var ErrPhoneVerificationLimited = errors.New("too many verification codes sent to this phone number")
+
+func phoneVerificationKey(phone string) string {
+	return fmt.Sprintf("auth:phone:verify:%s", targetHash(phone))
+}
+
+func phoneVerificationCooldownKey(phone string) string {
+	return fmt.Sprintf("auth:phone:verify:cooldown:%s", targetHash(phone))
+}
+
+func phoneVerificationDailyKey(phone string) string {
+	return fmt.Sprintf("auth:phone:verify:daily:%s", targetHash(phone))
+}
+
+// AllowPhoneVerification 检查发送间隔与 24 小时发送上限，超出时返回 ErrPhoneVerificationLimited
+func AllowPhoneVerification(ctx context.Context, rdb redis.UniversalClient, phone string) error {
+	ok, err := allowVerificationCode(ctx, rdb, phoneVerificationCooldownKey(phone), PhoneVerificationResendInterval)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrPhoneVerificationLimited
+	}
+
+	count, err := incrCounter(ctx, rdb, phoneVerificationDailyKey(phone), 24*time.Hour)
+	if err != nil {
+		return err
+	}
+	if count > PhoneVerificationDailyLimit {
+		return ErrPhoneVerificationLimited
+	}
+	return nil
+}
+
+// CreatePhoneVerification 为用户的手机号生成验证码，此前发送的验证码失效
+func CreatePhoneVerification(ctx context.Context, rdb redis.UniversalClient, userID uint64, phone string) (string, error) {
+	return createVerificationCode(ctx, rdb, phoneVerificationKey(phone), userID, phone, PhoneVerificationTTL)
+}
+
+// VerifyPhoneVerification 校验短信验证码，返回的 Target 为发送验证码时的手机号
+func VerifyPhoneVerification(ctx context.Context, rdb redis.UniversalClient, phone, code string) (*VerificationCode, error) {
+	return checkVerificationCode(ctx, rdb, phoneVerificationKey(phone), code, PhoneVerificationMaxAttempts, PhoneVerificationTTL)
+}
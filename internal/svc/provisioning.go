@@ -0,0 +1,102 @@
+package svc
+
+import (
+	"errors"
+	"strings"
+
+	"auth-service/internal/config"
+	model "auth-service/model/mysql"
+)
+
+var (
+	// ErrProvisioningDisabled 提供者禁止自动开通账号
+	ErrProvisioningDisabled = errors.New("automatic account provisioning is disabled")
+	// ErrProvisioningNotAllowed 用户的邮箱域名或组不在允许开通的范围内
+	ErrProvisioningNotAllowed = errors.New("account provisioning is not allowed for this user")
+)
+
+// ProvisioningPolicy SSO 用户首次登录时自动开通账号的策略，nil 表示允许所有用户开通且立即可用
+type ProvisioningPolicy struct {
+	config config.ProvisioningConfig
+}
+
+// NewProvisioningPolicy 创建自动开通策略
+func NewProvisioningPolicy(cfg config.ProvisioningConfig) *ProvisioningPolicy {
+	return &ProvisioningPolicy{config: cfg}
+}
+
+// Check 检查是否允许为外部身份自动开通账号。
+// 邮箱域名限制只接受已验证的邮箱，否则任何人都能在 IdP 填写受信任域名的邮箱绕过限制。
+func (p *ProvisioningPolicy) Check(email string, emailVerified bool, groups []string) error {
+	if p == nil {
+		return nil
+	}
+	if p.config.Disabled {
+		return ErrProvisioningDisabled
+	}
+
+	if len(p.config.AllowedDomains) > 0 {
+		if !emailVerified || !domainAllowed(p.config.AllowedDomains, email) {
+			return ErrProvisioningNotAllowed
+		}
+	}
+	if len(p.config.AllowedGroups) > 0 && !groupAllowed(p.config.AllowedGroups, groups) {
+		return ErrProvisioningNotAllowed
+	}
+	return nil
+}
+
+// InitialStatus 新账号的初始状态
+func (p *ProvisioningPolicy) InitialStatus() uint64 {
+	if p != nil && p.config.RequireApproval {
+		return model.UserStatusPending
+	}
+	return model.UserStatusActive
+}
+
+// DefaultRoles 新账号的默认角色
+func (p *ProvisioningPolicy) DefaultRoles() []string {
+	if p == nil {
+		return nil
+	}
+	return p.config.DefaultRoles
+}
+
+func domainAllowed(allowed []string, email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := email[at+1:]
+	for _, d := range allowed {
+		if strings.EqualFold(strings.TrimPrefix(d, "@"), domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupAllowed 组按名称或 DN 匹配: cn=devs,ou=groups,dc=example,dc=com 同时匹配 devs 和完整 DN
+func groupAllowed(allowed, groups []string) bool {
+	for _, g := range groups {
+		name := groupName(g)
+		for _, a := range allowed {
+			if strings.EqualFold(a, g) || strings.EqualFold(a, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// groupName 返回组 DN 第一个 RDN 的值，非 DN 形式的组名原样返回
+func groupName(group string) string {
+	rdn := group
+	if i := strings.Index(rdn, ","); i >= 0 {
+		rdn = rdn[:i]
+	}
+	if i := strings.Index(rdn, "="); i >= 0 {
+		return strings.TrimSpace(rdn[i+1:])
+	}
+	return group
+}
@@ -0,0 +1,366 @@
+package svc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"auth-service/internal/config"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+)
+
+const (
+	// RADIUSAuthPAP 密码以 User-Password 属性 (共享密钥加密) 发送
+	RADIUSAuthPAP = "pap"
+	// RADIUSAuthMSCHAPv2 MS-CHAPv2 (RFC 2759)，服务器只能校验密码，无法得到明文 (如 Windows NPS)
+	RADIUSAuthMSCHAPv2 = "mschapv2"
+
+	defaultRADIUSPort             = "1812"
+	defaultRADIUSTimeout          = 3 * time.Second
+	defaultRADIUSRetryInterval    = time.Second
+	defaultRADIUSFailoverCooldown = 30 * time.Second
+	defaultRADIUSNASIdentifier    = "auth-service"
+
+	// radiusMessageAuthenticatorType Message-Authenticator 属性 (RFC 3579)
+	radiusMessageAuthenticatorType radius.Type = 80
+)
+
+var (
+	// ErrRADIUSRejected 服务器拒绝认证 (Access-Reject)
+	ErrRADIUSRejected = errors.New("radius: access rejected")
+	// ErrRADIUSUnavailable 所有 RADIUS 服务器都无响应
+	ErrRADIUSUnavailable = errors.New("radius: no server responded")
+	// ErrRADIUSInvalidResponse 响应不符合协议 (如缺少 State 的 Access-Challenge 或 MS-CHAPv2 服务器认证失败)
+	ErrRADIUSInvalidResponse = errors.New("radius: invalid response")
+)
+
+// RADIUSProvider RADIUS 认证提供者
+type RADIUSProvider struct {
+	config        config.RADIUSConfig
+	secret        []byte
+	nasIdentifier string
+	timeout       time.Duration
+	retryInterval time.Duration
+	servers       []string
+	health        *serverHealth[string]
+}
+
+// RADIUSUserInfo 认证通过的 RADIUS 用户
+type RADIUSUserInfo struct {
+	Username     string
+	Groups       []string // Access-Accept 中的 Class 属性
+	ReplyMessage string
+}
+
+// RADIUSChallenge 服务器要求继续认证 (Access-Challenge, 如输入一次性密码)。
+// State 只在签发它的服务器上有效，后续请求必须发送到同一台服务器
+type RADIUSChallenge struct {
+	Server  string
+	State   []byte
+	Message string // Reply-Message，提示用户输入的内容
+}
+
+// RADIUSResult 认证结果: 认证通过时 User 非空，需要继续认证时 Challenge 非空
+type RADIUSResult struct {
+	User      *RADIUSUserInfo
+	Challenge *RADIUSChallenge
+}
+
+// NewRADIUSProvider 创建 RADIUS 提供者
+func NewRADIUSProvider(cfg config.RADIUSConfig) (*RADIUSProvider, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.Secret == "" {
+		return nil, errors.New("RADIUS secret is required")
+	}
+	switch strings.ToLower(cfg.AuthMethod) {
+	case "", RADIUSAuthPAP, RADIUSAuthMSCHAPv2:
+	default:
+		return nil, fmt.Errorf("invalid RADIUS auth method %q, expected %s or %s", cfg.AuthMethod, RADIUSAuthPAP, RADIUSAuthMSCHAPv2)
+	}
+
+	p := &RADIUSProvider{
+		config:        cfg,
+		secret:        []byte(cfg.Secret),
+		nasIdentifier: cfg.NASIdentifier,
+		timeout:       secondsOrDefault(cfg.Timeout, defaultRADIUSTimeout),
+		retryInterval: secondsOrDefault(cfg.RetryInterval, defaultRADIUSRetryInterval),
+		health:        newServerHealth[string](secondsOrDefault(cfg.FailoverCooldown, defaultRADIUSFailoverCooldown)),
+	}
+	if p.nasIdentifier == "" {
+		p.nasIdentifier = defaultRADIUSNASIdentifier
+	}
+	for _, address := range cfg.Servers {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			address = net.JoinHostPort(strings.Trim(address, "[]"), defaultRADIUSPort)
+		}
+		p.servers = append(p.servers, address)
+	}
+	if len(p.servers) == 0 {
+		return nil, errors.New("at least one RADIUS server is required")
+	}
+	return p, nil
+}
+
+// IsEnabled 检查 RADIUS 是否启用
+func (p *RADIUSProvider) IsEnabled() bool {
+	return p != nil && p.config.Enabled
+}
+
+// Authenticate 校验用户名与密码，依次尝试各台服务器直到有服务器响应
+func (p *RADIUSProvider) Authenticate(ctx context.Context, username, password string) (*RADIUSResult, error) {
+	var lastErr error
+	for _, server := range p.candidates() {
+		result, err := p.authenticate(ctx, server, username, password, nil)
+		if err == nil || !isRADIUSTransportError(err) {
+			p.health.markHealthy(server)
+			return result, err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		logx.WithContext(ctx).Errorf("RADIUS server %s did not respond: %v", server, err)
+		p.health.markFailed(server)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("%w: %v", ErrRADIUSUnavailable, lastErr)
+}
+
+// RespondChallenge 回应 Access-Challenge (如一次性密码)，只发送到签发该 State 的服务器
+func (p *RADIUSProvider) RespondChallenge(ctx context.Context, challenge *RADIUSChallenge, username, response string) (*RADIUSResult, error) {
+	result, err := p.authenticate(ctx, challenge.Server, username, response, challenge.State)
+	if err != nil && isRADIUSTransportError(err) {
+		return nil, fmt.Errorf("%w: %v", ErrRADIUSUnavailable, err)
+	}
+	return result, err
+}
+
+// authenticate 向一台服务器发送 Access-Request 并解析响应
+func (p *RADIUSProvider) authenticate(ctx context.Context, server, username, password string, state []byte) (*RADIUSResult, error) {
+	packet := radius.New(radius.CodeAccessRequest, p.secret)
+	if err := rfc2865.UserName_SetString(packet, username); err != nil {
+		return nil, err
+	}
+	if err := rfc2865.NASIdentifier_SetString(packet, p.nasIdentifier); err != nil {
+		return nil, err
+	}
+	if len(state) > 0 {
+		if err := rfc2865.State_Set(packet, state); err != nil {
+			return nil, err
+		}
+	}
+
+	var mschap *msCHAPv2Exchange
+	if strings.EqualFold(p.config.AuthMethod, RADIUSAuthMSCHAPv2) {
+		var err error
+		if mschap, err = newMSCHAPv2Exchange(username, password); err != nil {
+			return nil, err
+		}
+		if err := mschap.addTo(packet); err != nil {
+			return nil, err
+		}
+	} else if err := rfc2865.UserPassword_Set(packet, padUserPassword(password)); err != nil {
+		return nil, err
+	}
+	if err := setMessageAuthenticator(packet); err != nil {
+		return nil, err
+	}
+
+	response, err := p.exchange(ctx, server, packet)
+	if err != nil {
+		return nil, err
+	}
+	message := replyMessage(response)
+
+	switch response.Code {
+	case radius.CodeAccessAccept:
+		if mschap != nil {
+			if err := mschap.verifySuccess(response); err != nil {
+				return nil, err
+			}
+		}
+		user := &RADIUSUserInfo{Username: username, ReplyMessage: message}
+		// 服务器可以在 Access-Accept 中返回规范化的用户名 (RFC 2865 5.1)
+		if name := rfc2865.UserName_GetString(response); name != "" {
+			user.Username = name
+		}
+		for _, class := range response.Attributes[rfc2865.Class_Type] {
+			user.Groups = append(user.Groups, string(class))
+		}
+		return &RADIUSResult{User: user}, nil
+	case radius.CodeAccessChallenge:
+		state := rfc2865.State_Get(response)
+		if len(state) == 0 {
+			return nil, fmt.Errorf("%w: Access-Challenge without State", ErrRADIUSInvalidResponse)
+		}
+		return &RADIUSResult{Challenge: &RADIUSChallenge{Server: server, State: state, Message: message}}, nil
+	case radius.CodeAccessReject:
+		if message == "" {
+			message = msCHAPErrorMessage(response)
+		}
+		if message != "" {
+			return nil, fmt.Errorf("%w: %s", ErrRADIUSRejected, message)
+		}
+		return nil, ErrRADIUSRejected
+	}
+	return nil, fmt.Errorf("%w: unexpected code %v", ErrRADIUSInvalidResponse, response.Code)
+}
+
+// radiusTransportError 服务器无响应或网络错误，可以转移到下一台服务器
+type radiusTransportError struct {
+	err error
+}
+
+func (e *radiusTransportError) Error() string { return e.err.Error() }
+func (e *radiusTransportError) Unwrap() error { return e.err }
+
+func isRADIUSTransportError(err error) bool {
+	var transportErr *radiusTransportError
+	return errors.As(err, &transportErr)
+}
+
+// exchange 通过 UDP 发送请求并等待响应，在超时前按间隔重发。
+// 忽略标识符不匹配或未通过 Response Authenticator / Message-Authenticator 校验的包 (可能是伪造的响应)
+func (p *RADIUSProvider) exchange(ctx context.Context, server string, packet *radius.Packet) (*radius.Packet, error) {
+	wire, err := packet.Encode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode RADIUS request: %w", err)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, &radiusTransportError{err}
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	deadline := time.Now().Add(p.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	buf := make([]byte, radius.MaxPacketLength)
+	for {
+		if _, err := conn.Write(wire); err != nil {
+			return nil, &radiusTransportError{err}
+		}
+		retryAt := time.Now().Add(p.retryInterval)
+		if retryAt.After(deadline) {
+			retryAt = deadline
+		}
+		if err := conn.SetReadDeadline(retryAt); err != nil {
+			return nil, &radiusTransportError{err}
+		}
+
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					return nil, &radiusTransportError{ctxErr}
+				}
+				var netErr net.Error
+				if errors.As(err, &netErr) && netErr.Timeout() && time.Now().Before(deadline) {
+					break // resend
+				}
+				return nil, &radiusTransportError{err}
+			}
+
+			raw := buf[:n]
+			if n < 20 || raw[1] != wire[1] || !radius.IsAuthenticResponse(raw, wire, p.secret) {
+				continue
+			}
+			if err := verifyMessageAuthenticator(raw, wire[4:20], p.secret, p.config.RequireMessageAuthenticator); err != nil {
+				logx.WithContext(ctx).Errorf("Discarding RADIUS response from %s: %v", server, err)
+				continue
+			}
+			response, err := radius.Parse(append([]byte(nil), raw...), p.secret)
+			if err != nil {
+				continue
+			}
+			return response, nil
+		}
+	}
+}
+
+// setMessageAuthenticator 添加 Message-Authenticator 属性: 将该属性置零后对整个请求计算 HMAC-MD5
+func setMessageAuthenticator(packet *radius.Packet) error {
+	packet.Set(radiusMessageAuthenticatorType, make(radius.Attribute, md5.Size))
+	wire, err := packet.Encode()
+	if err != nil {
+		return fmt.Errorf("failed to encode RADIUS request: %w", err)
+	}
+	mac := hmac.New(md5.New, packet.Secret)
+	mac.Write(wire)
+	packet.Set(radiusMessageAuthenticatorType, mac.Sum(nil))
+	return nil
+}
+
+// verifyMessageAuthenticator 校验响应中的 Message-Authenticator: 以请求的 Authenticator 替换响应的
+// Authenticator、将该属性置零后计算 HMAC-MD5。响应中没有该属性时，required 为 false 则不校验
+func verifyMessageAuthenticator(response, requestAuthenticator, secret []byte, required bool) error {
+	offset := -1
+	for i := 20; i+2 <= len(response); {
+		length := int(response[i+1])
+		if length < 2 || i+length > len(response) {
+			return errors.New("malformed attributes")
+		}
+		if radius.Type(response[i]) == radiusMessageAuthenticatorType && length == 2+md5.Size {
+			offset = i + 2
+			break
+		}
+		i += length
+	}
+	if offset < 0 {
+		if required {
+			return errors.New("missing Message-Authenticator")
+		}
+		return nil
+	}
+
+	data := append([]byte(nil), response...)
+	copy(data[4:20], requestAuthenticator)
+	clear(data[offset : offset+md5.Size])
+	mac := hmac.New(md5.New, secret)
+	mac.Write(data)
+	if !hmac.Equal(mac.Sum(nil), response[offset:offset+md5.Size]) {
+		return errors.New("invalid Message-Authenticator")
+	}
+	return nil
+}
+
+// padUserPassword 密码以 NUL 补齐到 16 字节的整数倍后加密 (RFC 2865 5.2)
+func padUserPassword(password string) []byte {
+	size := (len(password) + 15) / 16 * 16
+	if size == 0 {
+		size = 16
+	}
+	padded := make([]byte, size)
+	copy(padded, password)
+	return padded
+}
+
+// replyMessage 合并响应中的所有 Reply-Message 属性
+func replyMessage(packet *radius.Packet) string {
+	var lines []string
+	for _, attr := range packet.Attributes[rfc2865.ReplyMessage_Type] {
+		lines = append(lines, string(bytes.TrimRight(attr, "\x00")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// candidates 本次认证应依次尝试的服务器: 健康的服务器按配置顺序，冷却中的服务器排在最后
+func (p *RADIUSProvider) candidates() []string {
+	return p.health.order(p.servers, false)
+}
@@ -0,0 +1,201 @@
+package svc
+
+import (
+	"crypto/des"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+)
+
+const (
+	// radiusVendorMicrosoft Microsoft 的 Vendor-Id (RFC 2548)
+	radiusVendorMicrosoft = 311
+
+	msCHAPError      byte = 2  // MS-CHAP-Error
+	msCHAPChallenge  byte = 11 // MS-CHAP-Challenge
+	msCHAP2Response  byte = 25 // MS-CHAP2-Response
+	msCHAP2Success   byte = 26 // MS-CHAP2-Success
+	msCHAPv2RespSize      = 50 // Ident + Flags + Peer-Challenge + Reserved + NT-Response
+)
+
+var (
+	msCHAPv2Magic1 = []byte("Magic server to client signing constant")
+	msCHAPv2Magic2 = []byte("Pad to make it do more than one iteration")
+)
+
+// msCHAPv2Exchange 一次 MS-CHAPv2 认证 (RFC 2759)。NAS 同时充当认证方，自行生成认证方挑战，
+// 服务器在 Access-Accept 中返回 MS-CHAP2-Success，据此确认服务器也知道用户密码
+type msCHAPv2Exchange struct {
+	username      string
+	passwordHash  []byte
+	authChallenge []byte
+	peerChallenge []byte
+	ntResponse    []byte
+	ident         byte
+}
+
+func newMSCHAPv2Exchange(username, password string) (*msCHAPv2Exchange, error) {
+	random := make([]byte, 16+16+1)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("failed to generate MS-CHAPv2 challenge: %w", err)
+	}
+
+	m := &msCHAPv2Exchange{
+		username:      username,
+		passwordHash:  ntPasswordHash(password),
+		authChallenge: random[:16],
+		peerChallenge: random[16:32],
+		ident:         random[32],
+	}
+	m.ntResponse = challengeResponse(challengeHash(m.peerChallenge, m.authChallenge, username), m.passwordHash)
+	return m, nil
+}
+
+// addTo 添加 MS-CHAP-Challenge 与 MS-CHAP2-Response 属性 (RFC 2548)
+func (m *msCHAPv2Exchange) addTo(packet *radius.Packet) error {
+	response := make([]byte, msCHAPv2RespSize)
+	response[0] = m.ident
+	copy(response[2:18], m.peerChallenge)
+	copy(response[26:50], m.ntResponse)
+
+	for _, attr := range []struct {
+		typ   byte
+		value []byte
+	}{
+		{msCHAPChallenge, m.authChallenge},
+		{msCHAP2Response, response},
+	} {
+		vsa, err := radius.NewVendorSpecific(radiusVendorMicrosoft, msVendorAttribute(attr.typ, attr.value))
+		if err != nil {
+			return err
+		}
+		packet.Add(rfc2865.VendorSpecific_Type, vsa)
+	}
+	return nil
+}
+
+// verifySuccess 校验 Access-Accept 中 MS-CHAP2-Success 的认证方响应 ("S=" + 40 位十六进制)
+func (m *msCHAPv2Exchange) verifySuccess(packet *radius.Packet) error {
+	success := msVendorAttributes(packet)[msCHAP2Success]
+	if len(success) < 1+42 {
+		return fmt.Errorf("%w: missing MS-CHAP2-Success", ErrRADIUSInvalidResponse)
+	}
+	expected := m.authenticatorResponse()
+	if subtle.ConstantTimeCompare([]byte(strings.ToUpper(string(success[1:43]))), []byte(expected)) != 1 {
+		return fmt.Errorf("%w: MS-CHAPv2 authenticator response mismatch", ErrRADIUSInvalidResponse)
+	}
+	return nil
+}
+
+// authenticatorResponse GenerateAuthenticatorResponse (RFC 2759 8.7)
+func (m *msCHAPv2Exchange) authenticatorResponse() string {
+	hash := md4.New()
+	hash.Write(m.passwordHash)
+	passwordHashHash := hash.Sum(nil)
+
+	digest := sha1.New()
+	digest.Write(passwordHashHash)
+	digest.Write(m.ntResponse)
+	digest.Write(msCHAPv2Magic1)
+	sum := digest.Sum(nil)
+
+	digest = sha1.New()
+	digest.Write(sum)
+	digest.Write(challengeHash(m.peerChallenge, m.authChallenge, m.username))
+	digest.Write(msCHAPv2Magic2)
+	return "S=" + strings.ToUpper(hex.EncodeToString(digest.Sum(nil)))
+}
+
+// challengeHash ChallengeHash (RFC 2759 8.2)，用户名不含域名前缀
+func challengeHash(peerChallenge, authChallenge []byte, username string) []byte {
+	if i := strings.LastIndexByte(username, '\\'); i >= 0 {
+		username = username[i+1:]
+	}
+	hash := sha1.New()
+	hash.Write(peerChallenge)
+	hash.Write(authChallenge)
+	hash.Write([]byte(username))
+	return hash.Sum(nil)[:8]
+}
+
+// ntPasswordHash NtPasswordHash (RFC 2759 8.3): 密码 UTF-16LE 编码后的 MD4
+func ntPasswordHash(password string) []byte {
+	units := utf16.Encode([]rune(password))
+	encoded := make([]byte, 0, len(units)*2)
+	for _, u := range units {
+		encoded = append(encoded, byte(u), byte(u>>8))
+	}
+	hash := md4.New()
+	hash.Write(encoded)
+	return hash.Sum(nil)
+}
+
+// challengeResponse ChallengeResponse (RFC 2759 8.5): 密码哈希补零到 21 字节，分为 3 个 DES 密钥分别加密挑战
+func challengeResponse(challenge, passwordHash []byte) []byte {
+	key := make([]byte, 21)
+	copy(key, passwordHash)
+
+	response := make([]byte, 24)
+	for i := 0; i < 3; i++ {
+		block, _ := des.NewCipher(desKey(key[i*7 : i*7+7]))
+		block.Encrypt(response[i*8:], challenge)
+	}
+	return response
+}
+
+// desKey 将 56 位密钥扩展为 DES 的 8 字节密钥 (奇偶校验位不参与加密)
+func desKey(key []byte) []byte {
+	return []byte{
+		key[0],
+		key[0]<<7 | key[1]>>1,
+		key[1]<<6 | key[2]>>2,
+		key[2]<<5 | key[3]>>3,
+		key[3]<<4 | key[4]>>4,
+		key[4]<<3 | key[5]>>5,
+		key[5]<<2 | key[6]>>6,
+		key[6] << 1,
+	}
+}
+
+// msVendorAttribute 编码 Microsoft 厂商子属性: Vendor-Type + Vendor-Length + 值
+func msVendorAttribute(typ byte, value []byte) radius.Attribute {
+	return append(radius.Attribute{typ, byte(len(value) + 2)}, value...)
+}
+
+// msVendorAttributes 解析包中的 Microsoft 厂商子属性，同一类型只保留第一个
+func msVendorAttributes(packet *radius.Packet) map[byte][]byte {
+	attrs := make(map[byte][]byte)
+	for _, vsa := range packet.Attributes[rfc2865.VendorSpecific_Type] {
+		vendorID, value, err := radius.VendorSpecific(vsa)
+		if err != nil || vendorID != radiusVendorMicrosoft {
+			continue
+		}
+		for len(value) >= 2 {
+			length := int(value[1])
+			if length < 2 || length > len(value) {
+				break
+			}
+			if _, ok := attrs[value[0]]; !ok {
+				attrs[value[0]] = value[2:length]
+			}
+			value = value[length:]
+		}
+	}
+	return attrs
+}
+
+// msCHAPErrorMessage Access-Reject 中 MS-CHAP-Error 的内容 (如 "E=648 R=0 V=3"，648 表示密码已过期)
+func msCHAPErrorMessage(packet *radius.Packet) string {
+	if value := msVendorAttributes(packet)[msCHAPError]; len(value) > 1 {
+		return string(value[1:])
+	}
+	return ""
+}
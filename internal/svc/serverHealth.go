@@ -0,0 +1,69 @@
+package svc
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// serverHealth 多台服务器的健康状态与故障转移顺序 (LDAP 与 RADIUS 共用)。
+// 连接失败的服务器在冷却时间内排到最后，所有服务器都失败时仍会尝试
+type serverHealth[S comparable] struct {
+	cooldown time.Duration
+
+	mu        sync.Mutex
+	unhealthy map[S]time.Time // 服务器 -> 冷却结束时间
+	next      int
+}
+
+func newServerHealth[S comparable](cooldown time.Duration) *serverHealth[S] {
+	return &serverHealth[S]{
+		cooldown:  cooldown,
+		unhealthy: make(map[S]time.Time),
+	}
+}
+
+// order 返回本次应依次尝试的服务器: 健康的服务器保持给定顺序 (roundRobin 时轮换起点)，冷却中的服务器排在最后
+func (h *serverHealth[S]) order(servers []S, roundRobin bool) []S {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	healthy := make([]S, 0, len(servers))
+	var cooling []S
+	for _, server := range servers {
+		if until, ok := h.unhealthy[server]; ok && now.Before(until) {
+			cooling = append(cooling, server)
+			continue
+		}
+		delete(h.unhealthy, server)
+		healthy = append(healthy, server)
+	}
+
+	if roundRobin && len(healthy) > 1 {
+		start := h.next % len(healthy)
+		h.next++
+		rotated := make([]S, 0, len(healthy))
+		healthy = append(append(rotated, healthy[start:]...), healthy[:start]...)
+	}
+
+	// Servers that failed longest ago are the most likely to be back
+	sort.SliceStable(cooling, func(i, j int) bool {
+		return h.unhealthy[cooling[i]].Before(h.unhealthy[cooling[j]])
+	})
+	return append(healthy, cooling...)
+}
+
+// markFailed 标记服务器连接失败，冷却时间内优先尝试其它服务器
+func (h *serverHealth[S]) markFailed(server S) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.unhealthy[server] = time.Now().Add(h.cooldown)
+}
+
+// markHealthy 服务器连接成功，结束冷却
+func (h *serverHealth[S]) markHealthy(server S) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.unhealthy, server)
+}
@@ -11,6 +11,7 @@ import (
 
 	"auth-service/internal/config"
 	middleware "auth-service/internal/middleware"
+	"auth-service/internal/types"
 	model "auth-service/model/mysql"
 
 	"github.com/mojocn/base64Captcha"
@@ -32,9 +33,30 @@ type ServiceContext struct {
 	Sonyflake       *sonyflake.Sonyflake
 	UserModel       model.UserModel
 
+	// UserIdentityModel 用户与外部身份 (OIDC / OAuth2 / LDAP) 的关联
+	UserIdentityModel model.UserIdentityModel
+
+	// UserRoleModel 用户角色
+	UserRoleModel model.UserRoleModel
+
 	// SSO Providers
-	OIDC OIDCClient
+	OIDC   *OIDCProviders
+	OAuth2 *OAuth2Providers
+
+	// LDAP 目录客户端，用于目录同步与密码修改；LDAP 登录通过 IdentityProviders 中的注册项完成
 	LDAP LDAPClient
+
+	// IdentityProviders 以用户名与密码登录的身份提供者 (LDAP、RADIUS 等)，包括各自的自动开通策略
+	IdentityProviders *IdentityProviders
+
+	// UpstreamTokens 加密保存的上游 IdP 令牌 (未启用令牌代理时为 nil)
+	UpstreamTokens *UpstreamTokenStore
+
+	// Email 通知邮件发送 (未配置 SMTP 时为 nil)
+	Email EmailSender
+
+	// SMS 短信验证码发送 (未配置短信网关时为 nil)
+	SMS SMSSender
 }
 
 func NewServiceContext(c config.Config) *ServiceContext {
@@ -73,25 +95,69 @@ func NewServiceContext(c config.Config) *ServiceContext {
 		// 适配CustomClaims到middleware.Claims接口
 		return &JwtClaimsAdapter{Claims: claims}, nil
 	})
+	// 邮箱未验证的受限令牌只能查看用户信息
+	authInterceptor.AllowScope(ScopeEmailUnverified, "/api/v1/me")
 
 	// 初始化 SSO 提供者
-	oidcProvider, ldapProvider := initSSOProviders(c)
+	oidcProviders, ldapProvider, identityProviders := initSSOProviders(c)
 
 	return &ServiceContext{
-		Config:          c,
-		DB:              db,
-		Redis:           rdb,
-		PasswordEncoder: &PasswordEncoder{},
-		Captcha:         captcha,
-		JWT:             jwtService,
-		AuthInterceptor: authInterceptor.Handle,
-		Sonyflake:       initSonyflake(),
-		UserModel:       model.NewUserModel(db),
-		OIDC:            oidcProvider,
-		LDAP:            ldapProvider,
+		Config:            c,
+		DB:                db,
+		Redis:             rdb,
+		PasswordEncoder:   &PasswordEncoder{},
+		Captcha:           captcha,
+		JWT:               jwtService,
+		AuthInterceptor:   authInterceptor.Handle,
+		Sonyflake:         initSonyflake(),
+		UserModel:         model.NewUserModel(db),
+		UserIdentityModel: model.NewUserIdentityModel(db),
+		UserRoleModel:     model.NewUserRoleModel(db),
+		OIDC:              oidcProviders,
+		OAuth2:            initOAuth2Providers(c),
+		LDAP:              ldapProvider,
+		IdentityProviders: identityProviders,
+		UpstreamTokens:    initUpstreamTokenStore(c, rdb),
+		Email:             initEmailSender(c),
+		SMS:               initSMSSender(c),
 	}
 }
 
+// Stop 停止 OIDC 发现文档的后台刷新并关闭 LDAP 连接池，服务退出时调用
+func (s *ServiceContext) Stop() {
+	s.OIDC.Stop()
+	if closer, ok := s.LDAP.(interface{ Close() }); ok {
+		closer.Close()
+	}
+}
+
+// initSMSSender 初始化短信发送，未配置或配置错误时返回 nil (手机号验证不可用)
+func initSMSSender(c config.Config) SMSSender {
+	sender, err := NewSMSSender(c.SMS)
+	if err != nil {
+		logx.Errorf("Failed to initialize SMS sender: %v", err)
+		return nil
+	}
+	if sender != nil {
+		logx.Infof("SMS sender %s initialized successfully", c.SMS.Provider)
+	}
+	return sender
+}
+
+// initEmailSender 初始化邮件发送，未配置 SMTP 服务器时返回 nil
+func initEmailSender(c config.Config) EmailSender {
+	if c.Email.Host == "" {
+		return nil
+	}
+	return NewClient(&Config{
+		Host:     c.Email.Host,
+		Port:     c.Email.Port,
+		Username: c.Email.Username,
+		Password: c.Email.Password,
+		From:     c.Email.From,
+	})
+}
+
 func initDatabase(c config.Config) (sqlx.SqlConn, error) {
 	// 初始化 MySQL 连接
 	conn := sqlx.NewMysql(c.Mysql.DataSource)
@@ -142,22 +208,67 @@ func initSonyflake() *sonyflake.Sonyflake {
 	return sonyflake.NewSonyflake(settings)
 }
 
+// initUpstreamTokenStore 初始化上游令牌存储，未启用令牌代理时返回 nil
+func initUpstreamTokenStore(c config.Config, rdb redis.UniversalClient) *UpstreamTokenStore {
+	broker := c.SSO.TokenBroker
+	if !broker.Enabled {
+		return nil
+	}
+
+	cipher, err := NewTokenCipher(broker.EncryptionKey)
+	if err != nil {
+		logx.Errorf("Failed to initialize token broker: %v", err)
+		return nil
+	}
+	return NewUpstreamTokenStore(rdb, cipher, time.Duration(broker.TokenTTL)*time.Second)
+}
+
 // initSSOProviders 初始化 SSO 提供者
-func initSSOProviders(c config.Config) (*OIDCProvider, *LDAPProvider) {
+func initSSOProviders(c config.Config) (*OIDCProviders, *LDAPProvider, *IdentityProviders) {
 	var (
-		oidcProvider *OIDCProvider
-		ldapProvider *LDAPProvider
-		err          error
+		oidcProviders     = NewOIDCProviders()
+		ldapProvider      *LDAPProvider
+		identityProviders = NewIdentityProviders()
+		err               error
 	)
 
-	// 初始化 OIDC 提供者
+	// 初始化 OIDC 提供者 (兼容单个 OIDC 配置与多个命名提供者)
+	oidcConfigs := c.SSO.OIDCProviders
 	if c.SSO.OIDC.Enabled {
-		oidcProvider, err = NewOIDCProvider(c.SSO.OIDC)
+		oidcConfigs = append([]config.OIDCConfig{c.SSO.OIDC}, oidcConfigs...)
+	}
+	for _, oc := range oidcConfigs {
+		if !oc.Enabled {
+			continue
+		}
+		if oc.Name == "" {
+			oc.Name = DefaultOIDCProviderName
+		}
+
+		mapper, err := NewClaimMapper(DefaultOIDCClaimMapping, oc.ClaimMapping)
 		if err != nil {
-			logx.Errorf("Failed to initialize OIDC provider: %v", err)
-		} else {
-			logx.Info("OIDC provider initialized successfully")
+			logx.Errorf("Invalid claim mapping for OIDC provider %s: %v", oc.Name, err)
+			continue
+		}
+		provider, err := NewOIDCProvider(oc)
+		if err != nil {
+			logx.Errorf("Failed to initialize OIDC provider %s: %v", oc.Name, err)
+			continue
+		}
+		if !oidcProviders.Register(OIDCProviderEntry{
+			Name:        oc.Name,
+			DisplayName: oc.DisplayName,
+			Icon:        oc.Icon,
+			Client:      provider,
+			Mapper:      mapper,
+			TrustEmail:  oc.TrustEmail,
+
+			Provisioning: NewProvisioningPolicy(oc.Provisioning),
+		}) {
+			logx.Errorf("Duplicate OIDC provider name: %s", oc.Name)
+			continue
 		}
+		logx.Infof("OIDC provider %s initialized successfully", oc.Name)
 	}
 
 	// 初始化 LDAP 提供者
@@ -166,11 +277,65 @@ func initSSOProviders(c config.Config) (*OIDCProvider, *LDAPProvider) {
 		if err != nil {
 			logx.Errorf("Failed to initialize LDAP provider: %v", err)
 		} else {
+			identityProviders.Register(NewLDAPIdentityEntry(ldapProvider, NewProvisioningPolicy(c.SSO.LDAP.Provisioning)))
 			logx.Info("LDAP provider initialized successfully")
 		}
 	}
 
-	return oidcProvider, ldapProvider
+	// 初始化 RADIUS 提供者
+	if c.SSO.RADIUS.Enabled {
+		radiusProvider, err := NewRADIUSProvider(c.SSO.RADIUS)
+		if err != nil {
+			logx.Errorf("Failed to initialize RADIUS provider: %v", err)
+		} else {
+			identityProviders.Register(IdentityProviderEntry{
+				Type:        types.SSOProviderRADIUS,
+				DisplayName: "RADIUS",
+				Provider:    NewRADIUSIdentityProvider(radiusProvider),
+
+				Provisioning: NewProvisioningPolicy(c.SSO.RADIUS.Provisioning),
+			})
+			logx.Info("RADIUS provider initialized successfully")
+		}
+	}
+
+	return oidcProviders, ldapProvider, identityProviders
+}
+
+// initOAuth2Providers 初始化社交 OAuth2 登录提供者
+func initOAuth2Providers(c config.Config) *OAuth2Providers {
+	providers := NewOAuth2Providers()
+
+	for _, oc := range c.SSO.OAuth2 {
+		if !oc.Enabled {
+			continue
+		}
+		if oc.Name == "" {
+			oc.Name = oc.Type
+		}
+
+		provider, err := NewOAuth2Provider(oc)
+		if err != nil {
+			logx.Errorf("Failed to initialize OAuth2 provider %s: %v", oc.Name, err)
+			continue
+		}
+		if !providers.Register(OAuth2ProviderEntry{
+			Name:        oc.Name,
+			Type:        oc.Type,
+			DisplayName: oc.DisplayName,
+			Icon:        oc.Icon,
+			Client:      provider,
+			TrustEmail:  oc.TrustEmail,
+
+			Provisioning: NewProvisioningPolicy(oc.Provisioning),
+		}) {
+			logx.Errorf("Duplicate OAuth2 provider name: %s", oc.Name)
+			continue
+		}
+		logx.Infof("OAuth2 provider %s (%s) initialized successfully", oc.Name, oc.Type)
+	}
+
+	return providers
 }
 
 // JwtClaimsAdapter 适配CustomClaims到middleware.Claims接口
@@ -188,6 +353,11 @@ func (a *JwtClaimsAdapter) VerifyExpiresAt() bool {
 	return a.Claims.StandardClaims.ExpiresAt > time.Now().Unix()
 }
 
+// GetScope 获取受限令牌的访问范围
+func (a *JwtClaimsAdapter) GetScope() string {
+	return a.Claims.Scope
+}
+
 func getMachineID() (uint16, error) {
 	// 1. 从环境变量获取
 	if machineID := os.Getenv("SNOWFLAKE_MACHINE_ID"); machineID != "" {
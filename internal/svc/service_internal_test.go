@@ -1,6 +1,7 @@
 package svc
 
 import (
+	"encoding/hex"
 	"os"
 	"testing"
 	"time"
@@ -64,14 +65,43 @@ func TestInitSSOProviders(t *testing.T) {
 	c.SSO.OIDC.Enabled = false
 	c.SSO.LDAP.Enabled = false
 
-	oidc, ldap := initSSOProviders(c)
-	assert.Nil(t, oidc)
+	oidc, ldap, identity := initSSOProviders(c)
+	assert.Empty(t, oidc.List())
 	assert.Nil(t, ldap)
+	assert.Empty(t, identity.List())
 
 	// Enable
 	c.SSO.OIDC.Enabled = true
 	c.SSO.LDAP.Enabled = true
 
 	// Just ensure it doesn't panic
-	oidc, ldap = initSSOProviders(c)
+	oidc, ldap, identity = initSSOProviders(c)
+
+	// RADIUS is registered as an identity provider
+	c.SSO.RADIUS = config.RADIUSConfig{Enabled: true, Secret: "secret", Servers: []string{"127.0.0.1"}}
+	_, _, identity = initSSOProviders(c)
+	entry, ok := identity.Get("radius")
+	assert.True(t, ok)
+	assert.Equal(t, "RADIUS", entry.DisplayName)
+}
+
+func TestMSCHAPv2Vectors(t *testing.T) {
+	// RFC 2759 9.2
+	unhex := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		assert.NoError(t, err)
+		return b
+	}
+	m := &msCHAPv2Exchange{
+		username:      "User",
+		passwordHash:  ntPasswordHash("clientPass"),
+		authChallenge: unhex("5B5D7C7D7B3F2F3E3C2C602132262628"),
+		peerChallenge: unhex("21402324255E262A28295F2B3A337C7E"),
+	}
+	assert.Equal(t, unhex("44EBBA8D5312B8D611474411F56989AE"), m.passwordHash)
+	assert.Equal(t, unhex("D02E4386BCE91226"), challengeHash(m.peerChallenge, m.authChallenge, m.username))
+
+	m.ntResponse = challengeResponse(challengeHash(m.peerChallenge, m.authChallenge, m.username), m.passwordHash)
+	assert.Equal(t, unhex("82309ECD8D708B5EA08FAA3981CD83544233114A3D85D6DF"), m.ntResponse)
+	assert.Equal(t, "S=407A5589115FD0D6209F510FE9C04566932CDA56", m.authenticatorResponse())
 }
@@ -0,0 +1,346 @@
+package svc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"auth-service/internal/config"
+
+	"github.com/google/uuid"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// 短信网关
+const (
+	SMSProviderLog     = "log"
+	SMSProviderAliyun  = "aliyun"
+	SMSProviderTencent = "tencent"
+	SMSProviderTwilio  = "twilio"
+)
+
+// NewSMSSender 按配置创建短信发送，未配置短信网关时返回 nil
+func NewSMSSender(c config.SMSConfig) (SMSSender, error) {
+	timeout := time.Duration(c.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	switch c.Provider {
+	case "":
+		return nil, nil
+	case SMSProviderLog:
+		return &logSMSSender{file: c.LogFile}, nil
+	case SMSProviderAliyun:
+		ac := c.Aliyun
+		if ac.AccessKeyID == "" || ac.AccessKeySecret == "" || ac.SignName == "" || ac.TemplateCode == "" {
+			return nil, errors.New("aliyun SMS requires AccessKeyID, AccessKeySecret, SignName and TemplateCode")
+		}
+		if ac.RegionID == "" {
+			ac.RegionID = "cn-hangzhou"
+		}
+		if ac.Endpoint == "" {
+			ac.Endpoint = "https://dysmsapi.aliyuncs.com"
+		}
+		return &aliyunSMSSender{config: ac, client: client}, nil
+	case SMSProviderTencent:
+		tc := c.Tencent
+		if tc.SecretID == "" || tc.SecretKey == "" || tc.SdkAppID == "" || tc.SignName == "" || tc.TemplateID == "" {
+			return nil, errors.New("tencent SMS requires SecretID, SecretKey, SdkAppID, SignName and TemplateID")
+		}
+		if tc.Region == "" {
+			tc.Region = "ap-guangzhou"
+		}
+		if tc.Endpoint == "" {
+			tc.Endpoint = "https://sms.tencentcloudapi.com"
+		}
+		return &tencentSMSSender{config: tc, client: client}, nil
+	case SMSProviderTwilio:
+		tw := c.Twilio
+		if tw.AccountSID == "" || tw.AuthToken == "" || (tw.From == "" && tw.MessagingServiceSID == "") {
+			return nil, errors.New("twilio SMS requires AccountSID, AuthToken and From or MessagingServiceSID")
+		}
+		if tw.Body == "" {
+			tw.Body = "Your verification code is {code}"
+		}
+		if tw.Endpoint == "" {
+			tw.Endpoint = "https://api.twilio.com"
+		}
+		return &twilioSMSSender{config: tw, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unsupported SMS provider: %s", c.Provider)
+	}
+}
+
+// logSMSSender 本地开发使用: 不发送短信，将验证码写入文件或服务日志
+type logSMSSender struct {
+	file string
+	mu   sync.Mutex
+}
+
+func (s *logSMSSender) SendVerificationCode(ctx context.Context, phone, code string) error {
+	if s.file == "" {
+		logx.WithContext(ctx).Infof("SMS verification code for %s: %s", phone, code)
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open SMS log file: %w", err)
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s\t%s\t%s\n", time.Now().Format(time.RFC3339), phone, code)
+	return err
+}
+
+// aliyunSMSSender 阿里云短信服务 SendSms (RPC 风格接口，HMAC-SHA1 签名)
+type aliyunSMSSender struct {
+	config config.AliyunSMSConfig
+	client *http.Client
+}
+
+func (s *aliyunSMSSender) SendVerificationCode(ctx context.Context, phone, code string) error {
+	templateParam, err := json.Marshal(map[string]string{"code": code})
+	if err != nil {
+		return err
+	}
+	params := url.Values{
+		"AccessKeyId":      {s.config.AccessKeyID},
+		"Action":           {"SendSms"},
+		"Format":           {"JSON"},
+		"PhoneNumbers":     {phone},
+		"RegionId":         {s.config.RegionID},
+		"SignName":         {s.config.SignName},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureNonce":   {uuid.New().String()},
+		"SignatureVersion": {"1.0"},
+		"TemplateCode":     {s.config.TemplateCode},
+		"TemplateParam":    {string(templateParam)},
+		"Timestamp":        {time.Now().UTC().Format("2006-01-02T15:04:05Z")},
+		"Version":          {"2017-05-25"},
+	}
+	params.Set("Signature", AliyunSignature(http.MethodGet, params, s.config.AccessKeySecret))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.config.Endpoint+"/?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	var result struct {
+		Code      string `json:"Code"`
+		Message   string `json:"Message"`
+		RequestID string `json:"RequestId"`
+	}
+	if err := doSMSRequest(s.client, req, &result); err != nil {
+		return fmt.Errorf("aliyun SMS request failed: %w", err)
+	}
+	if result.Code != "OK" {
+		return fmt.Errorf("aliyun SMS rejected the message: %s: %s (request %s)", result.Code, result.Message, result.RequestID)
+	}
+	return nil
+}
+
+// AliyunSignature 阿里云 RPC 接口签名: 参数按名称排序后编码，以 "AccessKeySecret&" 为密钥计算 HMAC-SHA1
+func AliyunSignature(method string, params url.Values, secret string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if k != "Signature" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, aliyunPercentEncode(k)+"="+aliyunPercentEncode(params.Get(k)))
+	}
+	stringToSign := method + "&" + aliyunPercentEncode("/") + "&" + aliyunPercentEncode(strings.Join(pairs, "&"))
+
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// aliyunPercentEncode RFC 3986 编码 (空格编码为 %20，保留 ~)
+func aliyunPercentEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
+
+// tencentSMSSender 腾讯云短信 SendSms (API 3.0，TC3-HMAC-SHA256 签名)
+type tencentSMSSender struct {
+	config config.TencentSMSConfig
+	client *http.Client
+}
+
+func (s *tencentSMSSender) SendVerificationCode(ctx context.Context, phone, code string) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"PhoneNumberSet":   []string{phone},
+		"SmsSdkAppId":      s.config.SdkAppID,
+		"SignName":         s.config.SignName,
+		"TemplateId":       s.config.TemplateID,
+		"TemplateParamSet": []string{code},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint, err := url.Parse(s.config.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid tencent SMS endpoint: %w", err)
+	}
+	now := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.Endpoint, strings.NewReader(string(payload)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", TencentAuthorization(s.config.SecretID, s.config.SecretKey, endpoint.Host, payload, now))
+	req.Header.Set("X-TC-Action", "SendSms")
+	req.Header.Set("X-TC-Version", "2021-01-11")
+	req.Header.Set("X-TC-Region", s.config.Region)
+	req.Header.Set("X-TC-Timestamp", fmt.Sprintf("%d", now.Unix()))
+
+	var result struct {
+		Response struct {
+			SendStatusSet []struct {
+				Code    string `json:"Code"`
+				Message string `json:"Message"`
+			} `json:"SendStatusSet"`
+			Error *struct {
+				Code    string `json:"Code"`
+				Message string `json:"Message"`
+			} `json:"Error"`
+			RequestID string `json:"RequestId"`
+		} `json:"Response"`
+	}
+	if err := doSMSRequest(s.client, req, &result); err != nil {
+		return fmt.Errorf("tencent SMS request failed: %w", err)
+	}
+	resp := result.Response
+	if resp.Error != nil {
+		return fmt.Errorf("tencent SMS rejected the request: %s: %s (request %s)", resp.Error.Code, resp.Error.Message, resp.RequestID)
+	}
+	if len(resp.SendStatusSet) == 0 || resp.SendStatusSet[0].Code != "Ok" {
+		status := "no send status"
+		if len(resp.SendStatusSet) > 0 {
+			status = resp.SendStatusSet[0].Code + ": " + resp.SendStatusSet[0].Message
+		}
+		return fmt.Errorf("tencent SMS rejected the message: %s (request %s)", status, resp.RequestID)
+	}
+	return nil
+}
+
+// TencentAuthorization 腾讯云 API 3.0 的 TC3-HMAC-SHA256 签名，签名 content-type 与 host 两个请求头
+func TencentAuthorization(secretID, secretKey, host string, payload []byte, now time.Time) string {
+	const (
+		algorithm = "TC3-HMAC-SHA256"
+		service   = "sms"
+	)
+	date := now.UTC().Format("2006-01-02")
+	payloadHash := sha256.Sum256(payload)
+	canonicalRequest := strings.Join([]string{
+		http.MethodPost,
+		"/",
+		"",
+		"content-type:application/json; charset=utf-8\nhost:" + host + "\n",
+		"content-type;host",
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + service + "/tc3_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{algorithm, fmt.Sprintf("%d", now.Unix()), scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	secretDate := hmacSHA256([]byte("TC3"+secretKey), date)
+	secretService := hmacSHA256(secretDate, service)
+	secretSigning := hmacSHA256(secretService, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(secretSigning, stringToSign))
+
+	return fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=content-type;host, Signature=%s", algorithm, secretID, scope, signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// twilioSMSSender Twilio Messaging API
+type twilioSMSSender struct {
+	config config.TwilioSMSConfig
+	client *http.Client
+}
+
+func (s *twilioSMSSender) SendVerificationCode(ctx context.Context, phone, code string) error {
+	form := url.Values{
+		"To":   {phone},
+		"Body": {strings.ReplaceAll(s.config.Body, "{code}", code)},
+	}
+	if s.config.MessagingServiceSID != "" {
+		form.Set("MessagingServiceSid", s.config.MessagingServiceSID)
+	} else {
+		form.Set("From", s.config.From)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", s.config.Endpoint, url.PathEscape(s.config.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.config.AccountSID, s.config.AuthToken)
+
+	var result struct {
+		SID     string `json:"sid"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := doSMSRequest(s.client, req, &result); err != nil {
+		if result.Message != "" {
+			return fmt.Errorf("twilio rejected the message: %d: %s", result.Code, result.Message)
+		}
+		return fmt.Errorf("twilio request failed: %w", err)
+	}
+	return nil
+}
+
+// doSMSRequest 发送请求并解析 JSON 响应，非 2xx 状态码时仍解析响应 (网关在其中返回错误原因) 并返回错误
+func doSMSRequest(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	decodeErr := json.Unmarshal(body, out)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("failed to decode response: %w", decodeErr)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package svc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SSOHandoffTTL 一次性交换码的有效期
+const SSOHandoffTTL = time.Minute
+
+// ErrInvalidHandoffCode 交换码不存在、已过期或已被使用
+var ErrInvalidHandoffCode = errors.New("invalid or expired handoff code")
+
+func ssoHandoffKey(code string) string {
+	return fmt.Sprintf("auth:sso:handoff:%s", code)
+}
+
+// CreateSSOHandoff 保存 SSO 登录结果并返回一次性交换码。
+// 浏览器回调只携带该交换码，令牌由前端通过交换接口获取，不会出现在 URL 或浏览历史中。
+func CreateSSOHandoff(ctx context.Context, rdb redis.UniversalClient, payload interface{}) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode handoff payload: %w", err)
+	}
+
+	code, err := randomURLToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate handoff code: %w", err)
+	}
+
+	if err := rdb.Set(ctx, ssoHandoffKey(code), data, SSOHandoffTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store handoff code: %w", err)
+	}
+	return code, nil
+}
+
+// randomURLToken 生成 256 位随机令牌 (URL 安全的 base64 编码)
+func randomURLToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ConsumeSSOHandoff 原子地取出并删除交换码对应的登录结果，交换码只能使用一次
+func ConsumeSSOHandoff(ctx context.Context, rdb redis.UniversalClient, code string) (json.RawMessage, error) {
+	if code == "" {
+		return nil, ErrInvalidHandoffCode
+	}
+
+	data, err := rdb.GetDel(ctx, ssoHandoffKey(code)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidHandoffCode
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume handoff code: %w", err)
+	}
+	return json.RawMessage(data), nil
+}
+
+// IsAllowedRedirectURL 检查登录完成后的跳转地址是否允许。
+// 站内相对路径 (以单个 / 开头) 始终允许；绝对 URL 必须与白名单中某项的
+// scheme 和 host 完全一致，且路径以该项的路径为前缀。
+func IsAllowedRedirectURL(allowed []string, redirectURL string) bool {
+	// 浏览器会忽略跳转地址中的空白与控制字符并把 \ 当作 /，
+	// "/\t/evil.com" 和 "/\evil.com" 都会变成协议相对地址 "//evil.com"
+	if redirectURL == "" || strings.ContainsFunc(redirectURL, func(r rune) bool {
+		return r == '\\' || unicode.IsSpace(r) || unicode.IsControl(r)
+	}) {
+		return false
+	}
+
+	target, err := url.Parse(redirectURL)
+	if err != nil || target.User != nil {
+		return false
+	}
+	// 站内相对路径: 没有 scheme 与 host，且不是 "//evil.com" 这类协议相对地址
+	if target.Scheme == "" && target.Host == "" {
+		return strings.HasPrefix(target.Path, "/") && !strings.HasPrefix(redirectURL, "//")
+	}
+	if (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return false
+	}
+
+	for _, entry := range allowed {
+		base, err := url.Parse(entry)
+		if err != nil || base.Host == "" {
+			continue
+		}
+		if !strings.EqualFold(base.Scheme, target.Scheme) || !strings.EqualFold(base.Host, target.Host) {
+			continue
+		}
+		basePath := strings.TrimSuffix(base.Path, "/")
+		if basePath == "" || target.Path == basePath || strings.HasPrefix(target.Path, basePath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// AppendQuery 在跳转地址上追加查询参数，保留原有参数与片段
+func AppendQuery(redirectURL string, params url.Values) string {
+	u, err := url.Parse(redirectURL)
+	if err != nil {
+		return redirectURL
+	}
+	query := u.Query()
+	for key, values := range params {
+		for _, v := range values {
+			query.Set(key, v)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
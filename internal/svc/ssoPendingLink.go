@@ -0,0 +1,138 @@
+package svc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// PendingLinkTTL 待确认关联的有效期
+	PendingLinkTTL = 10 * time.Minute
+	// PendingLinkMaxAttempts 所有权验证的最大失败次数，超过后待确认关联作废
+	PendingLinkMaxAttempts = 5
+	// PendingLinkCodeInterval 两次发送邮箱验证码的最小间隔
+	PendingLinkCodeInterval = time.Minute
+)
+
+// ErrInvalidLinkToken 关联令牌不存在、已过期或已被使用
+var ErrInvalidLinkToken = errors.New("invalid or expired link token")
+
+// PendingLink 外部身份的邮箱与已有账号相同，但不满足自动关联条件 (IdP 未验证邮箱或提供者不受信任)。
+// 用户证明对已有账号的所有权 (密码或邮箱验证码) 后才建立关联。
+type PendingLink struct {
+	Provider       string       `json:"provider"`
+	ProviderUserID string       `json:"provider_user_id"`
+	Email          string       `json:"email,omitempty"`
+	UserID         uint64       `json:"user_id"`                   // 邮箱匹配到的本地用户
+	Session        *OIDCSession `json:"session,omitempty"`         // OIDC 登录的 IdP 会话，关联后用于登出
+	EmailCodeHash  string       `json:"email_code_hash,omitempty"` // 邮箱验证码的 SHA-256
+	CodeSentAt     int64        `json:"code_sent_at,omitempty"`
+	CreatedAt      int64        `json:"created_at"`
+}
+
+func pendingLinkKey(token string) string {
+	return fmt.Sprintf("auth:sso:pendinglink:%s", token)
+}
+
+// 失败次数单独计数，以 INCR 原子累加
+func pendingLinkAttemptsKey(token string) string {
+	return fmt.Sprintf("auth:sso:pendinglink:attempts:%s", token)
+}
+
+// CreatePendingLink 保存待确认关联并返回关联令牌
+func CreatePendingLink(ctx context.Context, rdb redis.UniversalClient, link *PendingLink) (string, error) {
+	token, err := randomURLToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate link token: %w", err)
+	}
+	link.CreatedAt = time.Now().Unix()
+
+	data, err := json.Marshal(link)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode pending link: %w", err)
+	}
+	if err := rdb.Set(ctx, pendingLinkKey(token), data, PendingLinkTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store pending link: %w", err)
+	}
+	return token, nil
+}
+
+// GetPendingLink 获取待确认关联
+func GetPendingLink(ctx context.Context, rdb redis.UniversalClient, token string) (*PendingLink, error) {
+	if token == "" {
+		return nil, ErrInvalidLinkToken
+	}
+
+	data, err := rdb.Get(ctx, pendingLinkKey(token)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidLinkToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending link: %w", err)
+	}
+
+	var link PendingLink
+	if err := json.Unmarshal(data, &link); err != nil {
+		return nil, ErrInvalidLinkToken
+	}
+	return &link, nil
+}
+
+// UpdatePendingLink 更新待确认关联，保持原有的过期时间
+func UpdatePendingLink(ctx context.Context, rdb redis.UniversalClient, token string, link *PendingLink) error {
+	data, err := json.Marshal(link)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending link: %w", err)
+	}
+	if err := rdb.SetArgs(ctx, pendingLinkKey(token), data, redis.SetArgs{Mode: "XX", KeepTTL: true}).Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to update pending link: %w", err)
+	}
+	return nil
+}
+
+// CountPendingLinkAttempt 累计所有权验证次数并返回包括本次在内的次数。
+// 在校验之前计数，并发的猜测请求也不会超过 PendingLinkMaxAttempts
+func CountPendingLinkAttempt(ctx context.Context, rdb redis.UniversalClient, token string) (int64, error) {
+	return incrCounter(ctx, rdb, pendingLinkAttemptsKey(token), PendingLinkTTL)
+}
+
+// ConsumePendingLink 删除待确认关联，令牌已被使用或已过期时返回 ErrInvalidLinkToken
+func ConsumePendingLink(ctx context.Context, rdb redis.UniversalClient, token string) error {
+	n, err := rdb.Del(ctx, pendingLinkKey(token)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to delete pending link: %w", err)
+	}
+	rdb.Del(ctx, pendingLinkAttemptsKey(token))
+	if n == 0 {
+		return ErrInvalidLinkToken
+	}
+	return nil
+}
+
+// SetEmailCode 生成 6 位邮箱验证码，仅保存其哈希
+func (l *PendingLink) SetEmailCode() (string, error) {
+	code, err := generateVerificationCode()
+	if err != nil {
+		return "", err
+	}
+	l.EmailCodeHash = hashEmailCode(code)
+	l.CodeSentAt = time.Now().Unix()
+	return code, nil
+}
+
+// VerifyEmailCode 校验邮箱验证码
+func (l *PendingLink) VerifyEmailCode(code string) bool {
+	return matchVerificationCode(l.EmailCodeHash, code)
+}
+
+func hashEmailCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,61 @@
+package svc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// TokenCipher 使用 AES-256-GCM 加密保存的敏感令牌
+type TokenCipher struct {
+	aead cipher.AEAD
+}
+
+// NewTokenCipher 创建令牌加密器，密钥为任意长度的字符串，经 SHA-256 派生为 AES-256 密钥
+func NewTokenCipher(key string) (*TokenCipher, error) {
+	if key == "" {
+		return nil, errors.New("encryption key is required")
+	}
+
+	derived := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(derived[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return &TokenCipher{aead: aead}, nil
+}
+
+// Encrypt 加密明文，返回 base64(nonce || ciphertext)
+func (c *TokenCipher) Encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 的输出
+func (c *TokenCipher) Decrypt(encoded string) ([]byte, error) {
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ciphertext: %w", err)
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("ciphertext too short")
+	}
+	plaintext, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
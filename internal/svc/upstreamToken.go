@@ -0,0 +1,182 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/zeromicro/go-zero/core/syncx"
+)
+
+const (
+	// DefaultUpstreamTokenTTL 上游令牌默认保存时长
+	DefaultUpstreamTokenTTL = 30 * 24 * time.Hour
+
+	// upstreamTokenRefreshSkew 访问令牌剩余有效期低于该值时提前刷新
+	upstreamTokenRefreshSkew = 30 * time.Second
+)
+
+var (
+	// ErrUpstreamTokenNotFound 用户未通过该提供者登录，或令牌已过期清理
+	ErrUpstreamTokenNotFound = errors.New("upstream token not found")
+	// ErrUpstreamTokenExpired 访问令牌已过期且没有可用的刷新令牌
+	ErrUpstreamTokenExpired = errors.New("upstream token expired and cannot be refreshed")
+)
+
+// UpstreamToken IdP 签发给用户的上游令牌
+type UpstreamToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	ExpiresAt    int64  `json:"expires_at,omitempty"` // 访问令牌过期时间 (0 表示 IdP 未告知)
+	UpdatedAt    int64  `json:"updated_at"`
+}
+
+// NewUpstreamToken 由令牌端点响应创建上游令牌
+func NewUpstreamToken(resp *OIDCTokenResponse) *UpstreamToken {
+	now := time.Now()
+	token := &UpstreamToken{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		IDToken:      resp.IDToken,
+		TokenType:    resp.TokenType,
+		Scope:        resp.Scope,
+		UpdatedAt:    now.Unix(),
+	}
+	if resp.ExpiresIn > 0 {
+		token.ExpiresAt = now.Add(time.Duration(resp.ExpiresIn) * time.Second).Unix()
+	}
+	return token
+}
+
+// needsRefresh 访问令牌是否即将过期
+func (t *UpstreamToken) needsRefresh() bool {
+	return t.ExpiresAt > 0 && time.Now().Add(upstreamTokenRefreshSkew).Unix() >= t.ExpiresAt
+}
+
+// upstreamTokenRefresher 可刷新上游访问令牌的客户端 (如 OIDCClient)
+type upstreamTokenRefresher interface {
+	RefreshAccessToken(ctx context.Context, refreshToken string) (*OIDCTokenResponse, error)
+}
+
+// UpstreamTokenStore 按 用户-提供者 保存加密的上游令牌
+type UpstreamTokenStore struct {
+	rdb    redis.UniversalClient
+	cipher *TokenCipher
+	ttl    time.Duration
+	flight syncx.SingleFlight // 合并同一用户的并发刷新，避免轮换的刷新令牌被重复使用
+}
+
+// NewUpstreamTokenStore 创建上游令牌存储，ttl 为 0 时使用默认保存时长
+func NewUpstreamTokenStore(rdb redis.UniversalClient, cipher *TokenCipher, ttl time.Duration) *UpstreamTokenStore {
+	if ttl <= 0 {
+		ttl = DefaultUpstreamTokenTTL
+	}
+	return &UpstreamTokenStore{
+		rdb:    rdb,
+		cipher: cipher,
+		ttl:    ttl,
+		flight: syncx.NewSingleFlight(),
+	}
+}
+
+func upstreamTokenKey(provider string, userID uint64) string {
+	return fmt.Sprintf("auth:sso:upstream:%s:%d", provider, userID)
+}
+
+// Save 加密保存上游令牌
+func (s *UpstreamTokenStore) Save(ctx context.Context, userID uint64, provider string, token *UpstreamToken) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to encode upstream token: %w", err)
+	}
+	encrypted, err := s.cipher.Encrypt(data)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt upstream token: %w", err)
+	}
+	if err := s.rdb.Set(ctx, upstreamTokenKey(provider, userID), encrypted, s.ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save upstream token: %w", err)
+	}
+	return nil
+}
+
+// Get 读取并解密上游令牌
+func (s *UpstreamTokenStore) Get(ctx context.Context, userID uint64, provider string) (*UpstreamToken, error) {
+	encrypted, err := s.rdb.Get(ctx, upstreamTokenKey(provider, userID)).Result()
+	if err == redis.Nil {
+		return nil, ErrUpstreamTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upstream token: %w", err)
+	}
+
+	data, err := s.cipher.Decrypt(encrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt upstream token: %w", err)
+	}
+	var token UpstreamToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("failed to decode upstream token: %w", err)
+	}
+	return &token, nil
+}
+
+// Delete 删除上游令牌
+func (s *UpstreamTokenStore) Delete(ctx context.Context, userID uint64, provider string) error {
+	if err := s.rdb.Del(ctx, upstreamTokenKey(provider, userID)).Err(); err != nil {
+		return fmt.Errorf("failed to delete upstream token: %w", err)
+	}
+	return nil
+}
+
+// GetFresh 返回未过期的上游令牌，访问令牌即将过期时使用刷新令牌向 IdP 换取新令牌
+func (s *UpstreamTokenStore) GetFresh(ctx context.Context, userID uint64, provider string, client upstreamTokenRefresher) (*UpstreamToken, error) {
+	token, err := s.Get(ctx, userID, provider)
+	if err != nil {
+		return nil, err
+	}
+	if !token.needsRefresh() {
+		return token, nil
+	}
+
+	refreshed, err := s.flight.Do(upstreamTokenKey(provider, userID), func() (any, error) {
+		// 其他请求可能已完成刷新
+		current, err := s.Get(ctx, userID, provider)
+		if err != nil {
+			return nil, err
+		}
+		if !current.needsRefresh() {
+			return current, nil
+		}
+		if current.RefreshToken == "" {
+			return nil, ErrUpstreamTokenExpired
+		}
+
+		resp, err := client.RefreshAccessToken(ctx, current.RefreshToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to refresh upstream token: %w", err)
+		}
+
+		next := NewUpstreamToken(resp)
+		// IdP 未轮换刷新令牌或未重新签发 ID Token 时沿用原值
+		if next.RefreshToken == "" {
+			next.RefreshToken = current.RefreshToken
+		}
+		if next.IDToken == "" {
+			next.IDToken = current.IDToken
+		}
+		if err := s.Save(ctx, userID, provider, next); err != nil {
+			return nil, err
+		}
+		return next, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return refreshed.(*UpstreamToken), nil
+}
@@ -0,0 +1,145 @@
+package svc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrInvalidVerificationCode 验证码错误、已过期或失败次数过多
+var ErrInvalidVerificationCode = errors.New("invalid or expired verification code")
+
+// VerificationCode 发送到邮箱或手机号的待校验验证码
+type VerificationCode struct {
+	UserID    uint64 `json:"user_id"`
+	Target    string `json:"target"`    // 接收验证码的邮箱或手机号
+	CodeHash  string `json:"code_hash"` // 验证码的 SHA-256
+	CreatedAt int64  `json:"created_at"`
+}
+
+// targetHash 以接收方的哈希作为键，Redis 中不保存明文邮箱与手机号
+func targetHash(target string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(target)))
+	return hex.EncodeToString(sum[:])
+}
+
+// allowVerificationCode 在发送间隔内只允许发送一次
+func allowVerificationCode(ctx context.Context, rdb redis.UniversalClient, cooldownKey string, interval time.Duration) (bool, error) {
+	ok, err := rdb.SetNX(ctx, cooldownKey, 1, interval).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check verification code cooldown: %w", err)
+	}
+	return ok, nil
+}
+
+// incrCounter 原子地累加计数并返回累加后的值，计数窗口从第一次计数开始
+func incrCounter(ctx context.Context, rdb redis.UniversalClient, counterKey string, window time.Duration) (int64, error) {
+	count, err := rdb.Incr(ctx, counterKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment counter: %w", err)
+	}
+	if count == 1 {
+		rdb.Expire(ctx, counterKey, window)
+	}
+	return count, nil
+}
+
+// 失败次数单独计数，INCR 保证并发的猜测请求各自获得不同的计数
+func verificationAttemptsKey(key string) string {
+	return key + ":attempts"
+}
+
+// createVerificationCode 生成 6 位验证码并保存其哈希，此前发送的验证码失效
+func createVerificationCode(ctx context.Context, rdb redis.UniversalClient, key string, userID uint64, target string, ttl time.Duration) (string, error) {
+	code, err := generateVerificationCode()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(&VerificationCode{
+		UserID:    userID,
+		Target:    target,
+		CodeHash:  hashEmailCode(code),
+		CreatedAt: time.Now().Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode verification code: %w", err)
+	}
+	if err := rdb.Set(ctx, key, data, ttl).Err(); err != nil {
+		return "", fmt.Errorf("failed to store verification code: %w", err)
+	}
+	rdb.Del(ctx, verificationAttemptsKey(key))
+	return code, nil
+}
+
+// checkVerificationCode 校验验证码，成功后验证码作废 (只能使用一次)，失败次数达到上限后验证码作废。
+// 在比较之前计数，并发的猜测请求也不会超过 maxAttempts
+func checkVerificationCode(ctx context.Context, rdb redis.UniversalClient, key, code string, maxAttempts int, ttl time.Duration) (*VerificationCode, error) {
+	data, err := rdb.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidVerificationCode
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verification code: %w", err)
+	}
+
+	var verification VerificationCode
+	if err := json.Unmarshal(data, &verification); err != nil {
+		return nil, ErrInvalidVerificationCode
+	}
+
+	attemptsKey := verificationAttemptsKey(key)
+	attempts, err := incrCounter(ctx, rdb, attemptsKey, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if attempts > int64(maxAttempts) {
+		rdb.Del(ctx, key, attemptsKey)
+		return nil, ErrInvalidVerificationCode
+	}
+
+	if !matchVerificationCode(verification.CodeHash, code) {
+		if attempts >= int64(maxAttempts) {
+			rdb.Del(ctx, key, attemptsKey)
+		}
+		return nil, ErrInvalidVerificationCode
+	}
+
+	// 并发校验同一验证码时只有一个成功
+	n, err := rdb.Del(ctx, key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete verification code: %w", err)
+	}
+	rdb.Del(ctx, attemptsKey)
+	if n == 0 {
+		return nil, ErrInvalidVerificationCode
+	}
+	return &verification, nil
+}
+
+// generateVerificationCode 生成 6 位数字验证码
+func generateVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate verification code: %w", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// matchVerificationCode 以常量时间比较验证码与保存的哈希
+func matchVerificationCode(codeHash, code string) bool {
+	if codeHash == "" || code == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(codeHash), []byte(hashEmailCode(code))) == 1
+}
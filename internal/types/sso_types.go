@@ -6,9 +6,11 @@ package types
 type SSOProviderType string
 
 const (
-	SSOProviderLocal SSOProviderType = "local" // 本地认证
-	SSOProviderOIDC  SSOProviderType = "oidc"  // OpenID Connect
-	SSOProviderLDAP  SSOProviderType = "ldap"  // LDAP
+	SSOProviderLocal  SSOProviderType = "local"  // 本地认证
+	SSOProviderOIDC   SSOProviderType = "oidc"   // OpenID Connect
+	SSOProviderLDAP   SSOProviderType = "ldap"   // LDAP
+	SSOProviderOAuth2 SSOProviderType = "oauth2" // 社交 OAuth2 (GitHub, 微信等)
+	SSOProviderRADIUS SSOProviderType = "radius" // RADIUS
 )
 
 // ===================== OpenID Connect 类型 =====================
@@ -37,17 +39,68 @@ type OIDCCallbackResp struct {
 	Provider         string `json:"provider"`  // SSO 提供者
 }
 
+// ===================== 社交 OAuth2 类型 =====================
+
+// OAuth2LoginReq is defined in types.go
+
+// OAuth2LoginResp OAuth2 登录响应
+type OAuth2LoginResp struct {
+	AuthorizationURL string `json:"authorizationUrl"` // 跳转到社交平台的授权 URL
+	State            string `json:"state"`            // CSRF 防护 state
+}
+
+// OAuth2CallbackReq is defined in types.go
+
+// OAuth2CallbackResp OAuth2 回调响应 (登录成功)
+type OAuth2CallbackResp struct {
+	UserID           string `json:"userId"`
+	Username         string `json:"username"`
+	Email            string `json:"email,optional"`
+	DisplayName      string `json:"displayName,optional"`
+	AccessToken      string `json:"accessToken"`
+	AccessExpiresAt  int64  `json:"accessExpiresAt"`
+	RefreshToken     string `json:"refreshToken"`
+	RefreshExpiresAt int64  `json:"refreshExpiresAt"`
+	TokenType        string `json:"tokenType" default:"Bearer"`
+	IsNewUser        bool   `json:"isNewUser"`
+	Provider         string `json:"provider"`
+}
+
 // ===================== LDAP 类型 =====================
 
 // LDAPLoginReq is defined in types.go
 
 // LDAPLoginResp LDAP 登录响应
-type LDAPLoginResp struct {
+type LDAPLoginResp = SSOLoginResp
+
+// ===================== RADIUS 类型 =====================
+
+// RADIUSLoginReq is defined in types.go
+
+// RADIUSLoginResp RADIUS 登录响应 (Groups 为 Access-Accept 中的 Class 属性)
+type RADIUSLoginResp = SSOLoginResp
+
+// ===================== SSO 统一类型 =====================
+
+// SSORedirectResp 浏览器回调模式下的跳转结果，由 handler 转换为 302 跳转
+type SSORedirectResp struct {
+	Location string `json:"location"`
+}
+
+// SSOExchangeReq is defined in types.go
+
+// SSOLoginReq is defined in types.go
+
+// SSOChallengeResp is defined in types.go
+
+// SSOLoginResp 用户名密码类身份提供者 (LDAP、RADIUS 等) 登录响应
+type SSOLoginResp struct {
 	UserID           string   `json:"userId"`
 	Username         string   `json:"username"`
 	Email            string   `json:"email,optional"`
 	DisplayName      string   `json:"displayName,optional"`
 	Groups           []string `json:"groups,optional"`
+	Roles            []string `json:"roles,optional"`
 	AccessToken      string   `json:"accessToken"`
 	AccessExpiresAt  int64    `json:"accessExpiresAt"`
 	RefreshToken     string   `json:"refreshToken"`
@@ -57,10 +110,21 @@ type LDAPLoginResp struct {
 	Provider         string   `json:"provider"`
 }
 
-// ===================== SSO 统一类型 =====================
-
 // SSOProvidersResp is defined in types.go
 
+// SSOProviderHealth SSO 提供者健康状态
+type SSOProviderHealth struct {
+	ID            string `json:"id"`
+	Type          string `json:"type"`
+	Ready         bool   `json:"ready"`                  // 是否可用 (OIDC 发现文档已加载)
+	LastRefreshAt int64  `json:"lastRefreshAt,optional"` // 最近一次成功刷新的时间 (Unix 时间戳)
+}
+
+// HealthCheckResp 健康检查响应
+type HealthCheckResp struct {
+	SSO []SSOProviderHealth `json:"sso"`
+}
+
 // SSOUserInfo SSO 用户信息 (统一格式)
 type SSOUserInfo struct {
 	Provider       string            `json:"provider"`       // SSO 提供者
@@ -72,26 +136,46 @@ type SSOUserInfo struct {
 	LastName       string            `json:"lastName,optional"`
 	Picture        string            `json:"picture,optional"`    // 头像 URL
 	Groups         []string          `json:"groups,optional"`     // 用户组
+	Roles          []string          `json:"roles,optional"`      // 提供者映射到的角色 (LDAP 组映射)
 	Attributes     map[string]string `json:"attributes,optional"` // 额外属性
 	EmailVerified  bool              `json:"emailVerified"`
 }
 
-// SSOLinkReq 关联 SSO 账号请求
-type SSOLinkReq struct {
-	Provider string `json:"provider" validate:"required,oneof=oidc ldap"`
-}
+// SSOLinkReq is defined in types.go
 
 // SSOLinkResp 关联 SSO 账号响应
 type SSOLinkResp struct {
-	AuthorizationURL string `json:"authorizationUrl,optional"` // OIDC 需要跳转
+	AuthorizationURL string `json:"authorizationUrl,optional"` // OIDC / OAuth2 需要跳转到该地址完成关联
 	Message          string `json:"message,optional"`
 }
 
-// SSOUnlinkReq 解除 SSO 账号关联请求
-type SSOUnlinkReq struct {
-	Provider string `json:"provider" validate:"required,oneof=oidc ldap"`
+// SSOLinkRequiredResp 外部身份的邮箱与已有账号相同，证明账号所有权后才能关联并登录
+type SSOLinkRequiredResp struct {
+	LinkToken string   `json:"linkToken"` // 关联令牌，用于发送邮箱验证码和确认关联
+	Email     string   `json:"email"`     // 已有账号的邮箱 (脱敏)
+	Methods   []string `json:"methods"`   // 可用的验证方式: password, email_code
+	ExpiresAt int64    `json:"expiresAt"` // 关联令牌过期时间 (Unix 时间戳)
 }
 
+// SSOLinkCodeReq is defined in types.go
+
+// SSOLinkConfirmReq is defined in types.go
+
+// SSOLinkConfirmResp 确认关联响应 (登录成功)
+type SSOLinkConfirmResp struct {
+	UserID           string `json:"userId"`
+	Username         string `json:"username"`
+	Email            string `json:"email,optional"`
+	AccessToken      string `json:"accessToken"`
+	AccessExpiresAt  int64  `json:"accessExpiresAt"`
+	RefreshToken     string `json:"refreshToken"`
+	RefreshExpiresAt int64  `json:"refreshExpiresAt"`
+	TokenType        string `json:"tokenType" default:"Bearer"`
+	Provider         string `json:"provider"`
+}
+
+// SSOUnlinkReq is defined in types.go
+
 // SSOLinkedAccountsResp 已关联的 SSO 账号响应
 type SSOLinkedAccountsResp struct {
 	Accounts []SSOLinkedAccount `json:"accounts"`
@@ -102,5 +186,6 @@ type SSOLinkedAccount struct {
 	Provider       string `json:"provider"`
 	ProviderUserID string `json:"providerUserId"`
 	Email          string `json:"email,optional"`
-	LinkedAt       int64  `json:"linkedAt"` // Unix 时间戳
+	LinkedAt       int64  `json:"linkedAt"`             // Unix 时间戳
+	LastLoginAt    int64  `json:"lastLoginAt,optional"` // 最后一次通过该账号登录的时间 (Unix 时间戳)
 }
@@ -38,6 +38,34 @@ type LDAPLoginReq struct {
 	CaptchaAnswer string `json:"captchaAnswer,optional"`
 }
 
+type LDAPPasswordChangeReq struct {
+	ChangeToken string `json:"changeToken" validate:"required"` // 登录返回的修改密码令牌
+	OldPassword string `json:"oldPassword" validate:"required"`
+	NewPassword string `json:"newPassword" validate:"required"`
+}
+
+type LDAPPasswordChangeResp struct {
+	ChangeToken string `json:"changeToken"` // 修改密码令牌，用于 POST /sso/ldap/password
+	ExpiresIn   int64  `json:"expiresIn"`   // 令牌有效期 (秒)
+}
+
+type LDAPSyncStatusResp struct {
+	Running bool             `json:"running"`       // 是否正在同步
+	Last    *LDAPSyncSummary `json:"last,optional"` // 最近一次同步结果
+}
+
+type LDAPSyncSummary struct {
+	StartedAt  int64    `json:"startedAt"`
+	FinishedAt int64    `json:"finishedAt"`
+	Scanned    int      `json:"scanned"`         // 目录中的用户数
+	Created    int      `json:"created"`         // 新建的本地用户
+	Updated    int      `json:"updated"`         // 资料有变化的用户
+	Disabled   int      `json:"disabled"`        // 因目录中已删除或已禁用而禁用的用户
+	Skipped    int      `json:"skipped"`         // 未开通账号的目录用户 (开通策略不允许或需首次登录关联)
+	Failed     int      `json:"failed"`          // 同步失败的用户
+	Errors     []string `json:"errors,optional"` // 错误信息 (最多 20 条)
+}
+
 type LoginReq struct {
 	Username      string `json:"username" validate:"required"` // 用户名 (或 邮箱/手机号)
 	Password      string `json:"password" validate:"required"` // 密码
@@ -54,14 +82,52 @@ type LoginResp struct {
 	RefreshToken     string `json:"refreshToken"`               // 刷新令牌
 	RefreshExpiresAt int64  `json:"refreshExpiresAt"`           // 刷新令牌的过期时间（Unix 时间戳，单位：秒）
 	TokenType        string `json:"tokenType" default:"Bearer"` // 令牌类型
+	EmailVerified    bool   `json:"emailVerified"`              // 邮箱是否已验证
+	Scope            string `json:"scope,optional"`             // 受限令牌的访问范围 (邮箱未验证时为 email_unverified)
 }
 
 type LogoutReq struct {
-	AccessToken  string `json:"accessToken,optional"`
-	RefreshToken string `json:"refreshToken,optional"`
+	AccessToken           string `json:"accessToken,optional"`
+	RefreshToken          string `json:"refreshToken,optional"`
+	PostLogoutRedirectURL string `json:"postLogoutRedirectUrl,optional"` // OIDC 会话登出后 IdP 跳转回的地址
+}
+
+type LogoutResp struct {
+	EndSessionURL string `json:"endSessionUrl,optional"` // OIDC 会话需跳转到 IdP 完成登出
+}
+
+type MagicLinkConsumeReq struct {
+	Token    string `json:"token" validate:"required"` // 邮件链接中的登录令牌
+	DeviceID string `json:"deviceId,optional"`         // 申请链接时提供的设备标识
+}
+
+type MagicLinkReq struct {
+	Email         string `json:"email" validate:"required,email"` // 用户注册时填写的邮箱
+	DeviceID      string `json:"deviceId,optional"`               // 浏览器生成的设备标识，链接只能由同一设备使用
+	CaptchaID     string `json:"captchaId,optional"`
+	CaptchaAnswer string `json:"captchaAnswer,optional"`
+}
+
+type OAuth2CallbackReq struct {
+	Provider         string `path:"provider"`                   // OAuth2 提供者名称
+	Code             string `form:"code,optional"`              // 授权码
+	State            string `form:"state"`                      // CSRF 防护 state
+	Error            string `form:"error,optional"`             // 错误码
+	ErrorDescription string `form:"error_description,optional"` // 错误描述
+}
+
+type OAuth2LoginReq struct {
+	Provider    string `path:"provider"`             // OAuth2 提供者名称 (如 github, wechat)
+	RedirectURL string `form:"redirectUrl,optional"` // 登录成功后的重定向 URL
+}
+
+type OIDCBackchannelLogoutReq struct {
+	Provider    string `path:"provider,optional"` // OIDC 提供者名称 (为空时按 iss 匹配所有提供者)
+	LogoutToken string `form:"logout_token"`      // IdP 签发的登出令牌
 }
 
 type OIDCCallbackReq struct {
+	Provider         string `path:"provider,optional"`          // OIDC 提供者名称 (为空时使用 state 中记录的提供者)
 	Code             string `form:"code"`                       // 授权码
 	State            string `form:"state"`                      // CSRF 防护 state
 	Error            string `form:"error,optional"`             // 错误码
@@ -69,9 +135,31 @@ type OIDCCallbackReq struct {
 }
 
 type OIDCLoginReq struct {
+	Provider    string `path:"provider,optional"`    // OIDC 提供者名称 (默认 oidc)
 	RedirectURL string `form:"redirectUrl,optional"` // 登录成功后的重定向 URL
 }
 
+type PendingUser struct {
+	UserID    string   `json:"userId"`
+	Username  string   `json:"username"`
+	Email     string   `json:"email"`
+	Nickname  string   `json:"nickname,optional"`
+	Providers []string `json:"providers"` // 开通账号的外部身份提供者
+	CreatedAt int64    `json:"createdAt"`
+}
+
+type PendingUsersResp struct {
+	Users []PendingUser `json:"users"`
+}
+
+type RADIUSLoginReq struct {
+	Username       string `json:"username" validate:"required"`
+	Password       string `json:"password" validate:"required"` // 密码；回应挑战时为一次性密码等回应内容
+	ChallengeToken string `json:"challengeToken,optional"`      // 回应 Access-Challenge 时携带上一步返回的挑战令牌
+	CaptchaID      string `json:"captchaId,optional"`
+	CaptchaAnswer  string `json:"captchaAnswer,optional"`
+}
+
 type RefreshReq struct {
 	RefreshToken string `json:"refreshToken" validate:"required"`
 }
@@ -101,12 +189,65 @@ type RegisterResp struct {
 	CreatedAt int64  `json:"createdAt"`
 }
 
+type ResendVerificationReq struct {
+	Email         string `json:"email" validate:"required,email"` // 待验证的邮箱
+	CaptchaID     string `json:"captchaId,optional"`
+	CaptchaAnswer string `json:"captchaAnswer,optional"`
+}
+
+type ResetUserPasswordReq struct {
+	UserID      string `json:"userId" validate:"required"` // 用户 Public ID
+	NewPassword string `json:"newPassword" validate:"required,min=6,max=30"`
+}
+
+type ReviewUserReq struct {
+	UserID string `json:"userId" validate:"required"` // 用户 Public ID
+}
+
+type SSOChallengeResp struct {
+	ChallengeToken string `json:"challengeToken"` // 挑战令牌，与回应一起再次提交登录请求
+	Message        string `json:"message"`        // 提供者的提示 (如 RADIUS Reply-Message "请输入动态口令")
+	ExpiresIn      int64  `json:"expiresIn"`      // 令牌有效期 (秒)
+}
+
+type SSOExchangeReq struct {
+	Code string `json:"code" validate:"required"` // 回调跳转时携带的一次性交换码
+}
+
+type SSOLinkCodeReq struct {
+	LinkToken string `json:"linkToken" validate:"required"` // SSO 回调返回的关联令牌
+}
+
+type SSOLinkConfirmReq struct {
+	LinkToken string `json:"linkToken" validate:"required"` // SSO 回调返回的关联令牌
+	Password  string `json:"password,optional"`             // 已有账号的密码
+	EmailCode string `json:"emailCode,optional"`            // 发送到已有账号邮箱的验证码
+}
+
+type SSOLinkReq struct {
+	Provider       string `json:"provider" validate:"required"` // 提供者名称: ldap, radius, OIDC 或 OAuth2 提供者名称
+	RedirectURL    string `json:"redirectUrl,optional"`         // OIDC / OAuth2 关联完成后的跳转地址
+	Username       string `json:"username,optional"`            // LDAP / RADIUS 等用户名密码类提供者的用户名
+	Password       string `json:"password,optional"`            // 密码；回应挑战时为一次性密码等回应内容
+	ChallengeToken string `json:"challengeToken,optional"`      // 回应挑战时携带上一步返回的挑战令牌
+}
+
+type SSOLoginReq struct {
+	Provider       string `path:"provider"` // 身份提供者名称 (如 ldap, radius)
+	Username       string `json:"username" validate:"required"`
+	Password       string `json:"password" validate:"required"` // 密码；回应挑战时为一次性密码等回应内容
+	ChallengeToken string `json:"challengeToken,optional"`      // 回应挑战时携带上一步返回的挑战令牌
+	CaptchaID      string `json:"captchaId,optional"`
+	CaptchaAnswer  string `json:"captchaAnswer,optional"`
+}
+
 type SSOProvider struct {
-	ID       string `json:"id"`       // 提供者 ID: local, oidc, ldap
-	Name     string `json:"name"`     // 显示名称
-	Type     string `json:"type"`     // 类型
-	Enabled  bool   `json:"enabled"`  // 是否启用
-	LoginURL string `json:"loginUrl"` // 登录入口 URL
+	ID       string `json:"id"`            // 提供者 ID: local, ldap, radius 或 OIDC 提供者名称
+	Name     string `json:"name"`          // 显示名称
+	Type     string `json:"type"`          // 类型
+	Icon     string `json:"icon,optional"` // 图标 URL
+	Enabled  bool   `json:"enabled"`       // 是否启用
+	LoginURL string `json:"loginUrl"`      // 登录入口 URL
 }
 
 type SSOProvidersResp struct {
@@ -114,12 +255,41 @@ type SSOProvidersResp struct {
 	Providers       []SSOProvider `json:"providers"`
 }
 
+type SSOUnlinkReq struct {
+	Provider string `json:"provider" validate:"required"` // 要解除关联的提供者名称
+}
+
+type UpstreamTokenReq struct {
+	Provider     string `path:"provider"`          // OIDC 提供者名称
+	ClientID     string `header:"X-Client-Id"`     // 令牌代理客户端 ID
+	ClientSecret string `header:"X-Client-Secret"` // 令牌代理客户端密钥
+}
+
+type UpstreamTokenResp struct {
+	Provider    string `json:"provider"`
+	AccessToken string `json:"accessToken"`        // 上游 IdP 访问令牌
+	TokenType   string `json:"tokenType"`          // 令牌类型 (通常为 Bearer)
+	ExpiresAt   int64  `json:"expiresAt,optional"` // 过期时间 (Unix 秒, 0 表示未知)
+	Scope       string `json:"scope,optional"`     // 授权范围
+}
+
 type UserProfileResp struct {
-	UserID    string `json:"userId"`
-	Username  string `json:"username"`
-	Email     string `json:"email"`
-	Phone     string `json:"phone,optional"`
-	Nickname  string `json:"nickname,optional"`
-	CreatedAt int64  `json:"createdAt"`
-	UpdatedAt int64  `json:"updatedAt"`
+	UserID        string `json:"userId"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	Phone         string `json:"phone,optional"`
+	Nickname      string `json:"nickname,optional"`
+	CreatedAt     int64  `json:"createdAt"`
+	UpdatedAt     int64  `json:"updatedAt"`
+	EmailVerified bool   `json:"emailVerified"` // 邮箱是否已验证
+	PhoneVerified bool   `json:"phoneVerified"` // 手机号是否已验证
+}
+
+type VerifyEmailReq struct {
+	Email string `json:"email" validate:"required,email"` // 待验证的邮箱
+	Code  string `json:"code" validate:"required"`        // 邮件中的验证码
+}
+
+type VerifyPhoneReq struct {
+	Code string `json:"code" validate:"required"` // 短信中的验证码
 }
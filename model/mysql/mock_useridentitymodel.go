@@ -0,0 +1,83 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+)
+
+type MockUserIdentityModel struct {
+	InsertFunc                          func(ctx context.Context, data *UserIdentity) (sql.Result, error)
+	FindOneFunc                         func(ctx context.Context, id uint64) (*UserIdentity, error)
+	FindOneByProviderProviderUserIdFunc func(ctx context.Context, provider string, providerUserId string) (*UserIdentity, error)
+	FindOneByUserIdProviderFunc         func(ctx context.Context, userId uint64, provider string) (*UserIdentity, error)
+	FindAllByUserIdFunc                 func(ctx context.Context, userId uint64) ([]*UserIdentity, error)
+	FindAllByProviderFunc               func(ctx context.Context, provider string) ([]*UserIdentity, error)
+	UpdateFunc                          func(ctx context.Context, data *UserIdentity) error
+	DeleteFunc                          func(ctx context.Context, id uint64) error
+	WithSessionFunc                     func(session sqlx.Session) UserIdentityModel
+}
+
+func (m *MockUserIdentityModel) Insert(ctx context.Context, data *UserIdentity) (sql.Result, error) {
+	if m.InsertFunc != nil {
+		return m.InsertFunc(ctx, data)
+	}
+	return nil, nil
+}
+
+func (m *MockUserIdentityModel) FindOne(ctx context.Context, id uint64) (*UserIdentity, error) {
+	if m.FindOneFunc != nil {
+		return m.FindOneFunc(ctx, id)
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockUserIdentityModel) FindOneByProviderProviderUserId(ctx context.Context, provider string, providerUserId string) (*UserIdentity, error) {
+	if m.FindOneByProviderProviderUserIdFunc != nil {
+		return m.FindOneByProviderProviderUserIdFunc(ctx, provider, providerUserId)
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockUserIdentityModel) FindOneByUserIdProvider(ctx context.Context, userId uint64, provider string) (*UserIdentity, error) {
+	if m.FindOneByUserIdProviderFunc != nil {
+		return m.FindOneByUserIdProviderFunc(ctx, userId, provider)
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockUserIdentityModel) FindAllByUserId(ctx context.Context, userId uint64) ([]*UserIdentity, error) {
+	if m.FindAllByUserIdFunc != nil {
+		return m.FindAllByUserIdFunc(ctx, userId)
+	}
+	return nil, nil
+}
+
+func (m *MockUserIdentityModel) FindAllByProvider(ctx context.Context, provider string) ([]*UserIdentity, error) {
+	if m.FindAllByProviderFunc != nil {
+		return m.FindAllByProviderFunc(ctx, provider)
+	}
+	return nil, nil
+}
+
+func (m *MockUserIdentityModel) Update(ctx context.Context, data *UserIdentity) error {
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(ctx, data)
+	}
+	return nil
+}
+
+func (m *MockUserIdentityModel) Delete(ctx context.Context, id uint64) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockUserIdentityModel) withSession(session sqlx.Session) UserIdentityModel {
+	if m.WithSessionFunc != nil {
+		return m.WithSessionFunc(session)
+	}
+	return m
+}
@@ -8,15 +8,16 @@ import (
 )
 
 type MockUserModel struct {
-	InsertFunc            func(ctx context.Context, data *User) (sql.Result, error)
-	FindOneFunc           func(ctx context.Context, id uint64) (*User, error)
-	FindOneByEmailFunc    func(ctx context.Context, email string) (*User, error)
-	FindOneByPublicIdFunc func(ctx context.Context, publicId string) (*User, error)
-	FindOneByUsernameFunc func(ctx context.Context, username string) (*User, error)
-	FindOneByPhoneFunc    func(ctx context.Context, phone string) (*User, error)
-	UpdateFunc            func(ctx context.Context, data *User) error
-	DeleteFunc            func(ctx context.Context, id uint64) error
-	WithSessionFunc       func(session sqlx.Session) UserModel
+	InsertFunc                 func(ctx context.Context, data *User) (sql.Result, error)
+	FindOneFunc                func(ctx context.Context, id uint64) (*User, error)
+	FindOneByEmailFunc         func(ctx context.Context, email string) (*User, error)
+	FindOneByPublicIdFunc      func(ctx context.Context, publicId string) (*User, error)
+	FindOneByUsernameFunc      func(ctx context.Context, username string) (*User, error)
+	FindOneByPhoneFunc         func(ctx context.Context, phone string) (*User, error)
+	FindAllByAccountStatusFunc func(ctx context.Context, status uint64) ([]*User, error)
+	UpdateFunc                 func(ctx context.Context, data *User) error
+	DeleteFunc                 func(ctx context.Context, id uint64) error
+	WithSessionFunc            func(session sqlx.Session) UserModel
 }
 
 func (m *MockUserModel) Insert(ctx context.Context, data *User) (sql.Result, error) {
@@ -61,6 +62,13 @@ func (m *MockUserModel) FindOneByPhone(ctx context.Context, phone string) (*User
 	return nil, ErrNotFound
 }
 
+func (m *MockUserModel) FindAllByAccountStatus(ctx context.Context, status uint64) ([]*User, error) {
+	if m.FindAllByAccountStatusFunc != nil {
+		return m.FindAllByAccountStatusFunc(ctx, status)
+	}
+	return nil, nil
+}
+
 func (m *MockUserModel) Update(ctx context.Context, data *User) error {
 	if m.UpdateFunc != nil {
 		return m.UpdateFunc(ctx, data)
@@ -0,0 +1,67 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+)
+
+type MockUserRoleModel struct {
+	InsertFunc              func(ctx context.Context, data *UserRole) (sql.Result, error)
+	FindOneFunc             func(ctx context.Context, id uint64) (*UserRole, error)
+	FindOneByUserIdRoleFunc func(ctx context.Context, userId uint64, role string) (*UserRole, error)
+	FindAllByUserIdFunc     func(ctx context.Context, userId uint64) ([]*UserRole, error)
+	UpdateFunc              func(ctx context.Context, data *UserRole) error
+	DeleteFunc              func(ctx context.Context, id uint64) error
+	WithSessionFunc         func(session sqlx.Session) UserRoleModel
+}
+
+func (m *MockUserRoleModel) Insert(ctx context.Context, data *UserRole) (sql.Result, error) {
+	if m.InsertFunc != nil {
+		return m.InsertFunc(ctx, data)
+	}
+	return nil, nil
+}
+
+func (m *MockUserRoleModel) FindOne(ctx context.Context, id uint64) (*UserRole, error) {
+	if m.FindOneFunc != nil {
+		return m.FindOneFunc(ctx, id)
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockUserRoleModel) FindOneByUserIdRole(ctx context.Context, userId uint64, role string) (*UserRole, error) {
+	if m.FindOneByUserIdRoleFunc != nil {
+		return m.FindOneByUserIdRoleFunc(ctx, userId, role)
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MockUserRoleModel) FindAllByUserId(ctx context.Context, userId uint64) ([]*UserRole, error) {
+	if m.FindAllByUserIdFunc != nil {
+		return m.FindAllByUserIdFunc(ctx, userId)
+	}
+	return nil, nil
+}
+
+func (m *MockUserRoleModel) Update(ctx context.Context, data *UserRole) error {
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(ctx, data)
+	}
+	return nil
+}
+
+func (m *MockUserRoleModel) Delete(ctx context.Context, id uint64) error {
+	if m.DeleteFunc != nil {
+		return m.DeleteFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockUserRoleModel) withSession(session sqlx.Session) UserRoleModel {
+	if m.WithSessionFunc != nil {
+		return m.WithSessionFunc(session)
+	}
+	return m
+}
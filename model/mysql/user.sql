@@ -11,7 +11,7 @@ CREATE TABLE user (
     password_salt VARCHAR(255) DEFAULT NULL COMMENT '密码盐',
     mfa_secret VARCHAR(255) DEFAULT NULL COMMENT 'MFA秘钥 (加密存储)',
     mfa_enabled TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否启用了MFA (0-未启用, 1-启用)',
-    account_status TINYINT UNSIGNED NOT NULL DEFAULT 1 COMMENT '账户状态 (1-正常, 2-锁定, 3-禁用)',
+    account_status TINYINT UNSIGNED NOT NULL DEFAULT 1 COMMENT '账户状态 (1-正常, 2-锁定, 3-禁用, 4-待审批)',
     failed_login_attempts TINYINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '连续失败登录次数',
     lockout_until DATETIME DEFAULT NULL COMMENT '账户锁定截止时间',
     last_login_at DATETIME DEFAULT NULL COMMENT '最后一次登录时间',
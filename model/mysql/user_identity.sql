@@ -0,0 +1,16 @@
+CREATE TABLE user_identity (
+    id BIGINT UNSIGNED AUTO_INCREMENT COMMENT '自增主键',
+    user_id BIGINT UNSIGNED NOT NULL COMMENT '关联的本地用户 ID (user.id)',
+    provider VARCHAR(64) NOT NULL COMMENT '身份提供者名称 (ldap, OIDC 或 OAuth2 提供者名称)',
+    provider_user_id VARCHAR(255) NOT NULL COMMENT '提供者侧的用户标识 (OIDC sub, LDAP DN, OAuth2 用户 ID)',
+    email VARCHAR(255) DEFAULT NULL COMMENT '提供者返回的邮箱 (仅用于展示)',
+    linked_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '关联时间',
+    last_login_at DATETIME DEFAULT NULL COMMENT '最后一次通过该提供者登录的时间',
+
+    -- 主键
+    PRIMARY KEY (id),
+
+    -- 唯一约束: 一个外部身份只能关联一个本地用户，一个用户在同一提供者下只能关联一个身份
+    UNIQUE KEY uq_user_identity_provider_user (provider, provider_user_id),
+    UNIQUE KEY uq_user_identity_user_provider (user_id, provider)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户外部身份关联表';
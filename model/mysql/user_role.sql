@@ -0,0 +1,16 @@
+CREATE TABLE user_role (
+    id BIGINT UNSIGNED AUTO_INCREMENT COMMENT '自增主键',
+    user_id BIGINT UNSIGNED NOT NULL COMMENT '用户 ID (user.id)',
+    role VARCHAR(64) NOT NULL COMMENT '角色名称',
+    source VARCHAR(64) NOT NULL DEFAULT 'manual' COMMENT '角色来源 (manual-手动分配, provisioning-自动开通时分配, ldap-LDAP 组映射)',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '分配时间',
+
+    -- 主键
+    PRIMARY KEY (id),
+
+    -- 唯一约束
+    UNIQUE KEY uq_user_role_user_role (user_id, role),
+
+    -- 查询索引
+    KEY idx_user_role_role (role)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci COMMENT='用户角色表';
@@ -0,0 +1,56 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+)
+
+var _ UserIdentityModel = (*customUserIdentityModel)(nil)
+
+type (
+	// UserIdentityModel is an interface to be customized, add more methods here,
+	// and implement the added methods in customUserIdentityModel.
+	UserIdentityModel interface {
+		userIdentityModel
+		withSession(session sqlx.Session) UserIdentityModel
+		FindAllByUserId(ctx context.Context, userId uint64) ([]*UserIdentity, error)
+		FindAllByProvider(ctx context.Context, provider string) ([]*UserIdentity, error)
+	}
+
+	customUserIdentityModel struct {
+		*defaultUserIdentityModel
+	}
+)
+
+// NewUserIdentityModel returns a model for the database table.
+func NewUserIdentityModel(conn sqlx.SqlConn) UserIdentityModel {
+	return &customUserIdentityModel{
+		defaultUserIdentityModel: newUserIdentityModel(conn),
+	}
+}
+
+func (m *customUserIdentityModel) withSession(session sqlx.Session) UserIdentityModel {
+	return NewUserIdentityModel(sqlx.NewSqlConnFromSession(session))
+}
+
+// FindAllByUserId 查询用户关联的全部外部身份
+func (m *defaultUserIdentityModel) FindAllByUserId(ctx context.Context, userId uint64) ([]*UserIdentity, error) {
+	var resp []*UserIdentity
+	query := fmt.Sprintf("select %s from %s where `user_id` = ? order by `linked_at`", userIdentityRows, m.table)
+	if err := m.conn.QueryRowsCtx(ctx, &resp, query, userId); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// FindAllByProvider 查询某个提供者的全部外部身份 (如目录同步时查找已从 LDAP 删除的用户)
+func (m *defaultUserIdentityModel) FindAllByProvider(ctx context.Context, provider string) ([]*UserIdentity, error) {
+	var resp []*UserIdentity
+	query := fmt.Sprintf("select %s from %s where `provider` = ? order by `id`", userIdentityRows, m.table)
+	if err := m.conn.QueryRowsCtx(ctx, &resp, query, provider); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -17,6 +17,7 @@ type (
 		userModel
 		withSession(session sqlx.Session) UserModel
 		FindOneByPhone(ctx context.Context, phone string) (*User, error)
+		FindAllByAccountStatus(ctx context.Context, status uint64) ([]*User, error)
 	}
 	customUserModel struct {
 		*defaultUserModel
@@ -48,11 +49,22 @@ func (m *defaultUserModel) FindOneByPhone(ctx context.Context, phone string) (*U
 	}
 }
 
+// FindAllByAccountStatus 按账户状态查询用户 (未删除)，按创建时间排序
+func (m *defaultUserModel) FindAllByAccountStatus(ctx context.Context, status uint64) ([]*User, error) {
+	var resp []*User
+	query := fmt.Sprintf("select %s from %s where `account_status` = ? and `deleted_at` is null order by `created_at`", userRows, m.table)
+	if err := m.conn.QueryRowsCtx(ctx, &resp, query, status); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 // AccountStatus
 const (
 	UserStatusActive   = 1 // 用户状态: 正常
 	UserStatusLocked   = 2 // 用户状态: 锁定
 	UserStatusDisabled = 3 // 用户状态: 禁用
+	UserStatusPending  = 4 // 用户状态: 待审批 (SSO 自动开通后等待管理员审批)
 )
 
 // generateSecureToken 生成安全的随机令牌
@@ -52,7 +52,7 @@ type (
 		PasswordSalt        sql.NullString `db:"password_salt"`         // 密码盐
 		MfaSecret           sql.NullString `db:"mfa_secret"`            // MFA秘钥 (加密存储)
 		MfaEnabled          int64          `db:"mfa_enabled"`           // 是否启用了MFA (0-未启用, 1-启用)
-		AccountStatus       uint64         `db:"account_status"`        // 账户状态 (1-正常, 2-锁定, 3-禁用)
+		AccountStatus       uint64         `db:"account_status"`        // 账户状态 (1-正常, 2-锁定, 3-禁用, 4-待审批)
 		FailedLoginAttempts uint64         `db:"failed_login_attempts"` // 连续失败登录次数
 		LockoutUntil        sql.NullTime   `db:"lockout_until"`         // 账户锁定截止时间
 		LastLoginAt         sql.NullTime   `db:"last_login_at"`         // 最后一次登录时间
@@ -0,0 +1,55 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+)
+
+var _ UserRoleModel = (*customUserRoleModel)(nil)
+
+// RoleAdmin 管理员角色，可审批待开通账号
+const RoleAdmin = "admin"
+
+// Role source
+const (
+	RoleSourceManual       = "manual"       // 角色来源: 手动分配
+	RoleSourceProvisioning = "provisioning" // 角色来源: 自动开通时分配
+	RoleSourceLDAP         = "ldap"         // 角色来源: LDAP 组映射，每次 LDAP 登录时同步
+)
+
+type (
+	// UserRoleModel is an interface to be customized, add more methods here,
+	// and implement the added methods in customUserRoleModel.
+	UserRoleModel interface {
+		userRoleModel
+		withSession(session sqlx.Session) UserRoleModel
+		FindAllByUserId(ctx context.Context, userId uint64) ([]*UserRole, error)
+	}
+
+	customUserRoleModel struct {
+		*defaultUserRoleModel
+	}
+)
+
+// NewUserRoleModel returns a model for the database table.
+func NewUserRoleModel(conn sqlx.SqlConn) UserRoleModel {
+	return &customUserRoleModel{
+		defaultUserRoleModel: newUserRoleModel(conn),
+	}
+}
+
+func (m *customUserRoleModel) withSession(session sqlx.Session) UserRoleModel {
+	return NewUserRoleModel(sqlx.NewSqlConnFromSession(session))
+}
+
+// FindAllByUserId 查询用户的全部角色
+func (m *defaultUserRoleModel) FindAllByUserId(ctx context.Context, userId uint64) ([]*UserRole, error) {
+	var resp []*UserRole
+	query := fmt.Sprintf("select %s from %s where `user_id` = ? order by `id`", userRoleRows, m.table)
+	if err := m.conn.QueryRowsCtx(ctx, &resp, query, userId); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
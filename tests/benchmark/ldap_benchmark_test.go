@@ -0,0 +1,83 @@
+package benchmark_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/svc"
+	"auth-service/tests/common"
+)
+
+// newBenchLDAPProvider 启动模拟 LDAPS 握手延迟的 LDAP 服务器并创建提供者
+func newBenchLDAPProvider(b *testing.B, pool config.LDAPPoolConfig) *svc.LDAPProvider {
+	server := common.NewFakeLDAPServer(b,
+		&common.FakeLDAPEntry{DN: "cn=admin,dc=example,dc=com", Password: "admin-secret"},
+		&common.FakeLDAPEntry{
+			DN:         "uid=bench,ou=people,dc=example,dc=com",
+			Password:   "bench-password",
+			Attributes: map[string][]string{"uid": {"bench"}, "mail": {"bench@example.com"}},
+		},
+	)
+	server.Latency = 2 * time.Millisecond // TLS 握手
+	server.BindLatency = time.Millisecond // 绑定校验
+
+	provider, err := svc.NewLDAPProvider(config.LDAPConfig{
+		Enabled:      true,
+		Host:         server.Host(),
+		Port:         server.Port(),
+		BindDN:       "cn=admin,dc=example,dc=com",
+		BindPassword: "admin-secret",
+		BaseDN:       "dc=example,dc=com",
+		UserFilter:   "(uid=%s)",
+		Pool:         pool,
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(provider.Close)
+	return provider
+}
+
+// BenchmarkLDAPAuthenticateParallel 并发登录下的 LDAP 认证延迟: 复用管理员绑定的搜索连接与每次新建连接对比
+func BenchmarkLDAPAuthenticateParallel(b *testing.B) {
+	cases := []struct {
+		name string
+		pool config.LDAPPoolConfig
+	}{
+		{name: "Pooled", pool: config.LDAPPoolConfig{MaxOpen: 20}},
+		{name: "Unpooled", pool: config.LDAPPoolConfig{MaxOpen: 20, MaxIdle: -1}},
+	}
+
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			provider := newBenchLDAPProvider(b, tc.pool)
+			ctx := context.Background()
+
+			b.SetParallelism(4)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := provider.Authenticate(ctx, "bench", "bench-password"); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkLDAPGetUserGroups 单个请求的组查询延迟 (只使用搜索连接)
+func BenchmarkLDAPGetUserGroups(b *testing.B) {
+	provider := newBenchLDAPProvider(b, config.LDAPPoolConfig{})
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := provider.GetUserGroups(ctx, "uid=bench,ou=people,dc=example,dc=com"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
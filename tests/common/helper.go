@@ -6,6 +6,7 @@ import (
 
 	"auth-service/internal/config"
 	"auth-service/internal/svc"
+	"auth-service/internal/types"
 	model "auth-service/model/mysql"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -80,6 +81,19 @@ func (h *TestHelper) SetupTestRedis() redis.UniversalClient {
 	return rdb
 }
 
+// SetLDAP 设置 LDAP 客户端并以它注册 LDAP 身份提供者 (登录、目录同步与密码修改共用同一个客户端)，
+// 其它已注册的身份提供者保持不变
+func SetLDAP(svcCtx *svc.ServiceContext, client svc.LDAPClient, policy *svc.ProvisioningPolicy) {
+	svcCtx.LDAP = client
+	providers := svc.NewIdentityProviders(svc.NewLDAPIdentityEntry(client, policy))
+	for _, entry := range svcCtx.IdentityProviders.List() {
+		if entry.Type != types.SSOProviderLDAP {
+			providers.Register(*entry)
+		}
+	}
+	svcCtx.IdentityProviders = providers
+}
+
 // SetupServiceContext 设置服务上下文
 func (h *TestHelper) SetupServiceContext(withRedis bool) *svc.ServiceContext {
 	if h.db == nil {
@@ -101,13 +115,16 @@ func (h *TestHelper) SetupServiceContext(withRedis bool) *svc.ServiceContext {
 	}
 
 	svcCtx := &svc.ServiceContext{
-		Config:          cfg,
-		DB:              h.db,
-		PasswordEncoder: &svc.PasswordEncoder{},
-		UserModel:       model.NewUserModel(h.db),
-		OIDC:            &MockOIDCClient{},
-		LDAP:            &MockLDAPClient{},
+		Config:            cfg,
+		DB:                h.db,
+		PasswordEncoder:   &svc.PasswordEncoder{},
+		UserModel:         model.NewUserModel(h.db),
+		UserIdentityModel: &model.MockUserIdentityModel{},
+		UserRoleModel:     &model.MockUserRoleModel{},
+		OIDC:              svc.NewOIDCProviders(svc.OIDCProviderEntry{Name: svc.DefaultOIDCProviderName, Client: &MockOIDCClient{}}),
+		OAuth2:            svc.NewOAuth2Providers(),
 	}
+	SetLDAP(svcCtx, &MockLDAPClient{}, nil)
 
 	// Init Captcha with memory store for safely testing non-redis paths or fallback
 	driver := base64Captcha.NewDriverDigit(80, 240, 6, 0.7, 80)
@@ -0,0 +1,660 @@
+package common
+
+import (
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// FakeLDAPEntry 目录条目
+type FakeLDAPEntry struct {
+	DN         string
+	Password   string // 为空时不能以该条目绑定
+	Admin      bool   // 以该条目绑定后可以重置其它条目的密码
+	Attributes map[string][]string
+
+	// BindSubCode 密码正确时仍以该 AD 诊断子码拒绝绑定 (如 773 必须修改密码, 533 已禁用)，
+	// 773 与 532 在修改密码后清除
+	BindSubCode string
+}
+
+// FakeLDAPServer 内存 LDAP 服务器，支持简单绑定、(分页) 搜索、解绑，以及 Password Modify 扩展操作与
+// unicodePwd 修改 (AD)，用于测试 LDAPProvider
+type FakeLDAPServer struct {
+	// Latency 新连接的模拟建立延迟 (如 LDAPS 握手)
+	Latency time.Duration
+	// BindLatency 每次绑定的模拟延迟
+	BindLatency time.Duration
+	// PasswordPolicy 校验新密码，返回非空的诊断信息时以 Constraint Violation 拒绝修改
+	PasswordPolicy func(password string) string
+	// MaxPageSize 单次搜索最多返回的条目数 (模拟 AD 的 MaxPageSize)，非分页搜索超过时返回 Size Limit Exceeded，
+	// 分页搜索的每页也不超过该值；为 0 时不限制
+	MaxPageSize int
+
+	listener net.Listener
+	mu       sync.Mutex
+	entries  []*FakeLDAPEntry
+	conns    map[*fakeLDAPConn]struct{}
+
+	dials     atomic.Int64
+	binds     atomic.Int64
+	pages     atomic.Int64
+	abandoned atomic.Int64
+}
+
+type fakeLDAPConn struct {
+	net.Conn
+	mu     sync.Mutex
+	bindDN string
+	bound  bool
+}
+
+// NewFakeLDAPServer 在本地随机端口启动 LDAP 服务器，测试结束时自动关闭
+func NewFakeLDAPServer(t testing.TB, entries ...*FakeLDAPEntry) *FakeLDAPServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	s := &FakeLDAPServer{
+		listener: listener,
+		entries:  entries,
+		conns:    make(map[*fakeLDAPConn]struct{}),
+	}
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Host 监听地址
+func (s *FakeLDAPServer) Host() string {
+	return s.listener.Addr().(*net.TCPAddr).IP.String()
+}
+
+// Port 监听端口
+func (s *FakeLDAPServer) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Dials 已接受的连接数
+func (s *FakeLDAPServer) Dials() int64 {
+	return s.dials.Load()
+}
+
+// Binds 已处理的绑定请求数
+func (s *FakeLDAPServer) Binds() int64 {
+	return s.binds.Load()
+}
+
+// Pages 已处理的分页搜索请求数 (含放弃分页的请求)
+func (s *FakeLDAPServer) Pages() int64 {
+	return s.pages.Load()
+}
+
+// AbandonedPagedSearches 客户端以页大小 0 放弃的分页搜索数
+func (s *FakeLDAPServer) AbandonedPagedSearches() int64 {
+	return s.abandoned.Load()
+}
+
+// OpenConns 当前打开的连接数
+func (s *FakeLDAPServer) OpenConns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
+}
+
+// AddEntry 添加目录条目
+func (s *FakeLDAPServer) AddEntry(entry *FakeLDAPEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entry)
+}
+
+// DropConnections 断开所有客户端连接 (模拟服务器重启或防火墙回收空闲连接)
+func (s *FakeLDAPServer) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close()
+		delete(s.conns, c)
+	}
+}
+
+// ResetBinds 清除所有连接的绑定状态 (模拟 AD 在会话过期后丢失绑定)，之后的搜索返回 Operations Error
+func (s *FakeLDAPServer) ResetBinds() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.mu.Lock()
+		c.bound = false
+		c.mu.Unlock()
+	}
+}
+
+// Close 停止服务器
+func (s *FakeLDAPServer) Close() {
+	s.listener.Close()
+	s.DropConnections()
+}
+
+func (s *FakeLDAPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.dials.Add(1)
+		c := &fakeLDAPConn{Conn: conn}
+		s.mu.Lock()
+		s.conns[c] = struct{}{}
+		s.mu.Unlock()
+		go s.handle(c)
+	}
+}
+
+func (s *FakeLDAPServer) handle(c *fakeLDAPConn) {
+	defer func() {
+		c.Close()
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+	}()
+
+	if s.Latency > 0 {
+		time.Sleep(s.Latency)
+	}
+
+	for {
+		packet, err := ber.ReadPacket(c)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			s.binds.Add(1)
+			if s.BindLatency > 0 {
+				time.Sleep(s.BindLatency)
+			}
+			code, message := s.bind(c, op)
+			s.reply(c, messageID, ldapResult(ldap.ApplicationBindResponse, code, message))
+		case ldap.ApplicationUnbindRequest:
+			return
+		case ldap.ApplicationSearchRequest:
+			s.search(c, messageID, op, pagingControl(packet))
+		case ldap.ApplicationExtendedRequest:
+			code, message := s.extended(c, op)
+			s.reply(c, messageID, ldapResult(ldap.ApplicationExtendedResponse, code, message))
+		case ldap.ApplicationModifyRequest:
+			code, message := s.modify(c, op)
+			s.reply(c, messageID, ldapResult(ldap.ApplicationModifyResponse, code, message))
+		default:
+			s.reply(c, messageID, ldapResult(ldap.ApplicationExtendedResponse, ldap.LDAPResultUnwillingToPerform, "operation not supported"))
+		}
+	}
+}
+
+func (s *FakeLDAPServer) bind(c *fakeLDAPConn, op *ber.Packet) (uint16, string) {
+	dn := op.Children[1].Data.String()
+	password := op.Children[2].Data.String()
+
+	entry := s.find(dn)
+	var entryPassword, subCode string
+	if entry != nil {
+		s.mu.Lock()
+		entryPassword, subCode = entry.Password, entry.BindSubCode
+		s.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.bound = false
+	if dn == "" && password == "" {
+		c.bound = true
+		c.bindDN = ""
+		return ldap.LDAPResultSuccess, ""
+	}
+	if entry == nil || entryPassword == "" || entryPassword != password {
+		return ldap.LDAPResultInvalidCredentials, ""
+	}
+	if subCode != "" {
+		return ldap.LDAPResultInvalidCredentials, "80090308: LdapErr: DSID-0C09044E, comment: AcceptSecurityContext error, data " + subCode + ", v4563"
+	}
+	c.bound = true
+	c.bindDN = entry.DN
+	return ldap.LDAPResultSuccess, ""
+}
+
+func (s *FakeLDAPServer) search(c *fakeLDAPConn, messageID int64, op *ber.Packet, paging *ldap.ControlPaging) {
+	c.mu.Lock()
+	bound := c.bound
+	c.mu.Unlock()
+	if !bound {
+		s.reply(c, messageID, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultOperationsError,
+			"000004DC: LdapErr: DSID-0C090A5C, comment: In order to perform this operation a successful bind must be completed on the connection."))
+		return
+	}
+
+	baseDN := op.Children[0].Data.String()
+	scope := op.Children[1].Value.(int64)
+	sizeLimit := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+
+	// Root DSE: used by clients as a cheap liveness probe
+	if baseDN == "" && scope == ldap.ScopeBaseObject {
+		s.reply(c, messageID, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess, ""))
+		return
+	}
+
+	s.mu.Lock()
+	var matched []*FakeLDAPEntry
+	for _, entry := range s.entries {
+		if !inScope(entry.DN, baseDN, scope) || !matchFilter(s.entries, entry, filter) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+	s.mu.Unlock()
+
+	if scope == ldap.ScopeBaseObject && len(matched) == 0 && s.find(baseDN) == nil {
+		s.reply(c, messageID, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultNoSuchObject, ""))
+		return
+	}
+
+	if paging != nil {
+		s.searchPage(c, messageID, matched, paging)
+		return
+	}
+
+	if limit := int64(s.MaxPageSize); limit > 0 && (sizeLimit == 0 || sizeLimit > limit) {
+		sizeLimit = limit
+	}
+	for i, entry := range matched {
+		if sizeLimit > 0 && int64(i) >= sizeLimit {
+			s.reply(c, messageID, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSizeLimitExceeded, ""))
+			return
+		}
+		s.reply(c, messageID, searchEntry(entry))
+	}
+	s.reply(c, messageID, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess, ""))
+}
+
+const fakeLDAPPasswordModifyOID = "1.3.6.1.4.1.4203.1.11.1"
+
+// extended 处理 RFC 3062 Password Modify: 未指定用户时修改绑定用户自己的密码 (需要旧密码)，
+// 管理员可以不带旧密码修改其它条目的密码
+func (s *FakeLDAPServer) extended(c *fakeLDAPConn, op *ber.Packet) (uint16, string) {
+	if len(op.Children) == 0 || op.Children[0].Data.String() != fakeLDAPPasswordModifyOID {
+		return ldap.LDAPResultProtocolError, "unsupported extended operation"
+	}
+
+	var identity, oldPassword, newPassword string
+	if len(op.Children) > 1 {
+		value, err := ber.DecodePacketErr(op.Children[1].Data.Bytes())
+		if err != nil {
+			return ldap.LDAPResultProtocolError, "malformed password modify request"
+		}
+		for _, child := range value.Children {
+			switch child.Tag {
+			case 0:
+				identity = child.Data.String()
+			case 1:
+				oldPassword = child.Data.String()
+			case 2:
+				newPassword = child.Data.String()
+			}
+		}
+	}
+
+	c.mu.Lock()
+	bindDN, bound := c.bindDN, c.bound
+	c.mu.Unlock()
+	if !bound || bindDN == "" {
+		return ldap.LDAPResultUnwillingToPerform, "authentication required"
+	}
+	if identity == "" {
+		identity = bindDN
+	}
+	return s.setPassword(bindDN, identity, oldPassword, newPassword, oldPassword != "")
+}
+
+// modify 处理 AD 的 unicodePwd 修改: 删除旧值并添加新值为用户修改密码，替换为管理员重置密码
+func (s *FakeLDAPServer) modify(c *fakeLDAPConn, op *ber.Packet) (uint16, string) {
+	c.mu.Lock()
+	bindDN, bound := c.bindDN, c.bound
+	c.mu.Unlock()
+	if !bound || bindDN == "" {
+		return ldap.LDAPResultOperationsError, "000004DC: LdapErr: DSID-0C090A5C, comment: In order to perform this operation a successful bind must be completed on the connection."
+	}
+
+	dn := op.Children[0].Data.String()
+	var oldPassword, newPassword string
+	checkOld := false
+	for _, change := range op.Children[1].Children {
+		operation := change.Children[0].Value.(int64)
+		attr := change.Children[1]
+		if !strings.EqualFold(attr.Children[0].Data.String(), "unicodePwd") || len(attr.Children[1].Children) != 1 {
+			return ldap.LDAPResultUnwillingToPerform, "only single-valued unicodePwd modifications are supported"
+		}
+		value := decodeUnicodePwd(attr.Children[1].Children[0].Data.Bytes())
+		switch operation {
+		case ldap.DeleteAttribute:
+			oldPassword, checkOld = value, true
+		case ldap.AddAttribute, ldap.ReplaceAttribute:
+			newPassword = value
+		}
+	}
+	code, message := s.setPassword(bindDN, dn, oldPassword, newPassword, checkOld)
+	if code == ldap.LDAPResultInvalidCredentials {
+		// AD 在旧密码错误时返回 Constraint Violation
+		return ldap.LDAPResultConstraintViolation, "00000056: AtrErr: DSID-03190F80, #1:\n\t0: 00000056: DSID-03190F80, problem 1005 (CONSTRAINT_ATT_TYPE), data 0, Att 9005a (unicodePwd)"
+	}
+	return code, message
+}
+
+// setPassword 修改条目的密码: 非管理员只能修改自己的密码且必须提供正确的旧密码
+func (s *FakeLDAPServer) setPassword(bindDN, dn, oldPassword, newPassword string, checkOld bool) (uint16, string) {
+	binder := s.find(bindDN)
+	entry := s.find(dn)
+	if entry == nil {
+		return ldap.LDAPResultNoSuchObject, ""
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	self := strings.EqualFold(bindDN, entry.DN)
+	admin := binder != nil && binder.Admin
+	if !admin && (!self || !checkOld) {
+		return ldap.LDAPResultInsufficientAccessRights, "insufficient access"
+	}
+	if checkOld && entry.Password != oldPassword {
+		return ldap.LDAPResultInvalidCredentials, ""
+	}
+	if newPassword == "" {
+		return ldap.LDAPResultUnwillingToPerform, "password generation is not supported"
+	}
+	if s.PasswordPolicy != nil {
+		if message := s.PasswordPolicy(newPassword); message != "" {
+			return ldap.LDAPResultConstraintViolation, message
+		}
+	}
+	entry.Password = newPassword
+	if entry.BindSubCode == "773" || entry.BindSubCode == "532" {
+		entry.BindSubCode = ""
+	}
+	return ldap.LDAPResultSuccess, ""
+}
+
+// SetBindSubCode 设置条目的 AD 绑定诊断子码，为空时恢复正常绑定
+func (s *FakeLDAPServer) SetBindSubCode(dn, subCode string) {
+	entry := s.find(dn)
+	if entry == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry.BindSubCode = subCode
+}
+
+// Password 返回条目当前的密码
+func (s *FakeLDAPServer) Password(dn string) string {
+	entry := s.find(dn)
+	if entry == nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return entry.Password
+}
+
+// decodeUnicodePwd 解码 AD 的 unicodePwd 值: 带双引号的 UTF-16LE 字符串
+func decodeUnicodePwd(value []byte) string {
+	units := make([]uint16, len(value)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(value[i*2:])
+	}
+	return strings.Trim(string(utf16.Decode(units)), `"`)
+}
+
+// searchPage 返回分页搜索的一页，cookie 为下一页的起始位置
+func (s *FakeLDAPServer) searchPage(c *fakeLDAPConn, messageID int64, matched []*FakeLDAPEntry, paging *ldap.ControlPaging) {
+	s.pages.Add(1)
+	offset := 0
+	if len(paging.Cookie) > 0 {
+		var err error
+		if offset, err = strconv.Atoi(string(paging.Cookie)); err != nil || offset > len(matched) {
+			s.reply(c, messageID, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultUnwillingToPerform, "invalid paged results cookie"))
+			return
+		}
+	}
+
+	next := ""
+	if paging.PagingSize == 0 {
+		s.abandoned.Add(1)
+	} else {
+		size := int(paging.PagingSize)
+		if s.MaxPageSize > 0 && size > s.MaxPageSize {
+			size = s.MaxPageSize
+		}
+		end := min(offset+size, len(matched))
+		for _, entry := range matched[offset:end] {
+			s.reply(c, messageID, searchEntry(entry))
+		}
+		if end < len(matched) {
+			next = strconv.Itoa(end)
+		}
+	}
+
+	response := ldap.NewControlPaging(0)
+	response.SetCookie([]byte(next))
+	controls := ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+	controls.AppendChild(response.Encode())
+	s.reply(c, messageID, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess, ""), controls)
+}
+
+// pagingControl 返回请求中的 Simple Paged Results 控件
+func pagingControl(packet *ber.Packet) *ldap.ControlPaging {
+	if len(packet.Children) < 3 {
+		return nil
+	}
+	for _, child := range packet.Children[2].Children {
+		control, err := ldap.DecodeControl(child)
+		if err != nil {
+			continue
+		}
+		if paging, ok := control.(*ldap.ControlPaging); ok {
+			return paging
+		}
+	}
+	return nil
+}
+
+func (s *FakeLDAPServer) find(dn string) *FakeLDAPEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, dn) {
+			return entry
+		}
+	}
+	return nil
+}
+
+func (s *FakeLDAPServer) reply(c *fakeLDAPConn, messageID int64, op *ber.Packet, controls ...*ber.Packet) {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+	packet.AppendChild(op)
+	for _, control := range controls {
+		packet.AppendChild(control)
+	}
+	c.Write(packet.Bytes())
+}
+
+func ldapResult(tag ber.Tag, code uint16, message string) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "Diagnostic Message"))
+	return op
+}
+
+func searchEntry(entry *FakeLDAPEntry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "Object Name"))
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for name, values := range entry.Attributes {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+		}
+		attr.AppendChild(set)
+		attributes.AppendChild(attr)
+	}
+	op.AppendChild(attributes)
+	return op
+}
+
+func inScope(dn, baseDN string, scope int64) bool {
+	dn, baseDN = strings.ToLower(dn), strings.ToLower(baseDN)
+	switch scope {
+	case ldap.ScopeBaseObject:
+		return dn == baseDN
+	case ldap.ScopeSingleLevel:
+		i := strings.Index(dn, ",")
+		return i >= 0 && dn[i+1:] == baseDN
+	default:
+		return baseDN == "" || dn == baseDN || strings.HasSuffix(dn, ","+baseDN)
+	}
+}
+
+// matchFilter 支持 and / or / not / equality / present / substrings 过滤器，
+// 以及 AD 的 LDAP_MATCHING_RULE_IN_CHAIN 扩展匹配 (如 (member:1.2.840.113556.1.4.1941:=<DN>))
+func matchFilter(entries []*FakeLDAPEntry, entry *FakeLDAPEntry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchFilter(entries, entry, child) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchFilter(entries, entry, child) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matchFilter(entries, entry, filter.Children[0])
+	case ldap.FilterPresent:
+		name := filter.Data.String()
+		return strings.EqualFold(name, "objectClass") || len(attributeValues(entry, name)) > 0
+	case ldap.FilterEqualityMatch:
+		name, value := filter.Children[0].Data.String(), filter.Children[1].Data.String()
+		if strings.EqualFold(name, "distinguishedName") {
+			return strings.EqualFold(entry.DN, value)
+		}
+		for _, v := range attributeValues(entry, name) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterSubstrings:
+		name := filter.Children[0].Data.String()
+		for _, v := range attributeValues(entry, name) {
+			if matchSubstrings(strings.ToLower(v), filter.Children[1].Children) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterExtensibleMatch:
+		var rule, name, value string
+		for _, child := range filter.Children {
+			switch child.Tag {
+			case ldap.MatchingRuleAssertionMatchingRule:
+				rule = child.Data.String()
+			case ldap.MatchingRuleAssertionType:
+				name = child.Data.String()
+			case ldap.MatchingRuleAssertionMatchValue:
+				value = child.Data.String()
+			}
+		}
+		if rule != fakeLDAPMatchingRuleInChain {
+			return false
+		}
+		return inChain(entries, entry, name, value, map[string]bool{})
+	default:
+		return false
+	}
+}
+
+const fakeLDAPMatchingRuleInChain = "1.2.840.113556.1.4.1941"
+
+// inChain 条目的属性是否 (经由中间条目的同名属性) 传递地引用了目标 DN
+func inChain(entries []*FakeLDAPEntry, entry *FakeLDAPEntry, name, target string, visited map[string]bool) bool {
+	key := strings.ToLower(entry.DN)
+	if visited[key] {
+		return false
+	}
+	visited[key] = true
+
+	for _, v := range attributeValues(entry, name) {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+		for _, next := range entries {
+			if strings.EqualFold(next.DN, v) && inChain(entries, next, name, target, visited) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchSubstrings(value string, parts []*ber.Packet) bool {
+	for _, part := range parts {
+		sub := strings.ToLower(part.Data.String())
+		switch part.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, sub) {
+				return false
+			}
+			value = value[len(sub):]
+		case ldap.FilterSubstringsAny:
+			i := strings.Index(value, sub)
+			if i < 0 {
+				return false
+			}
+			value = value[i+len(sub):]
+		case ldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(value, sub) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func attributeValues(entry *FakeLDAPEntry, name string) []string {
+	for attr, values := range entry.Attributes {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+	return nil
+}
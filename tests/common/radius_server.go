@@ -0,0 +1,260 @@
+package common
+
+import (
+	"context"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+	"layeh.com/radius"
+	"layeh.com/radius/rfc2865"
+)
+
+// FakeRADIUSUser RADIUS 用户
+type FakeRADIUSUser struct {
+	Password string
+	OTP      string   // 非空时密码正确后以 Access-Challenge 要求输入该一次性密码
+	Class    []string // Access-Accept 中返回的 Class 属性
+}
+
+// FakeRADIUSServer 本地 UDP RADIUS 服务器，支持 PAP、MS-CHAPv2 与 Access-Challenge，用于测试 RADIUSProvider。
+// 响应始终携带 Message-Authenticator，请求中的 Message-Authenticator 不正确时丢弃请求
+type FakeRADIUSServer struct {
+	// OmitMessageAuthenticator 响应不携带 Message-Authenticator (模拟旧版服务器)
+	OmitMessageAuthenticator bool
+
+	conn   net.PacketConn
+	server *radius.PacketServer
+	secret []byte
+
+	mu     sync.Mutex
+	users  map[string]*FakeRADIUSUser
+	states map[string]string // State -> 用户名
+
+	silent   atomic.Bool
+	requests atomic.Int64
+}
+
+// NewFakeRADIUSServer 在本地随机端口启动 RADIUS 服务器，测试结束时自动关闭
+func NewFakeRADIUSServer(t testing.TB, secret string, users map[string]*FakeRADIUSUser) *FakeRADIUSServer {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	s := &FakeRADIUSServer{
+		conn:   conn,
+		secret: []byte(secret),
+		users:  users,
+		states: make(map[string]string),
+	}
+	s.server = &radius.PacketServer{
+		SecretSource: radius.StaticSecretSource(s.secret),
+		Handler:      radius.HandlerFunc(s.serveRADIUS),
+	}
+	go s.server.Serve(conn)
+	t.Cleanup(func() {
+		_ = s.server.Shutdown(context.Background())
+	})
+	return s
+}
+
+// Addr 监听地址 (host:port)
+func (s *FakeRADIUSServer) Addr() string {
+	return s.conn.LocalAddr().String()
+}
+
+// SetSilent 为 true 时丢弃所有请求 (模拟服务器宕机)
+func (s *FakeRADIUSServer) SetSilent(silent bool) {
+	s.silent.Store(silent)
+}
+
+// Requests 收到的请求数 (含重发与丢弃的请求)
+func (s *FakeRADIUSServer) Requests() int64 {
+	return s.requests.Load()
+}
+
+func (s *FakeRADIUSServer) serveRADIUS(w radius.ResponseWriter, r *radius.Request) {
+	s.requests.Add(1)
+	if s.silent.Load() || r.Code != radius.CodeAccessRequest || !s.validMessageAuthenticator(r.Packet) {
+		return
+	}
+
+	username := rfc2865.UserName_GetString(r.Packet)
+	s.mu.Lock()
+	user := s.users[username]
+	var challenged bool
+	if state := rfc2865.State_Get(r.Packet); len(state) > 0 {
+		// State 只能使用一次
+		challenged = s.states[string(state)] == username
+		delete(s.states, string(state))
+	}
+	s.mu.Unlock()
+
+	response := r.Response(radius.CodeAccessReject)
+	switch {
+	case user == nil:
+		rfc2865.ReplyMessage_SetString(response, "unknown user")
+	case challenged:
+		// 回应挑战: 一次性密码以与密码相同的方式发送
+		if success, ok := s.verifyPassword(r.Packet, username, user.OTP); ok {
+			s.accept(response, user, success)
+		}
+	default:
+		success, ok := s.verifyPassword(r.Packet, username, user.Password)
+		if !ok {
+			rfc2865.ReplyMessage_SetString(response, "invalid credentials")
+			break
+		}
+		if user.OTP == "" {
+			s.accept(response, user, success)
+			break
+		}
+		state := make([]byte, 16)
+		_, _ = rand.Read(state)
+		s.mu.Lock()
+		s.states[string(state)] = username
+		s.mu.Unlock()
+		response.Code = radius.CodeAccessChallenge
+		rfc2865.State_Set(response, state)
+		rfc2865.ReplyMessage_SetString(response, "Enter your one-time password")
+	}
+
+	if !s.OmitMessageAuthenticator {
+		s.signResponse(response, r.Authenticator)
+	}
+	_ = w.Write(response)
+}
+
+func (s *FakeRADIUSServer) accept(response *radius.Packet, user *FakeRADIUSUser, mschapSuccess radius.Attribute) {
+	response.Code = radius.CodeAccessAccept
+	for _, class := range user.Class {
+		rfc2865.Class_AddString(response, class)
+	}
+	if mschapSuccess != nil {
+		response.Add(rfc2865.VendorSpecific_Type, mschapSuccess)
+	}
+}
+
+// verifyPassword 校验 User-Password (PAP) 或 MS-CHAP2-Response，MS-CHAPv2 校验通过时返回 MS-CHAP2-Success 属性
+func (s *FakeRADIUSServer) verifyPassword(packet *radius.Packet, username, password string) (radius.Attribute, bool) {
+	if _, ok := packet.Lookup(rfc2865.UserPassword_Type); ok {
+		return nil, rfc2865.UserPassword_GetString(packet) == password
+	}
+
+	var authChallenge, response []byte
+	for _, vsa := range packet.Attributes[rfc2865.VendorSpecific_Type] {
+		vendorID, value, err := radius.VendorSpecific(vsa)
+		if err != nil || vendorID != 311 || len(value) < 2 || int(value[1]) != len(value) {
+			continue
+		}
+		switch value[0] {
+		case 11: // MS-CHAP-Challenge
+			authChallenge = value[2:]
+		case 25: // MS-CHAP2-Response
+			response = value[2:]
+		}
+	}
+	if len(authChallenge) != 16 || len(response) != 50 {
+		return nil, false
+	}
+
+	ident, peerChallenge, ntResponse := response[0], response[2:18], response[26:50]
+	challenge := fakeChallengeHash(peerChallenge, authChallenge, username)
+	passwordHash := fakeMD4(fakeUTF16LE(password))
+	if !hmac.Equal(fakeChallengeResponse(challenge, passwordHash), ntResponse) {
+		return nil, false
+	}
+
+	digest := sha1.Sum(append(append(fakeMD4(passwordHash), ntResponse...), "Magic server to client signing constant"...))
+	digest = sha1.Sum(append(append(digest[:], challenge...), "Pad to make it do more than one iteration"...))
+	success := append([]byte{26, 0, ident}, "S="+strings.ToUpper(hex.EncodeToString(digest[:]))...)
+	success[1] = byte(len(success))
+	vsa, err := radius.NewVendorSpecific(311, success)
+	if err != nil {
+		return nil, false
+	}
+	return vsa, true
+}
+
+// validMessageAuthenticator 请求中没有 Message-Authenticator 或其值正确
+func (s *FakeRADIUSServer) validMessageAuthenticator(packet *radius.Packet) bool {
+	value, ok := packet.Lookup(80)
+	if !ok {
+		return true
+	}
+	copied := *packet
+	copied.Attributes = make(radius.Attributes, len(packet.Attributes))
+	for typ, attrs := range packet.Attributes {
+		copied.Attributes[typ] = attrs
+	}
+	copied.Attributes[80] = []radius.Attribute{make(radius.Attribute, md5.Size)}
+	wire, err := copied.Encode()
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(md5.New, s.secret)
+	mac.Write(wire)
+	return hmac.Equal(mac.Sum(nil), value)
+}
+
+// signResponse 添加 Message-Authenticator: 以请求的 Authenticator 计算 (RFC 3579 3.2)
+func (s *FakeRADIUSServer) signResponse(response *radius.Packet, requestAuthenticator [16]byte) {
+	response.Set(80, make(radius.Attribute, md5.Size))
+	// Access-Request 编码时原样保留 Authenticator，借此得到以请求 Authenticator 填充的响应
+	unsigned := *response
+	unsigned.Code = radius.CodeAccessRequest
+	unsigned.Authenticator = requestAuthenticator
+	wire, err := unsigned.Encode()
+	if err != nil {
+		return
+	}
+	wire[0] = byte(response.Code)
+	mac := hmac.New(md5.New, s.secret)
+	mac.Write(wire)
+	response.Set(80, mac.Sum(nil))
+}
+
+func fakeChallengeHash(peerChallenge, authChallenge []byte, username string) []byte {
+	sum := sha1.Sum(append(append(append([]byte(nil), peerChallenge...), authChallenge...), username...))
+	return sum[:8]
+}
+
+func fakeUTF16LE(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return b
+}
+
+func fakeMD4(data []byte) []byte {
+	hash := md4.New()
+	hash.Write(data)
+	return hash.Sum(nil)
+}
+
+func fakeChallengeResponse(challenge, passwordHash []byte) []byte {
+	key := make([]byte, 21)
+	copy(key, passwordHash)
+	response := make([]byte, 24)
+	for i := 0; i < 3; i++ {
+		k := key[i*7:]
+		block, _ := des.NewCipher([]byte{
+			k[0], k[0]<<7 | k[1]>>1, k[1]<<6 | k[2]>>2, k[2]<<5 | k[3]>>3,
+			k[3]<<4 | k[4]>>4, k[4]<<3 | k[5]>>5, k[5]<<2 | k[6]>>6, k[6] << 1,
+		})
+		block.Encrypt(response[i*8:], challenge)
+	}
+	return response
+}
@@ -2,14 +2,20 @@ package common
 
 import (
 	"auth-service/internal/svc"
+	"auth-service/internal/types"
 	"context"
+	"iter"
 )
 
 // Manual Mock for LDAPClient
 type MockLDAPClient struct {
-	AuthenticateFunc  func(ctx context.Context, username, password string) (*svc.LDAPUserInfo, error)
-	GetUserGroupsFunc func(ctx context.Context, userDN string) ([]string, error)
-	IsEnabledFunc     func() bool
+	AuthenticateFunc   func(ctx context.Context, username, password string) (*svc.LDAPUserInfo, error)
+	GetUserGroupsFunc  func(ctx context.Context, userDN string) ([]string, error)
+	ListUsersFunc      func(ctx context.Context) ([]*svc.LDAPUserInfo, error)
+	SyncUsersFunc      func(ctx context.Context) iter.Seq2[*svc.LDAPUserInfo, error]
+	ChangePasswordFunc func(ctx context.Context, userDN, oldPassword, newPassword string) error
+	ResetPasswordFunc  func(ctx context.Context, userDN, newPassword string) error
+	IsEnabledFunc      func() bool
 }
 
 func (m *MockLDAPClient) Authenticate(ctx context.Context, username, password string) (*svc.LDAPUserInfo, error) {
@@ -26,6 +32,46 @@ func (m *MockLDAPClient) GetUserGroups(ctx context.Context, userDN string) ([]st
 	return nil, nil
 }
 
+func (m *MockLDAPClient) ListUsers(ctx context.Context) ([]*svc.LDAPUserInfo, error) {
+	if m.ListUsersFunc != nil {
+		return m.ListUsersFunc(ctx)
+	}
+	return nil, nil
+}
+
+// SyncUsers 未设置 SyncUsersFunc 时逐个返回 ListUsersFunc 的结果
+func (m *MockLDAPClient) SyncUsers(ctx context.Context) iter.Seq2[*svc.LDAPUserInfo, error] {
+	if m.SyncUsersFunc != nil {
+		return m.SyncUsersFunc(ctx)
+	}
+	return func(yield func(*svc.LDAPUserInfo, error) bool) {
+		users, err := m.ListUsers(ctx)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, user := range users {
+			if !yield(user, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *MockLDAPClient) ChangePassword(ctx context.Context, userDN, oldPassword, newPassword string) error {
+	if m.ChangePasswordFunc != nil {
+		return m.ChangePasswordFunc(ctx, userDN, oldPassword, newPassword)
+	}
+	return nil
+}
+
+func (m *MockLDAPClient) ResetPassword(ctx context.Context, userDN, newPassword string) error {
+	if m.ResetPasswordFunc != nil {
+		return m.ResetPasswordFunc(ctx, userDN, newPassword)
+	}
+	return nil
+}
+
 func (m *MockLDAPClient) IsEnabled() bool {
 	if m.IsEnabledFunc != nil {
 		return m.IsEnabledFunc()
@@ -33,12 +79,44 @@ func (m *MockLDAPClient) IsEnabled() bool {
 	return false
 }
 
+// Manual Mock for RADIUSClient
+type MockRADIUSClient struct {
+	AuthenticateFunc     func(ctx context.Context, username, password string) (*svc.RADIUSResult, error)
+	RespondChallengeFunc func(ctx context.Context, challenge *svc.RADIUSChallenge, username, response string) (*svc.RADIUSResult, error)
+	IsEnabledFunc        func() bool
+}
+
+func (m *MockRADIUSClient) Authenticate(ctx context.Context, username, password string) (*svc.RADIUSResult, error) {
+	if m.AuthenticateFunc != nil {
+		return m.AuthenticateFunc(ctx, username, password)
+	}
+	return nil, svc.ErrRADIUSRejected
+}
+
+func (m *MockRADIUSClient) RespondChallenge(ctx context.Context, challenge *svc.RADIUSChallenge, username, response string) (*svc.RADIUSResult, error) {
+	if m.RespondChallengeFunc != nil {
+		return m.RespondChallengeFunc(ctx, challenge, username, response)
+	}
+	return nil, svc.ErrRADIUSRejected
+}
+
+func (m *MockRADIUSClient) IsEnabled() bool {
+	if m.IsEnabledFunc != nil {
+		return m.IsEnabledFunc()
+	}
+	return false
+}
+
 // Manual Mock for OIDCClient
 type MockOIDCClient struct {
 	IsEnabledFunc           func() bool
-	GetAuthorizationURLFunc func(state, nonce string) string
-	ExchangeCodeFunc        func(ctx context.Context, code string) (*svc.OIDCTokenResponse, error)
+	GetAuthorizationURLFunc func(state, nonce, codeChallenge string) string
+	ExchangeCodeFunc        func(ctx context.Context, code, codeVerifier string) (*svc.OIDCTokenResponse, error)
+	VerifyIDTokenFunc       func(ctx context.Context, rawIDToken, nonce string) (*svc.OIDCIDToken, error)
 	GetUserInfoFunc         func(ctx context.Context, accessToken string) (*svc.OIDCUserInfo, error)
+	RefreshAccessTokenFunc  func(ctx context.Context, refreshToken string) (*svc.OIDCTokenResponse, error)
+	VerifyLogoutTokenFunc   func(ctx context.Context, rawLogoutToken string) (*svc.OIDCLogoutToken, error)
+	GetLogoutURLFunc        func(idToken, postLogoutRedirectURI string) string
 }
 
 func (m *MockOIDCClient) IsEnabled() bool {
@@ -48,16 +126,23 @@ func (m *MockOIDCClient) IsEnabled() bool {
 	return false
 }
 
-func (m *MockOIDCClient) GetAuthorizationURL(state, nonce string) string {
+func (m *MockOIDCClient) GetAuthorizationURL(state, nonce, codeChallenge string) string {
 	if m.GetAuthorizationURLFunc != nil {
-		return m.GetAuthorizationURLFunc(state, nonce)
+		return m.GetAuthorizationURLFunc(state, nonce, codeChallenge)
 	}
 	return ""
 }
 
-func (m *MockOIDCClient) ExchangeCode(ctx context.Context, code string) (*svc.OIDCTokenResponse, error) {
+func (m *MockOIDCClient) ExchangeCode(ctx context.Context, code, codeVerifier string) (*svc.OIDCTokenResponse, error) {
 	if m.ExchangeCodeFunc != nil {
-		return m.ExchangeCodeFunc(ctx, code)
+		return m.ExchangeCodeFunc(ctx, code, codeVerifier)
+	}
+	return nil, nil
+}
+
+func (m *MockOIDCClient) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*svc.OIDCIDToken, error) {
+	if m.VerifyIDTokenFunc != nil {
+		return m.VerifyIDTokenFunc(ctx, rawIDToken, nonce)
 	}
 	return nil, nil
 }
@@ -68,3 +153,99 @@ func (m *MockOIDCClient) GetUserInfo(ctx context.Context, accessToken string) (*
 	}
 	return nil, nil
 }
+
+func (m *MockOIDCClient) RefreshAccessToken(ctx context.Context, refreshToken string) (*svc.OIDCTokenResponse, error) {
+	if m.RefreshAccessTokenFunc != nil {
+		return m.RefreshAccessTokenFunc(ctx, refreshToken)
+	}
+	return nil, nil
+}
+
+func (m *MockOIDCClient) VerifyLogoutToken(ctx context.Context, rawLogoutToken string) (*svc.OIDCLogoutToken, error) {
+	if m.VerifyLogoutTokenFunc != nil {
+		return m.VerifyLogoutTokenFunc(ctx, rawLogoutToken)
+	}
+	return nil, nil
+}
+
+func (m *MockOIDCClient) GetLogoutURL(idToken, postLogoutRedirectURI string) string {
+	if m.GetLogoutURLFunc != nil {
+		return m.GetLogoutURLFunc(idToken, postLogoutRedirectURI)
+	}
+	return ""
+}
+
+// Manual Mock for OAuth2Client
+type MockOAuth2Client struct {
+	IsEnabledFunc           func() bool
+	GetAuthorizationURLFunc func(state string) string
+	ExchangeCodeFunc        func(ctx context.Context, code string) (*svc.OAuth2Token, error)
+	GetUserInfoFunc         func(ctx context.Context, token *svc.OAuth2Token) (*types.SSOUserInfo, error)
+}
+
+func (m *MockOAuth2Client) IsEnabled() bool {
+	if m.IsEnabledFunc != nil {
+		return m.IsEnabledFunc()
+	}
+	return false
+}
+
+func (m *MockOAuth2Client) GetAuthorizationURL(state string) string {
+	if m.GetAuthorizationURLFunc != nil {
+		return m.GetAuthorizationURLFunc(state)
+	}
+	return ""
+}
+
+func (m *MockOAuth2Client) ExchangeCode(ctx context.Context, code string) (*svc.OAuth2Token, error) {
+	if m.ExchangeCodeFunc != nil {
+		return m.ExchangeCodeFunc(ctx, code)
+	}
+	return nil, nil
+}
+
+func (m *MockOAuth2Client) GetUserInfo(ctx context.Context, token *svc.OAuth2Token) (*types.SSOUserInfo, error) {
+	if m.GetUserInfoFunc != nil {
+		return m.GetUserInfoFunc(ctx, token)
+	}
+	return nil, nil
+}
+
+// Manual Mock for EmailSender
+type MockEmailSender struct {
+	SendResetEmailFunc       func(to, resetURL string) error
+	SendVerificationCodeFunc func(to, code string) error
+	SendMagicLinkFunc        func(to, loginURL string) error
+}
+
+func (m *MockEmailSender) SendResetEmail(to, resetURL string) error {
+	if m.SendResetEmailFunc != nil {
+		return m.SendResetEmailFunc(to, resetURL)
+	}
+	return nil
+}
+
+func (m *MockEmailSender) SendVerificationCode(to, code string) error {
+	if m.SendVerificationCodeFunc != nil {
+		return m.SendVerificationCodeFunc(to, code)
+	}
+	return nil
+}
+
+func (m *MockEmailSender) SendMagicLink(to, loginURL string) error {
+	if m.SendMagicLinkFunc != nil {
+		return m.SendMagicLinkFunc(to, loginURL)
+	}
+	return nil
+}
+
+type MockSMSSender struct {
+	SendVerificationCodeFunc func(ctx context.Context, phone, code string) error
+}
+
+func (m *MockSMSSender) SendVerificationCode(ctx context.Context, phone, code string) error {
+	if m.SendVerificationCodeFunc != nil {
+		return m.SendVerificationCodeFunc(ctx, phone, code)
+	}
+	return nil
+}
@@ -122,6 +122,20 @@ func TestHandlers_Simple(t *testing.T) {
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
 
+	t.Run("SSOExchange_Bad", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/auth/sso/exchange", nil)
+		w := httptest.NewRecorder()
+		handler.SSOExchangeHandler(svcCtx)(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("OAuth2Callback_Bad", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/auth/sso/oauth2/github/callback", nil)
+		w := httptest.NewRecorder()
+		handler.OAuth2CallbackHandler(svcCtx)(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
 	t.Run("LDAPLogin_Bad", func(t *testing.T) {
 		req := httptest.NewRequest("POST", "/auth/sso/ldap/login", nil)
 		w := httptest.NewRecorder()
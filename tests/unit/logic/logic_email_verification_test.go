@@ -0,0 +1,255 @@
+package logic_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	model "auth-service/model/mysql"
+	"auth-service/tests/common"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+)
+
+func TestEmailVerification(t *testing.T) {
+	helper := common.NewTestHelper(t)
+	defer helper.Cleanup()
+	svcCtx := helper.SetupServiceContext(true)
+	svcCtx.Config.EmailVerification.Enable = true
+	ctx := context.Background()
+
+	suffix := time.Now().UnixNano()
+	user := &model.User{
+		Id:            uint64(suffix % 1000000),
+		PublicId:      fmt.Sprintf("pub_%d", suffix),
+		Username:      fmt.Sprintf("verify_%d", suffix),
+		Email:         fmt.Sprintf("verify_%d@example.com", suffix),
+		AccountStatus: model.UserStatusActive,
+	}
+	svcCtx.UserModel = &model.MockUserModel{
+		FindOneByEmailFunc: func(ctx context.Context, email string) (*model.User, error) {
+			if email == user.Email {
+				copied := *user
+				return &copied, nil
+			}
+			return nil, model.ErrNotFound
+		},
+		FindOneFunc: func(ctx context.Context, id uint64) (*model.User, error) {
+			if id == user.Id {
+				copied := *user
+				return &copied, nil
+			}
+			return nil, model.ErrNotFound
+		},
+		UpdateFunc: func(ctx context.Context, data *model.User) error {
+			user.EmailVerified = data.EmailVerified
+			return nil
+		},
+	}
+
+	sent := make(chan string, 4)
+	svcCtx.Email = &common.MockEmailSender{
+		SendVerificationCodeFunc: func(to, code string) error {
+			assert.Equal(t, user.Email, to)
+			sent <- code
+			return nil
+		},
+	}
+	receive := func(t *testing.T) string {
+		select {
+		case code := <-sent:
+			return code
+		case <-time.After(2 * time.Second):
+			t.Fatal("verification code was not sent")
+			return ""
+		}
+	}
+	assertNotSent := func(t *testing.T, msg string) {
+		select {
+		case <-sent:
+			t.Fatal(msg)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	resend := func(email string) *types.BaseResponse {
+		resp, err := logic.NewResendVerificationLogic(ctx, svcCtx).ResendVerification(&types.ResendVerificationReq{Email: email})
+		require.NoError(t, err)
+		return resp
+	}
+	verify := func(email, code string) *types.BaseResponse {
+		resp, err := logic.NewVerifyEmailLogic(ctx, svcCtx).VerifyEmail(&types.VerifyEmailReq{Email: email, Code: code})
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Unknown Email", func(t *testing.T) {
+		assert.EqualValues(t, 200, resend(fmt.Sprintf("unknown_%d@example.com", suffix)).Code)
+		assertNotSent(t, "no code should be sent to an unknown address")
+	})
+
+	var code string
+	t.Run("Resend Cooldown", func(t *testing.T) {
+		assert.EqualValues(t, 200, resend(user.Email).Code)
+		code = receive(t)
+		require.Len(t, code, 6)
+
+		assert.EqualValues(t, 200, resend(user.Email).Code)
+		assertNotSent(t, "a second code should not be sent within the resend interval")
+	})
+
+	t.Run("Wrong Code", func(t *testing.T) {
+		wrong := "000000"
+		if code == wrong {
+			wrong = "111111"
+		}
+		assert.EqualValues(t, 400, verify(user.Email, wrong).Code)
+		assert.EqualValues(t, 0, user.EmailVerified)
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		assert.EqualValues(t, 200, verify(user.Email, code).Code)
+		assert.EqualValues(t, 1, user.EmailVerified)
+		assert.EqualValues(t, 400, verify(user.Email, code).Code, "codes are single use")
+	})
+
+	t.Run("Already Verified", func(t *testing.T) {
+		require.NoError(t, svcCtx.Redis.FlushDB(ctx).Err())
+		assert.EqualValues(t, 200, resend(user.Email).Code)
+		assertNotSent(t, "verified emails do not receive codes")
+	})
+
+	t.Run("Too Many Attempts", func(t *testing.T) {
+		code, err := svc.CreateEmailVerification(ctx, svcCtx.Redis, user.Id, user.Email)
+		require.NoError(t, err)
+		wrong := "000000"
+		if code == wrong {
+			wrong = "111111"
+		}
+		for i := 0; i < svc.EmailVerificationMaxAttempts; i++ {
+			_, err := svc.VerifyEmailVerification(ctx, svcCtx.Redis, user.Email, wrong)
+			assert.ErrorIs(t, err, svc.ErrInvalidVerificationCode)
+		}
+		_, err = svc.VerifyEmailVerification(ctx, svcCtx.Redis, user.Email, code)
+		assert.ErrorIs(t, err, svc.ErrInvalidVerificationCode, "the code is discarded after too many attempts")
+	})
+
+	t.Run("Concurrent Attempts Are Counted Atomically", func(t *testing.T) {
+		code, err := svc.CreateEmailVerification(ctx, svcCtx.Redis, user.Id, user.Email)
+		require.NoError(t, err)
+		wrong := "000000"
+		if code == wrong {
+			wrong = "111111"
+		}
+
+		// 并发的错误猜测不能通过读-改-写丢失计数
+		var wg sync.WaitGroup
+		for i := 0; i < 4*svc.EmailVerificationMaxAttempts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := svc.VerifyEmailVerification(ctx, svcCtx.Redis, user.Email, wrong)
+				assert.ErrorIs(t, err, svc.ErrInvalidVerificationCode)
+			}()
+		}
+		wg.Wait()
+
+		_, err = svc.VerifyEmailVerification(ctx, svcCtx.Redis, user.Email, code)
+		assert.ErrorIs(t, err, svc.ErrInvalidVerificationCode, "the code is discarded after too many attempts")
+	})
+
+	t.Run("New Code Resets Attempts", func(t *testing.T) {
+		_, err := svc.CreateEmailVerification(ctx, svcCtx.Redis, user.Id, user.Email)
+		require.NoError(t, err)
+		for i := 0; i < svc.EmailVerificationMaxAttempts-1; i++ {
+			_, err := svc.VerifyEmailVerification(ctx, svcCtx.Redis, user.Email, "not-a-code")
+			assert.ErrorIs(t, err, svc.ErrInvalidVerificationCode)
+		}
+		code, err := svc.CreateEmailVerification(ctx, svcCtx.Redis, user.Id, user.Email)
+		require.NoError(t, err)
+		_, err = svc.VerifyEmailVerification(ctx, svcCtx.Redis, user.Email, "not-a-code")
+		assert.ErrorIs(t, err, svc.ErrInvalidVerificationCode)
+		verification, err := svc.VerifyEmailVerification(ctx, svcCtx.Redis, user.Email, code)
+		require.NoError(t, err)
+		assert.Equal(t, user.Email, verification.Target)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		svcCtx.Config.EmailVerification.Enable = false
+		defer func() { svcCtx.Config.EmailVerification.Enable = true }()
+		assert.EqualValues(t, 503, verify(user.Email, code).Code)
+	})
+}
+
+func TestLoginLogic_Login_EmailNotVerified(t *testing.T) {
+	login := func(t *testing.T, enforce string, emailVerified int) (*types.LoginResp, *svc.ServiceContext, error) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		mock.MatchExpectationsInOrder(false)
+
+		passwordHash := (&svc.PasswordEncoder{}).Hash("password123")
+		now := time.Now()
+		mock.ExpectQuery("SELECT \\* FROM user WHERE username = \\?").
+			WithArgs("testuser").
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "public_id", "nickname", "username", "email", "email_verified",
+				"phone", "phone_verified", "password_hash", "password_salt",
+				"mfa_secret", "mfa_enabled", "account_status", "failed_login_attempts",
+				"lockout_until", "last_login_at", "created_at", "updated_at", "deleted_at",
+			}).AddRow(
+				1, "user123", nil, "testuser", "test@example.com", emailVerified,
+				nil, 0, passwordHash, nil,
+				nil, 0, model.UserStatusActive, 0,
+				nil, nil, now, now, nil,
+			))
+		mock.ExpectQuery("SELECT \\* FROM user WHERE email = \\?").WithArgs("testuser").WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("SELECT \\* FROM user WHERE phone = \\?").WithArgs("testuser").WillReturnError(sql.ErrNoRows)
+		mock.ExpectExec("UPDATE user SET last_login_at = NOW\\(\\)").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		svcCtx := setupTestServiceContextWithJWT(t, mock)
+		svcCtx.DB = sqlx.NewSqlConnFromDB(db)
+		svcCtx.Config.EmailVerification.Enable = true
+		svcCtx.Config.EmailVerification.Enforce = enforce
+
+		resp, err := logic.NewLoginLogic(context.Background(), svcCtx).Login(&types.LoginReq{Username: "testuser", Password: "password123"})
+		return resp, svcCtx, err
+	}
+
+	t.Run("None", func(t *testing.T) {
+		resp, svcCtx, err := login(t, config.EmailVerificationEnforceNone, 0)
+		require.NoError(t, err)
+		assert.False(t, resp.EmailVerified)
+		claims, err := svcCtx.JWT.VerifyAccessToken(resp.AccessToken)
+		require.NoError(t, err)
+		assert.Empty(t, claims.Scope)
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		_, _, err := login(t, config.EmailVerificationEnforceBlock, 0)
+		assert.True(t, errors.Is(err, types.ErrEmailNotVerified))
+
+		resp, _, err := login(t, config.EmailVerificationEnforceBlock, 1)
+		require.NoError(t, err, "verified users can sign in")
+		assert.True(t, resp.EmailVerified)
+	})
+
+	t.Run("Restrict", func(t *testing.T) {
+		resp, svcCtx, err := login(t, config.EmailVerificationEnforceRestrict, 0)
+		require.NoError(t, err)
+		assert.Equal(t, svc.ScopeEmailUnverified, resp.Scope)
+		claims, err := svcCtx.JWT.VerifyAccessToken(resp.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, svc.ScopeEmailUnverified, claims.Scope)
+	})
+}
@@ -82,6 +82,7 @@ func setupTestServiceContextWithJWT(t *testing.T, mock sqlmock.Sqlmock) *svc.Ser
 		Redis:           rdb,
 		PasswordEncoder: &svc.PasswordEncoder{},
 		JWT:             jwt,
+		UserRoleModel:   &model.MockUserRoleModel{},
 	}
 }
 
@@ -133,6 +134,11 @@ func TestLoginLogic_Login_Success(t *testing.T) {
 
 	svcCtx := setupTestServiceContextWithJWT(t, mock)
 	svcCtx.DB = sqlx.NewSqlConnFromDB(db)
+	svcCtx.UserRoleModel = &model.MockUserRoleModel{
+		FindAllByUserIdFunc: func(ctx context.Context, userId uint64) ([]*model.UserRole, error) {
+			return []*model.UserRole{{UserId: userId, Role: "editor", Source: model.RoleSourceManual}}, nil
+		},
+	}
 
 	l := logic.NewLoginLogic(context.Background(), svcCtx)
 
@@ -163,6 +169,15 @@ func TestLoginLogic_Login_Success(t *testing.T) {
 		t.Error("RefreshToken should not be empty")
 	}
 
+	// 令牌包含用户的角色
+	claims, err := svcCtx.JWT.VerifyAccessToken(resp.AccessToken)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken() error = %v", err)
+	}
+	if len(claims.Roles) != 1 || claims.Roles[0] != "editor" {
+		t.Errorf("Roles = %v, want [editor]", claims.Roles)
+	}
+
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Errorf("Unfulfilled expectations: %v", err)
 	}
@@ -263,3 +278,54 @@ func TestLoginLogic_Login_UserNotFound(t *testing.T) {
 		t.Errorf("Expected ErrUserNotFound, got %v", err)
 	}
 }
+
+func TestLoginLogic_Login_InactiveAccount(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int64
+		want   error
+	}{
+		{"Pending", model.UserStatusPending, types.ErrAccountPending},
+		{"Disabled", model.UserStatusDisabled, types.ErrAccountDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+			mock.MatchExpectationsInOrder(false)
+
+			// 账号设置了本地密码 (如通过重置密码)，但仍待审批或已被禁用
+			now := time.Now()
+			mock.ExpectQuery("SELECT \\* FROM user WHERE username = \\?").
+				WithArgs("testuser").
+				WillReturnRows(sqlmock.NewRows([]string{
+					"id", "public_id", "nickname", "username", "email", "email_verified",
+					"phone", "phone_verified", "password_hash", "password_salt",
+					"mfa_secret", "mfa_enabled", "account_status", "failed_login_attempts",
+					"lockout_until", "last_login_at", "created_at", "updated_at", "deleted_at",
+				}).AddRow(
+					1, "user123", nil, "testuser", "test@example.com", 1,
+					nil, 0, (&svc.PasswordEncoder{}).Hash("password123"), nil,
+					nil, 0, tt.status, 0,
+					nil, nil, now, now, nil,
+				))
+			mock.ExpectQuery("SELECT \\* FROM user WHERE email = \\?").WithArgs("testuser").WillReturnError(sql.ErrNoRows)
+			mock.ExpectQuery("SELECT \\* FROM user WHERE phone = \\?").WithArgs("testuser").WillReturnError(sql.ErrNoRows)
+
+			svcCtx := setupTestServiceContextWithJWT(t, mock)
+			svcCtx.DB = sqlx.NewSqlConnFromDB(db)
+
+			resp, err := logic.NewLoginLogic(context.Background(), svcCtx).Login(&types.LoginReq{Username: "testuser", Password: "password123"})
+			if err != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+			if resp != nil {
+				t.Error("No tokens should be issued to an inactive account")
+			}
+		})
+	}
+}
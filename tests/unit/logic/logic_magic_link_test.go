@@ -0,0 +1,276 @@
+package logic_test
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	model "auth-service/model/mysql"
+	"auth-service/tests/common"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMagicLink(t *testing.T) {
+	helper := common.NewTestHelper(t)
+	defer helper.Cleanup()
+	svcCtx := helper.SetupServiceContext(true)
+	svcCtx.Config.FrontendURL = "https://app.example.com/"
+	svcCtx.Config.MagicLink.Enable = true
+	ctx := context.Background()
+
+	// 每次运行使用不同的用户，避免受上次运行留在 Redis 中的发送限制影响
+	suffix := time.Now().UnixNano()
+	user := &model.User{
+		Id:            uint64(suffix % 1000000),
+		PublicId:      fmt.Sprintf("pub_%d", suffix),
+		Username:      fmt.Sprintf("magic_%d", suffix),
+		Email:         fmt.Sprintf("magic_%d@example.com", suffix),
+		AccountStatus: model.UserStatusActive,
+	}
+	var updated *model.User
+	svcCtx.UserModel = &model.MockUserModel{
+		FindOneByEmailFunc: func(ctx context.Context, email string) (*model.User, error) {
+			if email == user.Email {
+				copied := *user
+				return &copied, nil
+			}
+			return nil, model.ErrNotFound
+		},
+		FindOneFunc: func(ctx context.Context, id uint64) (*model.User, error) {
+			if id == user.Id {
+				copied := *user
+				return &copied, nil
+			}
+			return nil, model.ErrNotFound
+		},
+		UpdateFunc: func(ctx context.Context, data *model.User) error {
+			updated = data
+			return nil
+		},
+	}
+	svcCtx.UserRoleModel = &model.MockUserRoleModel{
+		FindAllByUserIdFunc: func(ctx context.Context, userId uint64) ([]*model.UserRole, error) {
+			if userId == user.Id {
+				return []*model.UserRole{{UserId: user.Id, Role: "editor", Source: model.RoleSourceManual}}, nil
+			}
+			return nil, nil
+		},
+	}
+
+	sent := make(chan string, 8)
+	svcCtx.Email = &common.MockEmailSender{
+		SendMagicLinkFunc: func(to, loginURL string) error {
+			assert.Equal(t, user.Email, to)
+			sent <- loginURL
+			return nil
+		},
+	}
+	receive := func(t *testing.T) string {
+		select {
+		case loginURL := <-sent:
+			u, err := url.Parse(loginURL)
+			require.NoError(t, err)
+			assert.Equal(t, "https://app.example.com/magic-link", u.Scheme+"://"+u.Host+u.Path)
+			return u.Query().Get("token")
+		case <-time.After(2 * time.Second):
+			t.Fatal("magic link email was not sent")
+			return ""
+		}
+	}
+	assertNotSent := func(t *testing.T, msg string) {
+		select {
+		case <-sent:
+			t.Fatal(msg)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	request := func(email, deviceID string) *types.BaseResponse {
+		resp, err := logic.NewMagicLinkLogic(ctx, svcCtx).MagicLink(&types.MagicLinkReq{Email: email, DeviceID: deviceID})
+		require.NoError(t, err)
+		return resp
+	}
+	consume := func(token, deviceID string) *types.BaseResponse {
+		resp, err := logic.NewMagicLinkConsumeLogic(ctx, svcCtx).MagicLinkConsume(&types.MagicLinkConsumeReq{Token: token, DeviceID: deviceID})
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Not Enabled", func(t *testing.T) {
+		svcCtx.Config.MagicLink.Enable = false
+		defer func() { svcCtx.Config.MagicLink.Enable = true }()
+		assert.EqualValues(t, 503, request(user.Email, "").Code)
+		assert.EqualValues(t, 503, consume("token", "").Code)
+	})
+
+	t.Run("Unknown Email", func(t *testing.T) {
+		resp := request(fmt.Sprintf("unknown_%d@example.com", suffix), "")
+		assert.EqualValues(t, 200, resp.Code, "the response does not reveal whether the email is registered")
+		assertNotSent(t, "no email should be sent to an unknown address")
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		require.NoError(t, flushMagicLinkLimits(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 200, request(user.Email, "").Code)
+		token := receive(t)
+		require.NotEmpty(t, token)
+
+		resp := consume(token, "")
+		require.EqualValues(t, 200, resp.Code)
+		data, ok := resp.Data.(*types.LoginResp)
+		require.True(t, ok)
+		assert.Equal(t, user.PublicId, data.UserID)
+		assert.True(t, data.EmailVerified)
+
+		claims, err := svcCtx.JWT.VerifyAccessToken(data.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, user.Id, claims.UserID)
+		assert.Equal(t, []string{"editor"}, claims.Roles)
+
+		session, err := svc.GetLoginSession(ctx, svcCtx.Redis, claims.SessionID)
+		require.NoError(t, err)
+		require.NotNil(t, session, "the login is recorded in the session registry")
+		assert.Equal(t, svc.SessionMethodEmail, session.Method)
+		assert.Equal(t, user.Id, session.UserID)
+		oidcSession, err := svc.GetOIDCSession(ctx, svcCtx.Redis, claims.SessionID)
+		require.NoError(t, err)
+		assert.Nil(t, oidcSession, "magic link sessions are not OIDC sessions")
+
+		require.NotNil(t, updated)
+		assert.EqualValues(t, 1, updated.EmailVerified)
+		assert.True(t, updated.LastLoginAt.Valid)
+
+		assert.EqualValues(t, 400, consume(token, "").Code, "links are single use")
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		// 启用默认 OIDC 提供者，确认登出不会把邮件登录的会话当作 OIDC 会话结束
+		svcCtx.OIDC = svc.NewOIDCProviders(svc.OIDCProviderEntry{Name: svc.DefaultOIDCProviderName, Client: &common.MockOIDCClient{
+			IsEnabledFunc: func() bool { return true },
+			GetLogoutURLFunc: func(idToken, postLogoutRedirectURI string) string {
+				return "https://idp.example.com/logout"
+			},
+		}})
+
+		require.NoError(t, flushMagicLinkLimits(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 200, request(user.Email, "").Code)
+		resp := consume(receive(t), "")
+		require.EqualValues(t, 200, resp.Code)
+		data := resp.Data.(*types.LoginResp)
+		claims, err := svcCtx.JWT.VerifyAccessToken(data.AccessToken)
+		require.NoError(t, err)
+
+		logout, err := logic.NewLogoutLogic(ctx, svcCtx).Logout(&types.LogoutReq{
+			AccessToken:  data.AccessToken,
+			RefreshToken: data.RefreshToken,
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, logout.Code)
+		assert.Nil(t, logout.Data, "magic link sessions have no IdP session to end")
+
+		session, err := svc.GetLoginSession(ctx, svcCtx.Redis, claims.SessionID)
+		require.NoError(t, err)
+		assert.Nil(t, session, "the session is removed from the registry on logout")
+	})
+
+	t.Run("Rate Limit", func(t *testing.T) {
+		require.NoError(t, flushMagicLinkLimits(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 200, request(user.Email, "").Code)
+		receive(t)
+
+		assert.EqualValues(t, 200, request(user.Email, "").Code)
+		assertNotSent(t, "a second email should not be sent during the cooldown")
+
+		// 冷却结束后仍受每小时上限限制
+		for i := 1; i < svc.MagicLinkHourlyLimit; i++ {
+			require.NoError(t, flushMagicLinkCooldown(ctx, svcCtx.Redis))
+			assert.EqualValues(t, 200, request(user.Email, "").Code)
+			receive(t)
+		}
+		require.NoError(t, flushMagicLinkCooldown(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 200, request(user.Email, "").Code)
+		assertNotSent(t, "no email should be sent over the hourly limit")
+	})
+
+	t.Run("Forged Signature", func(t *testing.T) {
+		require.NoError(t, flushMagicLinkLimits(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 200, request(user.Email, "").Code)
+		token := receive(t)
+
+		id, _, ok := strings.Cut(token, ".")
+		require.True(t, ok)
+		assert.EqualValues(t, 400, consume(id+".forged", "").Code)
+		assert.EqualValues(t, 400, consume(id, "").Code)
+		assert.EqualValues(t, 400, consume("", "").Code)
+		assert.EqualValues(t, 200, consume(token, "").Code, "rejected forgeries do not consume the link")
+	})
+
+	t.Run("Device Binding", func(t *testing.T) {
+		svcCtx.Config.MagicLink.DeviceBinding = true
+		defer func() { svcCtx.Config.MagicLink.DeviceBinding = false }()
+
+		require.NoError(t, flushMagicLinkLimits(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 400, request(user.Email, "").Code, "a device id is required")
+
+		assert.EqualValues(t, 200, request(user.Email, "device-a").Code)
+		token := receive(t)
+
+		assert.EqualValues(t, 400, consume(token, "device-b").Code)
+		assert.EqualValues(t, 400, consume(token, "").Code)
+		assert.EqualValues(t, 200, consume(token, "device-a").Code, "the requesting device can still use the link")
+	})
+
+	t.Run("Email Changed", func(t *testing.T) {
+		require.NoError(t, flushMagicLinkLimits(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 200, request(user.Email, "").Code)
+		token := receive(t)
+
+		original := user.Email
+		user.Email = fmt.Sprintf("changed_%d@example.com", suffix)
+		defer func() { user.Email = original }()
+		assert.EqualValues(t, 400, consume(token, "").Code)
+	})
+
+	t.Run("Disabled Account", func(t *testing.T) {
+		require.NoError(t, flushMagicLinkLimits(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 200, request(user.Email, "").Code)
+		token := receive(t)
+
+		user.AccountStatus = model.UserStatusDisabled
+		defer func() { user.AccountStatus = model.UserStatusActive }()
+		assert.EqualValues(t, 1027, consume(token, "").Code)
+
+		require.NoError(t, flushMagicLinkLimits(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 200, request(user.Email, "").Code)
+		assertNotSent(t, "no link should be sent to a disabled account")
+	})
+}
+
+// flushMagicLinkCooldown 清除登录链接的发送间隔
+func flushMagicLinkCooldown(ctx context.Context, rdb redis.UniversalClient) error {
+	return flushKeys(ctx, rdb, "auth:magiclink:cooldown:*")
+}
+
+// flushMagicLinkLimits 清除登录链接的发送间隔与每小时计数
+func flushMagicLinkLimits(ctx context.Context, rdb redis.UniversalClient) error {
+	if err := flushMagicLinkCooldown(ctx, rdb); err != nil {
+		return err
+	}
+	return flushKeys(ctx, rdb, "auth:magiclink:hourly:*")
+}
+
+func flushKeys(ctx context.Context, rdb redis.UniversalClient, pattern string) error {
+	keys, err := rdb.Keys(ctx, pattern).Result()
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+	return rdb.Del(ctx, keys...).Err()
+}
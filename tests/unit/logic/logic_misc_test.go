@@ -8,6 +8,7 @@ import (
 	"auth-service/internal/logic"
 	"auth-service/internal/svc"
 	"auth-service/internal/types"
+	model "auth-service/model/mysql"
 
 	"github.com/mojocn/base64Captcha"
 	"github.com/zeromicro/go-zero/core/logx"
@@ -85,17 +86,17 @@ func TestForgotPasswordLogic_ForgotPassword(t *testing.T) {
 	svcCtx := &svc.ServiceContext{} // minimal context
 	l := logic.NewForgotPasswordLogic(context.Background(), svcCtx)
 
-	// Currently empty implementation
-	_, err := l.ForgotPassword(&types.ForgotPasswordReq{
+	// Email, Redis and FrontendURL are not configured
+	resp, err := l.ForgotPassword(&types.ForgotPasswordReq{
 		Email: "test@example.com",
 	})
 
 	if err != nil {
-		// If implementation is empty, it might return nil error or panic?
-		// The scaffolded code returns (resp, err) with named returns, initialized to nil.
-		// So err should be nil.
 		t.Errorf("Unexpected error: %v", err)
 	}
+	if resp.Code != 503 {
+		t.Errorf("Expected code 503, got %d", resp.Code)
+	}
 }
 
 func TestRefreshLogic_Refresh(t *testing.T) {
@@ -120,6 +121,11 @@ func TestRefreshLogic_Refresh(t *testing.T) {
 	svcCtx := &svc.ServiceContext{
 		Config: cfg,
 		JWT:    jwtService,
+		UserModel: &model.MockUserModel{
+			FindOneFunc: func(ctx context.Context, id uint64) (*model.User, error) {
+				return &model.User{Id: id, Username: "testuser", AccountStatus: model.UserStatusActive}, nil
+			},
+		},
 	}
 
 	// Generate a valid token pair
@@ -149,6 +155,59 @@ func TestRefreshLogic_Refresh(t *testing.T) {
 	}
 }
 
+func TestRefreshLogic_Refresh_DisabledAccount(t *testing.T) {
+	cfg := config.Config{
+		Auth: struct {
+			AccessSecret         string
+			AccessExpiresIn      int64
+			RefreshSecret        string
+			RefreshExpiresIn     int64
+			BlacklistCachePrefix string
+		}{
+			AccessSecret:     "secret",
+			AccessExpiresIn:  3600,
+			RefreshSecret:    "secret",
+			RefreshExpiresIn: 7200,
+		},
+	}
+
+	jwtService := svc.NewJWT(cfg, nil)
+
+	tests := []struct {
+		name    string
+		status  uint64
+		wantErr error
+	}{
+		{"Disabled", model.UserStatusDisabled, types.ErrAccountDisabled},
+		{"Pending", model.UserStatusPending, types.ErrAccountPending},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svcCtx := &svc.ServiceContext{
+				Config: cfg,
+				JWT:    jwtService,
+				UserModel: &model.MockUserModel{
+					FindOneFunc: func(ctx context.Context, id uint64) (*model.User, error) {
+						return &model.User{Id: id, Username: "testuser", AccountStatus: tt.status}, nil
+					},
+				},
+			}
+
+			tokenPair, err := jwtService.Generate(1, "testuser")
+			if err != nil {
+				t.Fatalf("Failed to generate token: %v", err)
+			}
+
+			l := logic.NewRefreshLogic(context.Background(), svcCtx)
+			_, err = l.Refresh(&types.RefreshReq{RefreshToken: tokenPair.RefreshToken})
+			if err != tt.wantErr {
+				t.Errorf("Refresh() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestLogoutLogic_Logout_Fail(t *testing.T) {
 	// Test failure path when Redis is missing (or JWT not capable of blacklist)
 	cfg := config.Config{
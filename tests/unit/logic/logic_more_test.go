@@ -3,6 +3,7 @@ package logic_test
 import (
 	"auth-service/internal/logic"
 	"auth-service/internal/svc"
+	"auth-service/internal/types"
 	"context"
 	"testing"
 
@@ -26,13 +27,13 @@ func TestHealthCheckLogic_HealthCheck(t *testing.T) {
 func TestConfirmPasswordLogic_ConfirmPassword(t *testing.T) {
 	svcCtx := &svc.ServiceContext{}
 	l := logic.NewConfirmPasswordLogic(context.Background(), svcCtx)
-	// Currently placeholder implementation
-	resp, err := l.ConfirmPassword(nil)
+	// Reset tokens cannot be verified without Redis
+	resp, err := l.ConfirmPassword(&types.ConfirmPasswordReq{Token: "token", NewPassword: "newPassword"})
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
-	if resp.Code != 200 {
-		t.Errorf("Expected code 200, got %d", resp.Code)
+	if resp.Code != 400 {
+		t.Errorf("Expected code 400, got %d", resp.Code)
 	}
 }
 
@@ -46,9 +47,12 @@ func TestSSOProvidersLogic_Get(t *testing.T) {
 	}
 
 	svcCtx := &svc.ServiceContext{
-		OIDC: mockOIDC,
-		LDAP: mockLDAP,
+		OIDC: svc.NewOIDCProviders(
+			svc.OIDCProviderEntry{Name: "oidc", Client: mockOIDC},
+			svc.OIDCProviderEntry{Name: "google", DisplayName: "Google", Icon: "/icons/google.svg", Client: mockOIDC},
+		),
 	}
+	common.SetLDAP(svcCtx, mockLDAP, nil)
 
 	l := logic.NewSSOProvidersLogic(context.Background(), svcCtx)
 	resp, err := l.SSOProviders()
@@ -58,11 +62,15 @@ func TestSSOProvidersLogic_Get(t *testing.T) {
 	}
 
 	foundOIDC := false
+	foundGoogle := false
 	foundLDAP := false
 	for _, p := range resp.Providers {
 		if p.Name == "OpenID Connect" {
 			foundOIDC = true
 		}
+		if p.ID == "google" && p.Name == "Google" && p.Icon == "/icons/google.svg" && p.LoginURL == "/api/v1/sso/oidc/google/login" {
+			foundGoogle = true
+		}
 		if p.Name == "LDAP / Active Directory" {
 			foundLDAP = true
 		}
@@ -71,6 +79,9 @@ func TestSSOProvidersLogic_Get(t *testing.T) {
 	if !foundOIDC {
 		t.Error("Expected OIDC provider")
 	}
+	if !foundGoogle {
+		t.Error("Expected named Google OIDC provider")
+	}
 	if !foundLDAP {
 		t.Error("Expected LDAP provider")
 	}
@@ -0,0 +1,210 @@
+package logic_test
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"auth-service/internal/logic"
+	"auth-service/internal/types"
+	model "auth-service/model/mysql"
+	"auth-service/tests/common"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordReset(t *testing.T) {
+	helper := common.NewTestHelper(t)
+	defer helper.Cleanup()
+	svcCtx := helper.SetupServiceContext(true)
+	svcCtx.Config.FrontendURL = "https://app.example.com/"
+	ctx := context.Background()
+
+	// 每次运行使用不同的用户，避免受上次运行留在 Redis 中的冷却时间影响
+	suffix := time.Now().UnixNano()
+	user := &model.User{
+		Id:            uint64(suffix % 1000000),
+		PublicId:      fmt.Sprintf("pub_%d", suffix),
+		Username:      fmt.Sprintf("reset_%d", suffix),
+		Email:         fmt.Sprintf("reset_%d@example.com", suffix),
+		PasswordHash:  svcCtx.PasswordEncoder.Hash("oldPassword"),
+		AccountStatus: model.UserStatusActive,
+	}
+	var updated *model.User
+	svcCtx.UserModel = &model.MockUserModel{
+		FindOneByEmailFunc: func(ctx context.Context, email string) (*model.User, error) {
+			if email == user.Email {
+				copied := *user
+				return &copied, nil
+			}
+			return nil, model.ErrNotFound
+		},
+		FindOneFunc: func(ctx context.Context, id uint64) (*model.User, error) {
+			if id == user.Id {
+				copied := *user
+				return &copied, nil
+			}
+			return nil, model.ErrNotFound
+		},
+		UpdateFunc: func(ctx context.Context, data *model.User) error {
+			updated = data
+			return nil
+		},
+	}
+
+	sent := make(chan string, 4)
+	svcCtx.Email = &common.MockEmailSender{
+		SendResetEmailFunc: func(to, resetURL string) error {
+			assert.Equal(t, user.Email, to)
+			sent <- resetURL
+			return nil
+		},
+	}
+	receive := func(t *testing.T) string {
+		select {
+		case resetURL := <-sent:
+			u, err := url.Parse(resetURL)
+			require.NoError(t, err)
+			assert.Equal(t, "https://app.example.com/reset-password", u.Scheme+"://"+u.Host+u.Path)
+			return u.Query().Get("token")
+		case <-time.After(2 * time.Second):
+			t.Fatal("reset email was not sent")
+			return ""
+		}
+	}
+	forgot := func(email string) *types.BaseResponse {
+		resp, err := logic.NewForgotPasswordLogic(ctx, svcCtx).ForgotPassword(&types.ForgotPasswordReq{Email: email})
+		require.NoError(t, err)
+		return resp
+	}
+	confirm := func(token, password string) *types.BaseResponse {
+		resp, err := logic.NewConfirmPasswordLogic(ctx, svcCtx).ConfirmPassword(&types.ConfirmPasswordReq{Token: token, NewPassword: password})
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Unknown Email", func(t *testing.T) {
+		resp := forgot(fmt.Sprintf("unknown_%d@example.com", suffix))
+		assert.EqualValues(t, 200, resp.Code, "the response does not reveal whether the email is registered")
+		select {
+		case <-sent:
+			t.Fatal("no email should be sent to an unknown address")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	var oldToken string
+	t.Run("Cooldown", func(t *testing.T) {
+		assert.EqualValues(t, 200, forgot(user.Email).Code)
+		oldToken = receive(t)
+		require.NotEmpty(t, oldToken)
+
+		assert.EqualValues(t, 200, forgot(user.Email).Code)
+		select {
+		case <-sent:
+			t.Fatal("a second email should not be sent during the cooldown")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("New Token Replaces Old", func(t *testing.T) {
+		// 冷却结束后再次申请
+		require.NoError(t, flushPasswordResetCooldown(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 200, forgot(user.Email).Code)
+		newToken := receive(t)
+		require.NotEqual(t, oldToken, newToken)
+
+		assert.EqualValues(t, 400, confirm(oldToken, "newPassword1").Code, "issuing a new token invalidates the old one")
+
+		session, err := svcCtx.JWT.Generate(user.Id, user.Username)
+		require.NoError(t, err)
+
+		resp := confirm(newToken, "newPassword1")
+		assert.EqualValues(t, 200, resp.Code)
+		require.NotNil(t, updated)
+		assert.True(t, svcCtx.PasswordEncoder.Compare(updated.PasswordHash, "newPassword1"))
+		assert.EqualValues(t, 1, updated.EmailVerified)
+
+		_, err = svcCtx.JWT.VerifyAccessToken(session.AccessToken)
+		assert.Error(t, err, "existing sessions are revoked")
+
+		assert.EqualValues(t, 400, confirm(newToken, "newPassword2").Code, "tokens are single use")
+	})
+
+	t.Run("Email Changed", func(t *testing.T) {
+		require.NoError(t, flushPasswordResetCooldown(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 200, forgot(user.Email).Code)
+		token := receive(t)
+
+		original := user.Email
+		user.Email = fmt.Sprintf("changed_%d@example.com", suffix)
+		defer func() { user.Email = original }()
+		assert.EqualValues(t, 400, confirm(token, "newPassword3").Code)
+	})
+
+	t.Run("Pending Account", func(t *testing.T) {
+		// 待审批的 SSO 账号不能通过重置密码获得本地密码
+		user.AccountStatus = model.UserStatusPending
+		require.NoError(t, flushPasswordResetCooldown(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 200, forgot(user.Email).Code)
+		select {
+		case <-sent:
+			t.Fatal("no reset email should be sent to a pending account")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		// 令牌在账号进入待审批状态之前签发
+		user.AccountStatus = model.UserStatusActive
+		require.NoError(t, flushPasswordResetCooldown(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 200, forgot(user.Email).Code)
+		token := receive(t)
+
+		user.AccountStatus = model.UserStatusPending
+		defer func() { user.AccountStatus = model.UserStatusActive }()
+		assert.EqualValues(t, 400, confirm(token, "newPassword4").Code)
+	})
+
+	t.Run("LDAP Linked Account", func(t *testing.T) {
+		svcCtx.UserIdentityModel = &model.MockUserIdentityModel{
+			FindOneByUserIdProviderFunc: func(ctx context.Context, userId uint64, provider string) (*model.UserIdentity, error) {
+				return &model.UserIdentity{UserId: userId, Provider: provider, ProviderUserId: "uid=reset,ou=users,dc=example,dc=com"}, nil
+			},
+		}
+		defer func() { svcCtx.UserIdentityModel = &model.MockUserIdentityModel{} }()
+
+		// 关联 LDAP 前设置了本地密码的账号仍可重置本地密码
+		require.NoError(t, flushPasswordResetCooldown(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 200, forgot(user.Email).Code)
+		require.NotEmpty(t, receive(t))
+
+		// 由 LDAP 开通的账号 (没有本地密码) 的密码保存在目录中
+		hash := user.PasswordHash
+		user.PasswordHash = ""
+		defer func() { user.PasswordHash = hash }()
+		require.NoError(t, flushPasswordResetCooldown(ctx, svcCtx.Redis))
+		assert.EqualValues(t, 200, forgot(user.Email).Code)
+		select {
+		case <-sent:
+			t.Fatal("no reset email should be sent when the password is managed by the directory")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("Invalid Token", func(t *testing.T) {
+		assert.EqualValues(t, 400, confirm("", "newPassword").Code)
+		assert.EqualValues(t, 400, confirm("not-a-token", "newPassword").Code)
+	})
+}
+
+// flushPasswordResetCooldown 清除重置邮件的冷却时间
+func flushPasswordResetCooldown(ctx context.Context, rdb redis.UniversalClient) error {
+	keys, err := rdb.Keys(ctx, "auth:password:reset:cooldown:*").Result()
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+	return rdb.Del(ctx, keys...).Err()
+}
@@ -0,0 +1,131 @@
+package logic_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	model "auth-service/model/mysql"
+	"auth-service/tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhoneVerification(t *testing.T) {
+	helper := common.NewTestHelper(t)
+	defer helper.Cleanup()
+	svcCtx := helper.SetupServiceContext(true)
+
+	suffix := time.Now().UnixNano()
+	user := &model.User{
+		Id:            uint64(suffix % 1000000),
+		PublicId:      fmt.Sprintf("pub_%d", suffix),
+		Username:      fmt.Sprintf("phone_%d", suffix),
+		Phone:         sql.NullString{String: fmt.Sprintf("138%08d", suffix%100000000), Valid: true},
+		AccountStatus: model.UserStatusActive,
+	}
+	svcCtx.UserModel = &model.MockUserModel{
+		FindOneFunc: func(ctx context.Context, id uint64) (*model.User, error) {
+			if id == user.Id {
+				copied := *user
+				return &copied, nil
+			}
+			return nil, model.ErrNotFound
+		},
+		UpdateFunc: func(ctx context.Context, data *model.User) error {
+			user.PhoneVerified = data.PhoneVerified
+			return nil
+		},
+	}
+
+	var (
+		code    string
+		sendErr error
+	)
+	svcCtx.SMS = &common.MockSMSSender{
+		SendVerificationCodeFunc: func(ctx context.Context, phone, sent string) error {
+			assert.Equal(t, user.Phone.String, phone)
+			code = sent
+			return sendErr
+		},
+	}
+	ctx := context.WithValue(context.Background(), "userID", int64(user.Id))
+	send := func() *types.BaseResponse {
+		resp, err := logic.NewSendPhoneCodeLogic(ctx, svcCtx).SendPhoneCode()
+		require.NoError(t, err)
+		return resp
+	}
+	verify := func(code string) *types.BaseResponse {
+		resp, err := logic.NewVerifyPhoneLogic(ctx, svcCtx).VerifyPhone(&types.VerifyPhoneReq{Code: code})
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Send", func(t *testing.T) {
+		assert.EqualValues(t, 200, send().Code)
+		require.Len(t, code, 6)
+		assert.EqualValues(t, 429, send().Code, "codes cannot be resent within the interval")
+	})
+
+	t.Run("Wrong Code", func(t *testing.T) {
+		wrong := "000000"
+		if code == wrong {
+			wrong = "111111"
+		}
+		assert.EqualValues(t, 400, verify(wrong).Code)
+		assert.EqualValues(t, 0, user.PhoneVerified)
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		assert.EqualValues(t, 200, verify(code).Code)
+		assert.EqualValues(t, 1, user.PhoneVerified)
+		assert.EqualValues(t, 400, verify(code).Code, "codes are single use")
+		assert.EqualValues(t, 400, send().Code, "the phone number is already verified")
+	})
+
+	t.Run("Other User's Code", func(t *testing.T) {
+		user.PhoneVerified = 0
+		other, err := svc.CreatePhoneVerification(context.Background(), svcCtx.Redis, user.Id+1, user.Phone.String)
+		require.NoError(t, err)
+		assert.EqualValues(t, 400, verify(other).Code)
+		assert.EqualValues(t, 0, user.PhoneVerified)
+	})
+
+	t.Run("Gateway Failure", func(t *testing.T) {
+		require.NoError(t, svcCtx.Redis.FlushDB(context.Background()).Err())
+		sendErr = errors.New("gateway unavailable")
+		defer func() { sendErr = nil }()
+		assert.EqualValues(t, 500, send().Code)
+	})
+
+	t.Run("Daily Limit", func(t *testing.T) {
+		require.NoError(t, svcCtx.Redis.FlushDB(context.Background()).Err())
+		phone := user.Phone.String
+		for i := 0; i < svc.PhoneVerificationDailyLimit; i++ {
+			require.NoError(t, svc.AllowPhoneVerification(context.Background(), svcCtx.Redis, phone))
+			keys, err := svcCtx.Redis.Keys(context.Background(), "auth:phone:verify:cooldown:*").Result()
+			require.NoError(t, err)
+			require.NoError(t, svcCtx.Redis.Del(context.Background(), keys...).Err())
+		}
+		assert.ErrorIs(t, svc.AllowPhoneVerification(context.Background(), svcCtx.Redis, phone), svc.ErrPhoneVerificationLimited)
+	})
+
+	t.Run("No Phone", func(t *testing.T) {
+		phone := user.Phone
+		user.Phone = sql.NullString{}
+		defer func() { user.Phone = phone }()
+		assert.EqualValues(t, 400, send().Code)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		svcCtx.SMS = nil
+		assert.EqualValues(t, 503, send().Code)
+	})
+}
@@ -44,10 +44,11 @@ func setupTestServiceContext(t *testing.T, mock sqlmock.Sqlmock) *svc.ServiceCon
 	}
 
 	return &svc.ServiceContext{
-		Config:          cfg,
-		DB:              conn,
-		PasswordEncoder: &svc.PasswordEncoder{},
-		UserModel:       model.NewUserModel(conn),
+		Config:            cfg,
+		DB:                conn,
+		PasswordEncoder:   &svc.PasswordEncoder{},
+		UserModel:         model.NewUserModel(conn),
+		UserIdentityModel: &model.MockUserIdentityModel{},
 	}
 }
 
@@ -174,3 +175,27 @@ func TestRegisterLogic_Register_UsernameTaken(t *testing.T) {
 		t.Errorf("Expected ErrUsernameTaken, got %v", err)
 	}
 }
+
+func TestRegisterLogic_Register_ReservedEmail(t *testing.T) {
+	svcCtx := setupTestServiceContext(t, nil)
+	svcCtx.UserModel = &model.MockUserModel{
+		FindOneByEmailFunc: func(ctx context.Context, email string) (*model.User, error) {
+			t.Errorf("reserved email %s should be rejected before any lookup", email)
+			return nil, model.ErrNotFound
+		},
+	}
+
+	l := logic.NewRegisterLogic(context.Background(), svcCtx)
+
+	// SSO 开通的账号使用占位邮箱，不能被抢先注册
+	for _, email := range []string{"github.42@oauth2.placeholder", "someone@NO-EMAIL.PLACEHOLDER"} {
+		_, err := l.Register(&types.RegisterReq{
+			Username: "squatter",
+			Password: "password123",
+			Email:    email,
+		})
+		if err != types.ErrEmailReserved {
+			t.Errorf("Register(%s): expected ErrEmailReserved, got %v", email, err)
+		}
+	}
+}
@@ -104,3 +104,42 @@ func TestAuthInterceptorMiddleware_Expired(t *testing.T) {
 		t.Errorf("Expected 401 for expired token, got %d", w.Code)
 	}
 }
+
+type scopedClaims struct {
+	mockClaims
+	scope string
+}
+
+func (c *scopedClaims) GetScope() string { return c.scope }
+
+func TestAuthInterceptorMiddleware_Scope(t *testing.T) {
+	m := middleware.NewAuthInterceptorMiddleware()
+	m.SetTokenValidator(func(tokenString string) (middleware.Claims, error) {
+		if tokenString == "restricted-token" {
+			return &scopedClaims{mockClaims: mockClaims{UserID: 1}, scope: "email_unverified"}, nil
+		}
+		return &scopedClaims{mockClaims: mockClaims{UserID: 1}}, nil
+	})
+	m.AllowScope("email_unverified", "/api/v1/me")
+
+	tests := []struct {
+		token string
+		path  string
+		want  int
+	}{
+		{"restricted-token", "/api/v1/me", http.StatusOK},
+		{"restricted-token", "/api/v1/password/change", http.StatusForbidden},
+		{"full-token", "/api/v1/password/change", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		w := httptest.NewRecorder()
+
+		m.Handle(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(w, req)
+
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.token, tt.path, tt.want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package model_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	model "auth-service/model/mysql"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+)
+
+func mockUserIdentityRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "user_id", "provider", "provider_user_id", "email", "linked_at", "last_login_at"})
+}
+
+func TestUserIdentityModel_Insert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m := model.NewUserIdentityModel(sqlx.NewSqlConnFromDB(db))
+
+	identity := &model.UserIdentity{
+		UserId:         1,
+		Provider:       "github",
+		ProviderUserId: "12345",
+		Email:          sql.NullString{String: "octo@example.com", Valid: true},
+		LinkedAt:       time.Now(),
+	}
+
+	mock.ExpectExec("insert into `user_identity`").
+		WithArgs(identity.UserId, identity.Provider, identity.ProviderUserId, identity.Email, identity.LinkedAt, identity.LastLoginAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, err := m.Insert(context.Background(), identity); err != nil {
+		t.Errorf("Insert failed: %v", err)
+	}
+}
+
+func TestUserIdentityModel_FindOneByProviderProviderUserId(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m := model.NewUserIdentityModel(sqlx.NewSqlConnFromDB(db))
+
+	mock.ExpectQuery("select (.+) from `user_identity` where `provider` = \\? and `provider_user_id` = \\?").
+		WithArgs("github", "12345").
+		WillReturnRows(mockUserIdentityRows().AddRow(1, 7, "github", "12345", "octo@example.com", time.Now(), nil))
+
+	res, err := m.FindOneByProviderProviderUserId(context.Background(), "github", "12345")
+	if err != nil {
+		t.Fatalf("FindOneByProviderProviderUserId failed: %v", err)
+	}
+	if res.UserId != 7 || res.LastLoginAt.Valid {
+		t.Errorf("unexpected identity: %+v", res)
+	}
+
+	mock.ExpectQuery("select (.+) from `user_identity` where `provider` = \\? and `provider_user_id` = \\?").
+		WithArgs("github", "missing").
+		WillReturnError(sql.ErrNoRows)
+
+	if _, err := m.FindOneByProviderProviderUserId(context.Background(), "github", "missing"); err != model.ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUserIdentityModel_FindAllByUserId(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m := model.NewUserIdentityModel(sqlx.NewSqlConnFromDB(db))
+
+	mock.ExpectQuery("select (.+) from `user_identity` where `user_id` = \\? order by `linked_at`").
+		WithArgs(7).
+		WillReturnRows(mockUserIdentityRows().
+			AddRow(1, 7, "github", "12345", nil, time.Now(), nil).
+			AddRow(2, 7, "ldap", "uid=jdoe,dc=example,dc=com", "jdoe@example.com", time.Now(), time.Now()))
+
+	res, err := m.FindAllByUserId(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("FindAllByUserId failed: %v", err)
+	}
+	if len(res) != 2 || res[1].Provider != "ldap" || !res[1].LastLoginAt.Valid {
+		t.Errorf("unexpected identities: %+v", res)
+	}
+}
+
+func TestUserIdentityModel_FindAllByProvider(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m := model.NewUserIdentityModel(sqlx.NewSqlConnFromDB(db))
+
+	mock.ExpectQuery("select (.+) from `user_identity` where `provider` = \\? order by `id`").
+		WithArgs("ldap").
+		WillReturnRows(mockUserIdentityRows().
+			AddRow(2, 7, "ldap", "uid=jdoe,dc=example,dc=com", "jdoe@example.com", time.Now(), nil).
+			AddRow(5, 9, "ldap", "uid=asmith,dc=example,dc=com", nil, time.Now(), time.Now()))
+
+	res, err := m.FindAllByProvider(context.Background(), "ldap")
+	if err != nil {
+		t.Fatalf("FindAllByProvider failed: %v", err)
+	}
+	if len(res) != 2 || res[0].UserId != 7 || res[1].ProviderUserId != "uid=asmith,dc=example,dc=com" {
+		t.Errorf("unexpected identities: %+v", res)
+	}
+}
@@ -0,0 +1,88 @@
+package model_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	model "auth-service/model/mysql"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/zeromicro/go-zero/core/stores/sqlx"
+)
+
+func mockUserRoleRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "user_id", "role", "source", "created_at"})
+}
+
+func TestUserRoleModel_Insert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m := model.NewUserRoleModel(sqlx.NewSqlConnFromDB(db))
+
+	mock.ExpectExec("insert into `user_role`").
+		WithArgs(7, "member", model.RoleSourceProvisioning).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	if _, err := m.Insert(context.Background(), &model.UserRole{UserId: 7, Role: "member", Source: model.RoleSourceProvisioning}); err != nil {
+		t.Errorf("Insert failed: %v", err)
+	}
+}
+
+func TestUserRoleModel_FindOneByUserIdRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m := model.NewUserRoleModel(sqlx.NewSqlConnFromDB(db))
+
+	mock.ExpectQuery("select (.+) from `user_role` where `user_id` = \\? and `role` = \\?").
+		WithArgs(7, model.RoleAdmin).
+		WillReturnRows(mockUserRoleRows().AddRow(1, 7, model.RoleAdmin, model.RoleSourceManual, time.Now()))
+
+	res, err := m.FindOneByUserIdRole(context.Background(), 7, model.RoleAdmin)
+	if err != nil {
+		t.Fatalf("FindOneByUserIdRole failed: %v", err)
+	}
+	if res.Role != model.RoleAdmin {
+		t.Errorf("Expected role %s, got %s", model.RoleAdmin, res.Role)
+	}
+
+	mock.ExpectQuery("select (.+) from `user_role` where `user_id` = \\? and `role` = \\?").
+		WithArgs(8, model.RoleAdmin).
+		WillReturnRows(mockUserRoleRows())
+
+	if _, err := m.FindOneByUserIdRole(context.Background(), 8, model.RoleAdmin); err != model.ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestUserRoleModel_FindAllByUserId(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m := model.NewUserRoleModel(sqlx.NewSqlConnFromDB(db))
+
+	mock.ExpectQuery("select (.+) from `user_role` where `user_id` = \\? order by `id`").
+		WithArgs(7).
+		WillReturnRows(mockUserRoleRows().
+			AddRow(1, 7, model.RoleAdmin, model.RoleSourceManual, time.Now()).
+			AddRow(2, 7, "member", model.RoleSourceProvisioning, time.Now()))
+
+	roles, err := m.FindAllByUserId(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("FindAllByUserId failed: %v", err)
+	}
+	if len(roles) != 2 {
+		t.Errorf("Expected 2 roles, got %d", len(roles))
+	}
+}
@@ -0,0 +1,106 @@
+package svc_test
+
+import (
+	"testing"
+
+	"auth-service/internal/config"
+	"auth-service/internal/svc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClaimMapper(t *testing.T) {
+	t.Run("Standard OIDC Claims", func(t *testing.T) {
+		mapper, err := svc.NewClaimMapper(svc.DefaultOIDCClaimMapping, config.ClaimMapping{})
+		require.NoError(t, err)
+
+		identity := mapper.Map(map[string]interface{}{
+			"sub":                "sub-1",
+			"preferred_username": "alice",
+			"email":              "alice@example.com",
+			"email_verified":     true,
+			"given_name":         "Alice",
+			"family_name":        "Liddell",
+			"phone_number":       "+8613800000000",
+			"groups":             []interface{}{"dev", "ops"},
+		})
+		assert.Equal(t, "alice", identity.Username)
+		assert.Equal(t, "alice@example.com", identity.Email)
+		assert.True(t, identity.EmailVerified)
+		assert.Equal(t, "Alice Liddell", identity.Nickname)
+		assert.Equal(t, "+8613800000000", identity.Phone)
+		assert.Equal(t, []string{"dev", "ops"}, identity.Groups)
+	})
+
+	t.Run("Overrides And Expressions", func(t *testing.T) {
+		mapper, err := svc.NewClaimMapper(svc.DefaultOIDCClaimMapping, config.ClaimMapping{
+			Username: []string{"employee_id", "${email|localpart|lower}"},
+			Nickname: []string{"${family_name}${given_name}"},
+			Groups:   []string{"realm_access.roles", "https://example.com/groups"},
+		})
+		require.NoError(t, err)
+
+		identity := mapper.Map(map[string]interface{}{
+			"email":                      "Bob.Smith@Example.com",
+			"email_verified":             "true",
+			"given_name":                 "三",
+			"family_name":                "张",
+			"https://example.com/groups": []interface{}{"staff"},
+		})
+		assert.Equal(t, "bob.smith", identity.Username, "falls back to the expression")
+		assert.Equal(t, "Bob.Smith@Example.com", identity.Email, "unset fields keep defaults")
+		assert.True(t, identity.EmailVerified)
+		assert.Equal(t, "张三", identity.Nickname)
+		assert.Equal(t, []string{"staff"}, identity.Groups, "claim names may contain dots")
+
+		identity = mapper.Map(map[string]interface{}{
+			"employee_id":  float64(10086),
+			"realm_access": map[string]interface{}{"roles": []interface{}{"admin"}},
+		})
+		assert.Equal(t, "10086", identity.Username)
+		assert.Empty(t, identity.Nickname, "expression with no resolved placeholders is empty")
+		assert.Equal(t, []string{"admin"}, identity.Groups)
+	})
+
+	t.Run("Case Insensitive Match Is Deterministic", func(t *testing.T) {
+		mapper, err := svc.NewClaimMapper(svc.DefaultLDAPAttributeMapping, config.ClaimMapping{})
+		require.NoError(t, err)
+
+		// 仅大小写不同的属性按名称排序后取第一个，不受 map 遍历顺序影响
+		for i := 0; i < 20; i++ {
+			identity := mapper.MapAttributes(map[string][]string{
+				"samaccountname": {"mallory"},
+				"SAMACCOUNTNAME": {"carol"},
+				"SamAccountName": {"dave"},
+			})
+			require.Equal(t, "carol", identity.Username)
+		}
+	})
+
+	t.Run("LDAP Attributes", func(t *testing.T) {
+		mapper, err := svc.NewClaimMapper(svc.DefaultLDAPAttributeMapping, config.ClaimMapping{})
+		require.NoError(t, err)
+
+		identity := mapper.MapAttributes(map[string][]string{
+			"SAMACCOUNTNAME": {"carol"},
+			"mail":           {"carol@corp.example.com"},
+			"cn":             {"Carol C"},
+			"mobile":         {"13900000000"},
+			"memberOf":       {"cn=dev,ou=groups,dc=corp", "cn=ops,ou=groups,dc=corp"},
+		})
+		assert.Equal(t, "carol", identity.Username, "attribute names are case-insensitive")
+		assert.Equal(t, "carol@corp.example.com", identity.Email)
+		assert.Equal(t, "Carol C", identity.Nickname)
+		assert.Equal(t, "13900000000", identity.Phone)
+		assert.Len(t, identity.Groups, 2)
+		assert.Contains(t, mapper.Attributes(), "memberOf")
+	})
+
+	t.Run("Rejects Unknown Filter", func(t *testing.T) {
+		_, err := svc.NewClaimMapper(svc.DefaultOIDCClaimMapping, config.ClaimMapping{
+			Username: []string{"${email|reverse}"},
+		})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,80 @@
+package svc_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"auth-service/internal/config"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityProviders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Registry", func(t *testing.T) {
+		enabled := svc.NewRADIUSIdentityProvider(&common.MockRADIUSClient{IsEnabledFunc: func() bool { return true }})
+		providers := svc.NewIdentityProviders(
+			svc.IdentityProviderEntry{Type: types.SSOProviderRADIUS, Provider: enabled},
+			svc.IdentityProviderEntry{Name: "radius-backup", Type: types.SSOProviderRADIUS, Provider: svc.NewRADIUSIdentityProvider(&common.MockRADIUSClient{})},
+		)
+		assert.False(t, providers.Register(svc.IdentityProviderEntry{Type: types.SSOProviderRADIUS, Provider: enabled}), "duplicate name")
+
+		entry, ok := providers.Get("radius")
+		require.True(t, ok, "the name defaults to the type")
+		assert.Equal(t, types.SSOProviderRADIUS, entry.Type)
+		_, ok = providers.Get("radius-backup")
+		assert.False(t, ok, "disabled providers are not returned")
+		assert.Len(t, providers.List(), 2)
+
+		var nilProviders *svc.IdentityProviders
+		_, ok = nilProviders.Get("radius")
+		assert.False(t, ok)
+	})
+
+	t.Run("LDAP", func(t *testing.T) {
+		provider := svc.NewLDAPIdentityProvider(&common.MockLDAPClient{
+			IsEnabledFunc: func() bool { return true },
+			AuthenticateFunc: func(ctx context.Context, username, password string) (*svc.LDAPUserInfo, error) {
+				if password != "secret" {
+					return nil, fmt.Errorf("bind failed: %w", svc.ErrLDAPPasswordExpired)
+				}
+				return &svc.LDAPUserInfo{DN: "UID=Alice,DC=example,DC=com", Username: username, Email: "alice@example.com", Roles: []string{"admin"}}, nil
+			},
+		})
+
+		info, err := provider.Authenticate(ctx, svc.IdentityCredentials{Username: "alice", Password: "secret"})
+		require.NoError(t, err)
+		assert.Equal(t, "uid=alice,dc=example,dc=com", info.ProviderUserID)
+		assert.Equal(t, []string{"admin"}, info.Roles)
+		assert.True(t, info.EmailVerified, "directory emails are managed by the organisation")
+
+		_, err = provider.Authenticate(ctx, svc.IdentityCredentials{Username: "alice", Password: "wrong"})
+		assert.ErrorIs(t, err, svc.ErrIdentityRejected)
+		assert.ErrorIs(t, err, svc.ErrLDAPPasswordExpired, "the directory error is kept")
+	})
+
+	t.Run("RADIUS Challenge", func(t *testing.T) {
+		server := newTestRADIUSServer(t)
+		provider := svc.NewRADIUSIdentityProvider(newTestRADIUSProvider(t, config.RADIUSConfig{}, server))
+
+		_, err := provider.Authenticate(ctx, svc.IdentityCredentials{Username: "bob", Password: "bob-password"})
+		var challenge *svc.IdentityChallenge
+		require.ErrorAs(t, err, &challenge)
+		assert.Equal(t, "Enter your one-time password", challenge.Message)
+
+		info, err := provider.Authenticate(ctx, svc.IdentityCredentials{Username: "bob", Password: "123456", State: challenge.State})
+		require.NoError(t, err)
+		assert.Equal(t, "bob", info.ProviderUserID)
+
+		_, err = provider.Authenticate(ctx, svc.IdentityCredentials{Username: "bob", Password: "123456", State: "not-json"})
+		assert.ErrorIs(t, err, svc.ErrIdentityRejected)
+		_, err = provider.Authenticate(ctx, svc.IdentityCredentials{Username: "alice", Password: "wrong"})
+		assert.ErrorIs(t, err, svc.ErrIdentityRejected)
+	})
+}
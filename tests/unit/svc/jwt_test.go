@@ -300,3 +300,134 @@ func TestJWT_InvalidToken(t *testing.T) {
 		})
 	}
 }
+
+func TestJWT_RevokeSession(t *testing.T) {
+	jwt := setupTestJWT(t)
+
+	tokenPair, err := jwt.Generate(12345, "testuser")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if tokenPair.SessionID == "" {
+		t.Fatal("Generate() should assign a session ID")
+	}
+
+	// 刷新后会话 ID 保持不变
+	refreshed, err := jwt.Refresh(tokenPair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if refreshed.SessionID != tokenPair.SessionID {
+		t.Errorf("Refresh() session ID = %s, want %s", refreshed.SessionID, tokenPair.SessionID)
+	}
+
+	// 吊销会话后刷新得到的令牌同样失效
+	if err := jwt.RevokeSession(tokenPair.SessionID); err != nil {
+		t.Fatalf("RevokeSession() error = %v", err)
+	}
+	if _, err := jwt.VerifyAccessToken(refreshed.AccessToken); err == nil {
+		t.Error("VerifyAccessToken() should fail after the session is revoked")
+	}
+	if _, err := jwt.Refresh(refreshed.RefreshToken); err == nil {
+		t.Error("Refresh() should fail after the session is revoked")
+	}
+
+	// 其它会话不受影响
+	other, err := jwt.Generate(12345, "testuser")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if _, err := jwt.VerifyAccessToken(other.AccessToken); err != nil {
+		t.Errorf("VerifyAccessToken() for another session error = %v", err)
+	}
+}
+
+func TestJWT_RevokeUserTokens(t *testing.T) {
+	jwt := setupTestJWT(t)
+
+	before, err := jwt.Generate(12345, "testuser")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if err := jwt.RevokeUserTokens(12345); err != nil {
+		t.Fatalf("RevokeUserTokens() error = %v", err)
+	}
+	if _, err := jwt.VerifyAccessToken(before.AccessToken); err == nil {
+		t.Error("VerifyAccessToken() should fail for tokens issued before revocation")
+	}
+	if _, err := jwt.Refresh(before.RefreshToken); err == nil {
+		t.Error("Refresh() should fail for tokens issued before revocation")
+	}
+
+	// 吊销后同一秒内签发的令牌仍然有效
+	after, err := jwt.Generate(12345, "testuser")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if _, err := jwt.VerifyAccessToken(after.AccessToken); err != nil {
+		t.Errorf("VerifyAccessToken() for token issued after revocation error = %v", err)
+	}
+	if _, err := jwt.Refresh(after.RefreshToken); err != nil {
+		t.Errorf("Refresh() for token issued after revocation error = %v", err)
+	}
+}
+
+func TestJWT_GenerateWithRoles(t *testing.T) {
+	jwt := setupTestJWT(t)
+
+	tokenPair, err := jwt.GenerateWithRoles(12345, "testuser", []string{"admin", "developer"})
+	if err != nil {
+		t.Fatalf("GenerateWithRoles() error = %v", err)
+	}
+
+	claims, err := jwt.VerifyAccessToken(tokenPair.AccessToken)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken() error = %v", err)
+	}
+	if len(claims.Roles) != 2 || claims.Roles[0] != "admin" || claims.Roles[1] != "developer" {
+		t.Errorf("Roles = %v, want [admin developer]", claims.Roles)
+	}
+
+	// 刷新后的令牌沿用角色
+	newTokenPair, err := jwt.Refresh(tokenPair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	claims, err = jwt.VerifyAccessToken(newTokenPair.AccessToken)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken() error = %v", err)
+	}
+	if len(claims.Roles) != 2 {
+		t.Errorf("Roles after refresh = %v, want [admin developer]", claims.Roles)
+	}
+}
+
+func TestJWT_GenerateWithScope(t *testing.T) {
+	jwt := setupTestJWT(t)
+
+	tokenPair, err := jwt.GenerateWithScope(12345, "testuser", nil, svc.ScopeEmailUnverified)
+	if err != nil {
+		t.Fatalf("GenerateWithScope() error = %v", err)
+	}
+
+	claims, err := jwt.VerifyAccessToken(tokenPair.AccessToken)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken() error = %v", err)
+	}
+	if claims.Scope != svc.ScopeEmailUnverified {
+		t.Errorf("Scope = %q, want %q", claims.Scope, svc.ScopeEmailUnverified)
+	}
+
+	// 刷新不能解除访问范围的限制
+	newTokenPair, err := jwt.Refresh(tokenPair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	claims, err = jwt.VerifyAccessToken(newTokenPair.AccessToken)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken() error = %v", err)
+	}
+	if claims.Scope != svc.ScopeEmailUnverified {
+		t.Errorf("Scope after refresh = %q, want %q", claims.Scope, svc.ScopeEmailUnverified)
+	}
+}
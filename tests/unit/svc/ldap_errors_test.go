@@ -0,0 +1,195 @@
+package svc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"auth-service/internal/config"
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLDAPBindErrors(t *testing.T) {
+	cases := []struct {
+		subCode string
+		reason  error
+	}{
+		{"52e", svc.ErrLDAPInvalidCredentials},
+		{"525", svc.ErrLDAPUserNotFound},
+		{"530", svc.ErrLDAPLogonRestricted},
+		{"531", svc.ErrLDAPLogonRestricted},
+		{"532", svc.ErrLDAPPasswordExpired},
+		{"533", svc.ErrLDAPAccountDisabled},
+		{"701", svc.ErrLDAPAccountExpired},
+		{"773", svc.ErrLDAPPasswordMustChange},
+		{"775", svc.ErrLDAPAccountLocked},
+		{"999", svc.ErrLDAPInvalidCredentials},
+	}
+	for _, c := range cases {
+		t.Run(c.subCode, func(t *testing.T) {
+			server := newTestLDAPServer(t)
+			provider, err := svc.NewLDAPProvider(testLDAPConfig(server, config.LDAPPoolConfig{}))
+			require.NoError(t, err)
+			defer provider.Close()
+
+			server.SetBindSubCode(testUserAlice, c.subCode)
+			_, err = provider.Authenticate(context.Background(), "alice", "alice-password")
+			assert.ErrorIs(t, err, c.reason)
+
+			var bindErr *svc.LDAPBindError
+			require.True(t, errors.As(err, &bindErr))
+			assert.Equal(t, c.subCode, bindErr.SubCode)
+			assert.Equal(t, testUserAlice, bindErr.DN)
+			assert.Contains(t, svc.LDAPDiagnosticMessage(err), "data "+c.subCode)
+		})
+	}
+
+	t.Run("Wrong Password Without Diagnostic", func(t *testing.T) {
+		server := newTestLDAPServer(t)
+		provider, err := svc.NewLDAPProvider(testLDAPConfig(server, config.LDAPPoolConfig{}))
+		require.NoError(t, err)
+		defer provider.Close()
+
+		_, err = provider.Authenticate(context.Background(), "alice", "wrong")
+		assert.ErrorIs(t, err, svc.ErrLDAPInvalidCredentials)
+		assert.EqualError(t, err, "invalid credentials")
+	})
+}
+
+func TestLDAPChangeRequiredPassword(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Active Directory", func(t *testing.T) {
+		provider, server := newPasswordTestProvider(t, svc.LDAPServerTypeAD)
+		server.SetBindSubCode(testUserAlice, "773")
+
+		err := provider.ChangePassword(ctx, testUserAlice, "wrong", "new-alice-password")
+		assert.ErrorIs(t, err, svc.ErrLDAPInvalidCredentials)
+
+		err = provider.ChangePassword(ctx, testUserAlice, "alice-password", "short")
+		assert.ErrorIs(t, err, svc.ErrLDAPPasswordPolicy)
+
+		require.NoError(t, provider.ChangePassword(ctx, testUserAlice, "alice-password", "new-alice-password"))
+		_, err = provider.Authenticate(ctx, "alice", "new-alice-password")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Other Directories", func(t *testing.T) {
+		provider, server := newPasswordTestProvider(t, svc.LDAPServerTypeOpenLDAP)
+		server.SetBindSubCode(testUserAlice, "532")
+
+		err := provider.ChangePassword(ctx, testUserAlice, "alice-password", "new-alice-password")
+		assert.ErrorIs(t, err, svc.ErrLDAPPasswordExpired)
+		assert.Equal(t, "alice-password", server.Password(testUserAlice))
+	})
+}
+
+func TestLDAPLoginDirectoryErrors(t *testing.T) {
+	h := common.NewTestHelper(t)
+	svcCtx := h.SetupServiceContext(true)
+	if svcCtx.Redis == nil {
+		t.Skip("Redis not available")
+	}
+	svcCtx.Config.Captcha.Enable = false
+
+	var authErr, changeErr error
+	var changed []string
+	common.SetLDAP(svcCtx, &common.MockLDAPClient{
+		IsEnabledFunc: func() bool { return true },
+		AuthenticateFunc: func(ctx context.Context, username, password string) (*svc.LDAPUserInfo, error) {
+			return nil, authErr
+		},
+		ChangePasswordFunc: func(ctx context.Context, userDN, oldPassword, newPassword string) error {
+			changed = append(changed, userDN+":"+oldPassword+":"+newPassword)
+			return changeErr
+		},
+	}, nil)
+	bindErr := func(subCode string, reason error) error {
+		return &svc.LDAPBindError{DN: testUserAlice, SubCode: subCode, Reason: reason, Err: errors.New("AcceptSecurityContext error")}
+	}
+	login := func() *types.BaseResponse {
+		resp, err := logic.NewLDAPLoginLogic(context.Background(), svcCtx).LDAPLogin(&types.LDAPLoginReq{
+			Username: "alice",
+			Password: "alice-password",
+		})
+		require.NoError(t, err)
+		return resp
+	}
+	changePassword := func(token, oldPassword string) *types.BaseResponse {
+		resp, err := logic.NewLDAPPasswordChangeLogic(context.Background(), svcCtx).LDAPPasswordChange(&types.LDAPPasswordChangeReq{
+			ChangeToken: token,
+			OldPassword: oldPassword,
+			NewPassword: "new-alice-password",
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Distinct Response Codes", func(t *testing.T) {
+		cases := []struct {
+			err  error
+			code int64
+		}{
+			{bindErr("52e", svc.ErrLDAPInvalidCredentials), 1002},
+			{bindErr("525", svc.ErrLDAPUserNotFound), 1002},
+			{errors.New("user not found"), 1002},
+			{bindErr("530", svc.ErrLDAPLogonRestricted), 1037},
+			{bindErr("533", svc.ErrLDAPAccountDisabled), 1035},
+			{bindErr("701", svc.ErrLDAPAccountExpired), 1036},
+			{bindErr("775", svc.ErrLDAPAccountLocked), 1034},
+		}
+		for _, c := range cases {
+			authErr = c.err
+			resp := login()
+			assert.Equal(t, c.code, resp.Code, c.err.Error())
+			assert.Nil(t, resp.Data)
+		}
+	})
+
+	t.Run("Must Change Password", func(t *testing.T) {
+		for code, reason := range map[int64]error{1032: svc.ErrLDAPPasswordMustChange, 1033: svc.ErrLDAPPasswordExpired} {
+			authErr = bindErr("773", reason)
+			resp := login()
+			require.Equal(t, code, resp.Code)
+			data, ok := resp.Data.(*types.LDAPPasswordChangeResp)
+			require.True(t, ok)
+			assert.NotEmpty(t, data.ChangeToken)
+			assert.Equal(t, int64(svc.LDAPPasswordChangeTTL.Seconds()), data.ExpiresIn)
+		}
+	})
+
+	t.Run("Change Password With Token", func(t *testing.T) {
+		authErr = bindErr("773", svc.ErrLDAPPasswordMustChange)
+		token := login().Data.(*types.LDAPPasswordChangeResp).ChangeToken
+
+		// 不满足密码策略时令牌保留
+		changed, changeErr = nil, svc.ErrLDAPPasswordPolicy
+		assert.EqualValues(t, 1030, changePassword(token, "alice-password").Code)
+
+		changeErr = nil
+		assert.EqualValues(t, 0, changePassword(token, "alice-password").Code)
+		assert.Equal(t, []string{
+			testUserAlice + ":alice-password:new-alice-password",
+			testUserAlice + ":alice-password:new-alice-password",
+		}, changed)
+
+		assert.EqualValues(t, 1038, changePassword(token, "alice-password").Code, "the token is single use")
+		assert.EqualValues(t, 1038, changePassword("unknown", "alice-password").Code)
+	})
+
+	t.Run("Wrong Old Password Invalidates Token", func(t *testing.T) {
+		authErr = bindErr("773", svc.ErrLDAPPasswordMustChange)
+		token := login().Data.(*types.LDAPPasswordChangeResp).ChangeToken
+
+		changeErr = svc.ErrLDAPInvalidCredentials
+		assert.EqualValues(t, 1002, changePassword(token, "wrong").Code)
+		changeErr = nil
+		assert.EqualValues(t, 1038, changePassword(token, "alice-password").Code)
+	})
+}
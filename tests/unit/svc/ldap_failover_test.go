@@ -0,0 +1,174 @@
+package svc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/svc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unusedAddress 返回一个当前没有监听的本地地址
+func unusedAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+	return address
+}
+
+func TestLDAPServerSet(t *testing.T) {
+	a := svc.LDAPServer{Host: "dc1.example.com", Port: 389}
+	b := svc.LDAPServer{Host: "dc2.example.com", Port: 389}
+	c := svc.LDAPServer{Host: "dc3.example.com", Port: 3268}
+
+	t.Run("Single Host", func(t *testing.T) {
+		servers := svc.NewLDAPServerSet(config.LDAPConfig{Host: "ldap.example.com", UseSSL: true}, nil)
+		assert.Equal(t, []svc.LDAPServer{{Host: "ldap.example.com", Port: 636}}, servers.Candidates())
+	})
+
+	t.Run("Priority Failover", func(t *testing.T) {
+		servers := svc.NewLDAPServerSet(config.LDAPConfig{
+			Servers:          []string{"dc1.example.com", "dc2.example.com:389", "dc3.example.com:3268"},
+			FailoverCooldown: 1,
+		}, nil)
+		assert.Equal(t, []svc.LDAPServer{a, b, c}, servers.Candidates())
+		assert.Equal(t, []svc.LDAPServer{a, b, c}, servers.Candidates())
+
+		servers.MarkFailed(a)
+		assert.Equal(t, []svc.LDAPServer{b, c, a}, servers.Candidates())
+		servers.MarkFailed(b)
+		assert.Equal(t, []svc.LDAPServer{c, a, b}, servers.Candidates(), "failed servers are still tried as a last resort")
+
+		servers.MarkHealthy(b)
+		assert.Equal(t, []svc.LDAPServer{b, c, a}, servers.Candidates())
+
+		// 冷却结束后恢复原有顺序
+		time.Sleep(1100 * time.Millisecond)
+		assert.Equal(t, []svc.LDAPServer{a, b, c}, servers.Candidates())
+	})
+
+	t.Run("Round Robin", func(t *testing.T) {
+		servers := svc.NewLDAPServerSet(config.LDAPConfig{
+			Servers:         []string{"dc1.example.com", "dc2.example.com", "dc3.example.com:3268"},
+			ServerSelection: svc.LDAPSelectionRoundRobin,
+		}, nil)
+		assert.Equal(t, []svc.LDAPServer{a, b, c}, servers.Candidates())
+		assert.Equal(t, []svc.LDAPServer{b, c, a}, servers.Candidates())
+		assert.Equal(t, []svc.LDAPServer{c, a, b}, servers.Candidates())
+
+		servers.MarkFailed(b)
+		first := servers.Candidates()
+		assert.Equal(t, b, first[2])
+		assert.NotEqual(t, first[0], servers.Candidates()[0])
+	})
+
+	t.Run("SRV Discovery", func(t *testing.T) {
+		lookups := 0
+		fail := false
+		resolver := func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			lookups++
+			assert.Equal(t, "_ldap._tcp.example.com", fmt.Sprintf("_%s._%s.%s", service, proto, name))
+			if fail {
+				return "", nil, errors.New("no such host")
+			}
+			return "", []*net.SRV{
+				{Target: "dc2.example.com.", Port: 389, Priority: 10},
+				{Target: "dc1.example.com.", Port: 389, Priority: 0},
+			}, nil
+		}
+
+		servers := svc.NewLDAPServerSet(config.LDAPConfig{
+			SRVDomain:          "example.com",
+			SRVRefreshInterval: 1,
+			Servers:            []string{"dc3.example.com:3268", "dc1.example.com"},
+		}, resolver)
+		assert.Equal(t, []svc.LDAPServer{a, b, c}, servers.Candidates(), "discovered servers first, static ones as fallback")
+		servers.Candidates()
+		assert.Equal(t, 1, lookups, "records are cached")
+
+		// 查询失败时继续使用上一次的结果
+		fail = true
+		time.Sleep(1100 * time.Millisecond)
+		assert.Equal(t, []svc.LDAPServer{a, b, c}, servers.Candidates())
+		assert.Equal(t, 2, lookups)
+	})
+
+	t.Run("SRV Discovery With LDAPS", func(t *testing.T) {
+		resolver := func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			return "", []*net.SRV{{Target: "dc1.example.com.", Port: 389}}, nil
+		}
+		servers := svc.NewLDAPServerSet(config.LDAPConfig{SRVDomain: "example.com", UseSSL: true}, resolver)
+		assert.Equal(t, []svc.LDAPServer{{Host: "dc1.example.com", Port: 636}}, servers.Candidates())
+	})
+}
+
+func TestLDAPProviderFailover(t *testing.T) {
+	server := newTestLDAPServer(t)
+	dead := unusedAddress(t)
+	live := fmt.Sprintf("%s:%d", server.Host(), server.Port())
+	ctx := context.Background()
+
+	t.Run("Skips Unavailable Server", func(t *testing.T) {
+		cfg := testLDAPConfig(server, config.LDAPPoolConfig{})
+		cfg.Servers = []string{dead, live}
+		provider, err := svc.NewLDAPProvider(cfg)
+		require.NoError(t, err)
+		defer provider.Close()
+
+		info, err := provider.Authenticate(ctx, "alice", "alice-password")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", info.Username)
+	})
+
+	t.Run("All Servers Unavailable", func(t *testing.T) {
+		cfg := testLDAPConfig(server, config.LDAPPoolConfig{})
+		cfg.Servers = []string{dead, unusedAddress(t)}
+		_, err := svc.NewLDAPProvider(cfg)
+		assert.ErrorContains(t, err, "failed to connect to LDAP server")
+	})
+
+	t.Run("Round Robin Spreads Connections", func(t *testing.T) {
+		other := newTestLDAPServer(t)
+		cfg := testLDAPConfig(server, config.LDAPPoolConfig{})
+		cfg.Servers = []string{live, fmt.Sprintf("%s:%d", other.Host(), other.Port())}
+		cfg.ServerSelection = svc.LDAPSelectionRoundRobin
+		provider, err := svc.NewLDAPProvider(cfg)
+		require.NoError(t, err)
+		defer provider.Close()
+
+		dials, otherDials := server.Dials(), other.Dials()
+		for i := 0; i < 4; i++ {
+			_, err := provider.Authenticate(ctx, "alice", "alice-password")
+			require.NoError(t, err)
+		}
+		assert.Positive(t, server.Dials()-dials)
+		assert.Positive(t, other.Dials()-otherDials)
+	})
+
+	t.Run("Fails Over When Server Goes Down", func(t *testing.T) {
+		primary := newTestLDAPServer(t)
+		cfg := testLDAPConfig(server, config.LDAPPoolConfig{})
+		cfg.Servers = []string{fmt.Sprintf("%s:%d", primary.Host(), primary.Port()), live}
+		provider, err := svc.NewLDAPProvider(cfg)
+		require.NoError(t, err)
+		defer provider.Close()
+
+		_, err = provider.Authenticate(ctx, "alice", "alice-password")
+		require.NoError(t, err)
+
+		// 主服务器下线 (如打补丁重启)，池中连接失效后转移到备用服务器
+		primary.Close()
+		dials := server.Dials()
+		_, err = provider.Authenticate(ctx, "alice", "alice-password")
+		require.NoError(t, err)
+		assert.Positive(t, server.Dials()-dials)
+	})
+}
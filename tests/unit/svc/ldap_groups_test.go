@@ -0,0 +1,326 @@
+package svc_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"auth-service/internal/config"
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	model "auth-service/model/mysql"
+	"auth-service/tests/common"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testGroupDevelopers = "cn=developers,ou=groups,dc=example,dc=com"
+	testGroupEngineer   = "cn=engineering,ou=groups,dc=example,dc=com"
+	testGroupStaff      = "cn=staff,ou=groups,dc=example,dc=com"
+	testGroupAll        = "cn=all,ou=groups,dc=example,dc=com"
+	testUserBob         = "uid=bob,ou=people,dc=example,dc=com"
+)
+
+// newNestedGroupLDAPServer bob -> developers -> engineering -> staff <-> all (循环嵌套)
+func newNestedGroupLDAPServer(t *testing.T) *common.FakeLDAPServer {
+	group := func(dn string, members []string, memberOf ...string) *common.FakeLDAPEntry {
+		return &common.FakeLDAPEntry{DN: dn, Attributes: map[string][]string{
+			"cn":       {strings.TrimPrefix(dn[:strings.Index(dn, ",")], "cn=")},
+			"member":   members,
+			"memberOf": memberOf,
+		}}
+	}
+	return common.NewFakeLDAPServer(t,
+		&common.FakeLDAPEntry{DN: testLDAPAdminDN, Password: testLDAPAdminPassword},
+		&common.FakeLDAPEntry{
+			DN:       testUserBob,
+			Password: "bob-password",
+			Attributes: map[string][]string{
+				"uid":      {"bob"},
+				"mail":     {"bob@example.com"},
+				"memberOf": {testGroupDevelopers},
+			},
+		},
+		group(testGroupDevelopers, []string{testUserBob}, testGroupEngineer),
+		group(testGroupEngineer, []string{testGroupDevelopers}, testGroupStaff),
+		group(testGroupStaff, []string{testGroupEngineer, testGroupAll}, testGroupAll),
+		group(testGroupAll, []string{testGroupStaff}, testGroupStaff),
+	)
+}
+
+func TestLDAPNestedGroups(t *testing.T) {
+	server := newNestedGroupLDAPServer(t)
+	ctx := context.Background()
+	allGroups := []string{testGroupDevelopers, testGroupEngineer, testGroupStaff, testGroupAll}
+
+	newProvider := func(t *testing.T, configure func(cfg *config.LDAPConfig)) *svc.LDAPProvider {
+		cfg := testLDAPConfig(server, config.LDAPPoolConfig{})
+		configure(&cfg)
+		provider, err := svc.NewLDAPProvider(cfg)
+		require.NoError(t, err)
+		t.Cleanup(provider.Close)
+		return provider
+	}
+
+	tests := []struct {
+		name      string
+		configure func(cfg *config.LDAPConfig)
+		want      []string
+	}{
+		{
+			name:      "Direct Groups",
+			configure: func(cfg *config.LDAPConfig) {},
+			want:      []string{testGroupDevelopers},
+		},
+		{
+			name:      "Recursive Via memberOf",
+			configure: func(cfg *config.LDAPConfig) { cfg.NestedGroups = svc.LDAPNestedGroupsRecursive },
+			want:      allGroups,
+		},
+		{
+			name: "Recursive Via Group Filter",
+			configure: func(cfg *config.LDAPConfig) {
+				cfg.NestedGroups = svc.LDAPNestedGroupsRecursive
+				cfg.GroupFilter = "(member=%s)"
+				cfg.GroupBaseDN = "ou=groups,dc=example,dc=com"
+			},
+			want: allGroups,
+		},
+		{
+			name: "Recursive Depth Limit",
+			configure: func(cfg *config.LDAPConfig) {
+				cfg.NestedGroups = svc.LDAPNestedGroupsRecursive
+				cfg.NestedGroupDepth = 2
+			},
+			want: []string{testGroupDevelopers, testGroupEngineer},
+		},
+		{
+			name:      "Matching Rule In Chain",
+			configure: func(cfg *config.LDAPConfig) { cfg.NestedGroups = svc.LDAPNestedGroupsInChain },
+			want:      allGroups,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newProvider(t, tt.configure)
+			groups, err := provider.ResolveGroups(ctx, testUserBob)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, groups)
+		})
+	}
+
+	t.Run("Authenticate Maps Roles", func(t *testing.T) {
+		provider := newProvider(t, func(cfg *config.LDAPConfig) {
+			cfg.NestedGroups = svc.LDAPNestedGroupsInChain
+			cfg.GroupRoles = []config.LDAPGroupRoleConfig{
+				{Group: "CN=Staff, OU=Groups, DC=example, DC=com", Roles: []string{"employee"}},
+				{Group: "cn=admins,ou=groups,dc=example,dc=com", Roles: []string{"admin"}},
+				{Group: "engineering", Roles: []string{"developer", "employee"}},
+			}
+		})
+
+		info, err := provider.Authenticate(ctx, "bob", "bob-password")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, allGroups, info.Groups)
+		assert.Equal(t, []string{"employee", "developer"}, info.Roles)
+
+		_, err = provider.Authenticate(ctx, "bob", "wrong")
+		assert.EqualError(t, err, "invalid credentials")
+	})
+
+	t.Run("Invalid Config", func(t *testing.T) {
+		cfg := testLDAPConfig(server, config.LDAPPoolConfig{})
+		cfg.NestedGroups = "deep"
+		_, err := svc.NewLDAPProvider(cfg)
+		assert.ErrorContains(t, err, "unsupported nested group mode")
+
+		cfg = testLDAPConfig(server, config.LDAPPoolConfig{})
+		cfg.GroupRoles = []config.LDAPGroupRoleConfig{{Group: "admins"}}
+		_, err = svc.NewLDAPProvider(cfg)
+		assert.ErrorContains(t, err, "roles are required")
+	})
+}
+
+func TestLDAPMapRoles(t *testing.T) {
+	server := newTestLDAPServer(t)
+	cfg := testLDAPConfig(server, config.LDAPPoolConfig{})
+	cfg.GroupRoles = []config.LDAPGroupRoleConfig{
+		{Group: "cn=app-*,ou=groups,dc=example,dc=com", Roles: []string{"app-user"}},
+		{Group: "cn=app-admins,ou=groups,dc=example,dc=com", Roles: []string{"app-admin", "app-user"}},
+		{Group: "*,ou=contractors,dc=example,dc=com", Roles: []string{"contractor"}},
+		{Group: "Domain Admins", Roles: []string{"admin"}},
+	}
+	provider, err := svc.NewLDAPProvider(cfg)
+	require.NoError(t, err)
+	defer provider.Close()
+
+	tests := []struct {
+		name   string
+		groups []string
+		want   []string
+	}{
+		{name: "No Groups", groups: nil, want: nil},
+		{name: "Wildcard", groups: []string{"cn=app-readers,ou=groups,dc=example,dc=com"}, want: []string{"app-user"}},
+		{name: "Deduplicated", groups: []string{"CN=App-Admins,OU=Groups,DC=Example,DC=Com"}, want: []string{"app-user", "app-admin"}},
+		{name: "Wildcard Spans RDNs", groups: []string{"cn=eve,ou=team-a,ou=contractors,dc=example,dc=com"}, want: []string{"contractor"}},
+		{name: "Match By CN", groups: []string{"CN=Domain Admins,CN=Users,DC=corp,DC=example,DC=com"}, want: []string{"admin"}},
+		{name: "Plain Group Name", groups: []string{"domain admins"}, want: []string{"admin"}},
+		{name: "Unmapped", groups: []string{"cn=staff,ou=groups,dc=example,dc=com"}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, provider.MapRoles(tt.groups))
+		})
+	}
+}
+
+func TestLDAPLoginRoleSync(t *testing.T) {
+	h := common.NewTestHelper(t)
+	svcCtx := h.SetupServiceContext(true)
+	if svcCtx.Redis == nil {
+		t.Skip("Redis not available")
+	}
+
+	// bob 的目录账号已关联到本地用户 5
+	identities := []*model.UserIdentity{{Id: 1, UserId: 5, Provider: "ldap", ProviderUserId: testUserBob}}
+	svcCtx.UserIdentityModel = newIdentityStore(&identities)
+
+	roles := []*model.UserRole{
+		{Id: 1, UserId: 5, Role: "admin", Source: model.RoleSourceManual},
+		{Id: 2, UserId: 5, Role: "viewer", Source: model.RoleSourceLDAP},
+		{Id: 3, UserId: 9, Role: "developer", Source: model.RoleSourceLDAP},
+	}
+	nextID := uint64(len(roles))
+	svcCtx.UserRoleModel = &model.MockUserRoleModel{
+		InsertFunc: func(ctx context.Context, data *model.UserRole) (sql.Result, error) {
+			nextID++
+			data.Id = nextID
+			roles = append(roles, data)
+			return sqlmock.NewResult(int64(nextID), 1), nil
+		},
+		FindAllByUserIdFunc: func(ctx context.Context, userID uint64) ([]*model.UserRole, error) {
+			var resp []*model.UserRole
+			for _, r := range roles {
+				if r.UserId == userID {
+					resp = append(resp, r)
+				}
+			}
+			return resp, nil
+		},
+		DeleteFunc: func(ctx context.Context, id uint64) error {
+			for i, r := range roles {
+				if r.Id == id {
+					roles = append(roles[:i], roles[i+1:]...)
+					break
+				}
+			}
+			return nil
+		},
+	}
+
+	var mappedRoles []string
+	common.SetLDAP(svcCtx, &common.MockLDAPClient{
+		IsEnabledFunc: func() bool { return true },
+		AuthenticateFunc: func(ctx context.Context, username, password string) (*svc.LDAPUserInfo, error) {
+			return &svc.LDAPUserInfo{DN: testUserBob, Username: username, Email: username + "@example.com", Roles: mappedRoles}, nil
+		},
+	}, nil)
+
+	ctx := context.Background()
+	ldapLogin := func() types.LDAPLoginResp {
+		resp, err := logic.NewLDAPLoginLogic(ctx, svcCtx).LDAPLogin(&types.LDAPLoginReq{Username: "bob", Password: "secret"})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code, resp.Message)
+		var data types.LDAPLoginResp
+		raw, _ := json.Marshal(resp.Data)
+		require.NoError(t, json.Unmarshal(raw, &data))
+		return data
+	}
+	userRoles := func(userID uint64) map[string]string {
+		sources := make(map[string]string)
+		for _, r := range roles {
+			if r.UserId == userID {
+				sources[r.Role] = r.Source
+			}
+		}
+		return sources
+	}
+
+	t.Run("Grants Mapped Roles", func(t *testing.T) {
+		mappedRoles = []string{"developer", "admin"}
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(5).
+			WillReturnRows(userRow(5, "bob", ""))
+
+		data := ldapLogin()
+		assert.Equal(t, []string{"admin", "developer"}, data.Roles)
+		// 手动分配的 admin 保留来源，不再授予的 LDAP 角色被回收，其他用户不受影响
+		assert.Equal(t, map[string]string{"admin": model.RoleSourceManual, "developer": model.RoleSourceLDAP}, userRoles(5))
+		assert.Equal(t, map[string]string{"developer": model.RoleSourceLDAP}, userRoles(9))
+
+		claims, err := svcCtx.JWT.VerifyAccessToken(data.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"admin", "developer"}, claims.Roles)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+
+	t.Run("Revokes Roles Removed In Directory", func(t *testing.T) {
+		mappedRoles = nil
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(5).
+			WillReturnRows(userRow(5, "bob", ""))
+
+		data := ldapLogin()
+		assert.Equal(t, []string{"admin"}, data.Roles)
+		assert.Equal(t, map[string]string{"admin": model.RoleSourceManual}, userRoles(5))
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+
+	t.Run("Linked Account Keeps Local Password", func(t *testing.T) {
+		mappedRoles = nil
+		userModel := svcCtx.UserModel
+		defer func() { svcCtx.UserModel = userModel }()
+		var updated []*model.User
+		svcCtx.UserModel = &model.MockUserModel{
+			FindOneFunc: func(ctx context.Context, id uint64) (*model.User, error) {
+				return &model.User{Id: id, PublicId: "pub_bob", Username: "bob", PasswordHash: "hash", AccountStatus: model.UserStatusActive}, nil
+			},
+			UpdateFunc: func(ctx context.Context, data *model.User) error {
+				updated = append(updated, data)
+				return nil
+			},
+		}
+
+		ldapLogin()
+		// 关联 LDAP 前设置的本地密码在登录时保留
+		assert.Empty(t, updated)
+	})
+
+	t.Run("Unlinked Local Account Is Not Synced", func(t *testing.T) {
+		mappedRoles = []string{"admin"}
+		common.SetLDAP(svcCtx, &common.MockLDAPClient{
+			IsEnabledFunc: func() bool { return true },
+			AuthenticateFunc: func(ctx context.Context, username, password string) (*svc.LDAPUserInfo, error) {
+				return &svc.LDAPUserInfo{DN: testUserAlice, Username: username, Email: username + "@example.com", Roles: mappedRoles}, nil
+			},
+		}, nil)
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+username.+").
+			WithArgs("alice").
+			WillReturnRows(userRow(9, "alice", "hash"))
+
+		resp, err := logic.NewLDAPLoginLogic(ctx, svcCtx).LDAPLogin(&types.LDAPLoginReq{Username: "alice", Password: "secret"})
+		require.NoError(t, err)
+		// 同名的本地账号需要确认所有权后才能关联，目录角色不会同步到该账号
+		assert.EqualValues(t, 1018, resp.Code)
+		assert.Equal(t, map[string]string{"developer": model.RoleSourceLDAP}, userRoles(9))
+		assert.Len(t, identities, 1)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,258 @@
+package svc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"auth-service/internal/config"
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	model "auth-service/model/mysql"
+	"auth-service/tests/common"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserAlice = "uid=alice,ou=people,dc=example,dc=com"
+
+func newPasswordTestProvider(t *testing.T, serverType string) (*svc.LDAPProvider, *common.FakeLDAPServer) {
+	server := newTestLDAPServer(t)
+	server.PasswordPolicy = func(password string) string {
+		if len(password) < 8 {
+			return "Password fails quality checking policy"
+		}
+		return ""
+	}
+
+	cfg := testLDAPConfig(server, config.LDAPPoolConfig{})
+	cfg.ServerType = serverType
+	provider, err := svc.NewLDAPProvider(cfg)
+	require.NoError(t, err)
+	t.Cleanup(provider.Close)
+	return provider, server
+}
+
+func TestLDAPProviderChangePassword(t *testing.T) {
+	for _, serverType := range []string{svc.LDAPServerTypeOpenLDAP, svc.LDAPServerTypeAD} {
+		t.Run(serverType, func(t *testing.T) {
+			ctx := context.Background()
+
+			t.Run("Change Own Password", func(t *testing.T) {
+				provider, server := newPasswordTestProvider(t, serverType)
+
+				require.NoError(t, provider.ChangePassword(ctx, testUserAlice, "alice-password", "new-alice-password"))
+				assert.Equal(t, "new-alice-password", server.Password(testUserAlice))
+			})
+
+			t.Run("Wrong Old Password", func(t *testing.T) {
+				provider, server := newPasswordTestProvider(t, serverType)
+
+				err := provider.ChangePassword(ctx, testUserAlice, "wrong", "new-alice-password")
+				assert.ErrorIs(t, err, svc.ErrLDAPInvalidCredentials)
+				assert.Equal(t, "alice-password", server.Password(testUserAlice))
+			})
+
+			t.Run("Policy Violation", func(t *testing.T) {
+				provider, server := newPasswordTestProvider(t, serverType)
+
+				err := provider.ChangePassword(ctx, testUserAlice, "alice-password", "short")
+				assert.ErrorIs(t, err, svc.ErrLDAPPasswordPolicy)
+				assert.Equal(t, "Password fails quality checking policy", svc.LDAPDiagnosticMessage(err))
+				assert.Equal(t, "alice-password", server.Password(testUserAlice))
+			})
+
+			t.Run("Admin Reset", func(t *testing.T) {
+				provider, server := newPasswordTestProvider(t, serverType)
+
+				require.NoError(t, provider.ResetPassword(ctx, testUserAlice, "reset-password"))
+				assert.Equal(t, "reset-password", server.Password(testUserAlice))
+
+				err := provider.ResetPassword(ctx, testUserAlice, "short")
+				assert.ErrorIs(t, err, svc.ErrLDAPPasswordPolicy)
+			})
+		})
+	}
+
+	t.Run("Reset Refused Without Admin Rights", func(t *testing.T) {
+		server := newTestLDAPServer(t)
+		cfg := testLDAPConfig(server, config.LDAPPoolConfig{})
+		cfg.BindDN = testUserAlice
+		cfg.BindPassword = "alice-password"
+		provider, err := svc.NewLDAPProvider(cfg)
+		require.NoError(t, err)
+		defer provider.Close()
+
+		err = provider.ResetPassword(context.Background(), "cn=admin,dc=example,dc=com", "reset-password")
+		assert.ErrorIs(t, err, svc.ErrLDAPPasswordChangeRefused)
+		assert.False(t, errors.Is(err, svc.ErrLDAPPasswordPolicy))
+		assert.Equal(t, testLDAPAdminPassword, server.Password(testLDAPAdminDN))
+	})
+
+	t.Run("Unsupported Server Type", func(t *testing.T) {
+		server := newTestLDAPServer(t)
+		cfg := testLDAPConfig(server, config.LDAPPoolConfig{})
+		cfg.ServerType = "novell"
+		_, err := svc.NewLDAPProvider(cfg)
+		assert.Error(t, err)
+	})
+}
+
+func TestLDAPPasswordRouting(t *testing.T) {
+	h := common.NewTestHelper(t)
+	svcCtx := h.SetupServiceContext(true)
+	if svcCtx.Redis == nil {
+		t.Skip("Redis not available")
+	}
+
+	identities := []*model.UserIdentity{{Id: 1, UserId: 5, Provider: "ldap", ProviderUserId: testUserAlice}}
+	svcCtx.UserIdentityModel = newIdentityStore(&identities)
+	svcCtx.UserRoleModel = &model.MockUserRoleModel{
+		FindOneByUserIdRoleFunc: func(ctx context.Context, userID uint64, role string) (*model.UserRole, error) {
+			if userID == 1 && role == model.RoleAdmin {
+				return &model.UserRole{Id: 1, UserId: 1, Role: role}, nil
+			}
+			return nil, model.ErrNotFound
+		},
+	}
+
+	var changeErr, resetErr error
+	var changed, reset []string
+	common.SetLDAP(svcCtx, &common.MockLDAPClient{
+		IsEnabledFunc: func() bool { return true },
+		ChangePasswordFunc: func(ctx context.Context, userDN, oldPassword, newPassword string) error {
+			changed = append(changed, userDN+":"+oldPassword+":"+newPassword)
+			return changeErr
+		},
+		ResetPasswordFunc: func(ctx context.Context, userDN, newPassword string) error {
+			reset = append(reset, userDN+":"+newPassword)
+			return resetErr
+		},
+	}, nil)
+	policyErr := fmt.Errorf("%w: %w", svc.ErrLDAPPasswordPolicy,
+		ldap.NewError(ldap.LDAPResultConstraintViolation, errors.New("Password fails quality checking policy")))
+	refusedErr := fmt.Errorf("%w: %w", svc.ErrLDAPPasswordChangeRefused,
+		ldap.NewError(ldap.LDAPResultUnwillingToPerform, errors.New("0000001F: SvcErr: DSID-031A12D2, problem 5003 (WILL_NOT_PERFORM)")))
+
+	userCtx := context.WithValue(context.Background(), "userID", int64(5))
+	adminCtx := context.WithValue(context.Background(), "userID", int64(1))
+
+	t.Run("Change Password Of LDAP User", func(t *testing.T) {
+		changed, changeErr = nil, nil
+		mock := h.GetMock()
+		mock.ExpectQuery("(?i)select.+from.+user.+where.+id.+").WithArgs(5).WillReturnRows(userRow(5, "alice", ""))
+
+		resp, err := logic.NewChangePasswordLogic(userCtx, svcCtx).ChangePassword(&types.ChangePasswordReq{
+			OldPassword: "alice-password",
+			NewPassword: "new-alice-password",
+		})
+		require.NoError(t, err)
+		assert.EqualValues(t, 200, resp.Code)
+		assert.Equal(t, []string{testUserAlice + ":alice-password:new-alice-password"}, changed)
+		assert.NoError(t, mock.ExpectationsWereMet(), "the local password hash must not be updated")
+	})
+
+	t.Run("Change Password Rejected By Directory", func(t *testing.T) {
+		cases := []struct {
+			err     error
+			code    int64
+			message string
+		}{
+			{svc.ErrLDAPInvalidCredentials, 400, "旧密码错误"},
+			{policyErr, 400, "新密码不符合目录的密码策略: Password fails quality checking policy"},
+			{refusedErr, 403, "目录拒绝修改密码: 0000001F: SvcErr: DSID-031A12D2, problem 5003 (WILL_NOT_PERFORM)"},
+		}
+		for _, c := range cases {
+			changeErr = c.err
+			h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").WithArgs(5).WillReturnRows(userRow(5, "alice", ""))
+
+			resp, err := logic.NewChangePasswordLogic(userCtx, svcCtx).ChangePassword(&types.ChangePasswordReq{
+				OldPassword: "alice-password",
+				NewPassword: "short",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, c.code, resp.Code)
+			assert.Equal(t, c.message, resp.Message)
+		}
+	})
+
+	t.Run("Change Password Of Local User", func(t *testing.T) {
+		changed = nil
+		hash := svcCtx.PasswordEncoder.Hash("local-password")
+		mock := h.GetMock()
+		mock.ExpectQuery("(?i)select.+from.+user.+where.+id.+").WithArgs(5).WillReturnRows(userRow(5, "alice", hash))
+		mock.ExpectExec("(?i)update.+user.+set").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		resp, err := logic.NewChangePasswordLogic(userCtx, svcCtx).ChangePassword(&types.ChangePasswordReq{
+			OldPassword: "local-password",
+			NewPassword: "new-local-password",
+		})
+		require.NoError(t, err)
+		assert.EqualValues(t, 200, resp.Code)
+		assert.Empty(t, changed, "users with a local password keep it even when an LDAP identity is linked")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Admin Reset Of LDAP User", func(t *testing.T) {
+		reset, resetErr = nil, nil
+		tokens, err := svcCtx.JWT.Generate(5, "alice")
+		require.NoError(t, err)
+		mock := h.GetMock()
+		mock.ExpectQuery("(?i)select.+from.+user.+where.+public_id.+").WithArgs("pub_alice").WillReturnRows(userRow(5, "alice", ""))
+
+		resp, err := logic.NewResetUserPasswordLogic(adminCtx, svcCtx).ResetUserPassword(&types.ResetUserPasswordReq{
+			UserID:      "pub_alice",
+			NewPassword: "reset-password",
+		})
+		require.NoError(t, err)
+		assert.EqualValues(t, 0, resp.Code)
+		assert.Equal(t, []string{testUserAlice + ":reset-password"}, reset)
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		_, err = svcCtx.JWT.VerifyAccessToken(tokens.AccessToken)
+		assert.ErrorContains(t, err, "revoked")
+	})
+
+	t.Run("Admin Reset Rejected By Directory", func(t *testing.T) {
+		for err, code := range map[error]int64{policyErr: 1030, refusedErr: 1031} {
+			resetErr = err
+			h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+public_id.+").WithArgs("pub_alice").WillReturnRows(userRow(5, "alice", ""))
+
+			resp, err := logic.NewResetUserPasswordLogic(adminCtx, svcCtx).ResetUserPassword(&types.ResetUserPasswordReq{
+				UserID:      "pub_alice",
+				NewPassword: "short",
+			})
+			require.NoError(t, err)
+			assert.Equal(t, code, resp.Code)
+		}
+	})
+
+	t.Run("Admin Reset Of Local User", func(t *testing.T) {
+		reset = nil
+		mock := h.GetMock()
+		mock.ExpectQuery("(?i)select.+from.+user.+where.+public_id.+").WithArgs("pub_bob").WillReturnRows(userRow(6, "bob", "hash"))
+		mock.ExpectExec("(?i)update.+user.+set").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		resp, err := logic.NewResetUserPasswordLogic(adminCtx, svcCtx).ResetUserPassword(&types.ResetUserPasswordReq{
+			UserID:      "pub_bob",
+			NewPassword: "reset-password",
+		})
+		require.NoError(t, err)
+		assert.EqualValues(t, 0, resp.Code)
+		assert.Empty(t, reset)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Admin Reset Requires Admin", func(t *testing.T) {
+		_, err := logic.NewResetUserPasswordLogic(userCtx, svcCtx).ResetUserPassword(&types.ResetUserPasswordReq{
+			UserID:      "pub_bob",
+			NewPassword: "reset-password",
+		})
+		assert.ErrorIs(t, err, types.ErrForbidden)
+	})
+}
@@ -0,0 +1,227 @@
+package svc_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/svc"
+	"auth-service/tests/common"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testLDAPAdminDN       = "cn=admin,dc=example,dc=com"
+	testLDAPAdminPassword = "admin-secret"
+)
+
+// newTestLDAPServer 启动包含管理员与 alice 的 LDAP 服务器
+func newTestLDAPServer(t testing.TB) *common.FakeLDAPServer {
+	return common.NewFakeLDAPServer(t,
+		&common.FakeLDAPEntry{DN: testLDAPAdminDN, Password: testLDAPAdminPassword, Admin: true},
+		&common.FakeLDAPEntry{
+			DN:       "uid=alice,ou=people,dc=example,dc=com",
+			Password: "alice-password",
+			Attributes: map[string][]string{
+				"uid":         {"alice"},
+				"mail":        {"alice@example.com"},
+				"displayName": {"Alice"},
+				"memberOf":    {"cn=staff,ou=groups,dc=example,dc=com"},
+			},
+		},
+	)
+}
+
+func testLDAPConfig(server *common.FakeLDAPServer, pool config.LDAPPoolConfig) config.LDAPConfig {
+	return config.LDAPConfig{
+		Enabled:      true,
+		Host:         server.Host(),
+		Port:         server.Port(),
+		BindDN:       testLDAPAdminDN,
+		BindPassword: testLDAPAdminPassword,
+		BaseDN:       "dc=example,dc=com",
+		UserFilter:   "(uid=%s)",
+		Pool:         pool,
+	}
+}
+
+func newTestLDAPPool(server *common.FakeLDAPServer, cfg config.LDAPPoolConfig) *svc.LDAPPool {
+	dial := func() (ldap.Client, error) {
+		return ldap.DialURL(fmt.Sprintf("ldap://%s:%d", server.Host(), server.Port()))
+	}
+	bind := func(conn ldap.Client) error {
+		return conn.Bind(testLDAPAdminDN, testLDAPAdminPassword)
+	}
+	return svc.NewLDAPPool(cfg, dial, bind)
+}
+
+func TestLDAPProviderPool(t *testing.T) {
+	server := newTestLDAPServer(t)
+	provider, err := svc.NewLDAPProvider(testLDAPConfig(server, config.LDAPPoolConfig{}))
+	require.NoError(t, err)
+	defer provider.Close()
+	ctx := context.Background()
+
+	t.Run("Reuses Search Connection", func(t *testing.T) {
+		dials := server.Dials()
+		for i := 0; i < 5; i++ {
+			info, err := provider.Authenticate(ctx, "alice", "alice-password")
+			require.NoError(t, err)
+			assert.Equal(t, "alice@example.com", info.Email)
+		}
+		// 1 个池连接 + 每次登录 1 个用户绑定的短连接
+		assert.EqualValues(t, 6, server.Dials()-dials)
+		assert.Equal(t, svc.LDAPPoolStats{Open: 1, Idle: 1}, provider.PoolStats())
+	})
+
+	t.Run("Wrong Password Does Not Affect Pool", func(t *testing.T) {
+		_, err := provider.Authenticate(ctx, "alice", "wrong")
+		assert.EqualError(t, err, "invalid credentials")
+
+		groups, err := provider.GetUserGroups(ctx, "uid=alice,ou=people,dc=example,dc=com")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"cn=staff,ou=groups,dc=example,dc=com"}, groups)
+		assert.Equal(t, 1, provider.PoolStats().Open)
+	})
+
+	t.Run("Unknown User", func(t *testing.T) {
+		_, err := provider.Authenticate(ctx, "mallory", "whatever")
+		assert.ErrorContains(t, err, "user not found")
+		assert.Equal(t, 1, provider.PoolStats().Open)
+	})
+
+	t.Run("Reconnects After Server Drops Connections", func(t *testing.T) {
+		server.DropConnections()
+
+		users, err := provider.SearchUsers(ctx, "(uid=*)", 10)
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+		assert.Equal(t, "alice", users[0].Username)
+		assert.Equal(t, svc.LDAPPoolStats{Open: 1, Idle: 1}, provider.PoolStats())
+	})
+
+	t.Run("Rebinds After Bind Is Lost", func(t *testing.T) {
+		dials, binds := server.Dials(), server.Binds()
+		server.ResetBinds()
+
+		_, err := provider.GetUserGroups(ctx, "uid=alice,ou=people,dc=example,dc=com")
+		require.NoError(t, err)
+		assert.EqualValues(t, 0, server.Dials()-dials, "the pooled connection is kept")
+		assert.EqualValues(t, 1, server.Binds()-binds)
+	})
+
+	t.Run("Test Connection", func(t *testing.T) {
+		assert.NoError(t, provider.TestConnection(ctx))
+	})
+}
+
+func TestLDAPPool(t *testing.T) {
+	server := newTestLDAPServer(t)
+	ctx := context.Background()
+	noop := func(conn ldap.Client) error { return nil }
+
+	t.Run("Bounded Connections", func(t *testing.T) {
+		pool := newTestLDAPPool(server, config.LDAPPoolConfig{MaxOpen: 2})
+		defer pool.Close()
+
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			maxOpen int
+		)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := pool.Do(ctx, func(conn ldap.Client) error {
+					mu.Lock()
+					if open := pool.Stats().Open; open > maxOpen {
+						maxOpen = open
+					}
+					mu.Unlock()
+					time.Sleep(5 * time.Millisecond)
+					return nil
+				})
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		assert.LessOrEqual(t, maxOpen, 2)
+		assert.Equal(t, svc.LDAPPoolStats{Open: 2, Idle: 2}, pool.Stats())
+	})
+
+	t.Run("Wait Timeout", func(t *testing.T) {
+		pool := newTestLDAPPool(server, config.LDAPPoolConfig{MaxOpen: 1, WaitTimeout: 1})
+		defer pool.Close()
+
+		held := make(chan struct{})
+		release := make(chan struct{})
+		go pool.Do(ctx, func(conn ldap.Client) error {
+			close(held)
+			<-release
+			return nil
+		})
+		<-held
+
+		start := time.Now()
+		assert.ErrorIs(t, pool.Do(ctx, noop), svc.ErrLDAPPoolTimeout)
+		assert.GreaterOrEqual(t, time.Since(start), time.Second)
+
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.ErrorIs(t, pool.Do(cancelled, noop), context.Canceled)
+
+		close(release)
+		assert.NoError(t, pool.Do(ctx, noop))
+	})
+
+	t.Run("Idle Timeout", func(t *testing.T) {
+		pool := newTestLDAPPool(server, config.LDAPPoolConfig{IdleTimeout: 1})
+		defer pool.Close()
+
+		require.NoError(t, pool.Do(ctx, noop))
+		assert.Equal(t, 1, pool.Stats().Idle)
+
+		dials := server.Dials()
+		time.Sleep(1100 * time.Millisecond)
+		require.NoError(t, pool.Do(ctx, noop))
+		assert.EqualValues(t, 1, server.Dials()-dials, "the idle connection is replaced")
+		assert.Equal(t, svc.LDAPPoolStats{Open: 1, Idle: 1}, pool.Stats())
+	})
+
+	t.Run("No Idle Connections", func(t *testing.T) {
+		pool := newTestLDAPPool(server, config.LDAPPoolConfig{MaxIdle: -1})
+		defer pool.Close()
+
+		require.NoError(t, pool.Do(ctx, noop))
+		assert.Equal(t, svc.LDAPPoolStats{}, pool.Stats())
+	})
+
+	t.Run("Bind Failure", func(t *testing.T) {
+		pool := svc.NewLDAPPool(config.LDAPPoolConfig{}, func() (ldap.Client, error) {
+			return ldap.DialURL(fmt.Sprintf("ldap://%s:%d", server.Host(), server.Port()))
+		}, func(conn ldap.Client) error {
+			return conn.Bind(testLDAPAdminDN, "wrong")
+		})
+		defer pool.Close()
+
+		err := pool.Do(ctx, noop)
+		assert.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials))
+		assert.Equal(t, svc.LDAPPoolStats{}, pool.Stats())
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		pool := newTestLDAPPool(server, config.LDAPPoolConfig{})
+		require.NoError(t, pool.Do(ctx, noop))
+		pool.Close()
+
+		assert.ErrorIs(t, pool.Do(ctx, noop), svc.ErrLDAPPoolClosed)
+		assert.Equal(t, svc.LDAPPoolStats{}, pool.Stats())
+	})
+}
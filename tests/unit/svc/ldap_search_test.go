@@ -0,0 +1,162 @@
+package svc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"testing"
+
+	"auth-service/internal/config"
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/tests/common"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLargeLDAPServer 包含 12 个用户且单次搜索最多返回 5 个条目的服务器 (模拟 AD 的 MaxPageSize)
+func newLargeLDAPServer(t *testing.T) *common.FakeLDAPServer {
+	server := newTestLDAPServer(t)
+	server.MaxPageSize = 5
+	for i := 1; i <= 11; i++ {
+		username := fmt.Sprintf("user%02d", i)
+		server.AddEntry(&common.FakeLDAPEntry{
+			DN:         "uid=" + username + ",ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{"uid": {username}},
+		})
+	}
+	return server
+}
+
+func newSearchTestProvider(t *testing.T, server *common.FakeLDAPServer, pageSize int) *svc.LDAPProvider {
+	cfg := testLDAPConfig(server, config.LDAPPoolConfig{})
+	cfg.PageSize = pageSize
+	provider, err := svc.NewLDAPProvider(cfg)
+	require.NoError(t, err)
+	t.Cleanup(provider.Close)
+	return provider
+}
+
+func TestLDAPPagedSearch(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Exceeds Server Size Limit Without Paging", func(t *testing.T) {
+		server := newLargeLDAPServer(t)
+		conn, err := ldap.DialURL(fmt.Sprintf("ldap://%s:%d", server.Host(), server.Port()))
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.Bind(testLDAPAdminDN, testLDAPAdminPassword))
+
+		_, err = conn.Search(ldap.NewSearchRequest("dc=example,dc=com", ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+			0, 0, false, "(uid=*)", []string{"uid"}, nil))
+		assert.True(t, ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded))
+	})
+
+	t.Run("Search All Pages", func(t *testing.T) {
+		server := newLargeLDAPServer(t)
+		provider := newSearchTestProvider(t, server, 0)
+
+		users, err := provider.SearchUsers(ctx, "(uid=*)", 0)
+		require.NoError(t, err)
+		assert.Len(t, users, 12)
+		assert.Equal(t, "alice", users[0].Username)
+		assert.Equal(t, "user11", users[11].Username)
+		assert.EqualValues(t, 3, server.Pages(), "pages are capped by the server's MaxPageSize")
+		assert.Zero(t, server.AbandonedPagedSearches())
+	})
+
+	t.Run("Search With Limit", func(t *testing.T) {
+		server := newLargeLDAPServer(t)
+		provider := newSearchTestProvider(t, server, 0)
+
+		users, err := provider.SearchUsers(ctx, "(uid=*)", 3)
+		require.NoError(t, err)
+		assert.Len(t, users, 3)
+		assert.EqualValues(t, 2, server.Pages(), "one page of 3 plus the abandon request")
+		assert.EqualValues(t, 1, server.AbandonedPagedSearches())
+	})
+
+	t.Run("Stop Iterating Early", func(t *testing.T) {
+		server := newLargeLDAPServer(t)
+		provider := newSearchTestProvider(t, server, 3)
+
+		var names []string
+		for user, err := range provider.IterUsers(ctx, svc.LDAPSearchOptions{Filter: "(uid=user*)"}) {
+			require.NoError(t, err)
+			names = append(names, user.Username)
+			if len(names) == 4 {
+				break
+			}
+		}
+		assert.Equal(t, []string{"user01", "user02", "user03", "user04"}, names)
+		assert.EqualValues(t, 1, server.AbandonedPagedSearches())
+		assert.Equal(t, 1, provider.PoolStats().Idle, "the connection is returned to the pool")
+	})
+
+	t.Run("Context Cancelled Between Pages", func(t *testing.T) {
+		server := newLargeLDAPServer(t)
+		provider := newSearchTestProvider(t, server, 2)
+
+		cancelCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var names []string
+		var iterErr error
+		for user, err := range provider.IterUsers(cancelCtx, svc.LDAPSearchOptions{Filter: "(uid=user*)"}) {
+			if err != nil {
+				iterErr = err
+				break
+			}
+			names = append(names, user.Username)
+			cancel()
+		}
+		assert.ErrorIs(t, iterErr, context.Canceled)
+		assert.Equal(t, []string{"user01", "user02"}, names, "the current page is finished before cancellation is checked")
+		assert.EqualValues(t, 1, server.AbandonedPagedSearches())
+	})
+
+	t.Run("Connection Lost Between Pages", func(t *testing.T) {
+		server := newLargeLDAPServer(t)
+		provider := newSearchTestProvider(t, server, 2)
+
+		var names []string
+		var iterErr error
+		for user, err := range provider.IterUsers(ctx, svc.LDAPSearchOptions{Filter: "(uid=user*)"}) {
+			if err != nil {
+				iterErr = err
+				break
+			}
+			names = append(names, user.Username)
+			server.DropConnections()
+		}
+		assert.ErrorIs(t, iterErr, svc.ErrLDAPSearchInterrupted)
+		assert.Equal(t, []string{"user01", "user02"}, names, "entries are not returned twice")
+	})
+}
+
+func TestLDAPSyncStopsOnSearchError(t *testing.T) {
+	h := common.NewTestHelper(t)
+	svcCtx := h.SetupServiceContext(true)
+	if svcCtx.Redis == nil {
+		t.Skip("Redis not available")
+	}
+	ctx := context.Background()
+	svcCtx.Redis.Del(ctx, "auth:ldap:sync:lock", "auth:ldap:sync:last")
+	t.Cleanup(func() { svcCtx.Redis.Del(ctx, "auth:ldap:sync:lock", "auth:ldap:sync:last") })
+
+	common.SetLDAP(svcCtx, &common.MockLDAPClient{
+		IsEnabledFunc: func() bool { return true },
+		SyncUsersFunc: func(ctx context.Context) iter.Seq2[*svc.LDAPUserInfo, error] {
+			return func(yield func(*svc.LDAPUserInfo, error) bool) {
+				yield(nil, svc.ErrLDAPSearchInterrupted)
+			}
+		},
+	}, nil)
+
+	// 读取目录中途失败时不能把未读到的已关联用户当作已删除禁用: sqlmock 没有任何预期的查询
+	_, err := logic.RunLDAPSync(ctx, svcCtx)
+	assert.True(t, errors.Is(err, svc.ErrLDAPSearchInterrupted))
+	assert.NoError(t, h.GetMock().ExpectationsWereMet())
+}
@@ -0,0 +1,214 @@
+package svc_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	model "auth-service/model/mysql"
+	"auth-service/tests/common"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLDAPListUsers(t *testing.T) {
+	server := newTestLDAPServer(t)
+	server.AddEntry(&common.FakeLDAPEntry{
+		DN: "uid=bob,ou=people,dc=example,dc=com",
+		Attributes: map[string][]string{
+			"uid":                {"bob"},
+			"mail":               {"bob@example.com"},
+			"userAccountControl": {"514"}, // NORMAL_ACCOUNT | ACCOUNTDISABLE
+			"memberOf":           {"cn=admins,ou=groups,dc=example,dc=com"},
+		},
+	})
+
+	cfg := testLDAPConfig(server, config.LDAPPoolConfig{})
+	cfg.UserAttributes = []string{"uid"}
+	cfg.GroupRoles = []config.LDAPGroupRoleConfig{{Group: "staff", Roles: []string{"employee"}}}
+	cfg.Sync.PageSize = 1
+	provider, err := svc.NewLDAPProvider(cfg)
+	require.NoError(t, err)
+	defer provider.Close()
+
+	users, err := provider.ListUsers(context.Background())
+	require.NoError(t, err)
+	require.Len(t, users, 2, "the admin entry has no uid and is not matched by the derived filter")
+
+	byName := make(map[string]*svc.LDAPUserInfo)
+	for _, user := range users {
+		byName[user.Username] = user
+	}
+	assert.Equal(t, "alice@example.com", byName["alice"].Email)
+	assert.False(t, byName["alice"].Disabled)
+	assert.Equal(t, []string{"employee"}, byName["alice"].Roles)
+	assert.True(t, byName["bob"].Disabled)
+	assert.Empty(t, byName["bob"].Roles)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = provider.ListUsers(cancelled)
+	assert.Error(t, err)
+}
+
+func TestLDAPSync(t *testing.T) {
+	h := common.NewTestHelper(t)
+	svcCtx := h.SetupServiceContext(true)
+	if svcCtx.Redis == nil {
+		t.Skip("Redis not available")
+	}
+	ctx := context.Background()
+	svcCtx.Redis.Del(ctx, "auth:ldap:sync:lock", "auth:ldap:sync:last")
+	t.Cleanup(func() { svcCtx.Redis.Del(ctx, "auth:ldap:sync:lock", "auth:ldap:sync:last") })
+
+	dn := func(username string) string { return "uid=" + username + ",ou=people,dc=example,dc=com" }
+	identities := []*model.UserIdentity{
+		{Id: 1, UserId: 5, Provider: "ldap", ProviderUserId: dn("alice")},
+		{Id: 2, UserId: 6, Provider: "ldap", ProviderUserId: dn("bob")},
+		{Id: 3, UserId: 7, Provider: "ldap", ProviderUserId: dn("erin")},
+	}
+	svcCtx.UserIdentityModel = newIdentityStore(&identities)
+
+	roles := []*model.UserRole{{Id: 1, UserId: 1, Role: model.RoleAdmin, Source: model.RoleSourceManual}}
+	svcCtx.UserRoleModel = &model.MockUserRoleModel{
+		InsertFunc: func(ctx context.Context, data *model.UserRole) (sql.Result, error) {
+			data.Id = uint64(len(roles) + 1)
+			roles = append(roles, data)
+			return sqlmock.NewResult(int64(data.Id), 1), nil
+		},
+		FindOneByUserIdRoleFunc: func(ctx context.Context, userID uint64, role string) (*model.UserRole, error) {
+			for _, r := range roles {
+				if r.UserId == userID && r.Role == role {
+					return r, nil
+				}
+			}
+			return nil, model.ErrNotFound
+		},
+		FindAllByUserIdFunc: func(ctx context.Context, userID uint64) ([]*model.UserRole, error) {
+			var resp []*model.UserRole
+			for _, r := range roles {
+				if r.UserId == userID {
+					resp = append(resp, r)
+				}
+			}
+			return resp, nil
+		},
+	}
+
+	var directory []*svc.LDAPUserInfo
+	common.SetLDAP(svcCtx, &common.MockLDAPClient{
+		IsEnabledFunc: func() bool { return true },
+		ListUsersFunc: func(ctx context.Context) ([]*svc.LDAPUserInfo, error) {
+			return directory, nil
+		},
+	}, nil)
+	common.SetLDAP(svcCtx, svcCtx.LDAP, svc.NewProvisioningPolicy(config.ProvisioningConfig{}))
+
+	t.Run("Sync Directory", func(t *testing.T) {
+		directory = []*svc.LDAPUserInfo{
+			{DN: dn("alice"), Username: "alice", Email: "alice@corp.example.com", DisplayName: "Alice", Roles: []string{"developer"}},
+			{DN: dn("bob"), Username: "bob", Email: "bob@example.com", Disabled: true},
+			{DN: dn("carol"), Username: "carol", Email: "carol@example.com", Roles: []string{"developer"}},
+			{DN: dn("dave"), Username: "dave", Email: "dave@example.com"},
+		}
+		erinTokens, err := svcCtx.JWT.Generate(7, "erin")
+		require.NoError(t, err)
+
+		mock := h.GetMock()
+		// alice: 邮箱与昵称已变化
+		mock.ExpectQuery("(?i)select.+from.+user.+where.+id.+").WithArgs(5).WillReturnRows(userRow(5, "alice", ""))
+		mock.ExpectExec("(?i)update.+user.+set").WillReturnResult(sqlmock.NewResult(0, 1))
+		// bob: 目录中已禁用
+		mock.ExpectQuery("(?i)select.+from.+user.+where.+id.+").WithArgs(6).WillReturnRows(userRow(6, "bob", ""))
+		mock.ExpectExec("(?i)update.+user.+set").WillReturnResult(sqlmock.NewResult(0, 1))
+		// carol: 新用户
+		mock.ExpectQuery("(?i)select.+from.+user.+where.+username.+").WithArgs("carol").WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("(?i)select.+from.+user.+where.+email.+").WithArgs("carol@example.com").WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("(?i)select.+from.+user.+where.+username.+").WithArgs("carol").WillReturnError(sql.ErrNoRows)
+		mock.ExpectExec("(?i)insert into.+user").WillReturnResult(sqlmock.NewResult(8, 1))
+		mock.ExpectQuery("(?i)select.+from.+user.+where.+id.+").WithArgs(8).WillReturnRows(userRow(8, "carol", ""))
+		// dave: 已有同名的本地账号，等待首次登录关联
+		mock.ExpectQuery("(?i)select.+from.+user.+where.+username.+").WithArgs("dave").WillReturnRows(userRow(9, "dave", "hash"))
+		// erin: 已从目录删除
+		mock.ExpectQuery("(?i)select.+from.+user.+where.+id.+").WithArgs(7).WillReturnRows(userRow(7, "erin", ""))
+		mock.ExpectExec("(?i)update.+user.+set").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		summary, err := logic.RunLDAPSync(ctx, svcCtx)
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, 4, summary.Scanned)
+		assert.Equal(t, 1, summary.Created)
+		assert.Equal(t, 1, summary.Updated)
+		assert.Equal(t, 2, summary.Disabled)
+		assert.Equal(t, 1, summary.Skipped)
+		assert.Equal(t, 0, summary.Failed)
+		assert.Empty(t, summary.Errors)
+
+		// carol 已关联身份并获得映射的角色
+		require.Len(t, identities, 4)
+		assert.EqualValues(t, 8, identities[3].UserId)
+		assert.Equal(t, dn("carol"), identities[3].ProviderUserId)
+		assert.False(t, identities[3].LastLoginAt.Valid)
+		var granted []string
+		for _, r := range roles {
+			if r.Source == model.RoleSourceLDAP {
+				granted = append(granted, r.Role)
+				assert.Contains(t, []uint64{5, 8}, r.UserId)
+			}
+		}
+		assert.Equal(t, []string{"developer", "developer"}, granted)
+
+		// 被禁用用户的令牌立即失效
+		_, err = svcCtx.JWT.VerifyAccessToken(erinTokens.AccessToken)
+		assert.ErrorContains(t, err, "revoked")
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		adminCtx := context.WithValue(ctx, "userID", int64(1))
+		resp, err := logic.NewLDAPSyncStatusLogic(adminCtx, svcCtx).LDAPSyncStatus()
+		require.NoError(t, err)
+
+		var status types.LDAPSyncStatusResp
+		raw, _ := json.Marshal(resp.Data)
+		require.NoError(t, json.Unmarshal(raw, &status))
+		assert.False(t, status.Running)
+		require.NotNil(t, status.Last)
+		assert.Equal(t, 1, status.Last.Created)
+		assert.Equal(t, 2, status.Last.Disabled)
+
+		_, err = logic.NewLDAPSyncStatusLogic(context.WithValue(ctx, "userID", int64(5)), svcCtx).LDAPSyncStatus()
+		assert.ErrorIs(t, err, types.ErrForbidden)
+	})
+
+	t.Run("Empty Directory Does Not Disable Users", func(t *testing.T) {
+		directory = nil
+
+		summary, err := logic.RunLDAPSync(ctx, svcCtx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, summary.Disabled)
+		require.Len(t, summary.Errors, 1)
+		assert.Contains(t, summary.Errors[0], "refusing to disable")
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+
+	t.Run("Already Running", func(t *testing.T) {
+		require.NoError(t, svcCtx.Redis.Set(ctx, "auth:ldap:sync:lock", "other-instance", time.Minute).Err())
+		defer svcCtx.Redis.Del(ctx, "auth:ldap:sync:lock")
+
+		_, err := logic.RunLDAPSync(ctx, svcCtx)
+		assert.ErrorIs(t, err, logic.ErrLDAPSyncRunning)
+
+		adminCtx := context.WithValue(ctx, "userID", int64(1))
+		resp, err := logic.NewLDAPSyncLogic(adminCtx, svcCtx).LDAPSync()
+		require.NoError(t, err)
+		assert.EqualValues(t, 1029, resp.Code)
+	})
+}
@@ -22,7 +22,7 @@ func TestLDAPLogin(t *testing.T) {
 
 	// Setup Mock LDAP
 	mockLDAP := &common.MockLDAPClient{}
-	svcCtx.LDAP = mockLDAP
+	common.SetLDAP(svcCtx, mockLDAP, nil)
 
 	// Initialize JWT manually for testing
 	// Use an anonymous struct compatible with Config.Auth if needed, or just set if defined
@@ -106,7 +106,7 @@ func TestLDAPLogin(t *testing.T) {
 		}
 	})
 
-	t.Run("LDAP Success - Existing User", func(t *testing.T) {
+	t.Run("LDAP Existing Local User Requires Confirmation", func(t *testing.T) {
 		mockLDAP.IsEnabledFunc = func() bool { return true }
 		mockLDAP.AuthenticateFunc = func(ctx context.Context, u, p string) (*svc.LDAPUserInfo, error) {
 			return &svc.LDAPUserInfo{
@@ -131,20 +131,17 @@ func TestLDAPLogin(t *testing.T) {
 			Password: "password",
 		}
 
+		// A matching username does not prove ownership of the local account; without Redis
+		// the pending link cannot be kept, so the user has to link from the account settings
 		resp, err := l.LDAPLogin(req)
 		if err != nil {
 			t.Fatalf("expected no error, got %v", err)
 		}
-		if resp.Code != 0 {
-			t.Errorf("expected code 0, got %d", resp.Code)
-		}
-
-		data := resp.Data.(types.LDAPLoginResp)
-		if data.Username != "testuser" {
-			t.Errorf("expected username testuser, got %s", data.Username)
+		if resp.Code != 1019 {
+			t.Errorf("expected code 1019, got %d", resp.Code)
 		}
-		if data.IsNewUser {
-			t.Errorf("expected existing user")
+		if resp.Data != nil {
+			t.Errorf("expected no tokens, got %v", resp.Data)
 		}
 	})
 
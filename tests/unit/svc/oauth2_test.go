@@ -0,0 +1,328 @@
+package svc_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	model "auth-service/model/mysql"
+	"auth-service/tests/common"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newOAuth2Provider 创建端点指向测试服务器的 OAuth2 提供者
+func newOAuth2Provider(t *testing.T, typ string, server *httptest.Server) *svc.OAuth2Provider {
+	provider, err := svc.NewOAuth2Provider(config.OAuth2Config{
+		Name:         typ,
+		Type:         typ,
+		Enabled:      true,
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		RedirectURL:  "http://localhost/callback",
+		AuthURL:      server.URL + "/authorize",
+		TokenURL:     server.URL + "/token",
+		UserInfoURL:  server.URL + "/user",
+	})
+	require.NoError(t, err)
+	return provider
+}
+
+func TestOAuth2Provider_Adapters(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Unknown Type", func(t *testing.T) {
+		_, err := svc.NewOAuth2Provider(config.OAuth2Config{Enabled: true, Type: "myspace"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		provider, err := svc.NewOAuth2Provider(config.OAuth2Config{Type: svc.OAuth2TypeGitHub})
+		assert.NoError(t, err)
+		assert.False(t, provider.IsEnabled())
+	})
+
+	t.Run("GitHub", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+			_ = r.ParseForm()
+			if r.PostForm.Get("code") != "good" {
+				// GitHub 以 200 返回错误
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "gh-token", "token_type": "bearer"})
+		})
+		mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer gh-token", r.Header.Get("Authorization"))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"id": 42, "login": "octocat", "name": "The Octocat", "email": "public@example.com",
+			})
+		})
+		mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"email": "other@example.com", "primary": false, "verified": true},
+				{"email": "octo@example.com", "primary": true, "verified": true},
+			})
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		provider := newOAuth2Provider(t, svc.OAuth2TypeGitHub, server)
+
+		authURL, err := url.Parse(provider.GetAuthorizationURL("st"))
+		require.NoError(t, err)
+		assert.Equal(t, "client-id", authURL.Query().Get("client_id"))
+		assert.Equal(t, "st", authURL.Query().Get("state"))
+
+		_, err = provider.ExchangeCode(ctx, "bad")
+		assert.Error(t, err)
+
+		token, err := provider.ExchangeCode(ctx, "good")
+		require.NoError(t, err)
+
+		info, err := provider.GetUserInfo(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, "42", info.ProviderUserID)
+		assert.Equal(t, "octocat", info.Username)
+		assert.Equal(t, "octo@example.com", info.Email)
+		assert.True(t, info.EmailVerified)
+		assert.Equal(t, svc.OAuth2TypeGitHub, info.Provider)
+	})
+
+	t.Run("WeChat", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "client-id", r.URL.Query().Get("appid"))
+			assert.Equal(t, "client-secret", r.URL.Query().Get("secret"))
+			if r.URL.Query().Get("code") != "good" {
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"errcode": 40029, "errmsg": "invalid code"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "wx-token", "expires_in": 7200, "openid": "o-1", "unionid": "u-1",
+			})
+		})
+		mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "o-1", r.URL.Query().Get("openid"))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"openid": "o-1", "unionid": "u-1", "nickname": "微信用户",
+			})
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		provider := newOAuth2Provider(t, svc.OAuth2TypeWeChat, server)
+		assert.True(t, strings.HasSuffix(provider.GetAuthorizationURL("st"), "#wechat_redirect"))
+
+		_, err := provider.ExchangeCode(ctx, "bad")
+		assert.Error(t, err)
+
+		token, err := provider.ExchangeCode(ctx, "good")
+		require.NoError(t, err)
+
+		info, err := provider.GetUserInfo(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, "u-1", info.ProviderUserID)
+		assert.Equal(t, "微信用户", info.DisplayName)
+		assert.Empty(t, info.Email)
+	})
+
+	t.Run("Feishu", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, "authorization_code", body["grant_type"])
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 0, "access_token": "fs-token", "token_type": "Bearer"})
+		})
+		mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": 0,
+				"data": map[string]string{"union_id": "on-1", "open_id": "ou-1", "name": "张三", "email": "zhangsan@example.com"},
+			})
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		provider := newOAuth2Provider(t, svc.OAuth2TypeFeishu, server)
+
+		token, err := provider.ExchangeCode(ctx, "good")
+		require.NoError(t, err)
+
+		info, err := provider.GetUserInfo(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, "on-1", info.ProviderUserID)
+		assert.Equal(t, "zhangsan@example.com", info.Email)
+		assert.False(t, info.EmailVerified)
+	})
+
+	t.Run("DingTalk", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			assert.Equal(t, "client-id", body["clientId"])
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"accessToken": "dt-token", "expireIn": 7200})
+		})
+		mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "dt-token", r.Header.Get("x-acs-dingtalk-access-token"))
+			_ = json.NewEncoder(w).Encode(map[string]string{"unionId": "dt-u-1", "nick": "钉钉用户"})
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		provider := newOAuth2Provider(t, svc.OAuth2TypeDingTalk, server)
+
+		token, err := provider.ExchangeCode(ctx, "good")
+		require.NoError(t, err)
+
+		info, err := provider.GetUserInfo(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, "dt-u-1", info.ProviderUserID)
+	})
+}
+
+func TestOAuth2(t *testing.T) {
+	h := common.NewTestHelper(t)
+	svcCtx := h.SetupServiceContext(true)
+	if svcCtx.Redis == nil {
+		t.Skip("Redis not available")
+	}
+
+	mockOAuth2 := &common.MockOAuth2Client{
+		IsEnabledFunc:           func() bool { return true },
+		GetAuthorizationURLFunc: func(state string) string { return "https://github.com/login/oauth/authorize?state=" + state },
+	}
+	svcCtx.OAuth2 = svc.NewOAuth2Providers(svc.OAuth2ProviderEntry{Type: svc.OAuth2TypeGitHub, Client: mockOAuth2})
+
+	ctx := context.Background()
+
+	t.Run("Login Unknown Provider", func(t *testing.T) {
+		resp, err := logic.NewOAuth2LoginLogic(ctx, svcCtx).OAuth2Login(&types.OAuth2LoginReq{Provider: "gitee"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1001, resp.Code)
+	})
+
+	t.Run("Login Stores State", func(t *testing.T) {
+		resp, err := logic.NewOAuth2LoginLogic(ctx, svcCtx).OAuth2Login(&types.OAuth2LoginReq{Provider: "github"})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code)
+
+		data := resp.Data.(types.OAuth2LoginResp)
+		val, err := svcCtx.Redis.Get(ctx, "auth:oauth2:state:"+data.State).Result()
+		require.NoError(t, err)
+
+		var state svc.OAuth2State
+		require.NoError(t, json.Unmarshal([]byte(val), &state))
+		assert.Equal(t, "github", state.Provider)
+	})
+
+	t.Run("Callback Provider Mismatch", func(t *testing.T) {
+		stateData, _ := json.Marshal(svc.OAuth2State{Provider: "github"})
+		svcCtx.Redis.Set(ctx, "auth:oauth2:state:st-mismatch", stateData, time.Minute)
+
+		resp, err := logic.NewOAuth2CallbackLogic(ctx, svcCtx).OAuth2Callback(&types.OAuth2CallbackReq{
+			Provider: "wechat",
+			State:    "st-mismatch",
+			Code:     "code",
+		})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1002, resp.Code)
+	})
+
+	t.Run("Callback State Is Single Use", func(t *testing.T) {
+		stateData, _ := json.Marshal(svc.OAuth2State{Provider: "github"})
+		svcCtx.Redis.Set(ctx, "auth:oauth2:state:st-concurrent", stateData, time.Minute)
+
+		// 并发的回调只有一个能取到 state 并兑换授权码
+		var exchanges atomic.Int32
+		mockOAuth2.ExchangeCodeFunc = func(ctx context.Context, code string) (*svc.OAuth2Token, error) {
+			exchanges.Add(1)
+			time.Sleep(50 * time.Millisecond)
+			return nil, errors.New("exchange failed")
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				logic.NewOAuth2CallbackLogic(ctx, svcCtx).OAuth2Callback(&types.OAuth2CallbackReq{
+					Provider: "github",
+					State:    "st-concurrent",
+					Code:     "code",
+				})
+			}()
+		}
+		wg.Wait()
+		assert.EqualValues(t, 1, exchanges.Load())
+	})
+
+	t.Run("Callback Unverified Email Creates Account", func(t *testing.T) {
+		stateData, _ := json.Marshal(svc.OAuth2State{Provider: "github"})
+		svcCtx.Redis.Set(ctx, "auth:oauth2:state:st-ok", stateData, time.Minute)
+
+		mockOAuth2.ExchangeCodeFunc = func(ctx context.Context, code string) (*svc.OAuth2Token, error) {
+			return &svc.OAuth2Token{AccessToken: "gh-token"}, nil
+		}
+		mockOAuth2.GetUserInfoFunc = func(ctx context.Context, token *svc.OAuth2Token) (*types.SSOUserInfo, error) {
+			return &types.SSOUserInfo{
+				Provider:       "github",
+				ProviderUserID: "42",
+				Username:       "octocat",
+				Email:          "victim@example.com", // 未验证的邮箱不能用于匹配已有账号
+			}, nil
+		}
+
+		// 占位邮箱不用于匹配已有账号，直接按开通策略创建
+		placeholder := "5f0c2b1e-0000-4000-8000-000000000000@no-email.placeholder"
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+username.+").
+			WithArgs("octocat").
+			WillReturnError(model.ErrNotFound)
+		h.GetMock().ExpectExec("(?i)insert.+into.+user.+").
+			WillReturnResult(sqlmock.NewResult(7, 1))
+		rows := sqlmock.NewRows([]string{
+			"id", "public_id", "nickname", "username", "email", "email_verified",
+			"phone", "phone_verified", "password_hash", "password_salt", "mfa_secret",
+			"mfa_enabled", "account_status", "failed_login_attempts", "lockout_until",
+			"last_login_at", "created_at", "updated_at", "deleted_at",
+		}).AddRow(
+			7, "pub_id_7", sql.NullString{}, "octocat", placeholder, 0,
+			sql.NullString{}, 0, "hash", sql.NullString{}, sql.NullString{},
+			0, 1, 0, sql.NullTime{}, sql.NullTime{}, time.Now(), time.Now(), sql.NullTime{},
+		)
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(7).
+			WillReturnRows(rows)
+
+		resp, err := logic.NewOAuth2CallbackLogic(ctx, svcCtx).OAuth2Callback(&types.OAuth2CallbackReq{
+			Provider: "github",
+			State:    "st-ok",
+			Code:     "code",
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code)
+
+		data := resp.Data.(types.OAuth2CallbackResp)
+		assert.Equal(t, "octocat", data.Username)
+		assert.True(t, data.IsNewUser)
+		assert.Equal(t, "github", data.Provider)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,111 @@
+package svc_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCProvider_Discovery(t *testing.T) {
+	var up atomic.Bool
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			http.Error(w, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "public, max-age=120")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/auth",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	}))
+	defer server.Close()
+
+	// IdP 在启动时不可用，提供者仍然创建成功
+	provider, err := svc.NewOIDCProvider(config.OIDCConfig{
+		Enabled:     true,
+		ProviderURL: server.URL,
+		ClientID:    "auth-service",
+	})
+	require.NoError(t, err)
+	defer provider.Stop()
+
+	status := provider.Status()
+	assert.False(t, status.Ready)
+	assert.NotEmpty(t, status.LastError)
+	assert.Equal(t, 1, status.ConsecutiveFailures)
+	assert.Empty(t, provider.GetAuthorizationURL("s", "n", ""))
+
+	_, err = provider.ExchangeCode(context.Background(), "code", "")
+	assert.Error(t, err)
+
+	// IdP 恢复后后台重试成功
+	up.Store(true)
+	require.Eventually(t, func() bool { return provider.Status().Ready }, 5*time.Second, 50*time.Millisecond)
+
+	status = provider.Status()
+	assert.Equal(t, server.URL, status.Issuer)
+	assert.Empty(t, status.LastError)
+	assert.Zero(t, status.ConsecutiveFailures)
+	assert.NotEmpty(t, provider.GetAuthorizationURL("s", "n", ""))
+
+	// 按 Cache-Control max-age 安排下一次刷新
+	assert.WithinDuration(t, status.LastRefresh.Add(120*time.Second), status.NextRefresh, time.Second)
+
+	// 刷新失败时保留上一次的发现文档
+	up.Store(false)
+	assert.Error(t, provider.Refresh(context.Background()))
+
+	status = provider.Status()
+	assert.True(t, status.Ready)
+	assert.Equal(t, 1, status.ConsecutiveFailures)
+	assert.NotEmpty(t, status.LastError)
+	assert.NotEmpty(t, provider.GetAuthorizationURL("s", "n", ""))
+}
+
+func TestHealthCheck_SSOProviders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream maintenance details", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	provider, err := svc.NewOIDCProvider(config.OIDCConfig{
+		Enabled:     true,
+		ProviderURL: server.URL,
+		ClientID:    "auth-service",
+	})
+	require.NoError(t, err)
+	svcCtx := &svc.ServiceContext{
+		OIDC: svc.NewOIDCProviders(svc.OIDCProviderEntry{Name: "corp", Client: provider}),
+	}
+	defer svcCtx.Stop()
+
+	resp, err := logic.NewHealthCheckLogic(context.Background(), svcCtx).HealthCheck()
+	require.NoError(t, err)
+
+	health := resp.Data.(types.HealthCheckResp).SSO
+	require.Len(t, health, 1)
+	assert.Equal(t, "corp", health[0].ID)
+	assert.False(t, health[0].Ready)
+
+	// 无需认证的健康检查不暴露 issuer 与 IdP 的错误响应
+	body, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "upstream maintenance details")
+	assert.NotContains(t, string(body), server.URL)
+}
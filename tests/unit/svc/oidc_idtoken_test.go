@@ -0,0 +1,303 @@
+package svc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/svc"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIdP 模拟 OIDC 提供者的 discovery 与 JWKS 端点
+type fakeIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &fakeIdP{key: key, kid: "key-1"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/auth",
+			"token_endpoint":         idp.server.URL + "/token",
+			"userinfo_endpoint":      idp.server.URL + "/userinfo",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{idp.jwk(idp.kid, &idp.key.PublicKey)},
+		})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (idp *fakeIdP) jwk(kid string, pub *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"alg": "RS256",
+		"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+func (idp *fakeIdP) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = idp.kid
+	raw, err := token.SignedString(idp.key)
+	require.NoError(t, err)
+	return raw
+}
+
+func (idp *fakeIdP) claims(nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   idp.server.URL,
+		"sub":   "user-123",
+		"aud":   "auth-service",
+		"exp":   now.Add(time.Hour).Unix(),
+		"iat":   now.Unix(),
+		"nonce": nonce,
+	}
+}
+
+func TestOIDCProvider_VerifyIDToken(t *testing.T) {
+	idp := newFakeIdP(t)
+
+	provider, err := svc.NewOIDCProvider(config.OIDCConfig{
+		Enabled:     true,
+		ProviderURL: idp.server.URL,
+		ClientID:    "auth-service",
+	})
+	require.NoError(t, err)
+	defer provider.Stop()
+
+	ctx := context.Background()
+
+	t.Run("Valid", func(t *testing.T) {
+		idToken, err := provider.VerifyIDToken(ctx, idp.sign(t, idp.claims("n-1")), "n-1")
+		require.NoError(t, err)
+		assert.Equal(t, "user-123", idToken.Subject)
+	})
+
+	t.Run("Keeps Custom Claims", func(t *testing.T) {
+		claims := idp.claims("n-1")
+		claims["realm_access"] = map[string]interface{}{"roles": []string{"admin"}}
+		idToken, err := provider.VerifyIDToken(ctx, idp.sign(t, claims), "n-1")
+		require.NoError(t, err)
+
+		identity := svc.NewOIDCProviders().Mapper("").Map(idToken.ClaimSet())
+		assert.Empty(t, identity.Groups)
+		mapper, err := svc.NewClaimMapper(svc.DefaultOIDCClaimMapping, config.ClaimMapping{Groups: []string{"realm_access.roles"}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"admin"}, mapper.Map(idToken.ClaimSet()).Groups)
+	})
+
+	t.Run("Nonce Mismatch", func(t *testing.T) {
+		_, err := provider.VerifyIDToken(ctx, idp.sign(t, idp.claims("n-1")), "n-2")
+		assert.Error(t, err)
+	})
+
+	t.Run("Issuer Mismatch", func(t *testing.T) {
+		claims := idp.claims("n-1")
+		claims["iss"] = "https://evil.example.com"
+		_, err := provider.VerifyIDToken(ctx, idp.sign(t, claims), "n-1")
+		assert.Error(t, err)
+	})
+
+	t.Run("Audience Mismatch", func(t *testing.T) {
+		claims := idp.claims("n-1")
+		claims["aud"] = "another-client"
+		_, err := provider.VerifyIDToken(ctx, idp.sign(t, claims), "n-1")
+		assert.Error(t, err)
+	})
+
+	t.Run("Multiple Audiences Require azp", func(t *testing.T) {
+		claims := idp.claims("n-1")
+		claims["aud"] = []string{"auth-service", "another-client"}
+		_, err := provider.VerifyIDToken(ctx, idp.sign(t, claims), "n-1")
+		assert.Error(t, err)
+
+		claims["azp"] = "auth-service"
+		_, err = provider.VerifyIDToken(ctx, idp.sign(t, claims), "n-1")
+		assert.NoError(t, err)
+
+		claims["azp"] = "another-client"
+		_, err = provider.VerifyIDToken(ctx, idp.sign(t, claims), "n-1")
+		assert.Error(t, err)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		claims := idp.claims("n-1")
+		claims["exp"] = time.Now().Add(-time.Hour).Unix()
+		_, err := provider.VerifyIDToken(ctx, idp.sign(t, claims), "n-1")
+		assert.Error(t, err)
+	})
+
+	t.Run("Bad Signature", func(t *testing.T) {
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.claims("n-1"))
+		token.Header["kid"] = idp.kid
+		raw, err := token.SignedString(other)
+		require.NoError(t, err)
+
+		_, err = provider.VerifyIDToken(ctx, raw, "n-1")
+		assert.Error(t, err)
+	})
+
+	t.Run("HMAC Rejected", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, idp.claims("n-1"))
+		raw, err := token.SignedString([]byte("secret"))
+		require.NoError(t, err)
+
+		_, err = provider.VerifyIDToken(ctx, raw, "n-1")
+		assert.Error(t, err)
+	})
+
+	t.Run("Key Rotation Refreshes JWKS", func(t *testing.T) {
+		rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		idp.key = rotated
+		idp.kid = "key-2"
+
+		idToken, err := provider.VerifyIDToken(ctx, idp.sign(t, idp.claims("n-1")), "n-1")
+		require.NoError(t, err)
+		assert.Equal(t, "user-123", idToken.Subject)
+	})
+}
+
+func TestOIDCProvider_PKCE(t *testing.T) {
+	// RFC 7636 Appendix B
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		svc.CodeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+
+	verifier, err := svc.GenerateCodeVerifier()
+	require.NoError(t, err)
+	assert.Len(t, verifier, 43)
+
+	idp := newFakeIdP(t)
+
+	var tokenForm url.Values
+	idp.server.Config.Handler.(*http.ServeMux).HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		tokenForm = r.PostForm
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "at",
+			"token_type":   "Bearer",
+		})
+	})
+
+	// 公共客户端: 无 ClientSecret
+	provider, err := svc.NewOIDCProvider(config.OIDCConfig{
+		Enabled:     true,
+		ProviderURL: idp.server.URL,
+		ClientID:    "public-spa",
+		RedirectURL: "http://localhost/callback",
+	})
+	require.NoError(t, err)
+	defer provider.Stop()
+
+	authURL, err := url.Parse(provider.GetAuthorizationURL("s", "n", svc.CodeChallengeS256(verifier)))
+	require.NoError(t, err)
+	assert.Equal(t, svc.CodeChallengeS256(verifier), authURL.Query().Get("code_challenge"))
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+
+	_, err = provider.ExchangeCode(context.Background(), "code", verifier)
+	require.NoError(t, err)
+	assert.Equal(t, verifier, tokenForm.Get("code_verifier"))
+	assert.Equal(t, "public-spa", tokenForm.Get("client_id"))
+	_, hasSecret := tokenForm["client_secret"]
+	assert.False(t, hasSecret, "public client must not send client_secret")
+}
+
+func TestOIDCProvider_VerifyLogoutToken(t *testing.T) {
+	idp := newFakeIdP(t)
+
+	provider, err := svc.NewOIDCProvider(config.OIDCConfig{
+		Enabled:     true,
+		ProviderURL: idp.server.URL,
+		ClientID:    "auth-service",
+	})
+	require.NoError(t, err)
+	defer provider.Stop()
+
+	ctx := context.Background()
+	logoutClaims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":    idp.server.URL,
+			"sub":    "user-123",
+			"aud":    "auth-service",
+			"iat":    time.Now().Unix(),
+			"jti":    "jti-1",
+			"sid":    "idp-session-1",
+			"events": map[string]interface{}{"http://schemas.openid.net/event/backchannel-logout": map[string]interface{}{}},
+		}
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		logoutToken, err := provider.VerifyLogoutToken(ctx, idp.sign(t, logoutClaims()))
+		require.NoError(t, err)
+		assert.Equal(t, "user-123", logoutToken.Subject)
+		assert.Equal(t, "idp-session-1", logoutToken.SessionID)
+	})
+
+	t.Run("Missing Event", func(t *testing.T) {
+		claims := logoutClaims()
+		delete(claims, "events")
+		_, err := provider.VerifyLogoutToken(ctx, idp.sign(t, claims))
+		assert.Error(t, err)
+	})
+
+	t.Run("Nonce Rejected", func(t *testing.T) {
+		claims := logoutClaims()
+		claims["nonce"] = "n-1"
+		_, err := provider.VerifyLogoutToken(ctx, idp.sign(t, claims))
+		assert.Error(t, err)
+	})
+
+	t.Run("Requires sub or sid", func(t *testing.T) {
+		claims := logoutClaims()
+		delete(claims, "sub")
+		delete(claims, "sid")
+		_, err := provider.VerifyLogoutToken(ctx, idp.sign(t, claims))
+		assert.Error(t, err)
+	})
+
+	t.Run("Audience Mismatch", func(t *testing.T) {
+		claims := logoutClaims()
+		claims["aud"] = "another-client"
+		_, err := provider.VerifyLogoutToken(ctx, idp.sign(t, claims))
+		assert.Error(t, err)
+	})
+
+	t.Run("ID Token Is Not A Logout Token", func(t *testing.T) {
+		_, err := provider.VerifyLogoutToken(ctx, idp.sign(t, idp.claims("n-1")))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,125 @@
+package svc_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/tests/common"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCLogout(t *testing.T) {
+	h := common.NewTestHelper(t)
+	svcCtx := h.SetupServiceContext(true)
+	if svcCtx.Redis == nil {
+		t.Skip("Redis not available")
+	}
+	svcCtx.Config.SSO.AllowedRedirectURLs = []string{"https://app.example.com"}
+
+	mockOIDC := &common.MockOIDCClient{
+		IsEnabledFunc: func() bool { return true },
+		ExchangeCodeFunc: func(ctx context.Context, code, codeVerifier string) (*svc.OIDCTokenResponse, error) {
+			return &svc.OIDCTokenResponse{AccessToken: "at", IDToken: "raw-id-token"}, nil
+		},
+		VerifyIDTokenFunc: func(ctx context.Context, rawIDToken, nonce string) (*svc.OIDCIDToken, error) {
+			return &svc.OIDCIDToken{Subject: "sub-1", SessionID: "idp-sid-1", Nonce: nonce}, nil
+		},
+		GetUserInfoFunc: func(ctx context.Context, accessToken string) (*svc.OIDCUserInfo, error) {
+			return &svc.OIDCUserInfo{Sub: "sub-1", Email: "sso@example.com", EmailVerified: true}, nil
+		},
+		GetLogoutURLFunc: func(idToken, postLogoutRedirectURI string) string {
+			return "https://idp.example.com/logout?id_token_hint=" + idToken + "&post_logout_redirect_uri=" + postLogoutRedirectURI
+		},
+	}
+	svcCtx.OIDC = svc.NewOIDCProviders(svc.OIDCProviderEntry{Name: svc.DefaultOIDCProviderName, Client: mockOIDC, TrustEmail: true})
+
+	ctx := context.Background()
+
+	// login 完成一次 OIDC 登录并返回令牌
+	login := func(t *testing.T, state string) types.OIDCCallbackResp {
+		stateData, _ := json.Marshal(svc.OIDCState{Nonce: "n", CodeVerifier: "v"})
+		svcCtx.Redis.Set(ctx, "auth:oidc:state:"+state, stateData, time.Minute)
+
+		rows := sqlmock.NewRows([]string{
+			"id", "public_id", "nickname", "username", "email", "email_verified",
+			"phone", "phone_verified", "password_hash", "password_salt", "mfa_secret",
+			"mfa_enabled", "account_status", "failed_login_attempts", "lockout_until",
+			"last_login_at", "created_at", "updated_at", "deleted_at",
+		}).AddRow(
+			5, "pub_id_5", sql.NullString{}, "ssouser", "sso@example.com", 1,
+			sql.NullString{}, 0, "hash", sql.NullString{}, sql.NullString{},
+			0, 1, 0, sql.NullTime{}, sql.NullTime{}, time.Now(), time.Now(), sql.NullTime{},
+		)
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+email.+").
+			WithArgs("sso@example.com").
+			WillReturnRows(rows)
+
+		resp, err := logic.NewOIDCCallbackLogic(ctx, svcCtx).OIDCCallback(&types.OIDCCallbackReq{State: state, Code: "code"})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code)
+		return resp.Data.(types.OIDCCallbackResp)
+	}
+
+	t.Run("RP-Initiated Logout Returns End Session URL", func(t *testing.T) {
+		tokens := login(t, "st-logout")
+
+		resp, err := logic.NewLogoutLogic(ctx, svcCtx).Logout(&types.LogoutReq{
+			AccessToken:           tokens.AccessToken,
+			RefreshToken:          tokens.RefreshToken,
+			PostLogoutRedirectURL: "https://app.example.com/bye",
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code)
+
+		data, ok := resp.Data.(types.LogoutResp)
+		require.True(t, ok)
+		assert.True(t, strings.HasPrefix(data.EndSessionURL, "https://idp.example.com/logout"))
+		assert.Contains(t, data.EndSessionURL, "id_token_hint=raw-id-token")
+		assert.Contains(t, data.EndSessionURL, "post_logout_redirect_uri=https://app.example.com/bye")
+	})
+
+	t.Run("Back-Channel Logout Revokes Sessions", func(t *testing.T) {
+		tokens := login(t, "st-backchannel")
+		_, err := svcCtx.JWT.VerifyAccessToken(tokens.AccessToken)
+		require.NoError(t, err)
+
+		mockOIDC.VerifyLogoutTokenFunc = func(ctx context.Context, rawLogoutToken string) (*svc.OIDCLogoutToken, error) {
+			return &svc.OIDCLogoutToken{Subject: "sub-1", SessionID: "idp-sid-1", JTI: "jti-1"}, nil
+		}
+
+		backchannel := logic.NewOIDCBackchannelLogoutLogic(ctx, svcCtx)
+		resp, err := backchannel.OIDCBackchannelLogout(&types.OIDCBackchannelLogoutReq{LogoutToken: "logout-token"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 0, resp.Code)
+
+		_, err = svcCtx.JWT.VerifyAccessToken(tokens.AccessToken)
+		assert.Error(t, err, "access token must be revoked")
+		_, err = svcCtx.JWT.Refresh(tokens.RefreshToken)
+		assert.Error(t, err, "refresh token must be revoked")
+
+		// 重放的登出令牌被拒绝
+		resp, err = backchannel.OIDCBackchannelLogout(&types.OIDCBackchannelLogoutReq{LogoutToken: "logout-token"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1010, resp.Code)
+	})
+
+	t.Run("Back-Channel Logout Rejects Invalid Token", func(t *testing.T) {
+		mockOIDC.VerifyLogoutTokenFunc = func(ctx context.Context, rawLogoutToken string) (*svc.OIDCLogoutToken, error) {
+			return nil, assert.AnError
+		}
+
+		resp, err := logic.NewOIDCBackchannelLogoutLogic(ctx, svcCtx).OIDCBackchannelLogout(&types.OIDCBackchannelLogoutReq{LogoutToken: "forged"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1010, resp.Code)
+	})
+}
@@ -0,0 +1,52 @@
+package svc_test
+
+import (
+	"testing"
+
+	"auth-service/internal/config"
+	"auth-service/internal/svc"
+	model "auth-service/model/mysql"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvisioningPolicy(t *testing.T) {
+	t.Run("Nil Policy Allows Everyone", func(t *testing.T) {
+		var policy *svc.ProvisioningPolicy
+		assert.NoError(t, policy.Check("", false, nil))
+		assert.EqualValues(t, model.UserStatusActive, policy.InitialStatus())
+		assert.Empty(t, policy.DefaultRoles())
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		policy := svc.NewProvisioningPolicy(config.ProvisioningConfig{Disabled: true})
+		assert.ErrorIs(t, policy.Check("alice@example.com", true, nil), svc.ErrProvisioningDisabled)
+	})
+
+	t.Run("Allowed Domains", func(t *testing.T) {
+		policy := svc.NewProvisioningPolicy(config.ProvisioningConfig{AllowedDomains: []string{"example.com", "@corp.example.org"}})
+		assert.NoError(t, policy.Check("alice@Example.COM", true, nil))
+		assert.NoError(t, policy.Check("bob@corp.example.org", true, nil))
+		assert.ErrorIs(t, policy.Check("eve@evil.com", true, nil), svc.ErrProvisioningNotAllowed)
+		assert.ErrorIs(t, policy.Check("alice@example.com.evil.com", true, nil), svc.ErrProvisioningNotAllowed)
+		// 未验证的邮箱不能证明属于该域名
+		assert.ErrorIs(t, policy.Check("alice@example.com", false, nil), svc.ErrProvisioningNotAllowed)
+		assert.ErrorIs(t, policy.Check("", true, nil), svc.ErrProvisioningNotAllowed)
+	})
+
+	t.Run("Allowed Groups", func(t *testing.T) {
+		policy := svc.NewProvisioningPolicy(config.ProvisioningConfig{AllowedGroups: []string{"staff", "cn=Admins,ou=groups,dc=example,dc=com"}})
+		assert.NoError(t, policy.Check("", false, []string{"cn=staff,ou=groups,dc=example,dc=com"}))
+		assert.NoError(t, policy.Check("", false, []string{"Staff"}))
+		assert.NoError(t, policy.Check("", false, []string{"CN=admins,OU=groups,DC=example,DC=com"}))
+		assert.ErrorIs(t, policy.Check("", false, []string{"cn=guests,ou=groups,dc=example,dc=com"}), svc.ErrProvisioningNotAllowed)
+		assert.ErrorIs(t, policy.Check("", false, nil), svc.ErrProvisioningNotAllowed)
+	})
+
+	t.Run("Approval And Default Roles", func(t *testing.T) {
+		policy := svc.NewProvisioningPolicy(config.ProvisioningConfig{RequireApproval: true, DefaultRoles: []string{"member"}})
+		assert.NoError(t, policy.Check("alice@example.com", false, nil))
+		assert.EqualValues(t, model.UserStatusPending, policy.InitialStatus())
+		assert.Equal(t, []string{"member"}, policy.DefaultRoles())
+	})
+}
@@ -0,0 +1,325 @@
+package svc_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"auth-service/internal/config"
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	model "auth-service/model/mysql"
+	"auth-service/tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRADIUSSecret = "radius-secret"
+
+func newTestRADIUSServer(t *testing.T) *common.FakeRADIUSServer {
+	return common.NewFakeRADIUSServer(t, testRADIUSSecret, map[string]*common.FakeRADIUSUser{
+		"alice": {Password: "alice-password", Class: []string{"staff"}},
+		"bob":   {Password: "bob-password", OTP: "123456"},
+		"carol": {Password: "a-password-longer-than-one-block"},
+	})
+}
+
+func newTestRADIUSProvider(t *testing.T, cfg config.RADIUSConfig, servers ...*common.FakeRADIUSServer) *svc.RADIUSProvider {
+	cfg.Enabled = true
+	if cfg.Secret == "" {
+		cfg.Secret = testRADIUSSecret
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 1
+	}
+	for _, server := range servers {
+		cfg.Servers = append(cfg.Servers, server.Addr())
+	}
+	provider, err := svc.NewRADIUSProvider(cfg)
+	require.NoError(t, err)
+	return provider
+}
+
+func TestRADIUSProvider(t *testing.T) {
+	ctx := context.Background()
+
+	for _, method := range []string{svc.RADIUSAuthPAP, svc.RADIUSAuthMSCHAPv2} {
+		t.Run(method, func(t *testing.T) {
+			provider := newTestRADIUSProvider(t, config.RADIUSConfig{AuthMethod: method}, newTestRADIUSServer(t))
+
+			result, err := provider.Authenticate(ctx, "alice", "alice-password")
+			require.NoError(t, err)
+			require.NotNil(t, result.User)
+			assert.Equal(t, "alice", result.User.Username)
+			assert.Equal(t, []string{"staff"}, result.User.Groups)
+
+			_, err = provider.Authenticate(ctx, "alice", "wrong")
+			assert.ErrorIs(t, err, svc.ErrRADIUSRejected)
+
+			result, err = provider.Authenticate(ctx, "carol", "a-password-longer-than-one-block")
+			require.NoError(t, err)
+			assert.NotNil(t, result.User)
+		})
+	}
+
+	t.Run("Invalid Config", func(t *testing.T) {
+		_, err := svc.NewRADIUSProvider(config.RADIUSConfig{Enabled: true, Servers: []string{"127.0.0.1"}})
+		assert.Error(t, err, "secret is required")
+		_, err = svc.NewRADIUSProvider(config.RADIUSConfig{Enabled: true, Secret: "s", Servers: []string{"127.0.0.1"}, AuthMethod: "chap"})
+		assert.Error(t, err)
+		_, err = svc.NewRADIUSProvider(config.RADIUSConfig{Enabled: true, Secret: "s"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Access Challenge", func(t *testing.T) {
+		primary, secondary := newTestRADIUSServer(t), newTestRADIUSServer(t)
+		provider := newTestRADIUSProvider(t, config.RADIUSConfig{}, primary, secondary)
+
+		result, err := provider.Authenticate(ctx, "bob", "bob-password")
+		require.NoError(t, err)
+		require.NotNil(t, result.Challenge)
+		assert.Nil(t, result.User)
+		assert.Equal(t, primary.Addr(), result.Challenge.Server)
+		assert.Equal(t, "Enter your one-time password", result.Challenge.Message)
+
+		_, err = provider.RespondChallenge(ctx, result.Challenge, "bob", "000000")
+		assert.ErrorIs(t, err, svc.ErrRADIUSRejected)
+		_, err = provider.RespondChallenge(ctx, result.Challenge, "bob", "123456")
+		assert.ErrorIs(t, err, svc.ErrRADIUSRejected, "the state is single use")
+
+		result, err = provider.Authenticate(ctx, "bob", "bob-password")
+		require.NoError(t, err)
+		result, err = provider.RespondChallenge(ctx, result.Challenge, "bob", "123456")
+		require.NoError(t, err)
+		require.NotNil(t, result.User)
+		assert.Equal(t, "bob", result.User.Username)
+		assert.Zero(t, secondary.Requests(), "the challenge is answered by the server that issued it")
+	})
+
+	t.Run("Failover", func(t *testing.T) {
+		primary, secondary := newTestRADIUSServer(t), newTestRADIUSServer(t)
+		provider := newTestRADIUSProvider(t, config.RADIUSConfig{}, primary, secondary)
+		primary.SetSilent(true)
+
+		result, err := provider.Authenticate(ctx, "alice", "alice-password")
+		require.NoError(t, err)
+		require.NotNil(t, result.User)
+		requests := primary.Requests()
+		assert.NotZero(t, requests)
+
+		// The silent server is skipped while cooling down
+		_, err = provider.Authenticate(ctx, "alice", "alice-password")
+		require.NoError(t, err)
+		assert.Equal(t, requests, primary.Requests())
+		assert.EqualValues(t, 2, secondary.Requests())
+	})
+
+	t.Run("All Servers Down", func(t *testing.T) {
+		server := newTestRADIUSServer(t)
+		server.SetSilent(true)
+		provider := newTestRADIUSProvider(t, config.RADIUSConfig{}, server)
+
+		_, err := provider.Authenticate(ctx, "alice", "alice-password")
+		assert.ErrorIs(t, err, svc.ErrRADIUSUnavailable)
+	})
+
+	t.Run("Wrong Secret", func(t *testing.T) {
+		provider := newTestRADIUSProvider(t, config.RADIUSConfig{Secret: "wrong-secret"}, newTestRADIUSServer(t))
+
+		// The server drops the request and unauthenticated responses would be discarded
+		_, err := provider.Authenticate(ctx, "alice", "alice-password")
+		assert.ErrorIs(t, err, svc.ErrRADIUSUnavailable)
+	})
+
+	t.Run("Message Authenticator", func(t *testing.T) {
+		server := newTestRADIUSServer(t)
+		server.OmitMessageAuthenticator = true
+
+		provider := newTestRADIUSProvider(t, config.RADIUSConfig{}, server)
+		_, err := provider.Authenticate(ctx, "alice", "alice-password")
+		assert.NoError(t, err)
+
+		provider = newTestRADIUSProvider(t, config.RADIUSConfig{RequireMessageAuthenticator: true}, server)
+		_, err = provider.Authenticate(ctx, "alice", "alice-password")
+		assert.ErrorIs(t, err, svc.ErrRADIUSUnavailable)
+	})
+}
+
+func TestRADIUSLogin(t *testing.T) {
+	h := common.NewTestHelper(t)
+	svcCtx := h.SetupServiceContext(true)
+	if svcCtx.Redis == nil {
+		t.Skip("Redis not available")
+	}
+
+	challenge := &svc.RADIUSChallenge{Server: "10.0.0.1:1812", State: []byte("state-1"), Message: "Enter your OTP"}
+	var responded []string
+	mockRADIUS := &common.MockRADIUSClient{
+		IsEnabledFunc: func() bool { return true },
+		AuthenticateFunc: func(ctx context.Context, username, password string) (*svc.RADIUSResult, error) {
+			if password != "bob-password" {
+				return nil, svc.ErrRADIUSRejected
+			}
+			return &svc.RADIUSResult{Challenge: challenge}, nil
+		},
+		RespondChallengeFunc: func(ctx context.Context, c *svc.RADIUSChallenge, username, response string) (*svc.RADIUSResult, error) {
+			responded = append(responded, c.Server+":"+string(c.State)+":"+username+":"+response)
+			if response != "123456" {
+				return nil, svc.ErrRADIUSRejected
+			}
+			return &svc.RADIUSResult{User: &svc.RADIUSUserInfo{Username: "bob"}}, nil
+		},
+	}
+	register := func(policy *svc.ProvisioningPolicy) {
+		svcCtx.IdentityProviders = svc.NewIdentityProviders(svc.IdentityProviderEntry{
+			Type:         types.SSOProviderRADIUS,
+			Provider:     svc.NewRADIUSIdentityProvider(mockRADIUS),
+			Provisioning: policy,
+		})
+	}
+	register(nil)
+	login := func(req *types.RADIUSLoginReq) *types.BaseResponse {
+		resp, err := logic.NewRADIUSLoginLogic(context.Background(), svcCtx).RADIUSLogin(req)
+		require.NoError(t, err)
+		return resp
+	}
+	challengeToken := func() string {
+		resp := login(&types.RADIUSLoginReq{Username: "bob", Password: "bob-password"})
+		require.EqualValues(t, 1039, resp.Code)
+		data := resp.Data.(*types.SSOChallengeResp)
+		assert.Equal(t, "Enter your OTP", data.Message)
+		assert.Equal(t, int64(svc.IdentityChallengeTTL.Seconds()), data.ExpiresIn)
+		return data.ChallengeToken
+	}
+
+	t.Run("Rejected", func(t *testing.T) {
+		assert.EqualValues(t, 1002, login(&types.RADIUSLoginReq{Username: "bob", Password: "wrong"}).Code)
+	})
+
+	t.Run("Challenge Then Login", func(t *testing.T) {
+		// bob 的 RADIUS 账号已关联到本地用户 7
+		identities := []*model.UserIdentity{{Id: 1, UserId: 7, Provider: "radius", ProviderUserId: "bob"}}
+		svcCtx.UserIdentityModel = newIdentityStore(&identities)
+		defer func() { svcCtx.UserIdentityModel = &model.MockUserIdentityModel{} }()
+		token := challengeToken()
+
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(7).
+			WillReturnRows(userRow(7, "bob", ""))
+
+		resp := login(&types.RADIUSLoginReq{Username: "bob", Password: "123456", ChallengeToken: token})
+		require.EqualValues(t, 0, resp.Code, resp.Message)
+		data := resp.Data.(types.RADIUSLoginResp)
+		assert.Equal(t, "bob", data.Username)
+		assert.Equal(t, "radius", data.Provider)
+		assert.NotEmpty(t, data.AccessToken)
+		assert.Equal(t, []string{"10.0.0.1:1812:state-1:bob:123456"}, responded)
+
+		assert.EqualValues(t, 1040, login(&types.RADIUSLoginReq{Username: "bob", Password: "123456", ChallengeToken: token}).Code,
+			"the challenge token is single use")
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+
+	t.Run("Username Match Requires Confirmation", func(t *testing.T) {
+		token := challengeToken()
+
+		// 同名的本地账号有本地密码，需要用它确认所有权后才能关联
+		hash := svcCtx.PasswordEncoder.Hash("local-password")
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+username.+").
+			WithArgs("bob").
+			WillReturnRows(userRow(7, "bob", hash))
+
+		resp := login(&types.RADIUSLoginReq{Username: "bob", Password: "123456", ChallengeToken: token})
+		require.EqualValues(t, 1018, resp.Code, resp.Message)
+		data := resp.Data.(types.SSOLinkRequiredResp)
+		assert.Equal(t, []string{"password"}, data.Methods)
+
+		link, err := svc.GetPendingLink(context.Background(), svcCtx.Redis, data.LinkToken)
+		require.NoError(t, err)
+		assert.Equal(t, "radius", link.Provider)
+		assert.Equal(t, "bob", link.ProviderUserID)
+		assert.EqualValues(t, 7, link.UserID)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+
+	t.Run("Username Match Without Local Password", func(t *testing.T) {
+		token := challengeToken()
+
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+username.+").
+			WithArgs("bob").
+			WillReturnRows(userRow(7, "bob", ""))
+
+		assert.EqualValues(t, 1019, login(&types.RADIUSLoginReq{Username: "bob", Password: "123456", ChallengeToken: token}).Code)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+
+	t.Run("Wrong One-Time Password", func(t *testing.T) {
+		token := challengeToken()
+		assert.EqualValues(t, 1002, login(&types.RADIUSLoginReq{Username: "bob", Password: "000000", ChallengeToken: token}).Code)
+		assert.EqualValues(t, 1040, login(&types.RADIUSLoginReq{Username: "bob", Password: "123456", ChallengeToken: token}).Code)
+	})
+
+	t.Run("Challenge Of Another User", func(t *testing.T) {
+		token := challengeToken()
+		responded = nil
+		assert.EqualValues(t, 1040, login(&types.RADIUSLoginReq{Username: "mallory", Password: "123456", ChallengeToken: token}).Code)
+		assert.Empty(t, responded)
+	})
+
+	t.Run("Provisioning Disabled", func(t *testing.T) {
+		register(svc.NewProvisioningPolicy(config.ProvisioningConfig{Disabled: true}))
+		defer register(nil)
+		token := challengeToken()
+
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+username.+").
+			WithArgs("bob").
+			WillReturnError(model.ErrNotFound)
+
+		assert.EqualValues(t, 1024, login(&types.RADIUSLoginReq{Username: "bob", Password: "123456", ChallengeToken: token}).Code)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+
+	t.Run("Generic Route", func(t *testing.T) {
+		ssoLogin := func(req *types.SSOLoginReq) *types.BaseResponse {
+			resp, err := logic.NewSSOLoginLogic(context.Background(), svcCtx).SSOLogin(req)
+			require.NoError(t, err)
+			return resp
+		}
+
+		resp := ssoLogin(&types.SSOLoginReq{Provider: "radius", Username: "bob", Password: "bob-password"})
+		require.EqualValues(t, 1039, resp.Code)
+		token := resp.Data.(*types.SSOChallengeResp).ChallengeToken
+
+		// The challenge token is bound to the provider that issued it
+		common.SetLDAP(svcCtx, &common.MockLDAPClient{IsEnabledFunc: func() bool { return true }}, nil)
+		assert.EqualValues(t, 1040, ssoLogin(&types.SSOLoginReq{Provider: "ldap", Username: "bob", Password: "123456", ChallengeToken: token}).Code)
+		assert.EqualValues(t, 1001, ssoLogin(&types.SSOLoginReq{Provider: "unknown", Username: "bob", Password: "bob-password"}).Code)
+	})
+
+	t.Run("Rejection Not Mapped As Directory", func(t *testing.T) {
+		// Only the LDAP provider maps directory bind errors to AD reasons and password change tokens
+		mustChange := &svc.LDAPBindError{DN: "uid=bob", SubCode: "773", Reason: svc.ErrLDAPPasswordMustChange}
+		rejecting := &common.MockRADIUSClient{
+			IsEnabledFunc: func() bool { return true },
+			AuthenticateFunc: func(ctx context.Context, username, password string) (*svc.RADIUSResult, error) {
+				return nil, fmt.Errorf("%w: %w", svc.ErrRADIUSRejected, mustChange)
+			},
+		}
+		svcCtx.IdentityProviders = svc.NewIdentityProviders(svc.IdentityProviderEntry{
+			Type:     types.SSOProviderRADIUS,
+			Provider: svc.NewRADIUSIdentityProvider(rejecting),
+		})
+		defer register(nil)
+
+		resp := login(&types.RADIUSLoginReq{Username: "bob", Password: "bob-password"})
+		assert.EqualValues(t, 1002, resp.Code)
+		assert.Nil(t, resp.Data)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		svcCtx.IdentityProviders = svc.NewIdentityProviders()
+		assert.EqualValues(t, 1001, login(&types.RADIUSLoginReq{Username: "bob", Password: "bob-password"}).Code)
+	})
+}
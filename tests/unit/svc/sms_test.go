@@ -0,0 +1,204 @@
+package svc_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/svc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSMSSender(t *testing.T) {
+	sender, err := svc.NewSMSSender(config.SMSConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, sender, "no provider configured")
+
+	_, err = svc.NewSMSSender(config.SMSConfig{Provider: "carrier-pigeon"})
+	assert.Error(t, err)
+	_, err = svc.NewSMSSender(config.SMSConfig{Provider: svc.SMSProviderAliyun})
+	assert.Error(t, err, "credentials are required")
+	_, err = svc.NewSMSSender(config.SMSConfig{Provider: svc.SMSProviderTwilio, Twilio: config.TwilioSMSConfig{AccountSID: "AC1", AuthToken: "token"}})
+	assert.Error(t, err, "a sender number or messaging service is required")
+}
+
+func TestLogSMSSender(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sms.log")
+	sender, err := svc.NewSMSSender(config.SMSConfig{Provider: svc.SMSProviderLog, LogFile: file})
+	require.NoError(t, err)
+
+	require.NoError(t, sender.SendVerificationCode(context.Background(), "13800138000", "123456"))
+	require.NoError(t, sender.SendVerificationCode(context.Background(), "13800138001", "654321"))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.True(t, strings.HasSuffix(lines[0], "\t13800138000\t123456"))
+	assert.True(t, strings.HasSuffix(lines[1], "\t13800138001\t654321"))
+}
+
+func TestAliyunSMSSender(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		if query.Get("PhoneNumbers") == "13800000000" {
+			w.Write([]byte(`{"Code":"isv.BUSINESS_LIMIT_CONTROL","Message":"limited","RequestId":"req-2"}`))
+			return
+		}
+		w.Write([]byte(`{"Code":"OK","Message":"OK","RequestId":"req-1","BizId":"biz"}`))
+	}))
+	defer server.Close()
+
+	sender, err := svc.NewSMSSender(config.SMSConfig{
+		Provider: svc.SMSProviderAliyun,
+		Aliyun: config.AliyunSMSConfig{
+			AccessKeyID:     "test-key",
+			AccessKeySecret: "test-secret",
+			SignName:        "测试签名",
+			TemplateCode:    "SMS_123",
+			Endpoint:        server.URL,
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, sender.SendVerificationCode(context.Background(), "13800138000", "123456"))
+	assert.Equal(t, "SendSms", query.Get("Action"))
+	assert.Equal(t, "test-key", query.Get("AccessKeyId"))
+	assert.Equal(t, "测试签名", query.Get("SignName"))
+	assert.Equal(t, "cn-hangzhou", query.Get("RegionId"))
+	assert.JSONEq(t, `{"code":"123456"}`, query.Get("TemplateParam"))
+	assert.Equal(t, svc.AliyunSignature(http.MethodGet, query, "test-secret"), query.Get("Signature"))
+
+	err = sender.SendVerificationCode(context.Background(), "13800000000", "123456")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "isv.BUSINESS_LIMIT_CONTROL")
+}
+
+func TestAliyunSignature(t *testing.T) {
+	// 阿里云 RPC 签名文档中的示例
+	params := url.Values{
+		"AccessKeyId":      {"testid"},
+		"Action":           {"DescribeRegions"},
+		"Format":           {"XML"},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureNonce":   {"3ee8c1b8-83d3-44af-a94f-4e0ad82fd6cf"},
+		"SignatureVersion": {"1.0"},
+		"Timestamp":        {"2016-02-23T12:46:24Z"},
+		"Version":          {"2014-05-26"},
+	}
+	assert.Equal(t, "OLeaidS1JvxuMvnyHOwuJ+uX5qY=", svc.AliyunSignature(http.MethodGet, params, "testsecret"))
+
+	params.Set("Signature", "ignored")
+	assert.Equal(t, "OLeaidS1JvxuMvnyHOwuJ+uX5qY=", svc.AliyunSignature(http.MethodGet, params, "testsecret"), "the signature itself is not signed")
+}
+
+func TestTencentSMSSender(t *testing.T) {
+	var (
+		headers http.Header
+		payload map[string]interface{}
+		host    string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		host = r.Host
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+
+		ts, _ := strconv.ParseInt(r.Header.Get("X-TC-Timestamp"), 10, 64)
+		if r.Header.Get("Authorization") != svc.TencentAuthorization("test-id", "test-key", r.Host, body, time.Unix(ts, 0)) {
+			w.Write([]byte(`{"Response":{"Error":{"Code":"AuthFailure.SignatureFailure","Message":"bad signature"},"RequestId":"req"}}`))
+			return
+		}
+		if payload["PhoneNumberSet"].([]interface{})[0] == "+8613800000000" {
+			w.Write([]byte(`{"Response":{"SendStatusSet":[{"Code":"LimitExceeded.PhoneNumberDailyLimit","Message":"limited"}],"RequestId":"req"}}`))
+			return
+		}
+		w.Write([]byte(`{"Response":{"SendStatusSet":[{"Code":"Ok","Message":"send success"}],"RequestId":"req"}}`))
+	}))
+	defer server.Close()
+
+	sender, err := svc.NewSMSSender(config.SMSConfig{
+		Provider: svc.SMSProviderTencent,
+		Tencent: config.TencentSMSConfig{
+			SecretID:   "test-id",
+			SecretKey:  "test-key",
+			SdkAppID:   "1400000000",
+			SignName:   "测试签名",
+			TemplateID: "100001",
+			Endpoint:   server.URL,
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, sender.SendVerificationCode(context.Background(), "+8613800138000", "123456"))
+	assert.Equal(t, "SendSms", headers.Get("X-TC-Action"))
+	assert.Equal(t, "2021-01-11", headers.Get("X-TC-Version"))
+	assert.Equal(t, "ap-guangzhou", headers.Get("X-TC-Region"))
+	assert.True(t, strings.HasPrefix(headers.Get("Authorization"), "TC3-HMAC-SHA256 Credential=test-id/"))
+	assert.Equal(t, strings.TrimPrefix(server.URL, "http://"), host)
+	assert.Equal(t, []interface{}{"123456"}, payload["TemplateParamSet"])
+	assert.Equal(t, "1400000000", payload["SmsSdkAppId"])
+
+	err = sender.SendVerificationCode(context.Background(), "+8613800000000", "123456")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "LimitExceeded.PhoneNumberDailyLimit")
+}
+
+func TestTwilioSMSSender(t *testing.T) {
+	var (
+		path string
+		form url.Values
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if user, pass, ok := r.BasicAuth(); !ok || user != "AC123" || pass != "auth-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":20003,"message":"Authenticate","status":401}`))
+			return
+		}
+		r.ParseForm()
+		form = r.PostForm
+		if form.Get("To") == "+15005550001" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":21211,"message":"The 'To' number is not a valid phone number.","status":400}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid":"SM123","status":"queued"}`))
+	}))
+	defer server.Close()
+
+	sender, err := svc.NewSMSSender(config.SMSConfig{
+		Provider: svc.SMSProviderTwilio,
+		Twilio: config.TwilioSMSConfig{
+			AccountSID: "AC123",
+			AuthToken:  "auth-token",
+			From:       "+15005550006",
+			Endpoint:   server.URL,
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, sender.SendVerificationCode(context.Background(), "+8613800138000", "123456"))
+	assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", path)
+	assert.Equal(t, "+8613800138000", form.Get("To"))
+	assert.Equal(t, "+15005550006", form.Get("From"))
+	assert.Equal(t, "Your verification code is 123456", form.Get("Body"))
+
+	err = sender.SendVerificationCode(context.Background(), "+15005550001", "123456")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "21211")
+}
@@ -0,0 +1,152 @@
+package svc_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/tests/common"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsAllowedRedirectURL(t *testing.T) {
+	allowed := []string{"https://app.example.com", "https://portal.example.com/sso/"}
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"/dashboard", true},
+		{"https://app.example.com/callback?x=1", true},
+		{"https://APP.example.com/", true},
+		{"https://portal.example.com/sso/done", true},
+		{"https://portal.example.com/sso", true},
+		{"https://portal.example.com/ssox", false},
+		{"https://portal.example.com/other", false},
+		{"http://app.example.com/callback", false},
+		{"https://app.example.com.evil.com/", false},
+		{"https://user@app.example.com/", false},
+		{"//evil.com/", false},
+		{"/\\evil.com", false},
+		{"/\t/evil.com", false},
+		{"/\n/evil.com", false},
+		{"/ /evil.com", false},
+		{"/path\\..\\\\evil.com", false},
+		{"/\x00/evil.com", false},
+		{"/dashboard?next=/settings#tab", true},
+		{"javascript:alert(1)", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, svc.IsAllowedRedirectURL(allowed, tt.url), tt.url)
+	}
+}
+
+func TestSSOBrowserRedirect(t *testing.T) {
+	h := common.NewTestHelper(t)
+	svcCtx := h.SetupServiceContext(true)
+	if svcCtx.Redis == nil {
+		t.Skip("Redis not available")
+	}
+	svcCtx.Config.SSO.AllowedRedirectURLs = []string{"https://app.example.com"}
+
+	mockOIDC := &common.MockOIDCClient{
+		IsEnabledFunc:           func() bool { return true },
+		GetAuthorizationURLFunc: func(state, nonce, codeChallenge string) string { return "https://idp.example.com/auth" },
+		ExchangeCodeFunc: func(ctx context.Context, code, codeVerifier string) (*svc.OIDCTokenResponse, error) {
+			return &svc.OIDCTokenResponse{AccessToken: "at", IDToken: "id-token"}, nil
+		},
+		VerifyIDTokenFunc: func(ctx context.Context, rawIDToken, nonce string) (*svc.OIDCIDToken, error) {
+			return &svc.OIDCIDToken{Subject: "sub-1", Nonce: nonce}, nil
+		},
+		GetUserInfoFunc: func(ctx context.Context, accessToken string) (*svc.OIDCUserInfo, error) {
+			return &svc.OIDCUserInfo{Sub: "sub-1", Email: "sso@example.com", EmailVerified: true}, nil
+		},
+	}
+	svcCtx.OIDC = svc.NewOIDCProviders(svc.OIDCProviderEntry{Name: svc.DefaultOIDCProviderName, Client: mockOIDC, TrustEmail: true})
+
+	ctx := context.Background()
+
+	t.Run("Login Rejects Unlisted Redirect", func(t *testing.T) {
+		resp, err := logic.NewOIDCLoginLogic(ctx, svcCtx).OIDCLogin(&types.OIDCLoginReq{RedirectURL: "https://evil.example.com/steal"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1008, resp.Code)
+	})
+
+	t.Run("Callback Redirects With One-Time Code", func(t *testing.T) {
+		stateData, _ := json.Marshal(svc.OIDCState{Nonce: "n", CodeVerifier: "v", RedirectURL: "https://app.example.com/sso?from=login"})
+		svcCtx.Redis.Set(ctx, "auth:oidc:state:st-redirect", stateData, time.Minute)
+
+		rows := sqlmock.NewRows([]string{
+			"id", "public_id", "nickname", "username", "email", "email_verified",
+			"phone", "phone_verified", "password_hash", "password_salt", "mfa_secret",
+			"mfa_enabled", "account_status", "failed_login_attempts", "lockout_until",
+			"last_login_at", "created_at", "updated_at", "deleted_at",
+		}).AddRow(
+			5, "pub_id_5", sql.NullString{}, "ssouser", "sso@example.com", 1,
+			sql.NullString{}, 0, "hash", sql.NullString{}, sql.NullString{},
+			0, 1, 0, sql.NullTime{}, sql.NullTime{}, time.Now(), time.Now(), sql.NullTime{},
+		)
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+email.+").
+			WithArgs("sso@example.com").
+			WillReturnRows(rows)
+
+		resp, err := logic.NewOIDCCallbackLogic(ctx, svcCtx).OIDCCallback(&types.OIDCCallbackReq{State: "st-redirect", Code: "auth-code"})
+		require.NoError(t, err)
+
+		redirect, ok := resp.Data.(types.SSORedirectResp)
+		require.True(t, ok, "expected redirect response")
+		location, err := url.Parse(redirect.Location)
+		require.NoError(t, err)
+		assert.Equal(t, "app.example.com", location.Host)
+		assert.Equal(t, "login", location.Query().Get("from"))
+		assert.Empty(t, location.Query().Get("accessToken"), "tokens must not appear in the URL")
+
+		code := location.Query().Get("code")
+		require.NotEmpty(t, code)
+
+		exchange := logic.NewSSOExchangeLogic(ctx, svcCtx)
+		resp, err = exchange.SSOExchange(&types.SSOExchangeReq{Code: code})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code)
+
+		var result types.OIDCCallbackResp
+		require.NoError(t, json.Unmarshal(resp.Data.(json.RawMessage), &result))
+		assert.Equal(t, "ssouser", result.Username)
+		assert.NotEmpty(t, result.AccessToken)
+
+		// 交换码只能使用一次
+		resp, err = exchange.SSOExchange(&types.SSOExchangeReq{Code: code})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1009, resp.Code)
+	})
+
+	t.Run("Callback Redirects With Error", func(t *testing.T) {
+		stateData, _ := json.Marshal(svc.OIDCState{Nonce: "n", RedirectURL: "https://app.example.com/sso"})
+		svcCtx.Redis.Set(ctx, "auth:oidc:state:st-denied", stateData, time.Minute)
+
+		resp, err := logic.NewOIDCCallbackLogic(ctx, svcCtx).OIDCCallback(&types.OIDCCallbackReq{
+			State: "st-denied",
+			Error: "access_denied",
+		})
+		require.NoError(t, err)
+
+		redirect, ok := resp.Data.(types.SSORedirectResp)
+		require.True(t, ok, "expected redirect response")
+		location, err := url.Parse(redirect.Location)
+		require.NoError(t, err)
+		assert.Equal(t, "login_failed", location.Query().Get("error"))
+		assert.Equal(t, "1003", location.Query().Get("error_code"))
+		assert.Empty(t, location.Query().Get("code"))
+	})
+}
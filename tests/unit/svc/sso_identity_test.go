@@ -0,0 +1,463 @@
+package svc_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	model "auth-service/model/mysql"
+	"auth-service/tests/common"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newIdentityStore 基于内存切片的 UserIdentityModel，用于模拟 user_identity 表
+func newIdentityStore(identities *[]*model.UserIdentity) *model.MockUserIdentityModel {
+	return &model.MockUserIdentityModel{
+		InsertFunc: func(ctx context.Context, data *model.UserIdentity) (sql.Result, error) {
+			data.Id = uint64(len(*identities) + 1)
+			*identities = append(*identities, data)
+			return sqlmock.NewResult(int64(data.Id), 1), nil
+		},
+		FindOneByProviderProviderUserIdFunc: func(ctx context.Context, provider, providerUserID string) (*model.UserIdentity, error) {
+			for _, identity := range *identities {
+				if identity.Provider == provider && identity.ProviderUserId == providerUserID {
+					return identity, nil
+				}
+			}
+			return nil, model.ErrNotFound
+		},
+		FindOneByUserIdProviderFunc: func(ctx context.Context, userID uint64, provider string) (*model.UserIdentity, error) {
+			for _, identity := range *identities {
+				if identity.UserId == userID && identity.Provider == provider {
+					return identity, nil
+				}
+			}
+			return nil, model.ErrNotFound
+		},
+		FindAllByUserIdFunc: func(ctx context.Context, userID uint64) ([]*model.UserIdentity, error) {
+			var res []*model.UserIdentity
+			for _, identity := range *identities {
+				if identity.UserId == userID {
+					res = append(res, identity)
+				}
+			}
+			return res, nil
+		},
+		FindAllByProviderFunc: func(ctx context.Context, provider string) ([]*model.UserIdentity, error) {
+			var res []*model.UserIdentity
+			for _, identity := range *identities {
+				if identity.Provider == provider {
+					res = append(res, identity)
+				}
+			}
+			return res, nil
+		},
+		DeleteFunc: func(ctx context.Context, id uint64) error {
+			for i, identity := range *identities {
+				if identity.Id == id {
+					*identities = append((*identities)[:i], (*identities)[i+1:]...)
+					break
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func userRow(id uint64, username, passwordHash string) *sqlmock.Rows {
+	return userRowWithStatus(id, username, passwordHash, model.UserStatusActive)
+}
+
+func userRowWithStatus(id uint64, username, passwordHash string, status uint64) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"id", "public_id", "nickname", "username", "email", "email_verified",
+		"phone", "phone_verified", "password_hash", "password_salt", "mfa_secret",
+		"mfa_enabled", "account_status", "failed_login_attempts", "lockout_until",
+		"last_login_at", "created_at", "updated_at", "deleted_at",
+	}).AddRow(
+		id, "pub_"+username, sql.NullString{}, username, username+"@example.com", 1,
+		sql.NullString{}, 0, passwordHash, sql.NullString{}, sql.NullString{},
+		0, status, 0, sql.NullTime{}, sql.NullTime{}, time.Now(), time.Now(), sql.NullTime{},
+	)
+}
+
+func TestSSOIdentityLinking(t *testing.T) {
+	h := common.NewTestHelper(t)
+	svcCtx := h.SetupServiceContext(true)
+	if svcCtx.Redis == nil {
+		t.Skip("Redis not available")
+	}
+
+	var identities []*model.UserIdentity
+	svcCtx.UserIdentityModel = newIdentityStore(&identities)
+
+	githubUser := &types.SSOUserInfo{Provider: "github", ProviderUserID: "42", Username: "octocat"}
+	mockOAuth2 := &common.MockOAuth2Client{
+		IsEnabledFunc:           func() bool { return true },
+		GetAuthorizationURLFunc: func(state string) string { return "https://github.com/login/oauth/authorize?state=" + state },
+		ExchangeCodeFunc: func(ctx context.Context, code string) (*svc.OAuth2Token, error) {
+			return &svc.OAuth2Token{AccessToken: "gh-token"}, nil
+		},
+		GetUserInfoFunc: func(ctx context.Context, token *svc.OAuth2Token) (*types.SSOUserInfo, error) {
+			return githubUser, nil
+		},
+	}
+	svcCtx.OAuth2 = svc.NewOAuth2Providers(svc.OAuth2ProviderEntry{Type: "github", Client: mockOAuth2})
+	common.SetLDAP(svcCtx, &common.MockLDAPClient{
+		IsEnabledFunc: func() bool { return true },
+		AuthenticateFunc: func(ctx context.Context, username, password string) (*svc.LDAPUserInfo, error) {
+			return &svc.LDAPUserInfo{DN: "UID=Alice,DC=example,DC=com", Username: username, Email: "alice@example.com"}, nil
+		},
+	}, nil)
+
+	ctx := context.WithValue(context.Background(), "userID", int64(7))
+	callback := func(state svc.OAuth2State) *types.BaseResponse {
+		stateData, _ := json.Marshal(state)
+		svcCtx.Redis.Set(ctx, "auth:oauth2:state:st-identity", stateData, time.Minute)
+		resp, err := logic.NewOAuth2CallbackLogic(ctx, svcCtx).OAuth2Callback(&types.OAuth2CallbackReq{
+			Provider: "github", State: "st-identity", Code: "code",
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Link Returns Authorization URL", func(t *testing.T) {
+		resp, err := logic.NewSSOLinkLogic(ctx, svcCtx).SSOLink(&types.SSOLinkReq{Provider: "github"})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code)
+		assert.Contains(t, resp.Data.(types.SSOLinkResp).AuthorizationURL, "https://github.com/login/oauth/authorize")
+	})
+
+	t.Run("Link Via Callback", func(t *testing.T) {
+		resp := callback(svc.OAuth2State{Provider: "github", LinkUserID: 7})
+		require.EqualValues(t, 0, resp.Code)
+		require.Len(t, identities, 1)
+		assert.EqualValues(t, 7, identities[0].UserId)
+		assert.Equal(t, "42", identities[0].ProviderUserId)
+	})
+
+	t.Run("Link Identity Of Another User", func(t *testing.T) {
+		resp := callback(svc.OAuth2State{Provider: "github", LinkUserID: 9})
+		assert.EqualValues(t, 1014, resp.Code)
+		assert.Len(t, identities, 1)
+	})
+
+	t.Run("Link Second Account Of Same Provider", func(t *testing.T) {
+		githubUser = &types.SSOUserInfo{Provider: "github", ProviderUserID: "43", Username: "other"}
+		defer func() { githubUser = &types.SSOUserInfo{Provider: "github", ProviderUserID: "42", Username: "octocat"} }()
+
+		resp := callback(svc.OAuth2State{Provider: "github", LinkUserID: 7})
+		assert.EqualValues(t, 1015, resp.Code)
+	})
+
+	t.Run("Login Resolves Linked User", func(t *testing.T) {
+		// 只按关联的用户 ID 查询，不再按邮箱匹配或创建用户
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(7).
+			WillReturnRows(userRow(7, "alice", ""))
+
+		resp := callback(svc.OAuth2State{Provider: "github"})
+		require.EqualValues(t, 0, resp.Code)
+		data := resp.Data.(types.OAuth2CallbackResp)
+		assert.Equal(t, "alice", data.Username)
+		assert.False(t, data.IsNewUser)
+		assert.True(t, identities[0].LastLoginAt.Valid)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+
+	t.Run("Link LDAP", func(t *testing.T) {
+		resp, err := logic.NewSSOLinkLogic(ctx, svcCtx).SSOLink(&types.SSOLinkReq{Provider: "ldap", Username: "alice", Password: "secret"})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code)
+		assert.Equal(t, "uid=alice,dc=example,dc=com", resp.Data.(types.SSOLinkedAccount).ProviderUserID)
+	})
+
+	t.Run("Linked Accounts", func(t *testing.T) {
+		resp, err := logic.NewSSOLinkedAccountsLogic(ctx, svcCtx).SSOLinkedAccounts()
+		require.NoError(t, err)
+		accounts := resp.Data.(types.SSOLinkedAccountsResp).Accounts
+		require.Len(t, accounts, 2)
+		assert.Equal(t, "github", accounts[0].Provider)
+		assert.NotZero(t, accounts[0].LastLoginAt)
+		assert.Equal(t, "ldap", accounts[1].Provider)
+	})
+
+	t.Run("Unlink", func(t *testing.T) {
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(7).
+			WillReturnRows(userRow(7, "alice", ""))
+
+		resp, err := logic.NewSSOUnlinkLogic(ctx, svcCtx).SSOUnlink(&types.SSOUnlinkReq{Provider: "github"})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code)
+		require.Len(t, identities, 1)
+		assert.Equal(t, "ldap", identities[0].Provider)
+
+		resp, err = logic.NewSSOUnlinkLogic(ctx, svcCtx).SSOUnlink(&types.SSOUnlinkReq{Provider: "github"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1016, resp.Code)
+	})
+
+	t.Run("Unlink Last Login Method", func(t *testing.T) {
+		// 没有本地密码时不能移除唯一的外部身份
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(7).
+			WillReturnRows(userRow(7, "alice", ""))
+
+		resp, err := logic.NewSSOUnlinkLogic(ctx, svcCtx).SSOUnlink(&types.SSOUnlinkReq{Provider: "ldap"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1017, resp.Code)
+		assert.Len(t, identities, 1)
+
+		// 设置了本地密码后可以解除
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(7).
+			WillReturnRows(userRow(7, "alice", "hash"))
+
+		resp, err = logic.NewSSOUnlinkLogic(ctx, svcCtx).SSOUnlink(&types.SSOUnlinkReq{Provider: "ldap"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 0, resp.Code)
+		assert.Empty(t, identities)
+	})
+
+	t.Run("Requires Login", func(t *testing.T) {
+		_, err := logic.NewSSOLinkedAccountsLogic(context.Background(), svcCtx).SSOLinkedAccounts()
+		assert.ErrorIs(t, err, types.ErrUnauthorized)
+	})
+}
+
+func TestSSOSafeLinking(t *testing.T) {
+	h := common.NewTestHelper(t)
+	svcCtx := h.SetupServiceContext(true)
+	if svcCtx.Redis == nil {
+		t.Skip("Redis not available")
+	}
+
+	var identities []*model.UserIdentity
+	svcCtx.UserIdentityModel = newIdentityStore(&identities)
+
+	var sentCode string
+	svcCtx.Email = &common.MockEmailSender{
+		SendVerificationCodeFunc: func(to, code string) error {
+			assert.Equal(t, "alice@example.com", to)
+			sentCode = code
+			return nil
+		},
+	}
+
+	// IdP 未验证邮箱: 任何人都可以在 IdP 注册 alice@example.com
+	mockOIDC := &common.MockOIDCClient{
+		IsEnabledFunc: func() bool { return true },
+		ExchangeCodeFunc: func(ctx context.Context, code, codeVerifier string) (*svc.OIDCTokenResponse, error) {
+			return &svc.OIDCTokenResponse{AccessToken: "at", IDToken: "raw-id-token"}, nil
+		},
+		VerifyIDTokenFunc: func(ctx context.Context, rawIDToken, nonce string) (*svc.OIDCIDToken, error) {
+			return &svc.OIDCIDToken{Subject: "attacker-sub", Nonce: nonce, SessionID: "idp-sid"}, nil
+		},
+		GetUserInfoFunc: func(ctx context.Context, accessToken string) (*svc.OIDCUserInfo, error) {
+			return &svc.OIDCUserInfo{Sub: "attacker-sub", Email: "alice@example.com"}, nil
+		},
+	}
+	svcCtx.OIDC = svc.NewOIDCProviders(svc.OIDCProviderEntry{Name: "keycloak", Client: mockOIDC, TrustEmail: true})
+
+	ctx := context.Background()
+	passwordHash := svcCtx.PasswordEncoder.Hash("alice-password")
+	callback := func(state svc.OIDCState) *types.BaseResponse {
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+email.+").
+			WithArgs("alice@example.com").
+			WillReturnRows(userRow(7, "alice", passwordHash))
+
+		state.Nonce = "nonce"
+		stateData, _ := json.Marshal(state)
+		svcCtx.Redis.Set(ctx, "auth:oidc:state:st-safe-link", stateData, time.Minute)
+		resp, err := logic.NewOIDCCallbackLogic(ctx, svcCtx).OIDCCallback(&types.OIDCCallbackReq{
+			Provider: "keycloak", State: "st-safe-link", Code: "code",
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Unverified Email Requires Confirmation", func(t *testing.T) {
+		resp := callback(svc.OIDCState{Provider: "keycloak"})
+		require.EqualValues(t, 1018, resp.Code)
+		data := resp.Data.(types.SSOLinkRequiredResp)
+		assert.NotEmpty(t, data.LinkToken)
+		assert.Equal(t, "a***@example.com", data.Email)
+		assert.ElementsMatch(t, []string{"password", "email_code"}, data.Methods)
+		assert.Empty(t, identities, "nothing is linked before ownership is proven")
+	})
+
+	t.Run("Browser Flow Carries Link Token", func(t *testing.T) {
+		resp := callback(svc.OIDCState{Provider: "keycloak", RedirectURL: "/sso/done"})
+		require.EqualValues(t, 302, resp.Code)
+		location := resp.Data.(types.SSORedirectResp).Location
+		assert.Contains(t, location, "error_code=1018")
+		assert.Contains(t, location, "link_token=")
+	})
+
+	t.Run("Confirm With Password", func(t *testing.T) {
+		token := callback(svc.OIDCState{Provider: "keycloak"}).Data.(types.SSOLinkRequiredResp).LinkToken
+
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(7).
+			WillReturnRows(userRow(7, "alice", passwordHash))
+		resp, err := logic.NewSSOLinkConfirmLogic(ctx, svcCtx).SSOLinkConfirm(&types.SSOLinkConfirmReq{LinkToken: token, Password: "wrong"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1021, resp.Code)
+
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(7).
+			WillReturnRows(userRow(7, "alice", passwordHash))
+		resp, err = logic.NewSSOLinkConfirmLogic(ctx, svcCtx).SSOLinkConfirm(&types.SSOLinkConfirmReq{LinkToken: token, Password: "alice-password"})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code)
+		data := resp.Data.(types.SSOLinkConfirmResp)
+		assert.Equal(t, "alice", data.Username)
+		assert.NotEmpty(t, data.AccessToken)
+
+		require.Len(t, identities, 1)
+		assert.Equal(t, "attacker-sub", identities[0].ProviderUserId)
+		assert.EqualValues(t, 7, identities[0].UserId)
+
+		// 关联令牌只能使用一次
+		resp, err = logic.NewSSOLinkConfirmLogic(ctx, svcCtx).SSOLinkConfirm(&types.SSOLinkConfirmReq{LinkToken: token, Password: "alice-password"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1020, resp.Code)
+		identities = nil
+	})
+
+	t.Run("Confirm With Email Code", func(t *testing.T) {
+		token := callback(svc.OIDCState{Provider: "keycloak"}).Data.(types.SSOLinkRequiredResp).LinkToken
+
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(7).
+			WillReturnRows(userRow(7, "alice", passwordHash))
+		resp, err := logic.NewSSOLinkCodeLogic(ctx, svcCtx).SSOLinkCode(&types.SSOLinkCodeReq{LinkToken: token})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code)
+		require.Len(t, sentCode, 6)
+
+		// 发送间隔内不能重复发送
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(7).
+			WillReturnRows(userRow(7, "alice", passwordHash))
+		resp, err = logic.NewSSOLinkCodeLogic(ctx, svcCtx).SSOLinkCode(&types.SSOLinkCodeReq{LinkToken: token})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1023, resp.Code)
+
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(7).
+			WillReturnRows(userRow(7, "alice", passwordHash))
+		resp, err = logic.NewSSOLinkConfirmLogic(ctx, svcCtx).SSOLinkConfirm(&types.SSOLinkConfirmReq{LinkToken: token, EmailCode: sentCode})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code)
+		assert.Len(t, identities, 1)
+		identities = nil
+	})
+
+	t.Run("Too Many Failed Attempts", func(t *testing.T) {
+		token := callback(svc.OIDCState{Provider: "keycloak"}).Data.(types.SSOLinkRequiredResp).LinkToken
+
+		for i := 0; i < svc.PendingLinkMaxAttempts; i++ {
+			h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+				WithArgs(7).
+				WillReturnRows(userRow(7, "alice", passwordHash))
+			resp, err := logic.NewSSOLinkConfirmLogic(ctx, svcCtx).SSOLinkConfirm(&types.SSOLinkConfirmReq{LinkToken: token, Password: "guess"})
+			require.NoError(t, err)
+			assert.EqualValues(t, 1021, resp.Code)
+		}
+
+		_, err := svc.GetPendingLink(ctx, svcCtx.Redis, token)
+		assert.ErrorIs(t, err, svc.ErrInvalidLinkToken)
+	})
+
+	t.Run("Concurrent Attempts Are Counted Atomically", func(t *testing.T) {
+		token, err := svc.CreatePendingLink(ctx, svcCtx.Redis, &svc.PendingLink{Provider: "keycloak", ProviderUserID: "sub-1", UserID: 7})
+		require.NoError(t, err)
+
+		const guesses = 20
+		counts := make(chan int64, guesses)
+		var wg sync.WaitGroup
+		for i := 0; i < guesses; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				n, err := svc.CountPendingLinkAttempt(ctx, svcCtx.Redis, token)
+				assert.NoError(t, err)
+				counts <- n
+			}()
+		}
+		wg.Wait()
+		close(counts)
+
+		seen := make(map[int64]bool)
+		for n := range counts {
+			seen[n] = true
+		}
+		assert.Len(t, seen, guesses, "every concurrent attempt gets its own count")
+
+		// 次数已超过上限时，即使密码正确也不再校验
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(7).
+			WillReturnRows(userRow(7, "alice", passwordHash))
+		resp, err := logic.NewSSOLinkConfirmLogic(ctx, svcCtx).SSOLinkConfirm(&types.SSOLinkConfirmReq{LinkToken: token, Password: "alice-password"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1021, resp.Code)
+		assert.Empty(t, identities)
+
+		_, err = svc.GetPendingLink(ctx, svcCtx.Redis, token)
+		assert.ErrorIs(t, err, svc.ErrInvalidLinkToken)
+	})
+
+	t.Run("No Way To Prove Ownership", func(t *testing.T) {
+		svcCtx.Email = nil
+		defer func() { svcCtx.Email = &common.MockEmailSender{} }()
+
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+email.+").
+			WithArgs("alice@example.com").
+			WillReturnRows(userRow(7, "alice", ""))
+		stateData, _ := json.Marshal(svc.OIDCState{Provider: "keycloak", Nonce: "nonce"})
+		svcCtx.Redis.Set(ctx, "auth:oidc:state:st-no-method", stateData, time.Minute)
+		resp, err := logic.NewOIDCCallbackLogic(ctx, svcCtx).OIDCCallback(&types.OIDCCallbackReq{
+			Provider: "keycloak", State: "st-no-method", Code: "code",
+		})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1019, resp.Code)
+	})
+
+	t.Run("OAuth2 Verified Email From Untrusted Provider", func(t *testing.T) {
+		mockOAuth2 := &common.MockOAuth2Client{
+			IsEnabledFunc: func() bool { return true },
+			ExchangeCodeFunc: func(ctx context.Context, code string) (*svc.OAuth2Token, error) {
+				return &svc.OAuth2Token{AccessToken: "gh-token"}, nil
+			},
+			GetUserInfoFunc: func(ctx context.Context, token *svc.OAuth2Token) (*types.SSOUserInfo, error) {
+				return &types.SSOUserInfo{Provider: "github", ProviderUserID: "42", Email: "alice@example.com", EmailVerified: true}, nil
+			},
+		}
+		svcCtx.OAuth2 = svc.NewOAuth2Providers(svc.OAuth2ProviderEntry{Type: "github", Client: mockOAuth2})
+
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+email.+").
+			WithArgs("alice@example.com").
+			WillReturnRows(userRow(7, "alice", passwordHash))
+		stateData, _ := json.Marshal(svc.OAuth2State{Provider: "github"})
+		svcCtx.Redis.Set(ctx, "auth:oauth2:state:st-untrusted", stateData, time.Minute)
+		resp, err := logic.NewOAuth2CallbackLogic(ctx, svcCtx).OAuth2Callback(&types.OAuth2CallbackReq{
+			Provider: "github", State: "st-untrusted", Code: "code",
+		})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1018, resp.Code)
+		assert.Empty(t, identities)
+	})
+
+	assert.NoError(t, h.GetMock().ExpectationsWereMet())
+}
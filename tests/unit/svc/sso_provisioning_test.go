@@ -0,0 +1,269 @@
+package svc_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	model "auth-service/model/mysql"
+	"auth-service/tests/common"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSOProvisioning(t *testing.T) {
+	h := common.NewTestHelper(t)
+	svcCtx := h.SetupServiceContext(true)
+	if svcCtx.Redis == nil {
+		t.Skip("Redis not available")
+	}
+
+	var identities []*model.UserIdentity
+	svcCtx.UserIdentityModel = newIdentityStore(&identities)
+
+	var roles []*model.UserRole
+	svcCtx.UserRoleModel = &model.MockUserRoleModel{
+		InsertFunc: func(ctx context.Context, data *model.UserRole) (sql.Result, error) {
+			roles = append(roles, data)
+			return sqlmock.NewResult(int64(len(roles)), 1), nil
+		},
+		FindOneByUserIdRoleFunc: func(ctx context.Context, userID uint64, role string) (*model.UserRole, error) {
+			for _, r := range roles {
+				if r.UserId == userID && r.Role == role {
+					return r, nil
+				}
+			}
+			return nil, model.ErrNotFound
+		},
+		FindAllByUserIdFunc: func(ctx context.Context, userID uint64) ([]*model.UserRole, error) {
+			var resp []*model.UserRole
+			for _, r := range roles {
+				if r.UserId == userID {
+					resp = append(resp, r)
+				}
+			}
+			return resp, nil
+		},
+	}
+
+	groups := []string{"cn=staff,ou=groups,dc=example,dc=com"}
+	common.SetLDAP(svcCtx, &common.MockLDAPClient{
+		IsEnabledFunc: func() bool { return true },
+		AuthenticateFunc: func(ctx context.Context, username, password string) (*svc.LDAPUserInfo, error) {
+			return &svc.LDAPUserInfo{DN: "uid=" + username + ",dc=example,dc=com", Username: username, Email: username + "@example.com", Groups: groups}, nil
+		},
+	}, nil)
+
+	ctx := context.Background()
+	ldapLogin := func(username string) *types.BaseResponse {
+		resp, err := logic.NewLDAPLoginLogic(ctx, svcCtx).LDAPLogin(&types.LDAPLoginReq{Username: username, Password: "secret"})
+		require.NoError(t, err)
+		return resp
+	}
+	expectUnknownUser := func(username string) {
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+username.+").
+			WithArgs(username).
+			WillReturnError(sql.ErrNoRows)
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+email.+").
+			WithArgs(username + "@example.com").
+			WillReturnError(sql.ErrNoRows)
+	}
+
+	t.Run("Provisioning Disabled", func(t *testing.T) {
+		common.SetLDAP(svcCtx, svcCtx.LDAP, svc.NewProvisioningPolicy(config.ProvisioningConfig{Disabled: true}))
+		expectUnknownUser("carol")
+
+		resp := ldapLogin("carol")
+		assert.EqualValues(t, 1024, resp.Code)
+		assert.Empty(t, identities)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+
+	t.Run("Group Not Allowed", func(t *testing.T) {
+		common.SetLDAP(svcCtx, svcCtx.LDAP, svc.NewProvisioningPolicy(config.ProvisioningConfig{AllowedGroups: []string{"admins"}}))
+		expectUnknownUser("carol")
+
+		resp := ldapLogin("carol")
+		assert.EqualValues(t, 1025, resp.Code)
+		assert.Empty(t, identities)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+
+	t.Run("New Account Pending Approval", func(t *testing.T) {
+		common.SetLDAP(svcCtx, svcCtx.LDAP, svc.NewProvisioningPolicy(config.ProvisioningConfig{
+			AllowedGroups:   []string{"staff"},
+			RequireApproval: true,
+			DefaultRoles:    []string{"member"},
+		}))
+		expectUnknownUser("carol")
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+username.+").
+			WithArgs("carol").
+			WillReturnError(sql.ErrNoRows)
+		h.GetMock().ExpectExec("(?i)insert into.+user").
+			WillReturnResult(sqlmock.NewResult(8, 1))
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(8).
+			WillReturnRows(userRowWithStatus(8, "carol", "", model.UserStatusPending))
+
+		resp := ldapLogin("carol")
+		assert.EqualValues(t, 1026, resp.Code)
+		require.Len(t, roles, 1)
+		assert.EqualValues(t, 8, roles[0].UserId)
+		assert.Equal(t, "member", roles[0].Role)
+		assert.Equal(t, model.RoleSourceProvisioning, roles[0].Source)
+		// 身份已关联，审批通过后直接登录
+		require.Len(t, identities, 1)
+		assert.EqualValues(t, 8, identities[0].UserId)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+
+	t.Run("Pending Account Cannot Sign In", func(t *testing.T) {
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(8).
+			WillReturnRows(userRowWithStatus(8, "carol", "", model.UserStatusPending))
+
+		resp := ldapLogin("carol")
+		assert.EqualValues(t, 1026, resp.Code)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+
+	t.Run("Admin Approval", func(t *testing.T) {
+		adminCtx := context.WithValue(ctx, "userID", int64(1))
+
+		// 非管理员不能审批
+		_, err := logic.NewApproveUserLogic(adminCtx, svcCtx).ApproveUser(&types.ReviewUserReq{UserID: "pub_carol"})
+		assert.ErrorIs(t, err, types.ErrForbidden)
+
+		roles = append(roles, &model.UserRole{UserId: 1, Role: model.RoleAdmin, Source: model.RoleSourceManual})
+
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+account_status.+").
+			WithArgs(model.UserStatusPending).
+			WillReturnRows(userRowWithStatus(8, "carol", "", model.UserStatusPending))
+		resp, err := logic.NewListPendingUsersLogic(adminCtx, svcCtx).ListPendingUsers()
+		require.NoError(t, err)
+		users := resp.Data.(types.PendingUsersResp).Users
+		require.Len(t, users, 1)
+		assert.Equal(t, "pub_carol", users[0].UserID)
+		assert.Equal(t, []string{"ldap"}, users[0].Providers)
+
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+public_id.+").
+			WithArgs("pub_carol").
+			WillReturnRows(userRowWithStatus(8, "carol", "", model.UserStatusPending))
+		h.GetMock().ExpectExec("(?i)update.+user.+set").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		resp, err = logic.NewApproveUserLogic(adminCtx, svcCtx).ApproveUser(&types.ReviewUserReq{UserID: "pub_carol"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 0, resp.Code)
+
+		// 已审批的用户不能再次审批或拒绝
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+public_id.+").
+			WithArgs("pub_carol").
+			WillReturnRows(userRow(8, "carol", ""))
+		resp, err = logic.NewRejectUserLogic(adminCtx, svcCtx).RejectUser(&types.ReviewUserReq{UserID: "pub_carol"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1028, resp.Code)
+
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(8).
+			WillReturnRows(userRow(8, "carol", ""))
+		resp = ldapLogin("carol")
+		assert.EqualValues(t, 0, resp.Code)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+
+	t.Run("Rejected Account Cannot Sign In", func(t *testing.T) {
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(8).
+			WillReturnRows(userRowWithStatus(8, "carol", "", model.UserStatusDisabled))
+
+		resp := ldapLogin("carol")
+		assert.EqualValues(t, 1027, resp.Code)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+
+	t.Run("OIDC Unverified Email Outside Allowed Domain", func(t *testing.T) {
+		mockOIDC := &common.MockOIDCClient{
+			IsEnabledFunc: func() bool { return true },
+			ExchangeCodeFunc: func(ctx context.Context, code, codeVerifier string) (*svc.OIDCTokenResponse, error) {
+				return &svc.OIDCTokenResponse{AccessToken: "at", IDToken: "raw-id-token"}, nil
+			},
+			VerifyIDTokenFunc: func(ctx context.Context, rawIDToken, nonce string) (*svc.OIDCIDToken, error) {
+				return &svc.OIDCIDToken{Subject: "dave-sub", Nonce: nonce}, nil
+			},
+			GetUserInfoFunc: func(ctx context.Context, accessToken string) (*svc.OIDCUserInfo, error) {
+				return &svc.OIDCUserInfo{Sub: "dave-sub", Email: "dave@example.com"}, nil
+			},
+		}
+		svcCtx.OIDC = svc.NewOIDCProviders(svc.OIDCProviderEntry{
+			Name:         "keycloak",
+			Client:       mockOIDC,
+			Provisioning: svc.NewProvisioningPolicy(config.ProvisioningConfig{AllowedDomains: []string{"example.com"}}),
+		})
+
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+email.+").
+			WithArgs("dave@example.com").
+			WillReturnError(sql.ErrNoRows)
+		stateData, _ := json.Marshal(svc.OIDCState{Provider: "keycloak", Nonce: "nonce"})
+		svcCtx.Redis.Set(ctx, "auth:oidc:state:st-provision", stateData, time.Minute)
+		resp, err := logic.NewOIDCCallbackLogic(ctx, svcCtx).OIDCCallback(&types.OIDCCallbackReq{
+			Provider: "keycloak", State: "st-provision", Code: "code",
+		})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1025, resp.Code)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+	})
+	t.Run("OIDC New Account Carries Default Roles", func(t *testing.T) {
+		mockOIDC := &common.MockOIDCClient{
+			IsEnabledFunc: func() bool { return true },
+			ExchangeCodeFunc: func(ctx context.Context, code, codeVerifier string) (*svc.OIDCTokenResponse, error) {
+				return &svc.OIDCTokenResponse{AccessToken: "at", IDToken: "raw-id-token"}, nil
+			},
+			VerifyIDTokenFunc: func(ctx context.Context, rawIDToken, nonce string) (*svc.OIDCIDToken, error) {
+				return &svc.OIDCIDToken{Subject: "erin-sub", Nonce: nonce}, nil
+			},
+			GetUserInfoFunc: func(ctx context.Context, accessToken string) (*svc.OIDCUserInfo, error) {
+				return &svc.OIDCUserInfo{Sub: "erin-sub", PreferredUsername: "erin", Email: "erin@example.com", EmailVerified: true}, nil
+			},
+		}
+		svcCtx.OIDC = svc.NewOIDCProviders(svc.OIDCProviderEntry{
+			Name:         "keycloak",
+			Client:       mockOIDC,
+			Provisioning: svc.NewProvisioningPolicy(config.ProvisioningConfig{DefaultRoles: []string{"member"}}),
+		})
+
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+email.+").
+			WithArgs("erin@example.com").
+			WillReturnError(sql.ErrNoRows)
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+username.+").
+			WithArgs("erin").
+			WillReturnError(sql.ErrNoRows)
+		h.GetMock().ExpectExec("(?i)insert into.+user").
+			WillReturnResult(sqlmock.NewResult(9, 1))
+		h.GetMock().ExpectQuery("(?i)select.+from.+user.+where.+id.+").
+			WithArgs(9).
+			WillReturnRows(userRow(9, "erin", ""))
+
+		stateData, _ := json.Marshal(svc.OIDCState{Provider: "keycloak", Nonce: "nonce"})
+		svcCtx.Redis.Set(ctx, "auth:oidc:state:st-default-roles", stateData, time.Minute)
+		resp, err := logic.NewOIDCCallbackLogic(ctx, svcCtx).OIDCCallback(&types.OIDCCallbackReq{
+			Provider: "keycloak", State: "st-default-roles", Code: "code",
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code, resp.Message)
+		assert.NoError(t, h.GetMock().ExpectationsWereMet())
+
+		// 开通时分配的默认角色写入令牌
+		data := resp.Data.(types.OIDCCallbackResp)
+		claims, err := svcCtx.JWT.VerifyAccessToken(data.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"member"}, claims.Roles)
+	})
+}
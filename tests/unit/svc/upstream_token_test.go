@@ -0,0 +1,140 @@
+package svc_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+	"auth-service/internal/logic"
+	"auth-service/internal/svc"
+	"auth-service/internal/types"
+	"auth-service/tests/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenCipher(t *testing.T) {
+	cipher, err := svc.NewTokenCipher("test-encryption-key")
+	require.NoError(t, err)
+
+	encrypted, err := cipher.Encrypt([]byte("secret-token"))
+	require.NoError(t, err)
+	assert.NotContains(t, encrypted, "secret-token")
+
+	plaintext, err := cipher.Decrypt(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-token", string(plaintext))
+
+	other, err := svc.NewTokenCipher("another-key")
+	require.NoError(t, err)
+	_, err = other.Decrypt(encrypted)
+	assert.Error(t, err, "decrypting with the wrong key must fail")
+
+	_, err = svc.NewTokenCipher("")
+	assert.Error(t, err)
+}
+
+func TestUpstreamTokenBroker(t *testing.T) {
+	h := common.NewTestHelper(t)
+	svcCtx := h.SetupServiceContext(true)
+	if svcCtx.Redis == nil {
+		t.Skip("Redis not available")
+	}
+
+	cipher, err := svc.NewTokenCipher("test-encryption-key")
+	require.NoError(t, err)
+	store := svc.NewUpstreamTokenStore(svcCtx.Redis, cipher, time.Hour)
+	svcCtx.UpstreamTokens = store
+	svcCtx.Config.SSO.TokenBroker.Clients = []config.TokenBrokerClient{
+		{ClientID: "reports", ClientSecret: "reports-secret", Providers: []string{"keycloak"}},
+	}
+
+	var refreshCalls int32
+	mockOIDC := &common.MockOIDCClient{
+		IsEnabledFunc: func() bool { return true },
+		RefreshAccessTokenFunc: func(ctx context.Context, refreshToken string) (*svc.OIDCTokenResponse, error) {
+			atomic.AddInt32(&refreshCalls, 1)
+			assert.Equal(t, "upstream-rt", refreshToken)
+			return &svc.OIDCTokenResponse{AccessToken: "upstream-at-2", TokenType: "Bearer", ExpiresIn: 300}, nil
+		},
+	}
+	svcCtx.OIDC = svc.NewOIDCProviders(svc.OIDCProviderEntry{Name: "keycloak", Client: mockOIDC})
+
+	ctx := context.WithValue(context.Background(), "userID", int64(7))
+
+	t.Run("Stored Encrypted", func(t *testing.T) {
+		require.NoError(t, store.Save(ctx, 7, "keycloak", &svc.UpstreamToken{AccessToken: "upstream-at-1", RefreshToken: "upstream-rt"}))
+
+		raw, err := svcCtx.Redis.Get(ctx, "auth:sso:upstream:keycloak:7").Result()
+		require.NoError(t, err)
+		assert.NotContains(t, raw, "upstream-at-1")
+		assert.NotContains(t, raw, "upstream-rt")
+	})
+
+	t.Run("Rejects Unknown Client", func(t *testing.T) {
+		resp, err := logic.NewUpstreamTokenLogic(ctx, svcCtx).UpstreamToken(&types.UpstreamTokenReq{
+			Provider: "keycloak", ClientID: "reports", ClientSecret: "wrong",
+		})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1012, resp.Code)
+	})
+
+	t.Run("Returns Valid Token Without Refresh", func(t *testing.T) {
+		resp, err := logic.NewUpstreamTokenLogic(ctx, svcCtx).UpstreamToken(&types.UpstreamTokenReq{
+			Provider: "keycloak", ClientID: "reports", ClientSecret: "reports-secret",
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code)
+		assert.Equal(t, "upstream-at-1", resp.Data.(types.UpstreamTokenResp).AccessToken)
+		assert.EqualValues(t, 0, atomic.LoadInt32(&refreshCalls))
+	})
+
+	t.Run("Refreshes Expiring Token", func(t *testing.T) {
+		require.NoError(t, store.Save(ctx, 7, "keycloak", &svc.UpstreamToken{
+			AccessToken:  "upstream-at-1",
+			RefreshToken: "upstream-rt",
+			IDToken:      "upstream-id",
+			ExpiresAt:    time.Now().Add(10 * time.Second).Unix(),
+		}))
+
+		resp, err := logic.NewUpstreamTokenLogic(ctx, svcCtx).UpstreamToken(&types.UpstreamTokenReq{
+			Provider: "keycloak", ClientID: "reports", ClientSecret: "reports-secret",
+		})
+		require.NoError(t, err)
+		require.EqualValues(t, 0, resp.Code)
+		data := resp.Data.(types.UpstreamTokenResp)
+		assert.Equal(t, "upstream-at-2", data.AccessToken)
+		assert.Greater(t, data.ExpiresAt, time.Now().Unix())
+		assert.EqualValues(t, 1, atomic.LoadInt32(&refreshCalls))
+
+		// 未轮换的刷新令牌和 ID Token 被保留
+		stored, err := store.Get(ctx, 7, "keycloak")
+		require.NoError(t, err)
+		assert.Equal(t, "upstream-rt", stored.RefreshToken)
+		assert.Equal(t, "upstream-id", stored.IDToken)
+	})
+
+	t.Run("Expired Without Refresh Token", func(t *testing.T) {
+		require.NoError(t, store.Save(ctx, 7, "keycloak", &svc.UpstreamToken{
+			AccessToken: "upstream-at-1",
+			ExpiresAt:   time.Now().Add(-time.Minute).Unix(),
+		}))
+
+		_, err := store.GetFresh(ctx, 7, "keycloak", mockOIDC)
+		assert.ErrorIs(t, err, svc.ErrUpstreamTokenExpired)
+
+		resp, err := logic.NewUpstreamTokenLogic(ctx, svcCtx).UpstreamToken(&types.UpstreamTokenReq{
+			Provider: "keycloak", ClientID: "reports", ClientSecret: "reports-secret",
+		})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1013, resp.Code)
+	})
+
+	t.Run("Not Linked", func(t *testing.T) {
+		_, err := store.Get(ctx, 8, "keycloak")
+		assert.ErrorIs(t, err, svc.ErrUpstreamTokenNotFound)
+	})
+}